@@ -0,0 +1,90 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+// Package atomicfile writes the broker's store files so that a reader, or a
+// broker restarting after a crash, finds either the old or the new contents.
+package atomicfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File skips writes of the contents it last wrote. Encode, when set, turns
+// the contents into the bytes written, for example by encrypting them.
+type File struct {
+	path    string
+	encode  func([]byte) ([]byte, error)
+	written []byte
+}
+
+func New(path string, encode func([]byte) ([]byte, error)) *File {
+	return &File{path: path, encode: encode}
+}
+
+func (f *File) Write(contents []byte) error {
+	if f.written != nil && bytes.Equal(contents, f.written) {
+		return nil
+	}
+
+	data := contents
+	if f.encode != nil {
+		var err error
+		if data, err = f.encode(contents); err != nil {
+			return err
+		}
+	}
+
+	if err := Write(f.path, data); err != nil {
+		return err
+	}
+
+	f.written = contents
+	return nil
+}
+
+// Write writes data to a temporary file next to path and renames it over
+// path. The temporary file is synced before the rename, and the directory
+// after it, so that the rename cannot reach the disk before the data does
+// and is not lost once Write has returned.
+func Write(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmpFile, err := ioutil.TempFile(dir, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
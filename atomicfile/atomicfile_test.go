@@ -0,0 +1,87 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package atomicfile_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/atomicfile"
+)
+
+var _ = Describe("File", func() {
+	var (
+		dir  string
+		path string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "atomicfile")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "store.json")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("replaces the contents of the file", func() {
+		file := atomicfile.New(path, nil)
+		Expect(file.Write([]byte("first"))).To(Succeed())
+		Expect(file.Write([]byte("second"))).To(Succeed())
+
+		Expect(ioutil.ReadFile(path)).To(Equal([]byte("second")))
+	})
+
+	It("leaves no temporary files behind", func() {
+		Expect(atomicfile.New(path, nil).Write([]byte("contents"))).To(Succeed())
+
+		entries, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("does not write the contents it last wrote again", func() {
+		file := atomicfile.New(path, nil)
+		Expect(file.Write([]byte("contents"))).To(Succeed())
+		Expect(os.Remove(path)).To(Succeed())
+
+		Expect(file.Write([]byte("contents"))).To(Succeed())
+
+		_, err := os.Stat(path)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("writes the encoded contents", func() {
+		file := atomicfile.New(path, func(contents []byte) ([]byte, error) {
+			return append([]byte("encoded "), contents...), nil
+		})
+		Expect(file.Write([]byte("contents"))).To(Succeed())
+
+		Expect(ioutil.ReadFile(path)).To(Equal([]byte("encoded contents")))
+	})
+
+	It("writes the contents again after a failed write", func() {
+		encodeErr := errors.New("encoding failed")
+		file := atomicfile.New(path, func(contents []byte) ([]byte, error) {
+			if encodeErr != nil {
+				return nil, encodeErr
+			}
+			return contents, nil
+		})
+		Expect(file.Write([]byte("contents"))).To(MatchError("encoding failed"))
+
+		encodeErr = nil
+		Expect(file.Write([]byte("contents"))).To(Succeed())
+		Expect(ioutil.ReadFile(path)).To(Equal([]byte("contents")))
+	})
+})
@@ -0,0 +1,83 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+// Package auditlog records the actions the broker forces through on behalf of
+// operators and admins, such as skipping a failed pre-delete errand.
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type Entry struct {
+	Time       time.Time
+	InstanceID string
+	Action     string
+}
+
+// FileLog appends each entry to a file as a line of JSON. Entries outlive the
+// instances they are about, which are dropped from the broker's other stores
+// once deleted. With an empty path nothing is written.
+type FileLog struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFileLog(path string) (*FileLog, error) {
+	l := &FileLog{path: path}
+	if path == "" {
+		return l, nil
+	}
+
+	f, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+	return l, f.Close()
+}
+
+// Record appends the entry and syncs the file, so that a recorded action is
+// not lost if the broker crashes.
+func (l *FileLog) Record(entry Entry) error {
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	f, err := l.open()
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing audit log %s: %s", l.path, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("writing audit log %s: %s", l.path, err)
+	}
+	return f.Close()
+}
+
+func (l *FileLog) open() (*os.File, error) {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log %s: %s", l.path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package auditlog_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/auditlog"
+)
+
+var _ = Describe("FileLog", func() {
+	var (
+		logDir  string
+		logPath string
+	)
+
+	BeforeEach(func() {
+		var err error
+		logDir, err = ioutil.TempDir("", "audit-log")
+		Expect(err).NotTo(HaveOccurred())
+		logPath = filepath.Join(logDir, "audit.log")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(logDir)).To(Succeed())
+	})
+
+	readEntries := func() []auditlog.Entry {
+		f, err := os.Open(logPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+
+		var entries []auditlog.Entry
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry auditlog.Entry
+			Expect(json.Unmarshal(scanner.Bytes(), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		Expect(scanner.Err()).NotTo(HaveOccurred())
+		return entries
+	}
+
+	It("appends each entry as a line of JSON", func() {
+		now := time.Now().UTC().Truncate(time.Second)
+
+		l, err := auditlog.NewFileLog(logPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Record(auditlog.Entry{Time: now, InstanceID: "instance-1", Action: "purge requested by the operator"})).To(Succeed())
+		Expect(l.Record(auditlog.Entry{Time: now, InstanceID: "instance-1", Action: "force deleting the deployment"})).To(Succeed())
+
+		Expect(readEntries()).To(Equal([]auditlog.Entry{
+			{Time: now, InstanceID: "instance-1", Action: "purge requested by the operator"},
+			{Time: now, InstanceID: "instance-1", Action: "force deleting the deployment"},
+		}))
+	})
+
+	It("keeps the entries recorded before a restart", func() {
+		l, err := auditlog.NewFileLog(logPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Record(auditlog.Entry{InstanceID: "instance-1", Action: "first"})).To(Succeed())
+
+		reopened, err := auditlog.NewFileLog(logPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reopened.Record(auditlog.Entry{InstanceID: "instance-2", Action: "second"})).To(Succeed())
+
+		entries := readEntries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Action).To(Equal("first"))
+		Expect(entries[1].Action).To(Equal("second"))
+	})
+
+	It("returns an error when the file cannot be opened", func() {
+		_, err := auditlog.NewFileLog(filepath.Join(logDir, "missing-dir", "audit.log"))
+		Expect(err).To(MatchError(ContainSubstring("opening audit log")))
+	})
+
+	Context("when no path is configured", func() {
+		It("records nothing", func() {
+			l, err := auditlog.NewFileLog("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(l.Record(auditlog.Entry{InstanceID: "instance-1", Action: "purge"})).To(Succeed())
+		})
+	})
+})
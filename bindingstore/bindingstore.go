@@ -0,0 +1,77 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package bindingstore
+
+import "sync"
+
+type Binding struct {
+	InstanceID    string
+	BindingID     string
+	RequestParams map[string]interface{} `json:",omitempty"`
+
+	// Created is false while the binding is still being created
+	// asynchronously, and its credentials are not yet known.
+	Created         bool
+	Credentials     map[string]interface{} `json:",omitempty"`
+	SyslogDrainURL  string                 `json:",omitempty"`
+	RouteServiceURL string                 `json:",omitempty"`
+}
+
+type MemoryStore struct {
+	mutex    sync.Mutex
+	bindings map[string]Binding
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{bindings: map[string]Binding{}}
+}
+
+func (s *MemoryStore) Save(binding Binding) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.bindings[key(binding.InstanceID, binding.BindingID)] = binding
+	return nil
+}
+
+func (s *MemoryStore) Get(instanceID, bindingID string) (Binding, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	binding, found := s.bindings[key(instanceID, bindingID)]
+	return binding, found, nil
+}
+
+func (s *MemoryStore) Delete(instanceID, bindingID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.bindings, key(instanceID, bindingID))
+	return nil
+}
+
+// DiscardStore keeps no bindings. It is used when no binding store path is
+// configured, so that binding credentials are not held in memory only to be
+// lost on restart. Adapters are then not given the credentials of the
+// bindings they delete.
+type DiscardStore struct{}
+
+func (DiscardStore) Save(Binding) error {
+	return nil
+}
+
+func (DiscardStore) Get(string, string) (Binding, bool, error) {
+	return Binding{}, false, nil
+}
+
+func (DiscardStore) Delete(string, string) error {
+	return nil
+}
+
+func key(instanceID, bindingID string) string {
+	return instanceID + "/" + bindingID
+}
@@ -0,0 +1,30 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package bindingstore_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
+)
+
+var _ = Describe("DiscardStore", func() {
+	It("does not keep saved bindings", func() {
+		store := bindingstore.DiscardStore{}
+		Expect(store.Save(bindingstore.Binding{
+			InstanceID:  "some-instance",
+			BindingID:   "some-binding",
+			Created:     true,
+			Credentials: map[string]interface{}{"user": "some-user"},
+		})).To(Succeed())
+
+		_, found, err := store.Get("some-instance", "some-binding")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+		Expect(store.Delete("some-instance", "some-binding")).To(Succeed())
+	})
+})
@@ -0,0 +1,122 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package bindingstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pivotal-cf/on-demand-service-broker/atomicfile"
+	"github.com/pivotal-cf/on-demand-service-broker/storecipher"
+)
+
+// EncryptedFileStore keeps bindings in memory and writes them to a file,
+// encrypted with the configured encryption key, after every change.
+type EncryptedFileStore struct {
+	path     string
+	file     *atomicfile.File
+	cipher   *storecipher.Cipher
+	mutex    sync.Mutex
+	bindings map[string]Binding
+}
+
+func NewEncryptedFileStore(path, encryptionKey string) (*EncryptedFileStore, error) {
+	if encryptionKey == "" {
+		return nil, errors.New("binding store encryption key can't be empty")
+	}
+
+	c, err := storecipher.New(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &EncryptedFileStore{path: path, cipher: c, bindings: map[string]Binding{}}
+	s.file = atomicfile.New(path, c.Encrypt)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading binding store %s: %s", path, err)
+	}
+
+	if len(data) > 0 {
+		plaintext, err := s.cipher.Decrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting binding store %s: %s", path, err)
+		}
+		if err := json.Unmarshal(plaintext, &s.bindings); err != nil {
+			return nil, fmt.Errorf("parsing binding store %s: %s", path, err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *EncryptedFileStore) Save(binding Binding) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	k := key(binding.InstanceID, binding.BindingID)
+	previous, existed := s.bindings[k]
+	s.bindings[k] = binding
+
+	if err := s.persist(); err != nil {
+		if existed {
+			s.bindings[k] = previous
+		} else {
+			delete(s.bindings, k)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *EncryptedFileStore) Get(instanceID, bindingID string) (Binding, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	binding, found := s.bindings[key(instanceID, bindingID)]
+	return binding, found, nil
+}
+
+func (s *EncryptedFileStore) Delete(instanceID, bindingID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	k := key(instanceID, bindingID)
+	previous, existed := s.bindings[k]
+	if !existed {
+		return nil
+	}
+	delete(s.bindings, k)
+
+	if err := s.persist(); err != nil {
+		s.bindings[k] = previous
+		return err
+	}
+
+	return nil
+}
+
+func (s *EncryptedFileStore) persist() error {
+	plaintext, err := json.Marshal(s.bindings)
+	if err != nil {
+		return err
+	}
+
+	if err := s.file.Write(plaintext); err != nil {
+		return fmt.Errorf("writing binding store %s: %s", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package bindingstore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
+)
+
+var _ = Describe("EncryptedFileStore", func() {
+	const encryptionKey = "some-encryption-key"
+
+	var (
+		storeDir  string
+		storePath string
+		store     *bindingstore.EncryptedFileStore
+
+		binding = bindingstore.Binding{
+			InstanceID:    "some-instance",
+			BindingID:     "some-binding",
+			RequestParams: map[string]interface{}{"app_guid": "some-app"},
+			Created:       true,
+			Credentials:   map[string]interface{}{"password": "super-secret-password"},
+		}
+	)
+
+	BeforeEach(func() {
+		var err error
+		storeDir, err = ioutil.TempDir("", "binding-store")
+		Expect(err).NotTo(HaveOccurred())
+		storePath = filepath.Join(storeDir, "bindings")
+
+		store, err = bindingstore.NewEncryptedFileStore(storePath, encryptionKey)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(storeDir)).To(Succeed())
+	})
+
+	It("returns a saved binding", func() {
+		Expect(store.Save(binding)).To(Succeed())
+
+		actual, found, err := store.Get("some-instance", "some-binding")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(actual).To(Equal(binding))
+	})
+
+	It("does not write credentials in plain text", func() {
+		Expect(store.Save(binding)).To(Succeed())
+
+		data, err := ioutil.ReadFile(storePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("super-secret-password"))
+		Expect(string(data)).NotTo(ContainSubstring("some-binding"))
+	})
+
+	It("reloads bindings from disk", func() {
+		Expect(store.Save(binding)).To(Succeed())
+
+		reloaded, err := bindingstore.NewEncryptedFileStore(storePath, encryptionKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		actual, found, err := reloaded.Get("some-instance", "some-binding")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(actual).To(Equal(binding))
+	})
+
+	It("forgets a deleted binding across reloads", func() {
+		Expect(store.Save(binding)).To(Succeed())
+		Expect(store.Delete("some-instance", "some-binding")).To(Succeed())
+
+		reloaded, err := bindingstore.NewEncryptedFileStore(storePath, encryptionKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, found, err := reloaded.Get("some-instance", "some-binding")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("cannot be opened with a different encryption key", func() {
+		Expect(store.Save(binding)).To(Succeed())
+
+		_, err := bindingstore.NewEncryptedFileStore(storePath, "another-key")
+		Expect(err).To(MatchError(ContainSubstring("decrypting binding store")))
+	})
+
+	It("requires an encryption key", func() {
+		_, err := bindingstore.NewEncryptedFileStore(storePath, "")
+		Expect(err).To(MatchError("binding store encryption key can't be empty"))
+	})
+
+	Context("when the file cannot be written", func() {
+		BeforeEach(func() {
+			var err error
+			store, err = bindingstore.NewEncryptedFileStore(filepath.Join(storeDir, "missing-dir", "bindings"), encryptionKey)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error and does not keep the binding", func() {
+			Expect(store.Save(binding)).To(MatchError(ContainSubstring("writing binding store")))
+
+			_, found, err := store.Get("some-instance", "some-binding")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,29 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package boshdirector
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// CancelTask asks the director to cancel a task. The task moves to the
+// cancelling state and is cancelled once the director reaches a checkpoint.
+func (c *Client) CancelTask(taskID int, logger *log.Logger) error {
+	logger.Printf("cancelling task %d\n", taskID)
+	request, err := prepareDelete(fmt.Sprintf("%s/tasks/%d", c.url, taskID), "")
+	if err != nil {
+		return err
+	}
+
+	return c.getResultCheckingForErrors(request, http.StatusNoContent, ignoreBody, logger)
+}
+
+func ignoreBody(*http.Response) error {
+	return nil
+}
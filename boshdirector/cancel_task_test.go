@@ -0,0 +1,63 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package boshdirector_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/mockhttp/mockbosh"
+)
+
+var _ = Describe("cancelling a task", func() {
+	const taskID = 42
+
+	var cancelErr error
+
+	JustBeforeEach(func() {
+		cancelErr = c.CancelTask(taskID, logger)
+	})
+
+	Context("when bosh accepts the cancellation", func() {
+		BeforeEach(func() {
+			director.VerifyAndMock(
+				mockbosh.CancelTask(taskID).RespondsCancelled(),
+			)
+		})
+
+		It("does not return an error", func() {
+			Expect(cancelErr).NotTo(HaveOccurred())
+		})
+
+		It("calls the authorization header builder", func() {
+			Expect(authHeaderBuilder.AddAuthHeaderCallCount()).To(BeNumerically(">", 0))
+		})
+	})
+
+	Context("when bosh cannot cancel the task", func() {
+		BeforeEach(func() {
+			director.VerifyAndMock(
+				mockbosh.CancelTask(taskID).RespondsInternalServerErrorWith("because reasons"),
+			)
+		})
+
+		It("returns an error", func() {
+			Expect(cancelErr).To(MatchError(ContainSubstring("expected status 204, was 500")))
+		})
+	})
+
+	Context("when the authorization header cannot be generated", func() {
+		BeforeEach(func() {
+			authHeaderBuilder.AddAuthHeaderReturns(errors.New("some-error"))
+		})
+
+		It("returns an error", func() {
+			Expect(cancelErr).To(MatchError(ContainSubstring("some-error")))
+		})
+	})
+})
@@ -21,3 +21,15 @@ func (c *Client) DeleteDeployment(name, contextID string, logger *log.Logger) (i
 		logger,
 	)
 }
+
+// ForceDeleteDeployment deletes a deployment, ignoring errors such as failing
+// drain scripts and unresponsive VMs.
+func (c *Client) ForceDeleteDeployment(name, contextID string, logger *log.Logger) (int, error) {
+	logger.Printf("force deleting deployment %s\n", name)
+	return c.deleteAndGetTaskIDCheckingForErrors(
+		fmt.Sprintf("%s/deployments/%s?force=true", c.url, name),
+		contextID,
+		http.StatusFound,
+		logger,
+	)
+}
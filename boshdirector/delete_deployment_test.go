@@ -96,3 +96,41 @@ var _ = Describe("deleting bosh deployments", func() {
 		})
 	})
 })
+
+var _ = Describe("force deleting bosh deployments", func() {
+	const deploymentName = "some-deployment"
+
+	var (
+		taskID    int
+		deleteErr error
+	)
+
+	JustBeforeEach(func() {
+		taskID, deleteErr = c.ForceDeleteDeployment(deploymentName, "some-context-id", logger)
+	})
+
+	Context("when bosh accepts the delete request", func() {
+		BeforeEach(func() {
+			director.VerifyAndMock(
+				mockbosh.ForceDeleteDeployment(deploymentName).WithContextID("some-context-id").RedirectsToTask(90),
+			)
+		})
+
+		It("returns the bosh task ID", func() {
+			Expect(deleteErr).NotTo(HaveOccurred())
+			Expect(taskID).To(Equal(90))
+		})
+	})
+
+	Context("when bosh cannot find the deployment", func() {
+		BeforeEach(func() {
+			director.VerifyAndMock(
+				mockbosh.ForceDeleteDeployment(deploymentName).RespondsNotFoundWith(""),
+			)
+		})
+
+		It("returns an error", func() {
+			Expect(deleteErr).To(BeAssignableToTypeOf(boshdirector.DeploymentNotFoundError{}))
+		})
+	})
+})
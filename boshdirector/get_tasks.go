@@ -17,6 +17,24 @@ func (c *Client) GetTasks(deploymentName string, logger *log.Logger) (BoshTasks,
 	return c.getTasks(deploymentName, "", logger)
 }
 
+// GetInFlightTasks returns the tasks of every deployment that are queued or
+// still running on the director.
+func (c *Client) GetInFlightTasks(logger *log.Logger) (BoshTasks, error) {
+	logger.Println("getting tasks in flight from bosh")
+
+	var tasks BoshTasks
+	if err := c.getDataCheckingForErrors(
+		fmt.Sprintf("%s/tasks?state=%s,%s,%s", c.url, TaskQueued, TaskProcessing, TaskCancelling),
+		http.StatusOK,
+		&tasks,
+		logger,
+	); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
 func (c *Client) GetNormalisedTasksByContext(deploymentName, contextID string, logger *log.Logger) (BoshTasks, error) {
 	logger.Printf("getting tasks for deployment %s with context %s from bosh\n", deploymentName, contextID)
 	tasks, err := c.getTasks(deploymentName, contextID, logger)
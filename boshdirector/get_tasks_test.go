@@ -71,6 +71,47 @@ var _ = Describe("getting tasks", func() {
 		})
 	})
 
+	Describe("GetInFlightTasks", func() {
+		var (
+			actualTasks      boshdirector.BoshTasks
+			actualTasksError error
+		)
+
+		JustBeforeEach(func() {
+			actualTasks, actualTasksError = c.GetInFlightTasks(logger)
+		})
+
+		Context("when bosh fetches the tasks successfully", func() {
+			expectedTasks := boshdirector.BoshTasks{
+				{ID: 1, State: boshdirector.TaskQueued, Deployment: "service-instance_some-instance"},
+				{ID: 2, State: boshdirector.TaskProcessing, Deployment: "some-other-deployment"},
+			}
+
+			BeforeEach(func() {
+				director.VerifyAndMock(
+					mockbosh.InFlightTasks().RespondsOKWithJSON(expectedTasks),
+				)
+			})
+
+			It("returns the queued and running tasks of every deployment", func() {
+				Expect(actualTasksError).NotTo(HaveOccurred())
+				Expect(actualTasks).To(Equal(expectedTasks))
+			})
+		})
+
+		Context("when bosh fails to fetch the tasks", func() {
+			BeforeEach(func() {
+				director.VerifyAndMock(
+					mockbosh.InFlightTasks().RespondsInternalServerErrorWith("because reasons"),
+				)
+			})
+
+			It("wraps the error", func() {
+				Expect(actualTasksError).To(MatchError(ContainSubstring("expected status 200, was 500")))
+			})
+		})
+	})
+
 	Describe("GetTasksByContextID", func() {
 		const (
 			contextID      = "some-id"
@@ -0,0 +1,31 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package boshdirector
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+type Instance struct {
+	Group string `json:"job"`
+	Index *int   `json:"index"`
+	ID    string `json:"id"`
+}
+
+func (c *Client) Instances(deploymentName string, logger *log.Logger) ([]Instance, error) {
+	logger.Printf("getting instances of deployment %s from bosh\n", deploymentName)
+
+	var instances []Instance
+	url := fmt.Sprintf("%s/deployments/%s/instances", c.url, deploymentName)
+	if err := c.getDataCheckingForErrors(url, http.StatusOK, &instances, logger); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
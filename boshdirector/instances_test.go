@@ -0,0 +1,45 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package boshdirector_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/mockhttp/mockbosh"
+)
+
+var _ = Describe("getting instances", func() {
+	const deploymentName = "some-deployment"
+
+	It("returns the instances of the deployment", func() {
+		director.VerifyAndMock(
+			mockbosh.Instances(deploymentName).RespondsOKWith(`[
+				{"agent_id": "agent-1", "job": "redis-server", "index": 0, "id": "redis-uuid"},
+				{"agent_id": "agent-2", "job": "proxy", "index": 1, "id": "proxy-uuid"}
+			]`),
+		)
+
+		instances, err := c.Instances(deploymentName, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		zero, one := 0, 1
+		Expect(instances).To(Equal([]boshdirector.Instance{
+			{Group: "redis-server", Index: &zero, ID: "redis-uuid"},
+			{Group: "proxy", Index: &one, ID: "proxy-uuid"},
+		}))
+	})
+
+	It("returns an error when bosh fails", func() {
+		director.VerifyAndMock(
+			mockbosh.Instances(deploymentName).RespondsInternalServerErrorWith("because reasons"),
+		)
+
+		_, err := c.Instances(deploymentName, logger)
+		Expect(err).To(MatchError(ContainSubstring("expected status 200, was 500")))
+	})
+})
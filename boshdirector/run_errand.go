@@ -7,20 +7,101 @@
 package boshdirector
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 )
 
-func (c *Client) RunErrand(deploymentName, errandName, contextID string, logger *log.Logger) (int, error) {
+// FirstInstance, in place of an index, selects the instance of a group with
+// the lowest index, so that an errand runs exactly once.
+const FirstInstance = "first"
+
+type ErrandInstance struct {
+	Group string `json:"group"`
+	ID    string `json:"id,omitempty"`
+}
+
+// ParseErrandInstance parses where an errand should run: an instance group,
+// a group/index (or group/id), or group/first.
+func ParseErrandInstance(selector string) (ErrandInstance, error) {
+	parts := strings.Split(selector, "/")
+	switch {
+	case parts[0] == "":
+		return ErrandInstance{}, fmt.Errorf("errand instance %q has no instance group", selector)
+	case len(parts) == 1:
+		return ErrandInstance{Group: parts[0]}, nil
+	case len(parts) == 2 && parts[1] != "":
+		return ErrandInstance{Group: parts[0], ID: parts[1]}, nil
+	default:
+		return ErrandInstance{}, fmt.Errorf("errand instance %q must be a group, group/index or group/%s", selector, FirstInstance)
+	}
+}
+
+// RunErrand runs an errand on the given instances of a deployment, or where
+// BOSH chooses when there are none.
+func (c *Client) RunErrand(deploymentName, errandName string, instances []string, contextID string, logger *log.Logger) (int, error) {
 	logger.Printf("running errand %s from deployment %s\n", errandName, deploymentName)
 
+	body, err := c.errandRunBody(deploymentName, instances, logger)
+	if err != nil {
+		return 0, err
+	}
+
 	return c.postAndGetTaskIDCheckingForErrors(
 		fmt.Sprintf("%s/deployments/%s/errands/%s/runs", c.url, deploymentName, errandName),
 		http.StatusFound,
-		[]byte("{}"),
+		body,
 		"application/json",
 		contextID,
 		logger,
 	)
 }
+
+func (c *Client) errandRunBody(deploymentName string, instances []string, logger *log.Logger) ([]byte, error) {
+	if len(instances) == 0 {
+		return []byte("{}"), nil
+	}
+
+	var deployed []Instance
+	selection := []ErrandInstance{}
+	for _, selector := range instances {
+		instance, err := ParseErrandInstance(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		if instance.ID == FirstInstance {
+			if deployed == nil {
+				if deployed, err = c.Instances(deploymentName, logger); err != nil {
+					return nil, err
+				}
+			}
+			if instance.ID, err = firstInstanceID(instance.Group, deployed); err != nil {
+				return nil, err
+			}
+		}
+
+		selection = append(selection, instance)
+	}
+
+	return json.Marshal(map[string][]ErrandInstance{"instances": selection})
+}
+
+func firstInstanceID(group string, instances []Instance) (string, error) {
+	var first *Instance
+	for i, instance := range instances {
+		if instance.Group != group || instance.Index == nil {
+			continue
+		}
+		if first == nil || *instance.Index < *first.Index {
+			first = &instances[i]
+		}
+	}
+
+	if first == nil {
+		return "", fmt.Errorf("no instances found in instance group %s", group)
+	}
+	return first.ID, nil
+}
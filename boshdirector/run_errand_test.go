@@ -8,7 +8,9 @@ package boshdirector_test
 
 import (
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/mockhttp/mockbosh"
 )
 
@@ -26,20 +28,99 @@ var _ = Describe("running errands", func() {
 				mockbosh.Errand(deploymentName, errandName).WithContextID(contextID).RedirectsToTask(taskID),
 			)
 
-			actualTaskID, actualErr := c.RunErrand(deploymentName, errandName, contextID, logger)
+			actualTaskID, actualErr := c.RunErrand(deploymentName, errandName, nil, contextID, logger)
 			Expect(actualTaskID).To(Equal(taskID))
 			Expect(actualErr).NotTo(HaveOccurred())
 		})
 	})
 
+	Context("on selected instances", func() {
+		It("passes the instances to BOSH", func() {
+			taskID := 5
+			director.VerifyAndMock(
+				mockbosh.Errand(deploymentName, errandName).
+					WithContextID(contextID).
+					WithInstances(`[{"group":"redis-server","id":"0"},{"group":"proxy"}]`).
+					RedirectsToTask(taskID),
+			)
+
+			actualTaskID, actualErr := c.RunErrand(deploymentName, errandName, []string{"redis-server/0", "proxy"}, contextID, logger)
+			Expect(actualTaskID).To(Equal(taskID))
+			Expect(actualErr).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("on the first instance of a group", func() {
+		It("selects the instance with the lowest index", func() {
+			taskID := 5
+			director.VerifyAndMock(
+				mockbosh.Instances(deploymentName).RespondsOKWith(`[
+					{"job": "redis-server", "index": 2, "id": "uuid-2"},
+					{"job": "proxy", "index": 0, "id": "proxy-uuid"},
+					{"job": "redis-server", "index": 1, "id": "uuid-1"},
+					{"job": "redis-server", "index": null, "id": "uuid-pending"}
+				]`),
+				mockbosh.Errand(deploymentName, errandName).
+					WithContextID(contextID).
+					WithInstances(`[{"group":"redis-server","id":"uuid-1"}]`).
+					RedirectsToTask(taskID),
+			)
+
+			actualTaskID, actualErr := c.RunErrand(deploymentName, errandName, []string{"redis-server/first"}, contextID, logger)
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(actualTaskID).To(Equal(taskID))
+		})
+
+		It("returns an error when the group has no instances", func() {
+			director.VerifyAndMock(
+				mockbosh.Instances(deploymentName).RespondsOKWith(`[]`),
+			)
+
+			_, actualErr := c.RunErrand(deploymentName, errandName, []string{"redis-server/first"}, contextID, logger)
+			Expect(actualErr).To(MatchError("no instances found in instance group redis-server"))
+		})
+	})
+
+	Context("on an invalid instance", func() {
+		It("returns an error without running the errand", func() {
+			_, actualErr := c.RunErrand(deploymentName, errandName, []string{"redis-server/0/1"}, contextID, logger)
+			Expect(actualErr).To(MatchError(ContainSubstring(`errand instance "redis-server/0/1" must be a group, group/index or group/first`)))
+		})
+	})
+
 	Context("has an error", func() {
 		It("invokes BOSH to queue up an errand", func() {
 			director.VerifyAndMock(
 				mockbosh.Errand(deploymentName, errandName).WithAnyContextID().RespondsInternalServerErrorWith("because reasons"),
 			)
 
-			_, actualErr := c.RunErrand(deploymentName, errandName, contextID, logger)
+			_, actualErr := c.RunErrand(deploymentName, errandName, nil, contextID, logger)
 			Expect(actualErr).To(HaveOccurred())
 		})
 	})
 })
+
+var _ = Describe("parsing errand instances", func() {
+	DescribeTable("valid selectors",
+		func(selector string, expected boshdirector.ErrandInstance) {
+			instance, err := boshdirector.ParseErrandInstance(selector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instance).To(Equal(expected))
+		},
+		Entry("a group", "redis-server", boshdirector.ErrandInstance{Group: "redis-server"}),
+		Entry("an index", "redis-server/0", boshdirector.ErrandInstance{Group: "redis-server", ID: "0"}),
+		Entry("an id", "redis-server/a-uuid", boshdirector.ErrandInstance{Group: "redis-server", ID: "a-uuid"}),
+		Entry("the first instance", "redis-server/first", boshdirector.ErrandInstance{Group: "redis-server", ID: boshdirector.FirstInstance}),
+	)
+
+	DescribeTable("invalid selectors",
+		func(selector string) {
+			_, err := boshdirector.ParseErrandInstance(selector)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("no group", "/0"),
+		Entry("no index", "redis-server/"),
+		Entry("too many parts", "redis-server/0/1"),
+	)
+})
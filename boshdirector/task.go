@@ -14,6 +14,7 @@ type BoshTask struct {
 	Description string
 	Result      string
 	ContextID   string `json:"context_id,omitempty"`
+	Deployment  string `json:"deployment,omitempty"`
 }
 
 type TaskStateType int
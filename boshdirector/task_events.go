@@ -0,0 +1,107 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package boshdirector
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	EventStateFinished = "finished"
+	EventStateFailed   = "failed"
+)
+
+type BoshTaskEvent struct {
+	Time     int64               `json:"time"`
+	Stage    string              `json:"stage,omitempty"`
+	Tags     []string            `json:"tags,omitempty"`
+	Total    int                 `json:"total,omitempty"`
+	Task     string              `json:"task,omitempty"`
+	Index    int                 `json:"index,omitempty"`
+	State    string              `json:"state,omitempty"`
+	Progress int                 `json:"progress,omitempty"`
+	Error    *BoshTaskEventError `json:"error,omitempty"`
+	Data     *BoshTaskEventData  `json:"data,omitempty"`
+}
+
+type BoshTaskEventError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type BoshTaskEventData struct {
+	Error string `json:"error,omitempty"`
+}
+
+type BoshTaskEvents []BoshTaskEvent
+
+func (c *Client) GetTaskEvents(taskID int, logger *log.Logger) (BoshTaskEvents, error) {
+	logger.Printf("getting task events for task %d from bosh\n", taskID)
+	events := BoshTaskEvents{}
+	var event BoshTaskEvent
+	eventReadyCallback := func() {
+		events = append(events, event)
+		event = BoshTaskEvent{}
+	}
+
+	err := c.getMultipleDataCheckingForErrors(
+		fmt.Sprintf("%s/tasks/%d/output?type=event", c.url, taskID),
+		http.StatusOK,
+		&event,
+		eventReadyCallback,
+		logger,
+	)
+
+	return events, err
+}
+
+// CurrentStage returns the stage of the latest event and the percentage of
+// its steps that have finished.
+func (e BoshTaskEvents) CurrentStage() (string, int, bool) {
+	for i := len(e) - 1; i >= 0; i-- {
+		current := e[i]
+		if current.Stage == "" {
+			continue
+		}
+
+		if current.Total == 0 {
+			return current.Stage, 0, true
+		}
+
+		finished := 0
+		for _, event := range e {
+			if event.Stage == current.Stage && sameTags(event.Tags, current.Tags) &&
+				(event.State == EventStateFinished || event.State == EventStateFailed) {
+				finished++
+			}
+		}
+		return current.Stage, finished * 100 / current.Total, true
+	}
+
+	return "", 0, false
+}
+
+// ErrorMessages returns the errors BOSH reported while running the task.
+func (e BoshTaskEvents) ErrorMessages() []string {
+	var messages []string
+	for _, event := range e {
+		if event.Error != nil && event.Error.Message != "" {
+			messages = append(messages, event.Error.Message)
+		}
+		if event.Data != nil && event.Data.Error != "" {
+			messages = append(messages, event.Data.Error)
+		}
+	}
+	return messages
+}
+
+func sameTags(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package boshdirector_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/mockhttp/mockbosh"
+)
+
+var _ = Describe("task events", func() {
+	const taskID = 2112
+
+	Describe("getting the events of a task", func() {
+		var (
+			events    boshdirector.BoshTaskEvents
+			eventsErr error
+		)
+
+		JustBeforeEach(func() {
+			events, eventsErr = c.GetTaskEvents(taskID, logger)
+		})
+
+		Context("when bosh returns the event stream", func() {
+			expectedEvents := boshdirector.BoshTaskEvents{
+				{Time: 1, Stage: "Preparing deployment", Total: 1, Task: "Preparing deployment", Index: 1, State: "started"},
+				{Time: 2, Stage: "Preparing deployment", Total: 1, Task: "Preparing deployment", Index: 1, State: "finished"},
+				{Time: 3, Error: &boshdirector.BoshTaskEventError{Code: 100, Message: "something broke"}},
+			}
+
+			BeforeEach(func() {
+				director.VerifyAndMock(
+					mockbosh.TaskEvents(taskID).RespondsWithEvents(expectedEvents),
+				)
+			})
+
+			It("returns every event", func() {
+				Expect(eventsErr).NotTo(HaveOccurred())
+				Expect(events).To(Equal(expectedEvents))
+			})
+		})
+
+		Context("when bosh fails to return the events", func() {
+			BeforeEach(func() {
+				director.VerifyAndMock(
+					mockbosh.TaskEvents(taskID).RespondsInternalServerErrorWith("because reasons"),
+				)
+			})
+
+			It("returns an error", func() {
+				Expect(eventsErr).To(MatchError(ContainSubstring("expected status 200, was 500")))
+			})
+		})
+	})
+
+	Describe("the current stage", func() {
+		It("counts the finished steps of the latest stage", func() {
+			events := boshdirector.BoshTaskEvents{
+				{Stage: "Preparing deployment", Total: 1, Index: 1, State: "started"},
+				{Stage: "Preparing deployment", Total: 1, Index: 1, State: "finished"},
+				{Stage: "Updating instance", Tags: []string{"redis-server"}, Total: 4, Index: 1, State: "started"},
+				{Stage: "Updating instance", Tags: []string{"redis-server"}, Total: 4, Index: 1, State: "finished"},
+				{Stage: "Updating instance", Tags: []string{"redis-server"}, Total: 4, Index: 2, State: "started"},
+				{Stage: "Updating instance", Tags: []string{"redis-server"}, Total: 4, Index: 3, State: "started"},
+			}
+
+			stage, percent, found := events.CurrentStage()
+			Expect(found).To(BeTrue())
+			Expect(stage).To(Equal("Updating instance"))
+			Expect(percent).To(Equal(25))
+		})
+
+		It("is not found without staged events", func() {
+			_, _, found := boshdirector.BoshTaskEvents{{Time: 1}}.CurrentStage()
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	It("collects the error messages", func() {
+		events := boshdirector.BoshTaskEvents{
+			{Stage: "Updating instance", State: "failed", Data: &boshdirector.BoshTaskEventData{Error: "redis-server/0 is not running after update"}},
+			{Error: &boshdirector.BoshTaskEventError{Code: 400007, Message: "'redis-server/0' is not running after update"}},
+		}
+
+		Expect(events.ErrorMessages()).To(Equal([]string{
+			"redis-server/0 is not running after update",
+			"'redis-server/0' is not running after update",
+		}))
+	})
+})
@@ -55,18 +55,21 @@ var _ = Describe("tasks", func() {
 					State:       "done",
 					Description: "snapshot deployment",
 					Result:      "snapshots of deployment 'redis-on-demand-broker-dev2' created",
+					Deployment:  "redis-on-demand-broker-dev2",
 				},
 				{
 					ID:          12729,
 					State:       "done",
 					Description: "snapshot deployment",
 					Result:      "snapshots of deployment 'redis-on-demand-broker-dev2' created",
+					Deployment:  "redis-on-demand-broker-dev2",
 				},
 				{
 					ID:          12427,
 					State:       "done",
 					Description: "snapshot deployment",
 					Result:      "snapshots of deployment 'redis-on-demand-broker-dev2' created",
+					Deployment:  "redis-on-demand-broker-dev2",
 				},
 			}))
 		})
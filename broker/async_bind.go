@@ -0,0 +1,483 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/pborman/uuid"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"gopkg.in/yaml.v2"
+)
+
+type AsyncBindingSpec struct {
+	IsAsync       bool
+	Binding       brokerapi.Binding
+	OperationData string
+}
+
+type AsyncUnbindingSpec struct {
+	IsAsync       bool
+	OperationData string
+}
+
+func bindingAsyncRequiredError() error {
+	return brokerapi.NewFailureResponse(brokerapi.ErrAsyncRequired, http.StatusUnprocessableEntity, "binding-async-required")
+}
+
+// AsyncBind is used when the platform accepts an incomplete binding. Plans
+// without a binding errand are bound synchronously.
+func (b *Broker) AsyncBind(
+	ctx context.Context,
+	instanceID,
+	bindingID string,
+	details brokerapi.BindDetails,
+) (AsyncBindingSpec, error) {
+	_, plan, found := b.offeringForPlan(details.PlanID)
+	if !found || plan.BindingErrand == "" {
+		binding, err := b.Bind(ctx, instanceID, bindingID, details)
+		return AsyncBindingSpec{Binding: binding}, err
+	}
+
+	requestID := uuid.New()
+	ctx = brokercontext.New(ctx, string(OperationTypeBind), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
+	logger := b.loggerFactory.NewWithContext(ctx)
+
+	errs := func(err DisplayableError) (AsyncBindingSpec, error) {
+		logger.Println(err)
+		return AsyncBindingSpec{}, err.ErrorForCFUser()
+	}
+
+	mappedParams, err := convertDetailsToMap(brokerapi.DetailsWithRawParameters(details))
+	if err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("converting to map %s", err)))
+	}
+
+	if err := validateParameters(b.planSchemas[plan.ID].bindingCreate, mappedParams); err != NilError {
+		return errs(err)
+	}
+
+	manifest, displayableErr := b.bindingDeployment(ctx, "bind", instanceID, logger)
+	if displayableErr != NilError {
+		return errs(displayableErr)
+	}
+
+	_, exists, err := b.bindingStore.Get(instanceID, bindingID)
+	if err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("getting binding from binding store: %s", err)))
+	}
+	if exists {
+		return errs(NewDisplayableError(
+			brokerapi.ErrBindingAlreadyExists,
+			fmt.Errorf("error binding: binding %s already exists", bindingID),
+		))
+	}
+
+	pendingBinding := bindingstore.Binding{
+		InstanceID:    instanceID,
+		BindingID:     bindingID,
+		RequestParams: mappedParams,
+	}
+	if err := b.bindingStore.Save(pendingBinding); err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("saving binding to binding store: %s", err)))
+	}
+
+	operationData, displayableErr := b.runBindingErrand(ctx, OperationTypeBind, instanceID, bindingID, plan, manifest, logger)
+	if displayableErr != NilError {
+		if err := b.bindingStore.Delete(instanceID, bindingID); err != nil {
+			logger.Printf("error removing binding %s from binding store: %s\n", bindingID, err)
+		}
+		return errs(displayableErr)
+	}
+
+	return AsyncBindingSpec{IsAsync: true, OperationData: operationData}, nil
+}
+
+// AsyncUnbind is used when the platform accepts an incomplete unbinding. Plans
+// without a binding errand are unbound synchronously.
+func (b *Broker) AsyncUnbind(
+	ctx context.Context,
+	instanceID,
+	bindingID string,
+	details brokerapi.UnbindDetails,
+) (AsyncUnbindingSpec, error) {
+	_, plan, found := b.offeringForPlan(details.PlanID)
+	if !found || plan.BindingErrand == "" {
+		return AsyncUnbindingSpec{}, b.Unbind(ctx, instanceID, bindingID, details)
+	}
+
+	requestID := uuid.New()
+	ctx = brokercontext.New(ctx, string(OperationTypeUnbind), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
+	logger := b.loggerFactory.NewWithContext(ctx)
+
+	errs := func(err DisplayableError) (AsyncUnbindingSpec, error) {
+		logger.Println(err)
+		return AsyncUnbindingSpec{}, err.ErrorForCFUser()
+	}
+
+	manifest, displayableErr := b.bindingDeployment(ctx, "unbind", instanceID, logger)
+	if displayableErr != NilError {
+		return errs(displayableErr)
+	}
+
+	_, exists, err := b.bindingStore.Get(instanceID, bindingID)
+	if err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("getting binding from binding store: %s", err)))
+	}
+	if !exists {
+		return errs(NewDisplayableError(
+			brokerapi.ErrBindingDoesNotExist,
+			fmt.Errorf("error unbinding: binding %s not found", bindingID),
+		))
+	}
+
+	operationData, displayableErr := b.runBindingErrand(ctx, OperationTypeUnbind, instanceID, bindingID, plan, manifest, logger)
+	if displayableErr != NilError {
+		return errs(displayableErr)
+	}
+
+	return AsyncUnbindingSpec{IsAsync: true, OperationData: operationData}, nil
+}
+
+func (b *Broker) bindingDeployment(ctx context.Context, action, instanceID string, logger *log.Logger) ([]byte, DisplayableError) {
+	manifest, found, err := b.boshClient.GetDeployment(b.deploymentName(instanceID), logger)
+	switch err.(type) {
+	case boshdirector.RequestError:
+		return nil, NewBoshRequestError(action, fmt.Errorf("could not get manifest: %s", err))
+	case error:
+		return nil, NewGenericError(ctx, fmt.Errorf("could not get manifest: %s", err))
+	}
+
+	if !found {
+		return nil, NewDisplayableError(
+			brokerapi.ErrInstanceDoesNotExist,
+			fmt.Errorf("error %sing: instance %s, not found", action, instanceID),
+		)
+	}
+
+	return manifest, NilError
+}
+
+// runBindingErrand deploys the binding into the errand's job properties, as
+// BOSH cannot pass arguments to an errand. The errand itself is run once the
+// deploy has succeeded, when the platform polls the binding last operation.
+func (b *Broker) runBindingErrand(
+	ctx context.Context,
+	operationType OperationType,
+	instanceID, bindingID string,
+	plan config.Plan,
+	manifest []byte,
+	logger *log.Logger,
+) (string, DisplayableError) {
+	boshContextID := uuid.New()
+
+	manifest, err := withBindingProperties(manifest, plan.BindingErrand, operationType, bindingID)
+	if err != nil {
+		return "", NewGenericError(ctx, err)
+	}
+
+	release, free, err := b.takeTaskSlot(false, logger)
+	if err != nil {
+		return "", NewGenericError(ctx, err)
+	}
+	if !free {
+		return "", NewDisplayableError(
+			errors.New(OperationInProgressMessage),
+			fmt.Errorf("not running binding errand %s for %s of binding %s: %s", plan.BindingErrand, operationType, bindingID, errWaitingForTaskSlot),
+		)
+	}
+	defer release()
+
+	logger.Printf("deploying binding %s for binding errand %s to %s it\n", bindingID, plan.BindingErrand, operationType)
+	taskID, err := b.boshClient.Deploy(manifest, boshContextID, logger)
+	switch err.(type) {
+	case boshdirector.RequestError:
+		return "", NewBoshRequestError(string(operationType), err)
+	case error:
+		return "", NewGenericError(ctx, fmt.Errorf("deploying binding for binding errand: %s", err))
+	}
+
+	operationData, err := json.Marshal(OperationData{
+		BoshTaskID:           taskID,
+		BoshContextID:        boshContextID,
+		OperationType:        operationType,
+		PlanID:               plan.ID,
+		PostDeployErrandName: plan.BindingErrand,
+	})
+	if err != nil {
+		return "", NewGenericError(brokercontext.WithBoshTaskID(ctx, taskID), err)
+	}
+
+	return string(operationData), NilError
+}
+
+// withBindingProperties sets the binding's ID, and whether it is being
+// created or deleted, in the binding property of the errand's jobs. The
+// errand's jobs are those named after it, or all the jobs of an instance
+// group named after it.
+func withBindingProperties(manifest []byte, errand string, operationType OperationType, bindingID string) ([]byte, error) {
+	var deployment map[string]interface{}
+	if err := yaml.Unmarshal(manifest, &deployment); err != nil {
+		return nil, fmt.Errorf("error parsing manifest: %s", err)
+	}
+
+	found := false
+	instanceGroups, _ := deployment["instance_groups"].([]interface{})
+	for _, g := range instanceGroups {
+		instanceGroup, _ := g.(map[interface{}]interface{})
+		jobs, _ := instanceGroup["jobs"].([]interface{})
+		for _, j := range jobs {
+			job, _ := j.(map[interface{}]interface{})
+			if job == nil || (job["name"] != errand && instanceGroup["name"] != errand) {
+				continue
+			}
+
+			properties, _ := job["properties"].(map[interface{}]interface{})
+			if properties == nil {
+				properties = map[interface{}]interface{}{}
+				job["properties"] = properties
+			}
+			properties["binding"] = map[string]interface{}{
+				"id":        bindingID,
+				"operation": string(operationType),
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("binding errand %s not found in manifest", errand)
+	}
+	return yaml.Marshal(deployment)
+}
+
+// LastBindingOperation reports on the binding errand. Once the errand has
+// succeeded the adapter is called to create or delete the binding.
+func (b *Broker) LastBindingOperation(
+	ctx context.Context,
+	instanceID,
+	bindingID,
+	operationDataRaw string,
+) (brokerapi.LastOperation, error) {
+	requestID := uuid.New()
+	ctx = brokercontext.New(ctx, "", requestID, b.serviceName("", ""), instanceID)
+	logger := b.loggerFactory.NewWithContext(ctx)
+
+	errs := func(err DisplayableError) (brokerapi.LastOperation, error) {
+		logger.Println(err)
+		return brokerapi.LastOperation{}, err.ErrorForCFUser()
+	}
+
+	if operationDataRaw == "" {
+		return errs(NewGenericError(ctx, errors.New("request missing operation data")))
+	}
+
+	var operationData OperationData
+	if err := json.Unmarshal([]byte(operationDataRaw), &operationData); err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("operation data cannot be parsed: %s", err)))
+	}
+
+	ctx = brokercontext.WithOperation(ctx, string(operationData.OperationType))
+	ctx = brokercontext.WithServiceName(ctx, b.serviceName("", operationData.PlanID))
+
+	if operationData.BoshTaskID == 0 {
+		return errs(NewGenericError(ctx, errors.New("no task ID found in operation data")))
+	}
+	if operationData.BoshContextID == "" {
+		return errs(NewGenericError(ctx, errors.New("no context ID found in operation data")))
+	}
+
+	ctx = brokercontext.WithBoshTaskID(ctx, operationData.BoshTaskID)
+
+	inProgress := brokerapi.LastOperation{
+		State:       brokerapi.InProgress,
+		Description: descriptions[brokerapi.InProgress][operationData.OperationType],
+	}
+
+	unlock, locked := b.instanceLocker.Lock(ctx, instanceID+"/"+bindingID)
+	if !locked {
+		return inProgress, nil
+	}
+	defer unlock()
+
+	task, err := b.lifeCycleRunner(operationData.PlanID).processPostDeployment(b.deploymentName(instanceID), operationData, logger)
+	if err == errWaitingForTaskSlot {
+		return inProgress, nil
+	}
+	if err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("error retrieving binding errand task from bosh: %s", err)))
+	}
+
+	lastOperation := constructLastOperation(ctx, task, operationData, "", false, logger)
+	logger.Printf(
+		"BOSH task ID %d status: %s %s errand for binding %s: Description: %s Result: %s\n",
+		task.ID, task.State, operationData.OperationType, bindingID, task.Description, task.Result,
+	)
+
+	if lastOperation.State == brokerapi.Failed && operationData.OperationType == OperationTypeBind {
+		b.discardPendingBinding(instanceID, bindingID, logger)
+	}
+
+	if lastOperation.State != brokerapi.Succeeded {
+		return lastOperation, nil
+	}
+
+	switch operationData.OperationType {
+	case OperationTypeBind:
+		err = b.completeBinding(ctx, instanceID, bindingID, operationData.PlanID, logger)
+	case OperationTypeUnbind:
+		err = b.completeUnbinding(ctx, instanceID, bindingID, operationData.PlanID, logger)
+	}
+
+	if err != nil {
+		logger.Printf("completing %s of binding %s: %s\n", operationData.OperationType, bindingID, err)
+		return brokerapi.LastOperation{
+			State: brokerapi.Failed,
+			Description: fmt.Sprintf(
+				"%s: %s",
+				descriptions[brokerapi.Failed][operationData.OperationType],
+				adapterToAPIError(ctx, err),
+			),
+		}, nil
+	}
+
+	return lastOperation, nil
+}
+
+// discardPendingBinding forgets a binding whose errand did not succeed, so
+// that the platform can retry it.
+func (b *Broker) discardPendingBinding(instanceID, bindingID string, logger *log.Logger) {
+	binding, found, err := b.bindingStore.Get(instanceID, bindingID)
+	if err != nil {
+		logger.Printf("error getting binding %s from binding store: %s\n", bindingID, err)
+		return
+	}
+	if !found || binding.Created {
+		return
+	}
+
+	if err := b.bindingStore.Delete(instanceID, bindingID); err != nil {
+		logger.Printf("error removing binding %s from binding store: %s\n", bindingID, err)
+	}
+}
+
+func (b *Broker) completeBinding(ctx context.Context, instanceID, bindingID, planID string, logger *log.Logger) error {
+	offering, _, found := b.offeringForPlan(planID)
+	if !found {
+		return fmt.Errorf("plan %s not found", planID)
+	}
+
+	binding, found, err := b.bindingStore.Get(instanceID, bindingID)
+	if err != nil {
+		return fmt.Errorf("getting binding from binding store: %s", err)
+	}
+	if !found {
+		return fmt.Errorf("binding %s not found in binding store", bindingID)
+	}
+	if binding.Created {
+		return nil
+	}
+
+	vms, manifest, err := b.getDeploymentInfo(instanceID, logger)
+	if err != nil {
+		return fmt.Errorf("gathering binding info %s", err)
+	}
+
+	logger.Printf("service adapter will create binding with ID %s for instance %s\n", bindingID, instanceID)
+	created, err := offering.AdapterClient.CreateBinding(ctx, bindingID, vms, manifest, binding.RequestParams, logger)
+	if err != nil {
+		if deleteErr := b.bindingStore.Delete(instanceID, bindingID); deleteErr != nil {
+			logger.Printf("error removing binding %s from binding store: %s\n", bindingID, deleteErr)
+		}
+		return err
+	}
+
+	binding.Created = true
+	binding.Credentials = created.Credentials
+	binding.SyslogDrainURL = created.SyslogDrainURL
+	binding.RouteServiceURL = created.RouteServiceURL
+
+	if err := b.bindingStore.Save(binding); err != nil {
+		return fmt.Errorf("saving binding to binding store: %s", err)
+	}
+
+	return nil
+}
+
+func (b *Broker) completeUnbinding(ctx context.Context, instanceID, bindingID, planID string, logger *log.Logger) error {
+	offering, _, found := b.offeringForPlan(planID)
+	if !found {
+		return fmt.Errorf("plan %s not found", planID)
+	}
+
+	binding, found, err := b.bindingStore.Get(instanceID, bindingID)
+	if err != nil {
+		return fmt.Errorf("getting binding from binding store: %s", err)
+	}
+	if !found {
+		return nil
+	}
+
+	vms, manifest, err := b.getDeploymentInfo(instanceID, logger)
+	if err != nil {
+		return fmt.Errorf("gathering unbinding info %s", err)
+	}
+
+	requestParams := map[string]interface{}{
+		"plan_id":    planID,
+		"service_id": offering.Catalog.ID,
+	}
+
+	logger.Printf("service adapter will delete binding with ID %s for instance %s\n", bindingID, instanceID)
+	if err := offering.AdapterClient.DeleteBinding(ctx, bindingID, vms, manifest, requestParams, binding.Credentials, logger); err != nil {
+		return err
+	}
+
+	if err := b.bindingStore.Delete(instanceID, bindingID); err != nil {
+		return fmt.Errorf("removing binding from binding store: %s", err)
+	}
+
+	return nil
+}
+
+func (b *Broker) GetBinding(ctx context.Context, instanceID, bindingID string) (brokerapi.Binding, error) {
+	requestID := uuid.New()
+	ctx = brokercontext.New(ctx, "", requestID, b.serviceName("", ""), instanceID)
+	logger := b.loggerFactory.NewWithContext(ctx)
+
+	errs := func(err DisplayableError) (brokerapi.Binding, error) {
+		logger.Println(err)
+		return brokerapi.Binding{}, err.ErrorForCFUser()
+	}
+
+	binding, found, err := b.bindingStore.Get(instanceID, bindingID)
+	if err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("getting binding from binding store: %s", err)))
+	}
+
+	if !found || !binding.Created {
+		return errs(NewDisplayableError(
+			brokerapi.ErrBindingDoesNotExist,
+			fmt.Errorf("getting binding: binding %s not found", bindingID),
+		))
+	}
+
+	return brokerapi.Binding{
+		Credentials:     binding.Credentials,
+		SyslogDrainURL:  binding.SyslogDrainURL,
+		RouteServiceURL: binding.RouteServiceURL,
+	}, nil
+}
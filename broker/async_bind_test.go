@@ -0,0 +1,584 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("asynchronous bindings", func() {
+	var (
+		instanceID   = "an-instance"
+		bindingID    = "a-binding"
+		deployTaskID = 76
+		errandTaskID = 77
+		boshVMs      = bosh.BoshVMs{"redis-server": []string{"an.ip"}}
+		deployDone   = boshdirector.BoshTask{ID: deployTaskID, State: boshdirector.TaskDone}
+
+		manifest []byte
+		store    *bindingstore.MemoryStore
+	)
+
+	BeforeEach(func() {
+		store = bindingstore.NewMemoryStore()
+		fakeBindingStore.SaveStub = store.Save
+		fakeBindingStore.GetStub = store.Get
+		fakeBindingStore.DeleteStub = store.Delete
+
+		manifest = []byte(`---
+name: a-deployment
+instance_groups:
+- name: redis-server
+  jobs:
+  - name: redis-server
+  - name: create-user
+    properties:
+      admin_user: admin
+`)
+		boshClient.GetDeploymentStub = func(string, *log.Logger) ([]byte, bool, error) {
+			return manifest, true, nil
+		}
+		boshClient.VMsReturns(boshVMs, nil)
+		boshClient.DeployReturns(deployTaskID, nil)
+		boshClient.RunErrandReturns(errandTaskID, nil)
+		boshClient.GetTaskReturns(boshdirector.BoshTask{ID: errandTaskID, State: boshdirector.TaskQueued}, nil)
+		serviceAdapter.CreateBindingReturns(sdk.Binding{
+			Credentials:    map[string]interface{}{"user": "some-user"},
+			SyslogDrainURL: "syslog",
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	asyncBind := func(planID string) (broker.AsyncBindingSpec, error) {
+		return b.AsyncBind(context.Background(), instanceID, bindingID, brokerapi.BindDetails{
+			AppGUID:       "app-guid",
+			PlanID:        planID,
+			ServiceID:     serviceOfferingID,
+			RawParameters: []byte(`{"foo":"bar"}`),
+		})
+	}
+
+	asyncUnbind := func(planID string) (broker.AsyncUnbindingSpec, error) {
+		return b.AsyncUnbind(context.Background(), instanceID, bindingID, brokerapi.UnbindDetails{
+			PlanID:    planID,
+			ServiceID: serviceOfferingID,
+		})
+	}
+
+	lastBindingOperation := func(operationData string) (brokerapi.LastOperation, error) {
+		return b.LastBindingOperation(context.Background(), instanceID, bindingID, operationData)
+	}
+
+	Describe("binding", func() {
+		Context("when the plan has no binding errand", func() {
+			It("binds synchronously", func() {
+				spec, err := asyncBind(existingPlanID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.IsAsync).To(BeFalse())
+				Expect(spec.Binding.Credentials).To(Equal(map[string]interface{}{"user": "some-user"}))
+				Expect(boshClient.DeployCallCount()).To(Equal(0))
+				Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the plan has a binding errand", func() {
+			var (
+				spec    broker.AsyncBindingSpec
+				bindErr error
+			)
+
+			JustBeforeEach(func() {
+				spec, bindErr = asyncBind(bindingErrandPlanID)
+			})
+
+			It("deploys the binding into the binding errand's properties", func() {
+				Expect(bindErr).NotTo(HaveOccurred())
+				Expect(boshClient.DeployCallCount()).To(Equal(1))
+				deployedManifest, contextID, _ := boshClient.DeployArgsForCall(0)
+				Expect(contextID).NotTo(BeEmpty())
+				Expect(deployedManifest).To(MatchYAML(`---
+name: a-deployment
+instance_groups:
+- name: redis-server
+  jobs:
+  - name: redis-server
+  - name: create-user
+    properties:
+      admin_user: admin
+      binding:
+        id: a-binding
+        operation: bind
+`))
+			})
+
+			It("does not run the errand or create the binding yet", func() {
+				Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+				Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(0))
+			})
+
+			It("returns bind operation data", func() {
+				Expect(spec.IsAsync).To(BeTrue())
+
+				var operationData broker.OperationData
+				Expect(json.Unmarshal([]byte(spec.OperationData), &operationData)).To(Succeed())
+				_, contextID, _ := boshClient.DeployArgsForCall(0)
+				Expect(operationData).To(Equal(broker.OperationData{
+					BoshTaskID:           deployTaskID,
+					BoshContextID:        contextID,
+					OperationType:        broker.OperationTypeBind,
+					PlanID:               bindingErrandPlanID,
+					PostDeployErrandName: "create-user",
+				}))
+			})
+
+			It("stores the pending binding request", func() {
+				binding, found, err := store.Get(instanceID, bindingID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(binding.Created).To(BeFalse())
+				Expect(binding.RequestParams).To(HaveKeyWithValue("parameters", map[string]interface{}{"foo": "bar"}))
+			})
+
+			Context("and the binding already exists", func() {
+				BeforeEach(func() {
+					Expect(store.Save(bindingstore.Binding{InstanceID: instanceID, BindingID: bindingID})).To(Succeed())
+				})
+
+				It("returns a binding already exists error", func() {
+					Expect(bindErr).To(Equal(brokerapi.ErrBindingAlreadyExists))
+					Expect(boshClient.DeployCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("and the deployment does not exist", func() {
+				BeforeEach(func() {
+					boshClient.GetDeploymentReturns(nil, false, nil)
+				})
+
+				It("returns an instance does not exist error", func() {
+					Expect(bindErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				})
+			})
+
+			Context("and the binding errand is not in the manifest", func() {
+				BeforeEach(func() {
+					manifest = []byte(`---
+name: a-deployment
+instance_groups:
+- name: redis-server
+  jobs:
+  - name: redis-server
+`)
+				})
+
+				It("returns a generic error without deploying", func() {
+					Expect(bindErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+					Expect(logBuffer.String()).To(ContainSubstring("binding errand create-user not found in manifest"))
+					Expect(boshClient.DeployCallCount()).To(Equal(0))
+				})
+
+				It("forgets the pending binding", func() {
+					_, found, _ := store.Get(instanceID, bindingID)
+					Expect(found).To(BeFalse())
+				})
+			})
+
+			Context("and the binding errand is an instance group", func() {
+				BeforeEach(func() {
+					manifest = []byte(`---
+name: a-deployment
+instance_groups:
+- name: create-user
+  lifecycle: errand
+  jobs:
+  - name: user-creator
+`)
+				})
+
+				It("deploys the binding into the properties of the group's jobs", func() {
+					Expect(bindErr).NotTo(HaveOccurred())
+					deployedManifest, _, _ := boshClient.DeployArgsForCall(0)
+					Expect(deployedManifest).To(MatchYAML(`---
+name: a-deployment
+instance_groups:
+- name: create-user
+  lifecycle: errand
+  jobs:
+  - name: user-creator
+    properties:
+      binding:
+        id: a-binding
+        operation: bind
+`))
+				})
+			})
+
+			Context("and the binding cannot be deployed", func() {
+				BeforeEach(func() {
+					boshClient.DeployReturns(0, errors.New("deployment locked"))
+				})
+
+				It("returns a generic error", func() {
+					Expect(bindErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+					Expect(logBuffer.String()).To(ContainSubstring("deploying binding for binding errand: deployment locked"))
+				})
+
+				It("forgets the pending binding", func() {
+					_, found, _ := store.Get(instanceID, bindingID)
+					Expect(found).To(BeFalse())
+				})
+			})
+
+			Describe("polling the binding last operation", func() {
+				var (
+					lastOperation    brokerapi.LastOperation
+					lastOperationErr error
+				)
+
+				JustBeforeEach(func() {
+					lastOperation, lastOperationErr = lastBindingOperation(spec.OperationData)
+				})
+
+				Context("while the binding is being deployed", func() {
+					BeforeEach(func() {
+						boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+							{ID: deployTaskID, State: boshdirector.TaskProcessing},
+						}, nil)
+					})
+
+					It("reports that binding is in progress", func() {
+						Expect(lastOperationErr).NotTo(HaveOccurred())
+						Expect(lastOperation.State).To(Equal(brokerapi.InProgress))
+					})
+
+					It("does not run the errand yet", func() {
+						Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when the binding has been deployed", func() {
+					BeforeEach(func() {
+						boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{deployDone}, nil)
+					})
+
+					It("runs the binding errand", func() {
+						Expect(boshClient.RunErrandCallCount()).To(Equal(1))
+						actualDeploymentName, errandName, _, contextID, _ := boshClient.RunErrandArgsForCall(0)
+						Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+						Expect(errandName).To(Equal("create-user"))
+						_, deployContextID, _ := boshClient.DeployArgsForCall(0)
+						Expect(contextID).To(Equal(deployContextID))
+					})
+
+					It("reports that binding is in progress", func() {
+						Expect(lastOperationErr).NotTo(HaveOccurred())
+						Expect(lastOperation.State).To(Equal(brokerapi.InProgress))
+					})
+				})
+
+				Context("when the binding could not be deployed", func() {
+					BeforeEach(func() {
+						boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+							{ID: deployTaskID, State: boshdirector.TaskError},
+						}, nil)
+					})
+
+					It("reports that binding failed without running the errand", func() {
+						Expect(lastOperation.State).To(Equal(brokerapi.Failed))
+						Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+					})
+
+					It("forgets the pending binding", func() {
+						_, found, _ := store.Get(instanceID, bindingID)
+						Expect(found).To(BeFalse())
+					})
+				})
+
+				Context("while the errand is running", func() {
+					BeforeEach(func() {
+						boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+							{ID: errandTaskID, State: boshdirector.TaskProcessing}, deployDone,
+						}, nil)
+					})
+
+					It("reports that binding is in progress", func() {
+						Expect(lastOperationErr).NotTo(HaveOccurred())
+						Expect(lastOperation).To(Equal(brokerapi.LastOperation{
+							State:       brokerapi.InProgress,
+							Description: "Binding in progress",
+						}))
+					})
+
+					It("has not fetched the binding", func() {
+						_, err := b.GetBinding(context.Background(), instanceID, bindingID)
+						Expect(err).To(Equal(brokerapi.ErrBindingDoesNotExist))
+					})
+				})
+
+				Context("when the errand has failed", func() {
+					BeforeEach(func() {
+						boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+							{ID: errandTaskID, State: boshdirector.TaskError}, deployDone,
+						}, nil)
+					})
+
+					It("reports that binding failed", func() {
+						Expect(lastOperation.State).To(Equal(brokerapi.Failed))
+						Expect(lastOperation.Description).To(ContainSubstring("Binding failed"))
+					})
+
+					It("does not create the binding", func() {
+						Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(0))
+					})
+
+					It("allows the binding to be retried", func() {
+						_, found, _ := store.Get(instanceID, bindingID)
+						Expect(found).To(BeFalse())
+
+						retrySpec, err := asyncBind(bindingErrandPlanID)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(retrySpec.IsAsync).To(BeTrue())
+						Expect(boshClient.DeployCallCount()).To(Equal(2))
+					})
+				})
+
+				Context("when the errand was cancelled", func() {
+					BeforeEach(func() {
+						boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+							{ID: errandTaskID, State: boshdirector.TaskCancelled}, deployDone,
+						}, nil)
+					})
+
+					It("forgets the pending binding", func() {
+						Expect(lastOperation.State).To(Equal(brokerapi.Failed))
+						_, found, _ := store.Get(instanceID, bindingID)
+						Expect(found).To(BeFalse())
+					})
+				})
+
+				Context("when the errand has succeeded", func() {
+					BeforeEach(func() {
+						boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+							{ID: errandTaskID, State: boshdirector.TaskDone}, deployDone,
+						}, nil)
+					})
+
+					It("reports that binding completed", func() {
+						Expect(lastOperationErr).NotTo(HaveOccurred())
+						Expect(lastOperation).To(Equal(brokerapi.LastOperation{
+							State:       brokerapi.Succeeded,
+							Description: "Binding completed",
+						}))
+					})
+
+					It("creates the binding with the original request", func() {
+						Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(1))
+						_, actualBindingID, vms, actualManifest, requestParams, _ := serviceAdapter.CreateBindingArgsForCall(0)
+						Expect(actualBindingID).To(Equal(bindingID))
+						Expect(vms).To(Equal(boshVMs))
+						Expect(actualManifest).To(Equal(manifest))
+						Expect(requestParams).To(HaveKeyWithValue("app_guid", "app-guid"))
+						Expect(requestParams).To(HaveKeyWithValue("parameters", map[string]interface{}{"foo": "bar"}))
+					})
+
+					It("makes the credentials available", func() {
+						binding, err := b.GetBinding(context.Background(), instanceID, bindingID)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(binding).To(Equal(brokerapi.Binding{
+							Credentials:    map[string]interface{}{"user": "some-user"},
+							SyslogDrainURL: "syslog",
+						}))
+					})
+
+					It("only creates the binding once", func() {
+						_, err := lastBindingOperation(spec.OperationData)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(1))
+					})
+
+					Context("and the adapter fails to create the binding", func() {
+						BeforeEach(func() {
+							serviceAdapter.CreateBindingReturns(sdk.Binding{}, serviceadapter.NewUnknownFailureError("user creation failed"))
+						})
+
+						It("reports that binding failed with the adapter error", func() {
+							Expect(lastOperation).To(Equal(brokerapi.LastOperation{
+								State:       brokerapi.Failed,
+								Description: "Binding failed: user creation failed",
+							}))
+						})
+
+						It("forgets the binding", func() {
+							_, found, _ := store.Get(instanceID, bindingID)
+							Expect(found).To(BeFalse())
+						})
+					})
+				})
+			})
+		})
+
+		It("requires the platform to accept asynchronous binding for plans with a binding errand", func() {
+			_, err := b.Bind(context.Background(), instanceID, bindingID, brokerapi.BindDetails{PlanID: bindingErrandPlanID})
+			Expect(err).To(MatchError(brokerapi.ErrAsyncRequired.Error()))
+			Expect(boshClient.DeployCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("unbinding", func() {
+		Context("when the plan has no binding errand", func() {
+			It("unbinds synchronously", func() {
+				spec, err := asyncUnbind(existingPlanID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.IsAsync).To(BeFalse())
+				Expect(serviceAdapter.DeleteBindingCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the plan has a binding errand", func() {
+			var (
+				spec      broker.AsyncUnbindingSpec
+				unbindErr error
+			)
+
+			BeforeEach(func() {
+				Expect(store.Save(bindingstore.Binding{
+					InstanceID:  instanceID,
+					BindingID:   bindingID,
+					Created:     true,
+					Credentials: map[string]interface{}{"user": "some-user"},
+				})).To(Succeed())
+			})
+
+			JustBeforeEach(func() {
+				spec, unbindErr = asyncUnbind(bindingErrandPlanID)
+			})
+
+			It("deploys the binding for the binding errand to delete and returns unbind operation data", func() {
+				Expect(unbindErr).NotTo(HaveOccurred())
+				Expect(spec.IsAsync).To(BeTrue())
+				Expect(boshClient.DeployCallCount()).To(Equal(1))
+				deployedManifest, _, _ := boshClient.DeployArgsForCall(0)
+				Expect(deployedManifest).To(MatchYAML(`---
+name: a-deployment
+instance_groups:
+- name: redis-server
+  jobs:
+  - name: redis-server
+  - name: create-user
+    properties:
+      admin_user: admin
+      binding:
+        id: a-binding
+        operation: unbind
+`))
+
+				var operationData broker.OperationData
+				Expect(json.Unmarshal([]byte(spec.OperationData), &operationData)).To(Succeed())
+				Expect(operationData.OperationType).To(Equal(broker.OperationTypeUnbind))
+				Expect(operationData.BoshTaskID).To(Equal(deployTaskID))
+				Expect(operationData.PostDeployErrandName).To(Equal("create-user"))
+			})
+
+			It("does not delete the binding yet", func() {
+				Expect(serviceAdapter.DeleteBindingCallCount()).To(Equal(0))
+			})
+
+			Context("and the binding does not exist", func() {
+				BeforeEach(func() {
+					Expect(store.Delete(instanceID, bindingID)).To(Succeed())
+				})
+
+				It("returns a binding does not exist error", func() {
+					Expect(unbindErr).To(Equal(brokerapi.ErrBindingDoesNotExist))
+				})
+			})
+
+			Context("and the errand has succeeded", func() {
+				var lastOperation brokerapi.LastOperation
+
+				BeforeEach(func() {
+					boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+						{ID: errandTaskID, State: boshdirector.TaskDone}, deployDone,
+					}, nil)
+				})
+
+				JustBeforeEach(func() {
+					var err error
+					lastOperation, err = lastBindingOperation(spec.OperationData)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("reports that unbinding completed", func() {
+					Expect(lastOperation.State).To(Equal(brokerapi.Succeeded))
+					Expect(lastOperation.Description).To(Equal("Unbinding completed"))
+				})
+
+				It("deletes the binding with the original credentials", func() {
+					Expect(serviceAdapter.DeleteBindingCallCount()).To(Equal(1))
+					_, actualBindingID, vms, actualManifest, requestParams, credentials, _ := serviceAdapter.DeleteBindingArgsForCall(0)
+					Expect(actualBindingID).To(Equal(bindingID))
+					Expect(vms).To(Equal(boshVMs))
+					Expect(actualManifest).To(Equal(manifest))
+					Expect(requestParams).To(Equal(map[string]interface{}{
+						"plan_id":    bindingErrandPlanID,
+						"service_id": serviceOfferingID,
+					}))
+					Expect(credentials).To(Equal(map[string]interface{}{"user": "some-user"}))
+
+					_, found, _ := store.Get(instanceID, bindingID)
+					Expect(found).To(BeFalse())
+				})
+			})
+		})
+
+		It("requires the platform to accept asynchronous unbinding for plans with a binding errand", func() {
+			err := b.Unbind(context.Background(), instanceID, bindingID, brokerapi.UnbindDetails{PlanID: bindingErrandPlanID})
+			Expect(err).To(MatchError(brokerapi.ErrAsyncRequired.Error()))
+		})
+	})
+
+	Describe("polling the binding last operation", func() {
+		It("errors when there is no operation data", func() {
+			_, err := lastBindingOperation("")
+			Expect(err).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+		})
+
+		It("errors when the operation data cannot be parsed", func() {
+			_, err := lastBindingOperation("not-json")
+			Expect(err).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+		})
+
+		It("errors when the operation data has no context ID", func() {
+			_, err := lastBindingOperation(`{"BoshTaskID":1,"OperationType":"bind"}`)
+			Expect(err).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+			Expect(logBuffer.String()).To(ContainSubstring("no context ID found in operation data"))
+		})
+
+		It("errors when BOSH cannot be reached", func() {
+			boshClient.GetNormalisedTasksByContextReturns(nil, errors.New("bosh is down"))
+			_, err := lastBindingOperation(`{"BoshTaskID":1,"BoshContextID":"a-context","OperationType":"bind","PostDeployErrandName":"create-user"}`)
+			Expect(err).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+			Expect(logBuffer.String()).To(ContainSubstring("bosh is down"))
+		})
+	})
+})
@@ -12,6 +12,7 @@ import (
 
 	"github.com/pborman/uuid"
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
 )
@@ -22,8 +23,12 @@ func (b *Broker) Bind(
 	bindingID string,
 	details brokerapi.BindDetails,
 ) (brokerapi.Binding, error) {
+	if _, plan, found := b.offeringForPlan(details.PlanID); found && plan.BindingErrand != "" {
+		return brokerapi.Binding{}, bindingAsyncRequiredError()
+	}
+
 	requestID := uuid.New()
-	ctx = brokercontext.New(ctx, string(OperationTypeBind), requestID, b.serviceOffering.Name, instanceID)
+	ctx = brokercontext.New(ctx, string(OperationTypeBind), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
 	logger := b.loggerFactory.NewWithContext(ctx)
 
 	errs := func(err DisplayableError) (brokerapi.Binding, error) {
@@ -31,6 +36,24 @@ func (b *Broker) Bind(
 		return brokerapi.Binding{}, err.ErrorForCFUser()
 	}
 
+	offering, found := b.offeringForRequest(details.ServiceID, details.PlanID)
+	if !found {
+		return errs(NewDisplayableError(
+			fmt.Errorf("plan %s not found", details.PlanID),
+			fmt.Errorf("error binding: no service offering for service %s and plan %s", details.ServiceID, details.PlanID),
+		))
+	}
+
+	detailsWithRawParameters := brokerapi.DetailsWithRawParameters(details)
+	mappedParams, err := convertDetailsToMap(detailsWithRawParameters)
+	if err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("converting to map %s", err)))
+	}
+
+	if err := validateParameters(b.planSchemas[details.PlanID].bindingCreate, mappedParams); err != NilError {
+		return errs(err)
+	}
+
 	vms, manifest, err := b.getDeploymentInfo(instanceID, logger)
 	switch err.(type) {
 	case boshdirector.RequestError:
@@ -44,13 +67,8 @@ func (b *Broker) Bind(
 	}
 
 	logger.Printf("service adapter will create binding with ID %s for instance %s\n", bindingID, instanceID)
-	detailsWithRawParameters := brokerapi.DetailsWithRawParameters(details)
-	mappedParams, err := convertDetailsToMap(detailsWithRawParameters)
-	if err != nil {
-		return errs(NewGenericError(ctx, fmt.Errorf("converting to map %s", err)))
-	}
 
-	binding, err := b.adapterClient.CreateBinding(bindingID, vms, manifest, mappedParams, logger)
+	binding, err := offering.AdapterClient.CreateBinding(ctx, bindingID, vms, manifest, mappedParams, logger)
 	if err != nil {
 		logger.Printf("creating binding: %v\n", err)
 	}
@@ -59,6 +77,18 @@ func (b *Broker) Bind(
 		return brokerapi.Binding{}, err
 	}
 
+	if err := b.bindingStore.Save(bindingstore.Binding{
+		InstanceID:      instanceID,
+		BindingID:       bindingID,
+		RequestParams:   mappedParams,
+		Created:         true,
+		Credentials:     binding.Credentials,
+		SyslogDrainURL:  binding.SyslogDrainURL,
+		RouteServiceURL: binding.RouteServiceURL,
+	}); err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("saving binding to binding store: %s", err)))
+	}
+
 	return brokerapi.Binding{
 		Credentials:     binding.Credentials,
 		SyslogDrainURL:  binding.SyslogDrainURL,
@@ -11,11 +11,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
 	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
@@ -72,7 +74,7 @@ var _ = Describe("Bind", func() {
 
 	It("creates the binding using the bosh topology and admin credentials", func() {
 		Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(1))
-		passedBindingID, passedVms, passedManifest, passedRequestParameters, _ := serviceAdapter.CreateBindingArgsForCall(0)
+		_, passedBindingID, passedVms, passedManifest, passedRequestParameters, _ := serviceAdapter.CreateBindingArgsForCall(0)
 		Expect(passedBindingID).To(Equal(bindingID))
 		Expect(passedVms).To(Equal(boshVms))
 		Expect(passedManifest).To(Equal(actualManifest))
@@ -99,6 +101,29 @@ var _ = Describe("Bind", func() {
 		Expect(bindErr).NotTo(HaveOccurred())
 	})
 
+	It("stores the binding", func() {
+		Expect(fakeBindingStore.SaveCallCount()).To(Equal(1))
+		storedBinding := fakeBindingStore.SaveArgsForCall(0)
+		Expect(storedBinding.InstanceID).To(Equal(instanceID))
+		Expect(storedBinding.BindingID).To(Equal(bindingID))
+		Expect(storedBinding.Created).To(BeTrue())
+		Expect(storedBinding.Credentials).To(Equal(adapterBindingResponse.Credentials))
+		Expect(storedBinding.SyslogDrainURL).To(Equal(adapterBindingResponse.SyslogDrainURL))
+		Expect(storedBinding.RouteServiceURL).To(Equal(adapterBindingResponse.RouteServiceURL))
+		Expect(storedBinding.RequestParams).To(HaveKeyWithValue("parameters", arbitraryParameters))
+	})
+
+	Context("when the binding cannot be stored", func() {
+		BeforeEach(func() {
+			fakeBindingStore.SaveReturns(errors.New("disk full"))
+		})
+
+		It("returns a generic error", func() {
+			Expect(bindErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+			Expect(logBuffer.String()).To(ContainSubstring("saving binding to binding store: disk full"))
+		})
+	})
+
 	It("logs using a request ID", func() {
 		Expect(logBuffer.String()).To(MatchRegexp(fmt.Sprintf(`\[[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\] \d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} service adapter will create binding with ID %s for instance %s`, bindingID, instanceID)))
 	})
@@ -288,6 +313,41 @@ var _ = Describe("Bind", func() {
 			It("returns a binding already exists error", func() {
 				Expect(bindErr).To(Equal(brokerapi.ErrBindingAlreadyExists))
 			})
+
+			It("does not store the binding", func() {
+				Expect(fakeBindingStore.SaveCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("with a structured error for a bad parameter", func() {
+			BeforeEach(func() {
+				serviceAdapter.CreateBindingReturns(sdk.Binding{}, serviceadapter.ErrorForExitCode(
+					sdk.ErrorExitCode,
+					`{"user_message": "role must be read or write", "operator_message": "unknown role admin", "error_code": "bad_parameter"}`,
+				))
+			})
+
+			It("returns the user message with a bad request status", func() {
+				Expect(bindErr).To(Equal(brokerapi.NewFailureResponse(
+					errors.New("role must be read or write"),
+					http.StatusBadRequest,
+					broker.AdapterErrorLoggerAction,
+				)))
+			})
+		})
+
+		Context("with the adapter timing out", func() {
+			BeforeEach(func() {
+				serviceAdapter.CreateBindingReturns(sdk.Binding{}, serviceadapter.NewTimeoutError("stopped after running create-binding for longer than 1m0s"))
+			})
+
+			It("tells the user the service took too long", func() {
+				Expect(bindErr).To(MatchError(broker.ServiceAdapterTimeoutMessage))
+			})
+
+			It("does not store the binding", func() {
+				Expect(fakeBindingStore.SaveCallCount()).To(Equal(0))
+			})
 		})
 
 		Context("with the app_guid not provided", func() {
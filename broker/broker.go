@@ -7,14 +7,21 @@
 package broker
 
 import (
+	"context"
+	"errors"
 	"log"
-	"strings"
+	"regexp"
 	"sync"
+	"time"
 
+	"github.com/pivotal-cf/on-demand-service-broker/auditlog"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
 	"github.com/pivotal-cf/on-demand-service-broker/config"
 	"github.com/pivotal-cf/on-demand-service-broker/loggerfactory"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+	"github.com/pivotal-cf/on-demand-service-broker/reservationstore"
 	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
 	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
@@ -23,11 +30,24 @@ type Broker struct {
 	boshClient     BoshClient
 	boshInfo       *boshdirector.Info
 	cfClient       CloudFoundryClient
-	adapterClient  ServiceAdapterClient
-	deployer       Deployer
-	deploymentLock *sync.Mutex
+	operationStore OperationStore
+	bindingStore   BindingStore
+	auditLog       AuditLog
+	instanceLocker *instanceLocker
 
-	serviceOffering config.ServiceOffering
+	reservationStore ReservationStore
+	quotaMutex       sync.Mutex
+	quotaReleases    int
+
+	deploymentNames *DeploymentNames
+
+	showTaskProgress       bool
+	failureMessagePatterns []*regexp.Regexp
+
+	operationQueue *operationQueue
+
+	serviceOfferings []ServiceOffering
+	planSchemas      map[string]planSchemas
 
 	loggerFactory *loggerfactory.LoggerFactory
 
@@ -38,10 +58,16 @@ func New(
 	boshInfo *boshdirector.Info,
 	boshClient BoshClient,
 	cfClient CloudFoundryClient,
-	serviceAdapter ServiceAdapterClient,
-	deployer Deployer,
-	serviceOffering config.ServiceOffering,
+	serviceOfferings []ServiceOffering,
+	operationStore OperationStore,
+	bindingStore BindingStore,
+	reservationStore ReservationStore,
+	auditLog AuditLog,
+	deploymentNameTemplate string,
+	lastOperationDetails config.LastOperationDetails,
+	maxInFlightBoshTasks int,
 	disableCfStartupChecks bool,
+	operationLockTimeout time.Duration,
 	loggerFactory *loggerfactory.LoggerFactory,
 
 ) (*Broker, error) {
@@ -49,21 +75,52 @@ func New(
 		boshClient:     boshClient,
 		boshInfo:       boshInfo,
 		cfClient:       cfClient,
-		adapterClient:  serviceAdapter,
-		deployer:       deployer,
-		deploymentLock: &sync.Mutex{},
+		operationStore: operationStore,
+		bindingStore:   bindingStore,
+		auditLog:       auditLog,
+		instanceLocker: newInstanceLocker(operationLockTimeout),
+
+		reservationStore: reservationStore,
 
-		serviceOffering: serviceOffering,
+		serviceOfferings: serviceOfferings,
 
 		loggerFactory: loggerFactory,
 
+		showTaskProgress: lastOperationDetails.ShowProgress,
+
+		operationQueue: newOperationQueue(maxInFlightBoshTasks),
+
 		disableCfStartupChecks: disableCfStartupChecks,
 	}
 
+	if len(serviceOfferings) == 0 {
+		return nil, errors.New("at least one service offering must be configured")
+	}
+
+	planSchemas, err := compilePlanSchemas(b.allPlans())
+	if err != nil {
+		return nil, err
+	}
+	b.planSchemas = planSchemas
+
+	b.deploymentNames, err = NewDeploymentNames(deploymentNameTemplate, b.serviceNames())
+	if err != nil {
+		return nil, err
+	}
+
+	b.failureMessagePatterns, err = compileFailureMessagePatterns(lastOperationDetails.FailureMessagePatterns)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := b.startupChecks(); err != nil {
 		return nil, err
 	}
 
+	if err := b.recogniseLegacyDeployments(); err != nil {
+		return nil, err
+	}
+
 	return b, nil
 }
 
@@ -84,51 +141,96 @@ type OperationData struct {
 	OperationType        OperationType
 	PlanID               string `json:",omitempty"`
 	PostDeployErrandName string `json:",omitempty"`
-}
-
-const InstancePrefix = "service-instance_"
-
-func deploymentName(instanceID string) string {
-	return InstancePrefix + instanceID
-}
-
-func instanceID(deploymentName string) string {
-	return strings.TrimPrefix(deploymentName, InstancePrefix)
+	Force                bool   `json:",omitempty"`
+
+	// PreErrands run before the deploy or delete, PostErrands after it
+	PreErrands  config.Errands `json:",omitempty"`
+	PostErrands config.Errands `json:",omitempty"`
+
+	// PreviousPlanID and RequestParams are kept for a deploy that only
+	// starts once its PreErrands have run or once it leaves the queue.
+	// Cloud Controller stores and returns the operation data, so the
+	// request is kept in the operation store under BoshContextID instead.
+	PreviousPlanID string                 `json:",omitempty"`
+	RequestParams  map[string]interface{} `json:"-"`
+
+	// Queued operations waited in the broker for a BOSH task slot, so
+	// their tasks are found by BoshContextID rather than BoshTaskID.
+	// QueuedAt, in Unix nanoseconds, keeps their order across restarts.
+	Queued   bool  `json:",omitempty"`
+	QueuedAt int64 `json:",omitempty"`
 }
 
 //go:generate counterfeiter -o fakes/fake_deployer.go . Deployer
 type Deployer interface {
-	Create(deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error)
-	Update(deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
-	Upgrade(deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	Create(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error)
+	Update(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	Upgrade(ctx context.Context, deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	GenerateManifest(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, logger *log.Logger) ([]byte, error)
 }
 
 //go:generate counterfeiter -o fakes/fake_service_adapter_client.go . ServiceAdapterClient
 type ServiceAdapterClient interface {
-	CreateBinding(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (serviceadapter.Binding, error)
-	DeleteBinding(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) error
-	GenerateDashboardUrl(instanceID string, plan serviceadapter.Plan, manifest []byte, logger *log.Logger) (string, error)
+	CreateBinding(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (serviceadapter.Binding, error)
+	DeleteBinding(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams, bindingCredentials map[string]interface{}, logger *log.Logger) error
+	GenerateDashboardUrl(ctx context.Context, instanceID string, plan serviceadapter.Plan, manifest []byte, logger *log.Logger) (string, error)
 }
 
 //go:generate counterfeiter -o fakes/fake_bosh_client.go . BoshClient
 type BoshClient interface {
 	GetTask(taskID int, logger *log.Logger) (boshdirector.BoshTask, error)
+	GetTaskEvents(taskID int, logger *log.Logger) (boshdirector.BoshTaskEvents, error)
+	GetTaskOutput(taskID int, logger *log.Logger) ([]boshdirector.BoshTaskOutput, error)
 	GetTasks(deploymentName string, logger *log.Logger) (boshdirector.BoshTasks, error)
+	GetInFlightTasks(logger *log.Logger) (boshdirector.BoshTasks, error)
 	GetNormalisedTasksByContext(deploymentName, contextID string, logger *log.Logger) (boshdirector.BoshTasks, error)
 	VMs(deploymentName string, logger *log.Logger) (bosh.BoshVMs, error)
 	GetDeployment(name string, logger *log.Logger) ([]byte, bool, error)
 	GetDeployments(logger *log.Logger) ([]boshdirector.Deployment, error)
+	Deploy(manifest []byte, contextID string, logger *log.Logger) (int, error)
 	DeleteDeployment(name, contextID string, logger *log.Logger) (int, error)
+	ForceDeleteDeployment(name, contextID string, logger *log.Logger) (int, error)
 	GetInfo(logger *log.Logger) (*boshdirector.Info, error)
-	RunErrand(deploymentName, errandName, contextID string, logger *log.Logger) (int, error)
+	RunErrand(deploymentName, errandName string, instances []string, contextID string, logger *log.Logger) (int, error)
+	CancelTask(taskID int, logger *log.Logger) error
 	VerifyAuth(logger *log.Logger) error
 }
 
+//go:generate counterfeiter -o fakes/fake_operation_store.go . OperationStore
+type OperationStore interface {
+	StartOperation(instanceID string, operation operationstore.Operation) error
+	UpdateOperation(instanceID string, boshTaskID int, status operationstore.OperationStatus) error
+	GetInstance(instanceID string) (operationstore.Instance, bool, error)
+}
+
+//go:generate counterfeiter -o fakes/fake_binding_store.go . BindingStore
+type BindingStore interface {
+	Save(binding bindingstore.Binding) error
+	Get(instanceID, bindingID string) (bindingstore.Binding, bool, error)
+	Delete(instanceID, bindingID string) error
+}
+
+//go:generate counterfeiter -o fakes/fake_reservation_store.go . ReservationStore
+type ReservationStore interface {
+	Reserve(reservation reservationstore.Reservation) error
+	Release(instanceID string) error
+	Reservations() ([]reservationstore.Reservation, error)
+}
+
+//go:generate counterfeiter -o fakes/fake_audit_log.go . AuditLog
+type AuditLog interface {
+	Record(entry auditlog.Entry) error
+}
+
 //go:generate counterfeiter -o fakes/fake_cloud_foundry_client.go . CloudFoundryClient
 type CloudFoundryClient interface {
 	GetAPIVersion(logger *log.Logger) (string, error)
 	CountInstancesOfPlan(serviceOfferingID, planID string, logger *log.Logger) (int, error)
+	CountInstancesOfPlanInOrg(serviceOfferingID, planID, orgGUID string, logger *log.Logger) (int, error)
+	CountInstancesOfPlanInSpace(serviceOfferingID, planID, spaceGUID string, logger *log.Logger) (int, error)
 	CountInstancesOfServiceOffering(serviceOfferingID string, logger *log.Logger) (instanceCountByPlanID map[cf.ServicePlan]int, err error)
+	CountInstancesOfServiceOfferingByOrg(serviceOfferingID string, logger *log.Logger) (instanceCountByOrgByPlan map[cf.ServicePlan]map[string]int, err error)
 	GetInstanceState(serviceInstanceGUID string, logger *log.Logger) (cf.InstanceState, error)
 	GetInstancesOfServiceOffering(serviceOfferingID string, logger *log.Logger) ([]string, error)
+	UpdateServiceInstance(serviceInstanceGUID string, parameters map[string]interface{}, logger *log.Logger) error
 }
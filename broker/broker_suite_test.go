@@ -13,6 +13,7 @@ import (
 	"log"
 	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -37,20 +38,30 @@ const (
 
 	postDeployErrandPlanID = "post-deploy-errand-plan-id"
 	preDeleteErrandPlanID  = "pre-delete-errand-plan-id"
+	bindingErrandPlanID    = "binding-errand-plan-id"
 )
 
 var (
-	b                   *broker.Broker
-	brokerCreationErr   error
-	boshInfo            *boshdirector.Info
-	boshClient          *fakes.FakeBoshClient
-	boshDirectorVersion boshdirector.Version
-	cfClient            *fakes.FakeCloudFoundryClient
-	serviceAdapter      *fakes.FakeServiceAdapterClient
-	fakeDeployer        *fakes.FakeDeployer
-	serviceCatalog      config.ServiceOffering
-	logBuffer           *bytes.Buffer
-	loggerFactory       *loggerfactory.LoggerFactory
+	b                    *broker.Broker
+	brokerCreationErr    error
+	boshInfo             *boshdirector.Info
+	boshClient           *fakes.FakeBoshClient
+	boshDirectorVersion  boshdirector.Version
+	cfClient             *fakes.FakeCloudFoundryClient
+	serviceAdapter       *fakes.FakeServiceAdapterClient
+	fakeDeployer         *fakes.FakeDeployer
+	fakeOperationStore   *fakes.FakeOperationStore
+	fakeBindingStore     *fakes.FakeBindingStore
+	fakeReservationStore *fakes.FakeReservationStore
+	fakeAuditLog         *fakes.FakeAuditLog
+	serviceCatalog       config.ServiceOffering
+	logBuffer            *bytes.Buffer
+	loggerFactory        *loggerfactory.LoggerFactory
+	operationLockTimeout time.Duration
+
+	deploymentNameTemplate string
+	lastOperationDetails   config.LastOperationDetails
+	maxInFlightBoshTasks   int
 
 	existingPlanServiceInstanceLimit    = 3
 	serviceOfferingServiceInstanceLimit = 5
@@ -113,7 +124,7 @@ var _ = BeforeEach(func() {
 	postDeployErrandPlan := config.Plan{
 		ID: postDeployErrandPlanID,
 		LifecycleErrands: &config.LifecycleErrands{
-			PostDeploy: "health-check",
+			PostDeploy: config.Errands{{Name: "health-check"}},
 		},
 		InstanceGroups: []serviceadapter.InstanceGroup{},
 	}
@@ -121,14 +132,24 @@ var _ = BeforeEach(func() {
 	preDeleteErrandPlan := config.Plan{
 		ID: preDeleteErrandPlanID,
 		LifecycleErrands: &config.LifecycleErrands{
-			PreDelete: "cleanup-resources",
+			PreDelete: config.Errands{{Name: "cleanup-resources"}},
 		},
 		InstanceGroups: []serviceadapter.InstanceGroup{},
 	}
 
+	bindingErrandPlan := config.Plan{
+		ID:             bindingErrandPlanID,
+		BindingErrand:  "create-user",
+		InstanceGroups: []serviceadapter.InstanceGroup{},
+	}
+
 	boshClient = new(fakes.FakeBoshClient)
 	serviceAdapter = new(fakes.FakeServiceAdapterClient)
 	fakeDeployer = new(fakes.FakeDeployer)
+	fakeOperationStore = new(fakes.FakeOperationStore)
+	fakeBindingStore = new(fakes.FakeBindingStore)
+	fakeReservationStore = new(fakes.FakeReservationStore)
+	fakeAuditLog = new(fakes.FakeAuditLog)
 	cfClient = new(fakes.FakeCloudFoundryClient)
 	cfClient.GetAPIVersionReturns("2.57.0", nil)
 
@@ -144,9 +165,15 @@ var _ = BeforeEach(func() {
 			secondPlan,
 			postDeployErrandPlan,
 			preDeleteErrandPlan,
+			bindingErrandPlan,
 		},
 	}
 
+	operationLockTimeout = 0
+	deploymentNameTemplate = ""
+	lastOperationDetails = config.LastOperationDetails{}
+	maxInFlightBoshTasks = 0
+
 	logBuffer = new(bytes.Buffer)
 	loggerFactory = loggerfactory.New(io.MultiWriter(GinkgoWriter, logBuffer), "broker-unit-tests", log.LstdFlags)
 })
@@ -188,10 +215,16 @@ func createBroker(info *boshdirector.Info) (*broker.Broker, error) {
 		info,
 		boshClient,
 		cfClient,
-		serviceAdapter,
-		fakeDeployer,
-		serviceCatalog,
+		[]broker.ServiceOffering{{Catalog: serviceCatalog, AdapterClient: serviceAdapter, Deployer: fakeDeployer}},
+		fakeOperationStore,
+		fakeBindingStore,
+		fakeReservationStore,
+		fakeAuditLog,
+		deploymentNameTemplate,
+		lastOperationDetails,
+		maxInFlightBoshTasks,
 		false,
+		operationLockTimeout,
 		loggerFactory,
 	)
 }
@@ -0,0 +1,129 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+// CancelOperation cancels the BOSH tasks of the operation in progress on an
+// instance and returns their IDs. Only operations the broker has recorded are
+// cancelled, as other tasks on the deployment may not be the broker's to
+// cancel. It does not wait for the instance's lock, as the operation being
+// cancelled may be holding it; the lock is released once the tasks are
+// cancelled, if the request that started the operation still holds it.
+func (b *Broker) CancelOperation(ctx context.Context, instanceID string, logger *log.Logger) ([]int, error) {
+	operation, recorded := b.operationInProgress(instanceID, logger)
+	if !recorded {
+		return nil, NewNoOperationInProgressError(fmt.Errorf("no operation in progress for instance %s", instanceID))
+	}
+
+	tasks, err := b.boshClient.GetTasks(b.deploymentName(instanceID), logger)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving tasks from bosh, for deployment '%s': %s", b.deploymentName(instanceID), err)
+	}
+
+	var cancelledTaskIDs []int
+	for _, task := range tasks.IncompleteTasks() {
+		if task.State == boshdirector.TaskCancelling || !operationIncludesTask(operation, task) {
+			continue
+		}
+
+		if err := b.boshClient.CancelTask(task.ID, logger); err != nil {
+			return cancelledTaskIDs, fmt.Errorf("error cancelling BOSH task %d: %s", task.ID, err)
+		}
+		cancelledTaskIDs = append(cancelledTaskIDs, task.ID)
+	}
+
+	if len(cancelledTaskIDs) == 0 {
+		return nil, NewNoOperationInProgressError(fmt.Errorf("no operation in progress for instance %s", instanceID))
+	}
+
+	logger.Printf("cancelled BOSH tasks %v for instance %s\n", cancelledTaskIDs, instanceID)
+	b.recordCancellation(instanceID, operation, logger)
+	if b.instanceLocker.ForceUnlock(instanceID, operation.RequestID) {
+		logger.Printf("released the operation lock of instance %s\n", instanceID)
+	}
+	b.releaseQuota(instanceID, logger)
+
+	return cancelledTaskIDs, nil
+}
+
+func (b *Broker) operationInProgress(instanceID string, logger *log.Logger) (operationstore.Operation, bool) {
+	instance, found, err := b.operationStore.GetInstance(instanceID)
+	if err != nil {
+		logger.Printf("error reading operations of instance %s: %s\n", instanceID, err)
+		return operationstore.Operation{}, false
+	}
+	if !found {
+		return operationstore.Operation{}, false
+	}
+
+	operation, found := instance.LastOperation()
+	if !found || operation.State != operationstore.OperationInProgress {
+		return operationstore.Operation{}, false
+	}
+
+	return operation, true
+}
+
+func (b *Broker) recordCancellation(instanceID string, operation operationstore.Operation, logger *log.Logger) {
+	if len(operation.BoshTaskIDs) == 0 {
+		return
+	}
+
+	status := operationstore.OperationStatus{
+		State:       operation.State,
+		Description: operation.Description,
+		Cancelled:   true,
+	}
+	if err := b.operationStore.UpdateOperation(instanceID, operation.BoshTaskIDs[0], status); err != nil {
+		logger.Printf("error recording cancellation of %s operation for instance %s: %s\n", operation.Type, instanceID, err)
+	}
+}
+
+// cancelledByBroker reports whether a cancelled task belongs to an operation
+// the broker cancelled, rather than one cancelled directly through BOSH.
+func (b *Broker) cancelledByBroker(instanceID string, task boshdirector.BoshTask, logger *log.Logger) bool {
+	if task.State != boshdirector.TaskCancelled {
+		return false
+	}
+
+	instance, found, err := b.operationStore.GetInstance(instanceID)
+	if err != nil {
+		logger.Printf("error reading operations of instance %s: %s\n", instanceID, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+
+	for _, operation := range instance.Operations {
+		if operationIncludesTask(operation, task) {
+			return operation.Cancelled
+		}
+	}
+	return false
+}
+
+func operationIncludesTask(operation operationstore.Operation, task boshdirector.BoshTask) bool {
+	if operation.BoshContextID != "" && task.ContextID == operation.BoshContextID {
+		return true
+	}
+
+	for _, id := range operation.BoshTaskIDs {
+		if id == task.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var _ = Describe("cancelling an operation", func() {
+	const (
+		instanceID = "some-instance-id"
+		contextID  = "some-context-id"
+	)
+
+	var (
+		cancelledTaskIDs []int
+		cancelErr        error
+	)
+
+	cancelledTasks := func() []int {
+		var taskIDs []int
+		for i := 0; i < boshClient.CancelTaskCallCount(); i++ {
+			taskID, _ := boshClient.CancelTaskArgsForCall(i)
+			taskIDs = append(taskIDs, taskID)
+		}
+		return taskIDs
+	}
+
+	BeforeEach(func() {
+		boshClient.GetTasksReturns(boshdirector.BoshTasks{
+			{ID: 3, State: boshdirector.TaskProcessing, ContextID: contextID},
+			{ID: 2, State: boshdirector.TaskQueued, ContextID: "someone-elses-context-id"},
+			{ID: 1, State: boshdirector.TaskDone, ContextID: contextID},
+		}, nil)
+		fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+			ID: instanceID,
+			Operations: []operationstore.Operation{
+				{Type: "create", BoshTaskIDs: []int{1}, BoshContextID: contextID, State: operationstore.OperationInProgress},
+			},
+		}, true, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+		cancelledTaskIDs, cancelErr = b.CancelOperation(context.Background(), instanceID, loggerFactory.New())
+	})
+
+	Context("when the broker has recorded the operation in progress", func() {
+		It("cancels the incomplete tasks of the operation", func() {
+			Expect(cancelErr).NotTo(HaveOccurred())
+			Expect(cancelledTaskIDs).To(Equal([]int{3}))
+			Expect(cancelledTasks()).To(Equal([]int{3}))
+
+			deployment, _ := boshClient.GetTasksArgsForCall(0)
+			Expect(deployment).To(Equal("service-instance_" + instanceID))
+		})
+
+		It("releases the instance's quota reservation", func() {
+			Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(1))
+			Expect(fakeReservationStore.ReleaseArgsForCall(0)).To(Equal(instanceID))
+		})
+
+		It("logs the cancelled tasks", func() {
+			Expect(logBuffer.String()).To(ContainSubstring("cancelled BOSH tasks [3] for instance %s", instanceID))
+		})
+
+		It("records that the broker cancelled the operation", func() {
+			Expect(fakeOperationStore.UpdateOperationCallCount()).To(Equal(1))
+			actualInstanceID, taskID, status := fakeOperationStore.UpdateOperationArgsForCall(0)
+			Expect(actualInstanceID).To(Equal(instanceID))
+			Expect(taskID).To(Equal(1))
+			Expect(status).To(Equal(operationstore.OperationStatus{State: operationstore.OperationInProgress, Cancelled: true}))
+		})
+	})
+
+	Context("when the recorded operation has no context ID", func() {
+		BeforeEach(func() {
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+				ID: instanceID,
+				Operations: []operationstore.Operation{
+					{Type: "update", BoshTaskIDs: []int{2}, State: operationstore.OperationInProgress},
+				},
+			}, true, nil)
+		})
+
+		It("cancels the tasks of the operation", func() {
+			Expect(cancelErr).NotTo(HaveOccurred())
+			Expect(cancelledTasks()).To(Equal([]int{2}))
+		})
+	})
+
+	Context("when the broker has no record of the operation", func() {
+		BeforeEach(func() {
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{}, false, nil)
+		})
+
+		It("returns an error saying no operation is in progress without cancelling any task", func() {
+			Expect(cancelErr).To(BeAssignableToTypeOf(broker.NoOperationInProgressError{}))
+			Expect(boshClient.CancelTaskCallCount()).To(Equal(0))
+			Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the recorded operation has finished", func() {
+		BeforeEach(func() {
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+				ID: instanceID,
+				Operations: []operationstore.Operation{
+					{Type: "create", BoshTaskIDs: []int{1}, BoshContextID: contextID, State: operationstore.OperationSucceeded},
+				},
+			}, true, nil)
+		})
+
+		It("returns an error saying no operation is in progress without cancelling any task", func() {
+			Expect(cancelErr).To(BeAssignableToTypeOf(broker.NoOperationInProgressError{}))
+			Expect(boshClient.CancelTaskCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the tasks are already being cancelled", func() {
+		BeforeEach(func() {
+			boshClient.GetTasksReturns(boshdirector.BoshTasks{
+				{ID: 3, State: boshdirector.TaskCancelling},
+				{ID: 1, State: boshdirector.TaskDone},
+			}, nil)
+		})
+
+		It("returns an error saying no operation is in progress", func() {
+			Expect(cancelErr).To(BeAssignableToTypeOf(broker.NoOperationInProgressError{}))
+			Expect(cancelErr).To(MatchError(fmt.Sprintf("no operation in progress for instance %s", instanceID)))
+			Expect(boshClient.CancelTaskCallCount()).To(Equal(0))
+			Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the tasks cannot be retrieved", func() {
+		BeforeEach(func() {
+			boshClient.GetTasksReturns(nil, errors.New("bosh is down"))
+		})
+
+		It("returns an error", func() {
+			Expect(cancelErr).To(MatchError(ContainSubstring("bosh is down")))
+		})
+	})
+
+	Context("when a task cannot be cancelled", func() {
+		BeforeEach(func() {
+			boshClient.CancelTaskReturns(errors.New("bosh is down"))
+		})
+
+		It("returns an error", func() {
+			Expect(cancelErr).To(MatchError("error cancelling BOSH task 3: bosh is down"))
+		})
+	})
+})
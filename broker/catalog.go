@@ -13,8 +13,17 @@ import (
 )
 
 func (b *Broker) Services(_ context.Context) []brokerapi.Service {
+	services := []brokerapi.Service{}
+	for _, offering := range b.serviceOfferings {
+		services = append(services, catalogService(offering))
+	}
+	return services
+}
+
+func catalogService(offering ServiceOffering) brokerapi.Service {
+	serviceOffering := offering.Catalog
 	servicePlans := []brokerapi.ServicePlan{}
-	for _, plan := range b.serviceOffering.Plans {
+	for _, plan := range serviceOffering.Plans {
 		planCosts := []brokerapi.ServicePlanCost{}
 		for _, cost := range plan.Metadata.Costs {
 			planCosts = append(planCosts, brokerapi.ServicePlanCost{Amount: cost.Amount, Unit: cost.Unit})
@@ -31,40 +40,54 @@ func (b *Broker) Services(_ context.Context) []brokerapi.Service {
 				Bullets:     plan.Metadata.Bullets,
 				Costs:       planCosts,
 			},
+			Schemas:         brokerapiSchemas(plan.Schemas),
+			MaintenanceInfo: maintenanceInfo(offering),
 		}
 		servicePlans = append(servicePlans, servicePlan)
 	}
 
 	var dashboardClient *brokerapi.ServiceDashboardClient
-	if b.serviceOffering.DashboardClient != nil {
+	if serviceOffering.DashboardClient != nil {
 		dashboardClient = &brokerapi.ServiceDashboardClient{
-			ID:          b.serviceOffering.DashboardClient.ID,
-			Secret:      b.serviceOffering.DashboardClient.Secret,
-			RedirectURI: b.serviceOffering.DashboardClient.RedirectUri,
+			ID:          serviceOffering.DashboardClient.ID,
+			Secret:      serviceOffering.DashboardClient.Secret,
+			RedirectURI: serviceOffering.DashboardClient.RedirectUri,
 		}
 	}
 
-	return []brokerapi.Service{
-		{
-			ID:            b.serviceOffering.ID,
-			Name:          b.serviceOffering.Name,
-			Description:   b.serviceOffering.Description,
-			Bindable:      b.serviceOffering.Bindable,
-			PlanUpdatable: b.serviceOffering.PlanUpdatable,
-			Plans:         servicePlans,
-			Metadata: &brokerapi.ServiceMetadata{
-				DisplayName:         b.serviceOffering.Metadata.DisplayName,
-				ImageUrl:            b.serviceOffering.Metadata.ImageURL,
-				LongDescription:     b.serviceOffering.Metadata.LongDescription,
-				ProviderDisplayName: b.serviceOffering.Metadata.ProviderDisplayName,
-				DocumentationUrl:    b.serviceOffering.Metadata.DocumentationURL,
-				SupportUrl:          b.serviceOffering.Metadata.SupportURL,
-			},
-			DashboardClient: dashboardClient,
-			Requires:        requiredPermissions(b.serviceOffering.Requires),
-			Tags:            b.serviceOffering.Tags,
+	return brokerapi.Service{
+		ID:                   serviceOffering.ID,
+		Name:                 serviceOffering.Name,
+		Description:          serviceOffering.Description,
+		Bindable:             serviceOffering.Bindable,
+		PlanUpdatable:        serviceOffering.PlanUpdatable,
+		InstancesRetrievable: true,
+		BindingsRetrievable:  offering.BindingsRetrievable,
+		Plans:                servicePlans,
+		Metadata: &brokerapi.ServiceMetadata{
+			DisplayName:         serviceOffering.Metadata.DisplayName,
+			ImageUrl:            serviceOffering.Metadata.ImageURL,
+			LongDescription:     serviceOffering.Metadata.LongDescription,
+			ProviderDisplayName: serviceOffering.Metadata.ProviderDisplayName,
+			DocumentationUrl:    serviceOffering.Metadata.DocumentationURL,
+			SupportUrl:          serviceOffering.Metadata.SupportURL,
 		},
+		DashboardClient: dashboardClient,
+		Requires:        requiredPermissions(serviceOffering.Requires),
+		Tags:            serviceOffering.Tags,
+	}
+}
+
+func maintenanceInfo(offering ServiceOffering) *brokerapi.MaintenanceInfo {
+	if offering.MaintenanceVersion == "" {
+		return nil
+	}
+
+	info := &brokerapi.MaintenanceInfo{Version: offering.MaintenanceVersion}
+	if offering.Catalog.MaintenanceInfo != nil {
+		info.Description = offering.Catalog.MaintenanceInfo.Description
 	}
+	return info
 }
 
 func requiredPermissions(permissions []string) []brokerapi.RequiredPermission {
@@ -13,5 +13,15 @@ import (
 )
 
 func (b *Broker) CountInstancesOfPlans(logger *log.Logger) (map[cf.ServicePlan]int, error) {
-	return b.cfClient.CountInstancesOfServiceOffering(b.serviceOffering.ID, logger)
+	counts := map[cf.ServicePlan]int{}
+	for _, offering := range b.serviceOfferings {
+		offeringCounts, err := b.cfClient.CountInstancesOfServiceOffering(offering.Catalog.ID, logger)
+		for plan, count := range offeringCounts {
+			counts[plan] = count
+		}
+		if err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
 }
@@ -0,0 +1,209 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+)
+
+// DashboardURLResyncParameter is the only parameter of the updates the
+// broker asks Cloud Foundry to make when re-syncing dashboard URLs. The broker
+// answers such an update with the instance's dashboard URL, without deploying.
+const DashboardURLResyncParameter = "resync_dashboard_url"
+
+// dashboardURLWorkers bounds how many instances have their dashboard URL
+// generated or re-synced at once, as each asks BOSH for a manifest and runs
+// the adapter.
+const dashboardURLWorkers = 5
+
+type InstanceDashboardURL struct {
+	InstanceID   string `json:"service_instance_id"`
+	DashboardURL string `json:"dashboard_url,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// regenerateDashboardURL asks the adapter for the dashboard URL of an
+// instance that is being deployed with the manifest. The operation has
+// already started, so failures are only logged.
+func (b *Broker) regenerateDashboardURL(
+	ctx context.Context,
+	instanceID string,
+	offering ServiceOffering,
+	plan config.Plan,
+	manifest []byte,
+	requestParams map[string]interface{},
+	previousPlanID string,
+	logger *log.Logger,
+) string {
+	// the deploy waits for errands or for a BOSH task slot, so its manifest
+	// is generated here from the same request to tell the platform the URL
+	if manifest == nil {
+		var err error
+		manifest, err = offering.Deployer.GenerateManifest(ctx, b.deploymentName(instanceID), plan.ID, requestParams, &previousPlanID, logger)
+		if err != nil {
+			logger.Printf("generating manifest for the dashboard URL of instance %s: %s\n", instanceID, err)
+			return ""
+		}
+	}
+
+	abridgedPlan := plan.AdapterPlan(offering.Catalog.GlobalProperties)
+	dashboardURL, err := offering.AdapterClient.GenerateDashboardUrl(ctx, instanceID, abridgedPlan, manifest, logger)
+	switch err.(type) {
+	case nil:
+		return dashboardURL
+	case serviceadapter.NotImplementedError:
+		return ""
+	default:
+		logger.Printf("regenerating dashboard URL of instance %s: %s\n", instanceID, err)
+		return ""
+	}
+}
+
+// DashboardURLs lists the dashboard URL the adapter generates for every
+// instance from its current manifest, so that an operator can compare them
+// with those Cloud Foundry holds. Nothing is updated in Cloud Foundry.
+func (b *Broker) DashboardURLs(ctx context.Context, logger *log.Logger) ([]InstanceDashboardURL, error) {
+	instanceIDs, err := b.Instances(logger)
+	if err != nil {
+		logger.Printf("error listing instances: %s", err)
+		return nil, err
+	}
+
+	dashboardURLs := forEachInstance(ctx, instanceIDs, func(instanceID string) InstanceDashboardURL {
+		instance, err := b.GetInstance(ctx, instanceID)
+		if err != nil {
+			return InstanceDashboardURL{InstanceID: instanceID, Error: err.Error()}
+		}
+		return InstanceDashboardURL{InstanceID: instanceID, DashboardURL: instance.DashboardURL}
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return dashboardURLs, nil
+}
+
+// ResyncDashboardURLs asks Cloud Foundry to update every instance with only
+// DashboardURLResyncParameter, so that it records the dashboard URL the broker
+// answers the update with. An instance whose update fails is listed with the
+// error.
+func (b *Broker) ResyncDashboardURLs(ctx context.Context, logger *log.Logger) ([]InstanceDashboardURL, error) {
+	instanceIDs, err := b.Instances(logger)
+	if err != nil {
+		logger.Printf("error listing instances: %s", err)
+		return nil, err
+	}
+
+	parameters := map[string]interface{}{DashboardURLResyncParameter: true}
+	dashboardURLs := forEachInstance(ctx, instanceIDs, func(instanceID string) InstanceDashboardURL {
+		if err := b.cfClient.UpdateServiceInstance(instanceID, parameters, logger); err != nil {
+			logger.Printf("error re-syncing dashboard URL of instance %s: %s", instanceID, err)
+			return InstanceDashboardURL{InstanceID: instanceID, Error: err.Error()}
+		}
+		return InstanceDashboardURL{InstanceID: instanceID}
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return dashboardURLs, nil
+}
+
+// forEachInstance calls dashboardURL for every instance, with at most
+// dashboardURLWorkers calls running at once, and lists the results in the
+// order of the instances. No more calls are started once ctx is done.
+func forEachInstance(ctx context.Context, instanceIDs []string, dashboardURL func(instanceID string) InstanceDashboardURL) []InstanceDashboardURL {
+	dashboardURLs := make([]InstanceDashboardURL, len(instanceIDs))
+	indices := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < dashboardURLWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				dashboardURLs[i] = dashboardURL(instanceIDs[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range instanceIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	return dashboardURLs
+}
+
+// dashboardURLResyncRequested reports whether an update only asks for the
+// dashboard URL of the instance, as made by ResyncDashboardURLs.
+func dashboardURLResyncRequested(details brokerapi.UpdateDetails) bool {
+	if details.PlanID != details.PreviousValues.PlanID || len(details.RawParameters) == 0 {
+		return false
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal(details.RawParameters, &params); err != nil {
+		return false
+	}
+	return len(params) == 1 && params[DashboardURLResyncParameter] == true
+}
+
+// resyncDashboardURL answers an update made by ResyncDashboardURLs with the
+// dashboard URL generated from the instance's current manifest.
+func (b *Broker) resyncDashboardURL(ctx context.Context, instanceID string, offering ServiceOffering, plan config.Plan, logger *log.Logger) (brokerapi.UpdateServiceSpec, error) {
+	errs := func(err DisplayableError) (brokerapi.UpdateServiceSpec, error) {
+		logger.Println(err)
+		return brokerapi.UpdateServiceSpec{}, err.ErrorForCFUser()
+	}
+
+	logger.Printf("re-syncing dashboard URL of instance %s", instanceID)
+
+	manifest, found, err := b.boshClient.GetDeployment(b.deploymentName(instanceID), logger)
+	switch err.(type) {
+	case boshdirector.RequestError:
+		return errs(NewBoshRequestError("update", fmt.Errorf("could not get manifest: %s", err)))
+	case error:
+		return errs(NewGenericError(ctx, fmt.Errorf("could not get manifest: %s", err)))
+	}
+
+	if !found {
+		return errs(NewDisplayableError(
+			brokerapi.ErrInstanceDoesNotExist,
+			fmt.Errorf("re-syncing dashboard URL: deployment %s not found", b.deploymentName(instanceID)),
+		))
+	}
+
+	abridgedPlan := plan.AdapterPlan(offering.Catalog.GlobalProperties)
+	dashboardURL, err := offering.AdapterClient.GenerateDashboardUrl(ctx, instanceID, abridgedPlan, manifest, logger)
+	switch err.(type) {
+	case nil, serviceadapter.NotImplementedError:
+		return brokerapi.UpdateServiceSpec{DashboardURL: dashboardURL}, nil
+	default:
+		logger.Printf("generating dashboard: %v\n", err)
+		return brokerapi.UpdateServiceSpec{}, adapterToAPIError(ctx, err)
+	}
+}
@@ -0,0 +1,422 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("dashboard URLs", func() {
+	const (
+		instanceID   = "some-instance-id"
+		dashboardURL = "https://dashboard.example.com/some-instance-id"
+	)
+
+	var (
+		manifest         = []byte("new-manifest")
+		deferredManifest = []byte("deferred-manifest")
+	)
+
+	BeforeEach(func() {
+		fakeDeployer.UpdateReturns(42, manifest, nil)
+		fakeDeployer.UpgradeReturns(42, manifest, nil)
+		fakeDeployer.GenerateManifestReturns(deferredManifest, nil)
+		serviceAdapter.GenerateDashboardUrlReturns(dashboardURL, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	Describe("updating", func() {
+		var (
+			updateSpec brokerapi.UpdateServiceSpec
+			updateErr  error
+		)
+
+		JustBeforeEach(func() {
+			updateSpec, updateErr = b.Update(
+				context.Background(),
+				instanceID,
+				brokerapi.UpdateDetails{
+					PlanID:         secondPlanID,
+					ServiceID:      serviceOfferingID,
+					PreviousValues: brokerapi.PreviousValues{PlanID: existingPlanID},
+				},
+				true,
+			)
+		})
+
+		It("returns the dashboard URL for the new manifest", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(updateSpec.DashboardURL).To(Equal(dashboardURL))
+
+			Expect(serviceAdapter.GenerateDashboardUrlCallCount()).To(Equal(1))
+			_, actualInstanceID, actualPlan, actualManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
+			Expect(actualInstanceID).To(Equal(instanceID))
+			Expect(actualPlan).To(Equal(secondPlan.AdapterPlan(serviceCatalog.GlobalProperties)))
+			Expect(actualManifest).To(Equal(manifest))
+		})
+
+		Context("when the adapter does not generate dashboard URLs", func() {
+			BeforeEach(func() {
+				serviceAdapter.GenerateDashboardUrlReturns("", serviceadapter.NewNotImplementedError("not implemented"))
+			})
+
+			It("returns no dashboard URL", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(updateSpec.DashboardURL).To(BeEmpty())
+			})
+		})
+
+		Context("when the adapter fails to generate the dashboard URL", func() {
+			BeforeEach(func() {
+				serviceAdapter.GenerateDashboardUrlReturns("", errors.New("adapter is broken"))
+			})
+
+			It("still updates the instance", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(updateSpec.DashboardURL).To(BeEmpty())
+				Expect(updateSpec.OperationData).NotTo(BeEmpty())
+				Expect(logBuffer.String()).To(ContainSubstring("regenerating dashboard URL of instance %s: adapter is broken", instanceID))
+			})
+		})
+
+		Context("when errands run before the deploy", func() {
+			BeforeEach(func() {
+				serviceCatalog.Plans[1].LifecycleErrands = &config.LifecycleErrands{
+					PreDeploy: config.Errands{{Name: "backup"}},
+				}
+				boshClient.RunErrandReturns(43, nil)
+			})
+
+			It("returns the dashboard URL for the manifest the deploy will have", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(updateSpec.DashboardURL).To(Equal(dashboardURL))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+
+				Expect(fakeDeployer.GenerateManifestCallCount()).To(Equal(1))
+				_, actualDeploymentName, actualPlanID, requestParams, previousPlanID, _ := fakeDeployer.GenerateManifestArgsForCall(0)
+				Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+				Expect(actualPlanID).To(Equal(secondPlanID))
+				Expect(requestParams).To(HaveKeyWithValue("plan_id", secondPlanID))
+				Expect(*previousPlanID).To(Equal(existingPlanID))
+
+				_, _, _, actualManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
+				Expect(actualManifest).To(Equal(deferredManifest))
+			})
+
+			Context("and the manifest cannot be generated", func() {
+				BeforeEach(func() {
+					fakeDeployer.GenerateManifestReturns(nil, errors.New("adapter is broken"))
+				})
+
+				It("still updates the instance without a dashboard URL", func() {
+					Expect(updateErr).NotTo(HaveOccurred())
+					Expect(updateSpec.DashboardURL).To(BeEmpty())
+					Expect(updateSpec.OperationData).NotTo(BeEmpty())
+					Expect(logBuffer.String()).To(ContainSubstring("generating manifest for the dashboard URL of instance %s: adapter is broken", instanceID))
+				})
+			})
+		})
+	})
+
+	Describe("upgrading", func() {
+		BeforeEach(func() {
+			cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: existingPlanID}, nil)
+		})
+
+		It("returns the dashboard URL for the new manifest", func() {
+			_, actualDashboardURL, err := b.Upgrade(context.Background(), instanceID, loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualDashboardURL).To(Equal(dashboardURL))
+
+			_, _, _, actualManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
+			Expect(actualManifest).To(Equal(manifest))
+		})
+
+		It("returns the dashboard URL for the manifest a queued upgrade will have", func() {
+			maxInFlightBoshTasks = 1
+			boshClient.GetInFlightTasksReturns(boshdirector.BoshTasks{{ID: 1, Deployment: deploymentName("busy-instance-id")}}, nil)
+			b = createDefaultBroker()
+
+			operationData, actualDashboardURL, err := b.Upgrade(context.Background(), instanceID, loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(operationData.Queued).To(BeTrue())
+			Expect(actualDashboardURL).To(Equal(dashboardURL))
+			Expect(fakeDeployer.UpgradeCallCount()).To(Equal(0))
+
+			Expect(fakeDeployer.GenerateManifestCallCount()).To(Equal(1))
+			_, _, actualPlanID, requestParams, previousPlanID, _ := fakeDeployer.GenerateManifestArgsForCall(0)
+			Expect(actualPlanID).To(Equal(existingPlanID))
+			Expect(requestParams).To(BeNil())
+			Expect(*previousPlanID).To(Equal(existingPlanID))
+
+			_, _, _, actualManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
+			Expect(actualManifest).To(Equal(deferredManifest))
+		})
+	})
+
+	Describe("listing the dashboard URL of every instance", func() {
+		var (
+			ctx           context.Context
+			dashboardURLs []broker.InstanceDashboardURL
+			listErr       error
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			cfClient.GetInstancesOfServiceOfferingReturns([]string{instanceID, "deleted-instance-id"}, nil)
+			cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: existingPlanID}, nil)
+			boshClient.GetDeploymentStub = func(name string, logger *log.Logger) ([]byte, bool, error) {
+				return manifest, name == deploymentName(instanceID), nil
+			}
+		})
+
+		JustBeforeEach(func() {
+			dashboardURLs, listErr = b.DashboardURLs(ctx, loggerFactory.New())
+		})
+
+		It("generates the dashboard URL of each instance from its current manifest", func() {
+			Expect(listErr).NotTo(HaveOccurred())
+			Expect(dashboardURLs).To(Equal([]broker.InstanceDashboardURL{
+				{InstanceID: instanceID, DashboardURL: dashboardURL},
+				{InstanceID: "deleted-instance-id", Error: brokerapi.ErrInstanceDoesNotExist.Error()},
+			}))
+		})
+
+		Context("when the instances cannot be listed", func() {
+			BeforeEach(func() {
+				cfClient.GetInstancesOfServiceOfferingReturns(nil, errors.New("cloud controller is down"))
+			})
+
+			It("returns an error", func() {
+				Expect(listErr).To(MatchError("cloud controller is down"))
+			})
+		})
+
+		Context("when the request is cancelled", func() {
+			BeforeEach(func() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(context.Background())
+				cancel()
+			})
+
+			It("stops generating dashboard URLs", func() {
+				Expect(listErr).To(Equal(context.Canceled))
+				Expect(serviceAdapter.GenerateDashboardUrlCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("generating the dashboard URLs of many instances", func() {
+		var (
+			release chan struct{}
+			mutex   sync.Mutex
+			running int
+			peak    int
+		)
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			running, peak = 0, 0
+
+			var instanceIDs []string
+			for i := 0; i < 12; i++ {
+				instanceIDs = append(instanceIDs, fmt.Sprintf("instance-%d", i))
+			}
+			cfClient.GetInstancesOfServiceOfferingReturns(instanceIDs, nil)
+			cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: existingPlanID}, nil)
+			boshClient.GetDeploymentReturns(manifest, true, nil)
+			serviceAdapter.GenerateDashboardUrlStub = func(_ context.Context, instanceID string, _ sdk.Plan, _ []byte, _ *log.Logger) (string, error) {
+				mutex.Lock()
+				running++
+				if running > peak {
+					peak = running
+				}
+				mutex.Unlock()
+
+				<-release
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+				return "https://dashboard.example.com/" + instanceID, nil
+			}
+		})
+
+		It("generates a bounded number at once and lists them in order", func() {
+			done := make(chan []broker.InstanceDashboardURL)
+			go func() {
+				defer GinkgoRecover()
+				dashboardURLs, err := b.DashboardURLs(context.Background(), loggerFactory.New())
+				Expect(err).NotTo(HaveOccurred())
+				done <- dashboardURLs
+			}()
+
+			peakRunning := func() int {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return peak
+			}
+			Eventually(peakRunning).Should(Equal(5))
+			Consistently(peakRunning).Should(Equal(5))
+			close(release)
+
+			var dashboardURLs []broker.InstanceDashboardURL
+			Eventually(done).Should(Receive(&dashboardURLs))
+			Expect(dashboardURLs).To(HaveLen(12))
+			for i, dashboardURL := range dashboardURLs {
+				Expect(dashboardURL.InstanceID).To(Equal(fmt.Sprintf("instance-%d", i)))
+				Expect(dashboardURL.DashboardURL).To(Equal(fmt.Sprintf("https://dashboard.example.com/instance-%d", i)))
+			}
+		})
+	})
+
+	Describe("re-syncing the dashboard URL of every instance", func() {
+		var (
+			dashboardURLs []broker.InstanceDashboardURL
+			resyncErr     error
+		)
+
+		BeforeEach(func() {
+			cfClient.GetInstancesOfServiceOfferingReturns([]string{instanceID, "another-instance-id"}, nil)
+			cfClient.UpdateServiceInstanceStub = func(instanceGUID string, _ map[string]interface{}, _ *log.Logger) error {
+				if instanceGUID == "another-instance-id" {
+					return errors.New("cloud controller is down")
+				}
+				return nil
+			}
+		})
+
+		JustBeforeEach(func() {
+			dashboardURLs, resyncErr = b.ResyncDashboardURLs(context.Background(), loggerFactory.New())
+		})
+
+		It("asks Cloud Foundry to update each instance with only the re-sync parameter", func() {
+			Expect(resyncErr).NotTo(HaveOccurred())
+			Expect(cfClient.UpdateServiceInstanceCallCount()).To(Equal(2))
+
+			var updatedInstanceIDs []string
+			for i := 0; i < cfClient.UpdateServiceInstanceCallCount(); i++ {
+				instanceGUID, parameters, _ := cfClient.UpdateServiceInstanceArgsForCall(i)
+				updatedInstanceIDs = append(updatedInstanceIDs, instanceGUID)
+				Expect(parameters).To(Equal(map[string]interface{}{broker.DashboardURLResyncParameter: true}))
+			}
+			Expect(updatedInstanceIDs).To(ConsistOf(instanceID, "another-instance-id"))
+		})
+
+		It("lists the instances whose update failed with the error", func() {
+			Expect(dashboardURLs).To(Equal([]broker.InstanceDashboardURL{
+				{InstanceID: instanceID},
+				{InstanceID: "another-instance-id", Error: "cloud controller is down"},
+			}))
+		})
+
+		Context("when the instances cannot be listed", func() {
+			BeforeEach(func() {
+				cfClient.GetInstancesOfServiceOfferingReturns(nil, errors.New("cloud controller is down"))
+			})
+
+			It("returns an error", func() {
+				Expect(resyncErr).To(MatchError("cloud controller is down"))
+				Expect(cfClient.UpdateServiceInstanceCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("answering an update made to re-sync the dashboard URL", func() {
+		var (
+			rawParameters []byte
+			updateSpec    brokerapi.UpdateServiceSpec
+			updateErr     error
+		)
+
+		BeforeEach(func() {
+			rawParameters = []byte(`{"resync_dashboard_url": true}`)
+			boshClient.GetDeploymentReturns([]byte("current-manifest"), true, nil)
+		})
+
+		JustBeforeEach(func() {
+			updateSpec, updateErr = b.Update(
+				context.Background(),
+				instanceID,
+				brokerapi.UpdateDetails{
+					PlanID:         existingPlanID,
+					ServiceID:      serviceOfferingID,
+					RawParameters:  rawParameters,
+					PreviousValues: brokerapi.PreviousValues{PlanID: existingPlanID},
+				},
+				true,
+			)
+		})
+
+		It("returns the dashboard URL for the current manifest without deploying", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(updateSpec).To(Equal(brokerapi.UpdateServiceSpec{DashboardURL: dashboardURL}))
+
+			Expect(boshClient.GetDeploymentCallCount()).To(Equal(1))
+			actualDeploymentName, _ := boshClient.GetDeploymentArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+
+			_, actualInstanceID, actualPlan, actualManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
+			Expect(actualInstanceID).To(Equal(instanceID))
+			Expect(actualPlan).To(Equal(existingPlan.AdapterPlan(serviceCatalog.GlobalProperties)))
+			Expect(actualManifest).To(Equal([]byte("current-manifest")))
+
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(0))
+		})
+
+		Context("when the deployment does not exist", func() {
+			BeforeEach(func() {
+				boshClient.GetDeploymentReturns(nil, false, nil)
+			})
+
+			It("returns an error", func() {
+				Expect(updateErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				Expect(serviceAdapter.GenerateDashboardUrlCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the adapter fails to generate the dashboard URL", func() {
+			BeforeEach(func() {
+				serviceAdapter.GenerateDashboardUrlReturns("", errors.New("adapter failed"))
+			})
+
+			It("returns an error", func() {
+				Expect(updateErr).To(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when other parameters are given too", func() {
+			BeforeEach(func() {
+				rawParameters = []byte(`{"resync_dashboard_url": true, "foo": "bar"}`)
+			})
+
+			It("updates the instance", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+			})
+		})
+	})
+})
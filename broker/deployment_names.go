@@ -0,0 +1,171 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+)
+
+const (
+	InstancePrefix = "service-instance_"
+
+	InstanceIDPlaceholder  = "{instance_id}"
+	ServiceNamePlaceholder = "{service_name}"
+
+	LegacyDeploymentNameTemplate = InstancePrefix + InstanceIDPlaceholder
+)
+
+// DeploymentNames maps service instances to the names of their BOSH
+// deployments. Instances deployed before the naming template changed keep
+// their legacy service-instance_ names.
+type DeploymentNames struct {
+	prefix string
+	suffix string
+
+	legacyLock  sync.RWMutex
+	legacyNames map[string]bool
+}
+
+// NewDeploymentNames parses a naming template, which must contain
+// {instance_id} once and may contain {service_name} when the broker has a
+// single service offering. An empty template gives the legacy names.
+func NewDeploymentNames(template string, serviceNames []string) (*DeploymentNames, error) {
+	if template == "" {
+		template = LegacyDeploymentNameTemplate
+	}
+
+	if strings.Contains(template, ServiceNamePlaceholder) {
+		if len(serviceNames) != 1 {
+			return nil, fmt.Errorf("deployment name template %q can only use %s with a single service offering", template, ServiceNamePlaceholder)
+		}
+		template = strings.Replace(template, ServiceNamePlaceholder, serviceNames[0], -1)
+	}
+
+	if strings.Count(template, InstanceIDPlaceholder) != 1 {
+		return nil, fmt.Errorf("deployment name template %q must contain %s exactly once", template, InstanceIDPlaceholder)
+	}
+
+	parts := strings.Split(template, InstanceIDPlaceholder)
+	if parts[0] == "" && parts[1] == "" {
+		return nil, fmt.Errorf("deployment name template %q must contain more than %s", template, InstanceIDPlaceholder)
+	}
+	if strings.ContainsAny(parts[0]+parts[1], "{}") {
+		return nil, fmt.Errorf("deployment name template %q contains an unknown placeholder", template)
+	}
+
+	return &DeploymentNames{
+		prefix:      parts[0],
+		suffix:      parts[1],
+		legacyNames: map[string]bool{},
+	}, nil
+}
+
+func (n *DeploymentNames) usesLegacyNames() bool {
+	return n.prefix == InstancePrefix && n.suffix == ""
+}
+
+// RecogniseLegacyDeployments records which of the deployments still have
+// legacy names, so that their instances keep being found under them. Other
+// brokers on the director may use the same names, so only the deployments of
+// instances that isOurs knows about are recorded.
+func (n *DeploymentNames) RecogniseLegacyDeployments(deployments []boshdirector.Deployment, isOurs func(instanceID string) bool) {
+	if n.usesLegacyNames() {
+		return
+	}
+
+	n.legacyLock.Lock()
+	defer n.legacyLock.Unlock()
+	for _, deployment := range deployments {
+		if strings.HasPrefix(deployment.Name, InstancePrefix) && isOurs(strings.TrimPrefix(deployment.Name, InstancePrefix)) {
+			n.legacyNames[deployment.Name] = true
+		}
+	}
+}
+
+func (n *DeploymentNames) DeploymentName(instanceID string) string {
+	legacyName := InstancePrefix + instanceID
+
+	n.legacyLock.RLock()
+	defer n.legacyLock.RUnlock()
+	if n.legacyNames[legacyName] {
+		return legacyName
+	}
+
+	return n.prefix + instanceID + n.suffix
+}
+
+// InstanceID returns the ID of the instance a deployment belongs to, and
+// false when the deployment does not belong to this broker.
+func (n *DeploymentNames) InstanceID(deploymentName string) (string, bool) {
+	n.legacyLock.RLock()
+	legacy := n.legacyNames[deploymentName]
+	n.legacyLock.RUnlock()
+	if legacy {
+		return strings.TrimPrefix(deploymentName, InstancePrefix), true
+	}
+
+	if len(deploymentName) <= len(n.prefix)+len(n.suffix) ||
+		!strings.HasPrefix(deploymentName, n.prefix) ||
+		!strings.HasSuffix(deploymentName, n.suffix) {
+		return "", false
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(deploymentName, n.prefix), n.suffix), true
+}
+
+func (b *Broker) deploymentName(instanceID string) string {
+	return b.deploymentNames.DeploymentName(instanceID)
+}
+
+func (b *Broker) recogniseLegacyDeployments() error {
+	if b.deploymentNames.usesLegacyNames() {
+		return nil
+	}
+
+	logger := b.loggerFactory.New()
+	deployments, err := b.boshClient.GetDeployments(logger)
+	if err != nil {
+		return fmt.Errorf("error listing deployments with legacy names: %s", err)
+	}
+
+	var legacyDeployments []boshdirector.Deployment
+	for _, deployment := range deployments {
+		if strings.HasPrefix(deployment.Name, InstancePrefix) {
+			legacyDeployments = append(legacyDeployments, deployment)
+		}
+	}
+	if len(legacyDeployments) == 0 {
+		return nil
+	}
+
+	// legacy deployments are only recognised here, so an instance missed now
+	// would be looked for under a name with no deployment behind it
+	instanceIDs, err := b.Instances(logger)
+	if err != nil {
+		return fmt.Errorf("error listing instances with legacy deployment names: %s", err)
+	}
+	cfInstances := map[string]bool{}
+	for _, instanceID := range instanceIDs {
+		cfInstances[instanceID] = true
+	}
+
+	b.deploymentNames.RecogniseLegacyDeployments(legacyDeployments, func(instanceID string) bool {
+		if cfInstances[instanceID] {
+			return true
+		}
+		_, recorded, err := b.operationStore.GetInstance(instanceID)
+		if err != nil {
+			logger.Printf("error reading operations of instance %s: %s\n", instanceID, err)
+		}
+		return recorded
+	})
+	return nil
+}
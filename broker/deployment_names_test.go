@@ -0,0 +1,166 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var _ = Describe("DeploymentNames", func() {
+	Describe("parsing a template", func() {
+		It("defaults to the legacy names", func() {
+			names, err := broker.NewDeploymentNames("", []string{"redis"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names.DeploymentName("some-id")).To(Equal("service-instance_some-id"))
+		})
+
+		It("substitutes the service name", func() {
+			names, err := broker.NewDeploymentNames("{service_name}-{instance_id}-staging", []string{"redis"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names.DeploymentName("some-id")).To(Equal("redis-some-id-staging"))
+		})
+
+		DescribeTable("rejecting invalid templates",
+			func(template string, serviceNames []string, expectedErr string) {
+				_, err := broker.NewDeploymentNames(template, serviceNames)
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			},
+			Entry("without the instance ID", "redis", []string{"redis"}, "must contain {instance_id} exactly once"),
+			Entry("with the instance ID twice", "{instance_id}-{instance_id}", []string{"redis"}, "must contain {instance_id} exactly once"),
+			Entry("with only the instance ID", "{instance_id}", []string{"redis"}, "must contain more than {instance_id}"),
+			Entry("with an unknown placeholder", "{org}-{instance_id}", []string{"redis"}, "contains an unknown placeholder"),
+			Entry("with the service name and several offerings", "{service_name}-{instance_id}", []string{"redis", "kafka"}, "can only use {service_name} with a single service offering"),
+		)
+	})
+
+	Describe("finding the instance of a deployment", func() {
+		var names *broker.DeploymentNames
+
+		BeforeEach(func() {
+			var err error
+			names, err = broker.NewDeploymentNames("redis-{instance_id}-staging", nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("recognises templated names", func() {
+			instanceID, ours := names.InstanceID("redis-some-id-staging")
+			Expect(ours).To(BeTrue())
+			Expect(instanceID).To(Equal("some-id"))
+		})
+
+		It("does not recognise other names", func() {
+			for _, name := range []string{"redis-some-id-production", "kafka-some-id-staging", "redis--staging", "service-instance_some-id"} {
+				_, ours := names.InstanceID(name)
+				Expect(ours).To(BeFalse(), name)
+			}
+		})
+
+		It("recognises legacy names of existing deployments", func() {
+			names.RecogniseLegacyDeployments(
+				[]boshdirector.Deployment{{Name: "service-instance_some-id"}, {Name: "other"}},
+				func(string) bool { return true },
+			)
+
+			instanceID, ours := names.InstanceID("service-instance_some-id")
+			Expect(ours).To(BeTrue())
+			Expect(instanceID).To(Equal("some-id"))
+			Expect(names.DeploymentName("some-id")).To(Equal("service-instance_some-id"))
+			Expect(names.DeploymentName("other-id")).To(Equal("redis-other-id-staging"))
+		})
+	})
+
+	Describe("a broker with a deployment name template", func() {
+		BeforeEach(func() {
+			deploymentNameTemplate = "{service_name}-{instance_id}"
+			boshClient.GetDeploymentsReturns([]boshdirector.Deployment{
+				{Name: "service-instance_legacy-id"},
+				{Name: "service-instance_foreign-id"},
+			}, nil)
+			boshClient.GetDeploymentReturns(nil, false, nil)
+			cfClient.GetInstancesOfServiceOfferingReturns([]string{"legacy-id"}, nil)
+		})
+
+		It("provisions deployments with templated names", func() {
+			b = createDefaultBroker()
+			_, err := b.Provision(
+				context.Background(),
+				"new-id",
+				brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, actualDeploymentName, _, _, _, _ := fakeDeployer.CreateArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal("a-cool-redis-service-new-id"))
+		})
+
+		It("keeps the legacy names of existing deployments", func() {
+			b = createDefaultBroker()
+			_, err := b.Deprovision(
+				context.Background(),
+				"legacy-id",
+				brokerapi.DeprovisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+			Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+
+			actualDeploymentName, _ := boshClient.GetDeploymentArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal("service-instance_legacy-id"))
+		})
+
+		It("does not claim legacy deployments of other brokers' instances", func() {
+			b = createDefaultBroker()
+			_, err := b.Deprovision(
+				context.Background(),
+				"foreign-id",
+				brokerapi.DeprovisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+			Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+
+			actualDeploymentName, _ := boshClient.GetDeploymentArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal("a-cool-redis-service-foreign-id"))
+		})
+
+		It("keeps the legacy names of instances only in the operation store", func() {
+			cfClient.GetInstancesOfServiceOfferingReturns([]string{}, nil)
+			fakeOperationStore.GetInstanceReturnsOnCall(0, operationstore.Instance{ID: "legacy-id"}, true, nil)
+			b = createDefaultBroker()
+			_, err := b.Deprovision(
+				context.Background(),
+				"legacy-id",
+				brokerapi.DeprovisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+			Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+
+			actualDeploymentName, _ := boshClient.GetDeploymentArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal("service-instance_legacy-id"))
+		})
+
+		It("fails to start when CF cannot list the instances", func() {
+			cfClient.GetInstancesOfServiceOfferingReturns(nil, errors.New("CF is down"))
+			_, err := createBroker(createBOSHInfoWithMajorVersion(boshdirector.MinimumMajorSemverDirectorVersionForLifecycleErrands, "semver"))
+			Expect(err).To(MatchError("error listing instances with legacy deployment names: CF is down"))
+		})
+
+		It("fails to start when the deployments cannot be listed", func() {
+			boshClient.GetDeploymentsReturns(nil, errors.New("bosh is down"))
+			_, err := createBroker(createBOSHInfoWithMajorVersion(boshdirector.MinimumMajorSemverDirectorVersionForLifecycleErrands, "semver"))
+			Expect(err).To(MatchError("error listing deployments with legacy names: bosh is down"))
+		})
+	})
+})
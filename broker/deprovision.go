@@ -23,45 +23,87 @@ import (
 func (b *Broker) Deprovision(
 	ctx context.Context,
 	instanceID string,
-	_ brokerapi.DeprovisionDetails,
+	details brokerapi.DeprovisionDetails,
 	asyncAllowed bool,
 ) (brokerapi.DeprovisionServiceSpec, error) {
-	b.deploymentLock.Lock()
-	defer b.deploymentLock.Unlock()
-
 	requestID := uuid.New()
-	ctx = brokercontext.New(ctx, string(OperationTypeDelete), requestID, b.serviceOffering.Name, instanceID)
+	ctx = brokercontext.New(ctx, string(OperationTypeDelete), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
 	logger := b.loggerFactory.NewWithContext(ctx)
 
 	if !asyncAllowed {
 		return brokerapi.DeprovisionServiceSpec{}, brokerapi.ErrAsyncRequired
 	}
 
-	if err := b.assertDeploymentExists(ctx, instanceID, logger); err != NilError {
+	operationData, err := b.deprovisionInstance(ctx, instanceID, false, logger)
+	if err != NilError {
 		return deprovisionErr(err, logger)
 	}
 
+	return deprovisionSpec(ctx, operationData, logger)
+}
+
+func (b *Broker) deprovisionInstance(ctx context.Context, instanceID string, force bool, logger *log.Logger) (OperationData, DisplayableError) {
+	unlock, locked := b.instanceLocker.Lock(ctx, instanceID)
+	if !locked {
+		return OperationData{}, newInstanceLockedError(instanceID)
+	}
+	defer unlock()
+
+	if err := b.assertDeploymentExists(ctx, instanceID, logger); err != NilError {
+		if force && err.ErrorForCFUser() == brokerapi.ErrInstanceDoesNotExist {
+			b.auditForcedAction(instanceID, "deployment not found, treating the instance as deleted", logger)
+			b.releaseQuota(instanceID, logger)
+		}
+		return OperationData{}, err
+	}
+
 	if err := b.assertNoOperationsInProgress(ctx, instanceID, logger); err != NilError {
-		return deprovisionErr(err, logger)
+		return OperationData{}, err
 	}
 
 	instanceState, err := b.cfClient.GetInstanceState(instanceID, logger)
 	if err != nil {
-		return deprovisionErr(NewGenericError(ctx, err), logger)
+		if !force {
+			return OperationData{}, NewGenericError(ctx, err)
+		}
+		b.auditForcedAction(instanceID, fmt.Sprintf("cannot get instance state (%s), skipping the pre-delete errand", err), logger)
+	}
+
+	_, plan, found := b.offeringForPlan(instanceState.PlanID)
+
+	// forced deletes clean up instances that operators need gone, so they
+	// are not held back
+	if !force {
+		operationData := OperationData{OperationType: OperationTypeDelete}
+		if found {
+			operationData.PreErrands = plan.PreDeleteErrands()
+		}
+		queuedOperationData, queued, release, err := b.queueIfThrottled(ctx, instanceID, operationData, logger)
+		if err != nil {
+			return OperationData{}, NewGenericError(ctx, err)
+		}
+		if queued {
+			b.recordOperationStarted(ctx, instanceID, "", nil, queuedOperationData, logger)
+			return queuedOperationData, NilError
+		}
+		defer release()
 	}
 
-	plan, found := b.serviceOffering.FindPlanByID(instanceState.PlanID)
 	if found {
-		if errand := plan.PreDeleteErrand(); errand != "" {
-			return b.runPreDeleteErrand(ctx, instanceID, errand, logger)
+		if errands := plan.PreDeleteErrands(); len(errands) > 0 {
+			operationData, err := b.runPreDeleteErrands(ctx, instanceID, errands, force, logger)
+			if err == NilError || !force {
+				return operationData, err
+			}
+			b.auditForcedAction(instanceID, fmt.Sprintf("pre-delete errand %s failed to start, skipping the pre-delete errands", errands[0].Name), logger)
 		}
 	}
 
-	return b.deleteInstance(ctx, instanceID, plan, logger)
+	return b.deleteInstance(ctx, instanceID, force, logger)
 }
 
 func (b *Broker) assertDeploymentExists(ctx context.Context, instanceID string, logger *log.Logger) DisplayableError {
-	_, deploymentFound, err := b.boshClient.GetDeployment(deploymentName(instanceID), logger)
+	_, deploymentFound, err := b.boshClient.GetDeployment(b.deploymentName(instanceID), logger)
 
 	switch err.(type) {
 	case boshdirector.RequestError:
@@ -69,7 +111,7 @@ func (b *Broker) assertDeploymentExists(ctx context.Context, instanceID string,
 	case error:
 		return NewGenericError(
 			ctx,
-			fmt.Errorf("error deprovisioning: cannot get deployment %s: %s", deploymentName(instanceID), err),
+			fmt.Errorf("error deprovisioning: cannot get deployment %s: %s", b.deploymentName(instanceID), err),
 		)
 	}
 
@@ -85,14 +127,14 @@ func (b *Broker) assertDeploymentExists(ctx context.Context, instanceID string,
 
 func (b *Broker) assertNoOperationsInProgress(ctx context.Context, instanceID string, logger *log.Logger) DisplayableError {
 
-	tasks, err := b.boshClient.GetTasks(deploymentName(instanceID), logger)
+	tasks, err := b.boshClient.GetTasks(b.deploymentName(instanceID), logger)
 	switch err.(type) {
 	case boshdirector.RequestError:
 		return NewBoshRequestError("delete", err)
 	case error:
 		return NewGenericError(
 			ctx,
-			fmt.Errorf("error deprovisioning: cannot get tasks for deployment %s: %s\n", deploymentName(instanceID), err),
+			fmt.Errorf("error deprovisioning: cannot get tasks for deployment %s: %s\n", b.deploymentName(instanceID), err),
 		)
 	}
 
@@ -101,7 +143,7 @@ func (b *Broker) assertNoOperationsInProgress(ctx context.Context, instanceID st
 		userError := errors.New("An operation is in progress for your service instance. Please try again later.")
 		operatorError := NewOperationInProgressError(
 			fmt.Errorf("error deprovisioning: deployment %s is still in progress: tasks %s\n",
-				deploymentName(instanceID),
+				b.deploymentName(instanceID),
 				incompleteTasks.ToLog()),
 		)
 		return NewDisplayableError(userError, operatorError)
@@ -110,72 +152,79 @@ func (b *Broker) assertNoOperationsInProgress(ctx context.Context, instanceID st
 	return NilError
 }
 
-func (b *Broker) runPreDeleteErrand(
+func (b *Broker) runPreDeleteErrands(
 	ctx context.Context,
 	instanceID string,
-	preDeleteErrand string,
+	preDeleteErrands config.Errands,
+	force bool,
 	logger *log.Logger,
-) (brokerapi.DeprovisionServiceSpec, error) {
+) (OperationData, DisplayableError) {
 	logger.Printf("running pre-delete errand for instance %s\n", instanceID)
 
-	boshContextID := uuid.New()
-
-	taskID, err := b.boshClient.RunErrand(
-		deploymentName(instanceID),
-		preDeleteErrand,
-		boshContextID,
-		logger,
-	)
-	if err != nil {
-		return deprovisionErr(NewGenericError(ctx, err), logger)
-	}
-
-	operationData, err := json.Marshal(OperationData{
+	operationData, err := b.runFirstPreErrand(instanceID, OperationData{
 		OperationType: OperationTypeDelete,
-		BoshTaskID:    taskID,
-		BoshContextID: boshContextID,
-	})
-
+		BoshContextID: uuid.New(),
+		PreErrands:    preDeleteErrands,
+		Force:         force,
+	}, logger)
 	if err != nil {
-		return deprovisionErr(NewGenericError(ctx, err), logger)
+		return OperationData{}, NewGenericError(ctx, err)
 	}
 
-	return brokerapi.DeprovisionServiceSpec{IsAsync: true, OperationData: string(operationData)}, nil
+	b.recordOperationStarted(ctx, instanceID, "", nil, operationData, logger)
+
+	return operationData, NilError
 }
 
 func (b *Broker) deleteInstance(
 	ctx context.Context,
 	instanceID string,
-	planConfig config.Plan,
+	force bool,
 	logger *log.Logger,
-) (brokerapi.DeprovisionServiceSpec, error) {
+) (OperationData, DisplayableError) {
 	logger.Printf("deleting deployment for instance %s\n", instanceID)
-	taskID, err := b.boshClient.DeleteDeployment(deploymentName(instanceID), "", logger)
+
+	deleteDeployment := b.boshClient.DeleteDeployment
+	if force {
+		b.auditForcedAction(instanceID, "force deleting the deployment", logger)
+		deleteDeployment = b.boshClient.ForceDeleteDeployment
+	}
+
+	taskID, err := deleteDeployment(b.deploymentName(instanceID), "", logger)
 	switch err.(type) {
 	case boshdirector.RequestError:
-		return deprovisionErr(NewBoshRequestError("delete", err), logger)
+		return OperationData{}, NewBoshRequestError("delete", err)
 	case error:
-		return deprovisionErr(NewGenericError(
+		return OperationData{}, NewGenericError(
 			ctx,
 			fmt.Errorf("error deprovisioning: deleting bosh deployment: %s", err),
-		), logger)
+		)
 	}
 
 	logger.Printf("Bosh task id for Delete instance %s was %d\n", instanceID, taskID)
-	ctx = brokercontext.WithBoshTaskID(ctx, taskID)
 
-	operationData, err := json.Marshal(OperationData{
+	operationData := OperationData{
 		OperationType: OperationTypeDelete,
 		BoshTaskID:    taskID,
-	})
+		Force:         force,
+	}
+
+	b.recordOperationStarted(ctx, instanceID, "", nil, operationData, logger)
+
+	return operationData, NilError
+}
+
+func deprovisionSpec(ctx context.Context, operationData OperationData, logger *log.Logger) (brokerapi.DeprovisionServiceSpec, error) {
+	ctx = brokercontext.WithBoshTaskID(ctx, operationData.BoshTaskID)
 
+	operationDataJSON, err := json.Marshal(operationData)
 	if err != nil {
 		return deprovisionErr(NewGenericError(ctx, err), logger)
 	}
 
 	return brokerapi.DeprovisionServiceSpec{
 		IsAsync:       true,
-		OperationData: string(operationData),
+		OperationData: string(operationDataJSON),
 	}, nil
 }
 
@@ -18,6 +18,8 @@ import (
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
 )
 
 var _ = Describe("deprovisioning instances", func() {
@@ -78,6 +80,18 @@ var _ = Describe("deprovisioning instances", func() {
 		Expect(logBuffer.String()).To(ContainSubstring(fmt.Sprintf("Bosh task id for Delete instance %s was %d", instanceID, deleteTaskID)))
 	})
 
+	It("records the delete operation in the operation store", func() {
+		Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(1))
+		actualInstanceID, operation := fakeOperationStore.StartOperationArgsForCall(0)
+		Expect(actualInstanceID).To(Equal(instanceID))
+		Expect(operation.RequestID).NotTo(BeEmpty())
+		operation.RequestID = ""
+		Expect(operation).To(Equal(operationstore.Operation{
+			Type:        "delete",
+			BoshTaskIDs: []int{deleteTaskID},
+		}))
+	})
+
 	It("does not log anything about pre-delete errands", func() {
 		Expect(logBuffer.String()).NotTo(ContainSubstring("pre-delete errand"))
 	})
@@ -172,7 +186,7 @@ var _ = Describe("deprovisioning instances", func() {
 
 		It("executes the specified errand", func() {
 			Expect(boshClient.RunErrandCallCount()).To(Equal(1))
-			argDeploymentName, argErrandName, contextID, _ := boshClient.RunErrandArgsForCall(0)
+			argDeploymentName, argErrandName, _, contextID, _ := boshClient.RunErrandArgsForCall(0)
 			Expect(argDeploymentName).To(Equal(broker.InstancePrefix + instanceID))
 			Expect(argErrandName).To(Equal("cleanup-resources"))
 			Expect(contextID).To(MatchRegexp(
@@ -187,13 +201,28 @@ var _ = Describe("deprovisioning instances", func() {
 		It("includes the operation type, task id, and context id in the operation data", func() {
 			var operationData broker.OperationData
 
-			_, _, contextID, _ := boshClient.RunErrandArgsForCall(0)
+			_, _, _, contextID, _ := boshClient.RunErrandArgsForCall(0)
 
 			Expect(json.Unmarshal([]byte(deprovisionSpec.OperationData), &operationData)).To(Succeed())
 			Expect(operationData).To(Equal(broker.OperationData{
 				BoshTaskID:    errandTaskID,
 				BoshContextID: contextID,
 				OperationType: broker.OperationTypeDelete,
+				PreErrands:    config.Errands{{Name: "cleanup-resources"}},
+			}))
+		})
+
+		It("records the errand task and context id in the operation store", func() {
+			_, _, _, contextID, _ := boshClient.RunErrandArgsForCall(0)
+
+			Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(1))
+			_, operation := fakeOperationStore.StartOperationArgsForCall(0)
+			Expect(operation.RequestID).NotTo(BeEmpty())
+			operation.RequestID = ""
+			Expect(operation).To(Equal(operationstore.Operation{
+				Type:          "delete",
+				BoshTaskIDs:   []int{errandTaskID},
+				BoshContextID: contextID,
 			}))
 		})
 
@@ -10,6 +10,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
@@ -17,11 +18,23 @@ import (
 )
 
 const (
-	GenericErrorPrefix         = "There was a problem completing your request. Please contact your operations team providing the following information:"
-	PendingChangesErrorMessage = "Service cannot be updated at this time, please try again later or contact your operator for more information"
-	OperationInProgressMessage = "An operation is in progress for your service instance. Please try again later."
-
-	UpdateLoggerAction = ""
+	GenericErrorPrefix             = "There was a problem completing your request. Please contact your operations team providing the following information:"
+	PendingChangesErrorMessage     = "Service cannot be updated at this time, please try again later or contact your operator for more information"
+	OperationInProgressMessage     = "An operation is in progress for your service instance. Please try again later."
+	MaintenanceInfoConflictMessage = "passed maintenance_info does not match the catalog maintenance_info"
+	OperationCancelledMessage      = "cancelled by operator"
+	OrgQuotaExceededMessage        = "The quota for this service plan has been exceeded in your organization. Please contact your Operator for help."
+	SpaceQuotaExceededMessage      = "The quota for this service plan has been exceeded in your space. Please contact your Operator for help."
+	ServiceAdapterTimeoutMessage   = "The service took too long to respond to your request. Please try again later or contact your Operator for help."
+
+	PlanResourceQuotaExceededMessage    = "The %s quota for this service plan has been exceeded. Please contact your Operator for help."
+	ServiceResourceQuotaExceededMessage = "The %s quota for this service has been exceeded. Please contact your Operator for help."
+
+	PlanTransitionNotAllowedMessage  = "Changing the plan from %s to %s is not allowed. Please contact your Operator for help."
+	PlanTransitionUnconfirmedMessage = "Changing the plan from %s to %s must be confirmed by passing the parameter %s with the value true."
+
+	UpdateLoggerAction       = ""
+	AdapterErrorLoggerAction = "service-adapter-error"
 )
 
 type OperationInProgressError struct {
@@ -32,6 +45,21 @@ func NewOperationInProgressError(e error) error {
 	return OperationInProgressError{e}
 }
 
+type NoOperationInProgressError struct {
+	error
+}
+
+func NewNoOperationInProgressError(e error) error {
+	return NoOperationInProgressError{e}
+}
+
+func newInstanceLockedError(instanceID string) DisplayableError {
+	return NewDisplayableError(
+		errors.New(OperationInProgressMessage),
+		NewOperationInProgressError(fmt.Errorf("broker: operation in progress for instance %s", instanceID)),
+	)
+}
+
 var NilError = DisplayableError{nil, nil}
 
 type DisplayableError struct {
@@ -94,6 +122,32 @@ func NewGenericError(ctx context.Context, err error) DisplayableError {
 	}
 }
 
+// NewAdapterError shows the user the message a failed adapter marked as safe
+// to show, with the status its error code asks for, and keeps its message
+// for the operator out of the response.
+func NewAdapterError(ctx context.Context, err serviceadapter.UnknownFailureError) DisplayableError {
+	var errorForCFUser error = err
+	if err.Error() == "" {
+		errorForCFUser = NewGenericError(ctx, err).ErrorForCFUser()
+	}
+
+	var status int
+	switch err.ErrorCode {
+	case serviceadapter.ErrorCodeBadParameter:
+		status = http.StatusBadRequest
+	case serviceadapter.ErrorCodeInternal:
+		status = http.StatusInternalServerError
+	default:
+		// codes meant only for the operator say nothing about the request
+		status = http.StatusInternalServerError
+	}
+
+	return NewDisplayableError(
+		brokerapi.NewFailureResponse(errors.New(errorForCFUser.Error()), status, AdapterErrorLoggerAction),
+		fmt.Errorf("service adapter failed with error code %q: %s", err.ErrorCode, err.OperatorMessage),
+	)
+}
+
 func adapterToAPIError(ctx context.Context, err error) error {
 	if err == nil {
 		return nil
@@ -106,7 +160,13 @@ func adapterToAPIError(ctx context.Context, err error) error {
 		return brokerapi.ErrBindingDoesNotExist
 	case serviceadapter.AppGuidNotProvidedError:
 		return brokerapi.ErrAppGuidNotProvided
+	case serviceadapter.TimeoutError:
+		return errors.New(ServiceAdapterTimeoutMessage)
 	case serviceadapter.UnknownFailureError:
+		if failure := err.(serviceadapter.UnknownFailureError); failure.Structured() {
+			return NewAdapterError(ctx, failure).ErrorForCFUser()
+		}
+
 		if err.Error() == "" {
 			//Adapter returns an unknown error with no message
 			err = NewGenericError(ctx, err).ErrorForCFUser()
@@ -25,6 +25,34 @@ type FakeBoshClient struct {
 		result1 boshdirector.BoshTask
 		result2 error
 	}
+	GetTaskEventsStub        func(taskID int, logger *log.Logger) (boshdirector.BoshTaskEvents, error)
+	getTaskEventsMutex       sync.RWMutex
+	getTaskEventsArgsForCall []struct {
+		taskID int
+		logger *log.Logger
+	}
+	getTaskEventsReturns struct {
+		result1 boshdirector.BoshTaskEvents
+		result2 error
+	}
+	getTaskEventsReturnsOnCall map[int]struct {
+		result1 boshdirector.BoshTaskEvents
+		result2 error
+	}
+	GetTaskOutputStub        func(taskID int, logger *log.Logger) ([]boshdirector.BoshTaskOutput, error)
+	getTaskOutputMutex       sync.RWMutex
+	getTaskOutputArgsForCall []struct {
+		taskID int
+		logger *log.Logger
+	}
+	getTaskOutputReturns struct {
+		result1 []boshdirector.BoshTaskOutput
+		result2 error
+	}
+	getTaskOutputReturnsOnCall map[int]struct {
+		result1 []boshdirector.BoshTaskOutput
+		result2 error
+	}
 	GetTasksStub        func(deploymentName string, logger *log.Logger) (boshdirector.BoshTasks, error)
 	getTasksMutex       sync.RWMutex
 	getTasksArgsForCall []struct {
@@ -39,6 +67,19 @@ type FakeBoshClient struct {
 		result1 boshdirector.BoshTasks
 		result2 error
 	}
+	GetInFlightTasksStub        func(logger *log.Logger) (boshdirector.BoshTasks, error)
+	getInFlightTasksMutex       sync.RWMutex
+	getInFlightTasksArgsForCall []struct {
+		logger *log.Logger
+	}
+	getInFlightTasksReturns struct {
+		result1 boshdirector.BoshTasks
+		result2 error
+	}
+	getInFlightTasksReturnsOnCall map[int]struct {
+		result1 boshdirector.BoshTasks
+		result2 error
+	}
 	GetNormalisedTasksByContextStub        func(deploymentName, contextID string, logger *log.Logger) (boshdirector.BoshTasks, error)
 	getNormalisedTasksByContextMutex       sync.RWMutex
 	getNormalisedTasksByContextArgsForCall []struct {
@@ -97,6 +138,21 @@ type FakeBoshClient struct {
 		result1 []boshdirector.Deployment
 		result2 error
 	}
+	DeployStub        func(manifest []byte, contextID string, logger *log.Logger) (int, error)
+	deployMutex       sync.RWMutex
+	deployArgsForCall []struct {
+		manifest  []byte
+		contextID string
+		logger    *log.Logger
+	}
+	deployReturns struct {
+		result1 int
+		result2 error
+	}
+	deployReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
 	DeleteDeploymentStub        func(name, contextID string, logger *log.Logger) (int, error)
 	deleteDeploymentMutex       sync.RWMutex
 	deleteDeploymentArgsForCall []struct {
@@ -112,6 +168,21 @@ type FakeBoshClient struct {
 		result1 int
 		result2 error
 	}
+	ForceDeleteDeploymentStub        func(name, contextID string, logger *log.Logger) (int, error)
+	forceDeleteDeploymentMutex       sync.RWMutex
+	forceDeleteDeploymentArgsForCall []struct {
+		name      string
+		contextID string
+		logger    *log.Logger
+	}
+	forceDeleteDeploymentReturns struct {
+		result1 int
+		result2 error
+	}
+	forceDeleteDeploymentReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
 	GetInfoStub        func(logger *log.Logger) (*boshdirector.Info, error)
 	getInfoMutex       sync.RWMutex
 	getInfoArgsForCall []struct {
@@ -125,11 +196,12 @@ type FakeBoshClient struct {
 		result1 *boshdirector.Info
 		result2 error
 	}
-	RunErrandStub        func(deploymentName, errandName, contextID string, logger *log.Logger) (int, error)
+	RunErrandStub        func(deploymentName, errandName string, instances []string, contextID string, logger *log.Logger) (int, error)
 	runErrandMutex       sync.RWMutex
 	runErrandArgsForCall []struct {
 		deploymentName string
 		errandName     string
+		instances      []string
 		contextID      string
 		logger         *log.Logger
 	}
@@ -141,6 +213,18 @@ type FakeBoshClient struct {
 		result1 int
 		result2 error
 	}
+	CancelTaskStub        func(taskID int, logger *log.Logger) error
+	cancelTaskMutex       sync.RWMutex
+	cancelTaskArgsForCall []struct {
+		taskID int
+		logger *log.Logger
+	}
+	cancelTaskReturns struct {
+		result1 error
+	}
+	cancelTaskReturnsOnCall map[int]struct {
+		result1 error
+	}
 	VerifyAuthStub        func(logger *log.Logger) error
 	verifyAuthMutex       sync.RWMutex
 	verifyAuthArgsForCall []struct {
@@ -208,6 +292,110 @@ func (fake *FakeBoshClient) GetTaskReturnsOnCall(i int, result1 boshdirector.Bos
 	}{result1, result2}
 }
 
+func (fake *FakeBoshClient) GetTaskEvents(taskID int, logger *log.Logger) (boshdirector.BoshTaskEvents, error) {
+	fake.getTaskEventsMutex.Lock()
+	ret, specificReturn := fake.getTaskEventsReturnsOnCall[len(fake.getTaskEventsArgsForCall)]
+	fake.getTaskEventsArgsForCall = append(fake.getTaskEventsArgsForCall, struct {
+		taskID int
+		logger *log.Logger
+	}{taskID, logger})
+	fake.recordInvocation("GetTaskEvents", []interface{}{taskID, logger})
+	fake.getTaskEventsMutex.Unlock()
+	if fake.GetTaskEventsStub != nil {
+		return fake.GetTaskEventsStub(taskID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getTaskEventsReturns.result1, fake.getTaskEventsReturns.result2
+}
+
+func (fake *FakeBoshClient) GetTaskEventsCallCount() int {
+	fake.getTaskEventsMutex.RLock()
+	defer fake.getTaskEventsMutex.RUnlock()
+	return len(fake.getTaskEventsArgsForCall)
+}
+
+func (fake *FakeBoshClient) GetTaskEventsArgsForCall(i int) (int, *log.Logger) {
+	fake.getTaskEventsMutex.RLock()
+	defer fake.getTaskEventsMutex.RUnlock()
+	return fake.getTaskEventsArgsForCall[i].taskID, fake.getTaskEventsArgsForCall[i].logger
+}
+
+func (fake *FakeBoshClient) GetTaskEventsReturns(result1 boshdirector.BoshTaskEvents, result2 error) {
+	fake.GetTaskEventsStub = nil
+	fake.getTaskEventsReturns = struct {
+		result1 boshdirector.BoshTaskEvents
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBoshClient) GetTaskEventsReturnsOnCall(i int, result1 boshdirector.BoshTaskEvents, result2 error) {
+	fake.GetTaskEventsStub = nil
+	if fake.getTaskEventsReturnsOnCall == nil {
+		fake.getTaskEventsReturnsOnCall = make(map[int]struct {
+			result1 boshdirector.BoshTaskEvents
+			result2 error
+		})
+	}
+	fake.getTaskEventsReturnsOnCall[i] = struct {
+		result1 boshdirector.BoshTaskEvents
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBoshClient) GetTaskOutput(taskID int, logger *log.Logger) ([]boshdirector.BoshTaskOutput, error) {
+	fake.getTaskOutputMutex.Lock()
+	ret, specificReturn := fake.getTaskOutputReturnsOnCall[len(fake.getTaskOutputArgsForCall)]
+	fake.getTaskOutputArgsForCall = append(fake.getTaskOutputArgsForCall, struct {
+		taskID int
+		logger *log.Logger
+	}{taskID, logger})
+	fake.recordInvocation("GetTaskOutput", []interface{}{taskID, logger})
+	fake.getTaskOutputMutex.Unlock()
+	if fake.GetTaskOutputStub != nil {
+		return fake.GetTaskOutputStub(taskID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getTaskOutputReturns.result1, fake.getTaskOutputReturns.result2
+}
+
+func (fake *FakeBoshClient) GetTaskOutputCallCount() int {
+	fake.getTaskOutputMutex.RLock()
+	defer fake.getTaskOutputMutex.RUnlock()
+	return len(fake.getTaskOutputArgsForCall)
+}
+
+func (fake *FakeBoshClient) GetTaskOutputArgsForCall(i int) (int, *log.Logger) {
+	fake.getTaskOutputMutex.RLock()
+	defer fake.getTaskOutputMutex.RUnlock()
+	return fake.getTaskOutputArgsForCall[i].taskID, fake.getTaskOutputArgsForCall[i].logger
+}
+
+func (fake *FakeBoshClient) GetTaskOutputReturns(result1 []boshdirector.BoshTaskOutput, result2 error) {
+	fake.GetTaskOutputStub = nil
+	fake.getTaskOutputReturns = struct {
+		result1 []boshdirector.BoshTaskOutput
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBoshClient) GetTaskOutputReturnsOnCall(i int, result1 []boshdirector.BoshTaskOutput, result2 error) {
+	fake.GetTaskOutputStub = nil
+	if fake.getTaskOutputReturnsOnCall == nil {
+		fake.getTaskOutputReturnsOnCall = make(map[int]struct {
+			result1 []boshdirector.BoshTaskOutput
+			result2 error
+		})
+	}
+	fake.getTaskOutputReturnsOnCall[i] = struct {
+		result1 []boshdirector.BoshTaskOutput
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeBoshClient) GetTasks(deploymentName string, logger *log.Logger) (boshdirector.BoshTasks, error) {
 	fake.getTasksMutex.Lock()
 	ret, specificReturn := fake.getTasksReturnsOnCall[len(fake.getTasksArgsForCall)]
@@ -260,6 +448,57 @@ func (fake *FakeBoshClient) GetTasksReturnsOnCall(i int, result1 boshdirector.Bo
 	}{result1, result2}
 }
 
+func (fake *FakeBoshClient) GetInFlightTasks(logger *log.Logger) (boshdirector.BoshTasks, error) {
+	fake.getInFlightTasksMutex.Lock()
+	ret, specificReturn := fake.getInFlightTasksReturnsOnCall[len(fake.getInFlightTasksArgsForCall)]
+	fake.getInFlightTasksArgsForCall = append(fake.getInFlightTasksArgsForCall, struct {
+		logger *log.Logger
+	}{logger})
+	fake.recordInvocation("GetInFlightTasks", []interface{}{logger})
+	fake.getInFlightTasksMutex.Unlock()
+	if fake.GetInFlightTasksStub != nil {
+		return fake.GetInFlightTasksStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getInFlightTasksReturns.result1, fake.getInFlightTasksReturns.result2
+}
+
+func (fake *FakeBoshClient) GetInFlightTasksCallCount() int {
+	fake.getInFlightTasksMutex.RLock()
+	defer fake.getInFlightTasksMutex.RUnlock()
+	return len(fake.getInFlightTasksArgsForCall)
+}
+
+func (fake *FakeBoshClient) GetInFlightTasksArgsForCall(i int) *log.Logger {
+	fake.getInFlightTasksMutex.RLock()
+	defer fake.getInFlightTasksMutex.RUnlock()
+	return fake.getInFlightTasksArgsForCall[i].logger
+}
+
+func (fake *FakeBoshClient) GetInFlightTasksReturns(result1 boshdirector.BoshTasks, result2 error) {
+	fake.GetInFlightTasksStub = nil
+	fake.getInFlightTasksReturns = struct {
+		result1 boshdirector.BoshTasks
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBoshClient) GetInFlightTasksReturnsOnCall(i int, result1 boshdirector.BoshTasks, result2 error) {
+	fake.GetInFlightTasksStub = nil
+	if fake.getInFlightTasksReturnsOnCall == nil {
+		fake.getInFlightTasksReturnsOnCall = make(map[int]struct {
+			result1 boshdirector.BoshTasks
+			result2 error
+		})
+	}
+	fake.getInFlightTasksReturnsOnCall[i] = struct {
+		result1 boshdirector.BoshTasks
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeBoshClient) GetNormalisedTasksByContext(deploymentName string, contextID string, logger *log.Logger) (boshdirector.BoshTasks, error) {
 	fake.getNormalisedTasksByContextMutex.Lock()
 	ret, specificReturn := fake.getNormalisedTasksByContextReturnsOnCall[len(fake.getNormalisedTasksByContextArgsForCall)]
@@ -471,6 +710,64 @@ func (fake *FakeBoshClient) GetDeploymentsReturnsOnCall(i int, result1 []boshdir
 	}{result1, result2}
 }
 
+func (fake *FakeBoshClient) Deploy(manifest []byte, contextID string, logger *log.Logger) (int, error) {
+	var manifestCopy []byte
+	if manifest != nil {
+		manifestCopy = make([]byte, len(manifest))
+		copy(manifestCopy, manifest)
+	}
+	fake.deployMutex.Lock()
+	ret, specificReturn := fake.deployReturnsOnCall[len(fake.deployArgsForCall)]
+	fake.deployArgsForCall = append(fake.deployArgsForCall, struct {
+		manifest  []byte
+		contextID string
+		logger    *log.Logger
+	}{manifestCopy, contextID, logger})
+	fake.recordInvocation("Deploy", []interface{}{manifestCopy, contextID, logger})
+	fake.deployMutex.Unlock()
+	if fake.DeployStub != nil {
+		return fake.DeployStub(manifest, contextID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.deployReturns.result1, fake.deployReturns.result2
+}
+
+func (fake *FakeBoshClient) DeployCallCount() int {
+	fake.deployMutex.RLock()
+	defer fake.deployMutex.RUnlock()
+	return len(fake.deployArgsForCall)
+}
+
+func (fake *FakeBoshClient) DeployArgsForCall(i int) ([]byte, string, *log.Logger) {
+	fake.deployMutex.RLock()
+	defer fake.deployMutex.RUnlock()
+	return fake.deployArgsForCall[i].manifest, fake.deployArgsForCall[i].contextID, fake.deployArgsForCall[i].logger
+}
+
+func (fake *FakeBoshClient) DeployReturns(result1 int, result2 error) {
+	fake.DeployStub = nil
+	fake.deployReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBoshClient) DeployReturnsOnCall(i int, result1 int, result2 error) {
+	fake.DeployStub = nil
+	if fake.deployReturnsOnCall == nil {
+		fake.deployReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.deployReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeBoshClient) DeleteDeployment(name string, contextID string, logger *log.Logger) (int, error) {
 	fake.deleteDeploymentMutex.Lock()
 	ret, specificReturn := fake.deleteDeploymentReturnsOnCall[len(fake.deleteDeploymentArgsForCall)]
@@ -524,6 +821,59 @@ func (fake *FakeBoshClient) DeleteDeploymentReturnsOnCall(i int, result1 int, re
 	}{result1, result2}
 }
 
+func (fake *FakeBoshClient) ForceDeleteDeployment(name string, contextID string, logger *log.Logger) (int, error) {
+	fake.forceDeleteDeploymentMutex.Lock()
+	ret, specificReturn := fake.forceDeleteDeploymentReturnsOnCall[len(fake.forceDeleteDeploymentArgsForCall)]
+	fake.forceDeleteDeploymentArgsForCall = append(fake.forceDeleteDeploymentArgsForCall, struct {
+		name      string
+		contextID string
+		logger    *log.Logger
+	}{name, contextID, logger})
+	fake.recordInvocation("ForceDeleteDeployment", []interface{}{name, contextID, logger})
+	fake.forceDeleteDeploymentMutex.Unlock()
+	if fake.ForceDeleteDeploymentStub != nil {
+		return fake.ForceDeleteDeploymentStub(name, contextID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.forceDeleteDeploymentReturns.result1, fake.forceDeleteDeploymentReturns.result2
+}
+
+func (fake *FakeBoshClient) ForceDeleteDeploymentCallCount() int {
+	fake.forceDeleteDeploymentMutex.RLock()
+	defer fake.forceDeleteDeploymentMutex.RUnlock()
+	return len(fake.forceDeleteDeploymentArgsForCall)
+}
+
+func (fake *FakeBoshClient) ForceDeleteDeploymentArgsForCall(i int) (string, string, *log.Logger) {
+	fake.forceDeleteDeploymentMutex.RLock()
+	defer fake.forceDeleteDeploymentMutex.RUnlock()
+	return fake.forceDeleteDeploymentArgsForCall[i].name, fake.forceDeleteDeploymentArgsForCall[i].contextID, fake.forceDeleteDeploymentArgsForCall[i].logger
+}
+
+func (fake *FakeBoshClient) ForceDeleteDeploymentReturns(result1 int, result2 error) {
+	fake.ForceDeleteDeploymentStub = nil
+	fake.forceDeleteDeploymentReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeBoshClient) ForceDeleteDeploymentReturnsOnCall(i int, result1 int, result2 error) {
+	fake.ForceDeleteDeploymentStub = nil
+	if fake.forceDeleteDeploymentReturnsOnCall == nil {
+		fake.forceDeleteDeploymentReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.forceDeleteDeploymentReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeBoshClient) GetInfo(logger *log.Logger) (*boshdirector.Info, error) {
 	fake.getInfoMutex.Lock()
 	ret, specificReturn := fake.getInfoReturnsOnCall[len(fake.getInfoArgsForCall)]
@@ -575,19 +925,25 @@ func (fake *FakeBoshClient) GetInfoReturnsOnCall(i int, result1 *boshdirector.In
 	}{result1, result2}
 }
 
-func (fake *FakeBoshClient) RunErrand(deploymentName string, errandName string, contextID string, logger *log.Logger) (int, error) {
+func (fake *FakeBoshClient) RunErrand(deploymentName string, errandName string, instances []string, contextID string, logger *log.Logger) (int, error) {
+	var instancesCopy []string
+	if instances != nil {
+		instancesCopy = make([]string, len(instances))
+		copy(instancesCopy, instances)
+	}
 	fake.runErrandMutex.Lock()
 	ret, specificReturn := fake.runErrandReturnsOnCall[len(fake.runErrandArgsForCall)]
 	fake.runErrandArgsForCall = append(fake.runErrandArgsForCall, struct {
 		deploymentName string
 		errandName     string
+		instances      []string
 		contextID      string
 		logger         *log.Logger
-	}{deploymentName, errandName, contextID, logger})
-	fake.recordInvocation("RunErrand", []interface{}{deploymentName, errandName, contextID, logger})
+	}{deploymentName, errandName, instancesCopy, contextID, logger})
+	fake.recordInvocation("RunErrand", []interface{}{deploymentName, errandName, instancesCopy, contextID, logger})
 	fake.runErrandMutex.Unlock()
 	if fake.RunErrandStub != nil {
-		return fake.RunErrandStub(deploymentName, errandName, contextID, logger)
+		return fake.RunErrandStub(deploymentName, errandName, instances, contextID, logger)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -601,10 +957,10 @@ func (fake *FakeBoshClient) RunErrandCallCount() int {
 	return len(fake.runErrandArgsForCall)
 }
 
-func (fake *FakeBoshClient) RunErrandArgsForCall(i int) (string, string, string, *log.Logger) {
+func (fake *FakeBoshClient) RunErrandArgsForCall(i int) (string, string, []string, string, *log.Logger) {
 	fake.runErrandMutex.RLock()
 	defer fake.runErrandMutex.RUnlock()
-	return fake.runErrandArgsForCall[i].deploymentName, fake.runErrandArgsForCall[i].errandName, fake.runErrandArgsForCall[i].contextID, fake.runErrandArgsForCall[i].logger
+	return fake.runErrandArgsForCall[i].deploymentName, fake.runErrandArgsForCall[i].errandName, fake.runErrandArgsForCall[i].instances, fake.runErrandArgsForCall[i].contextID, fake.runErrandArgsForCall[i].logger
 }
 
 func (fake *FakeBoshClient) RunErrandReturns(result1 int, result2 error) {
@@ -629,6 +985,55 @@ func (fake *FakeBoshClient) RunErrandReturnsOnCall(i int, result1 int, result2 e
 	}{result1, result2}
 }
 
+func (fake *FakeBoshClient) CancelTask(taskID int, logger *log.Logger) error {
+	fake.cancelTaskMutex.Lock()
+	ret, specificReturn := fake.cancelTaskReturnsOnCall[len(fake.cancelTaskArgsForCall)]
+	fake.cancelTaskArgsForCall = append(fake.cancelTaskArgsForCall, struct {
+		taskID int
+		logger *log.Logger
+	}{taskID, logger})
+	fake.recordInvocation("CancelTask", []interface{}{taskID, logger})
+	fake.cancelTaskMutex.Unlock()
+	if fake.CancelTaskStub != nil {
+		return fake.CancelTaskStub(taskID, logger)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.cancelTaskReturns.result1
+}
+
+func (fake *FakeBoshClient) CancelTaskCallCount() int {
+	fake.cancelTaskMutex.RLock()
+	defer fake.cancelTaskMutex.RUnlock()
+	return len(fake.cancelTaskArgsForCall)
+}
+
+func (fake *FakeBoshClient) CancelTaskArgsForCall(i int) (int, *log.Logger) {
+	fake.cancelTaskMutex.RLock()
+	defer fake.cancelTaskMutex.RUnlock()
+	return fake.cancelTaskArgsForCall[i].taskID, fake.cancelTaskArgsForCall[i].logger
+}
+
+func (fake *FakeBoshClient) CancelTaskReturns(result1 error) {
+	fake.CancelTaskStub = nil
+	fake.cancelTaskReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeBoshClient) CancelTaskReturnsOnCall(i int, result1 error) {
+	fake.CancelTaskStub = nil
+	if fake.cancelTaskReturnsOnCall == nil {
+		fake.cancelTaskReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.cancelTaskReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeBoshClient) VerifyAuth(logger *log.Logger) error {
 	fake.verifyAuthMutex.Lock()
 	ret, specificReturn := fake.verifyAuthReturnsOnCall[len(fake.verifyAuthArgsForCall)]
@@ -682,8 +1087,14 @@ func (fake *FakeBoshClient) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.getTaskMutex.RLock()
 	defer fake.getTaskMutex.RUnlock()
+	fake.getTaskEventsMutex.RLock()
+	defer fake.getTaskEventsMutex.RUnlock()
+	fake.getTaskOutputMutex.RLock()
+	defer fake.getTaskOutputMutex.RUnlock()
 	fake.getTasksMutex.RLock()
 	defer fake.getTasksMutex.RUnlock()
+	fake.getInFlightTasksMutex.RLock()
+	defer fake.getInFlightTasksMutex.RUnlock()
 	fake.getNormalisedTasksByContextMutex.RLock()
 	defer fake.getNormalisedTasksByContextMutex.RUnlock()
 	fake.vMsMutex.RLock()
@@ -692,12 +1103,18 @@ func (fake *FakeBoshClient) Invocations() map[string][][]interface{} {
 	defer fake.getDeploymentMutex.RUnlock()
 	fake.getDeploymentsMutex.RLock()
 	defer fake.getDeploymentsMutex.RUnlock()
+	fake.deployMutex.RLock()
+	defer fake.deployMutex.RUnlock()
 	fake.deleteDeploymentMutex.RLock()
 	defer fake.deleteDeploymentMutex.RUnlock()
+	fake.forceDeleteDeploymentMutex.RLock()
+	defer fake.forceDeleteDeploymentMutex.RUnlock()
 	fake.getInfoMutex.RLock()
 	defer fake.getInfoMutex.RUnlock()
 	fake.runErrandMutex.RLock()
 	defer fake.runErrandMutex.RUnlock()
+	fake.cancelTaskMutex.RLock()
+	defer fake.cancelTaskMutex.RUnlock()
 	fake.verifyAuthMutex.RLock()
 	defer fake.verifyAuthMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
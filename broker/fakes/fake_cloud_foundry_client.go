@@ -38,6 +38,38 @@ type FakeCloudFoundryClient struct {
 		result1 int
 		result2 error
 	}
+	CountInstancesOfPlanInOrgStub        func(serviceOfferingID, planID, orgGUID string, logger *log.Logger) (int, error)
+	countInstancesOfPlanInOrgMutex       sync.RWMutex
+	countInstancesOfPlanInOrgArgsForCall []struct {
+		serviceOfferingID string
+		planID            string
+		orgGUID           string
+		logger            *log.Logger
+	}
+	countInstancesOfPlanInOrgReturns struct {
+		result1 int
+		result2 error
+	}
+	countInstancesOfPlanInOrgReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	CountInstancesOfPlanInSpaceStub        func(serviceOfferingID, planID, spaceGUID string, logger *log.Logger) (int, error)
+	countInstancesOfPlanInSpaceMutex       sync.RWMutex
+	countInstancesOfPlanInSpaceArgsForCall []struct {
+		serviceOfferingID string
+		planID            string
+		spaceGUID         string
+		logger            *log.Logger
+	}
+	countInstancesOfPlanInSpaceReturns struct {
+		result1 int
+		result2 error
+	}
+	countInstancesOfPlanInSpaceReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
 	CountInstancesOfServiceOfferingStub        func(serviceOfferingID string, logger *log.Logger) (instanceCountByPlanID map[cf.ServicePlan]int, err error)
 	countInstancesOfServiceOfferingMutex       sync.RWMutex
 	countInstancesOfServiceOfferingArgsForCall []struct {
@@ -52,6 +84,20 @@ type FakeCloudFoundryClient struct {
 		result1 map[cf.ServicePlan]int
 		result2 error
 	}
+	CountInstancesOfServiceOfferingByOrgStub        func(serviceOfferingID string, logger *log.Logger) (instanceCountByOrgByPlan map[cf.ServicePlan]map[string]int, err error)
+	countInstancesOfServiceOfferingByOrgMutex       sync.RWMutex
+	countInstancesOfServiceOfferingByOrgArgsForCall []struct {
+		serviceOfferingID string
+		logger            *log.Logger
+	}
+	countInstancesOfServiceOfferingByOrgReturns struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}
+	countInstancesOfServiceOfferingByOrgReturnsOnCall map[int]struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}
 	GetInstanceStateStub        func(serviceInstanceGUID string, logger *log.Logger) (cf.InstanceState, error)
 	getInstanceStateMutex       sync.RWMutex
 	getInstanceStateArgsForCall []struct {
@@ -80,6 +126,19 @@ type FakeCloudFoundryClient struct {
 		result1 []string
 		result2 error
 	}
+	UpdateServiceInstanceStub        func(serviceInstanceGUID string, parameters map[string]interface{}, logger *log.Logger) error
+	updateServiceInstanceMutex       sync.RWMutex
+	updateServiceInstanceArgsForCall []struct {
+		serviceInstanceGUID string
+		parameters          map[string]interface{}
+		logger              *log.Logger
+	}
+	updateServiceInstanceReturns struct {
+		result1 error
+	}
+	updateServiceInstanceReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -188,6 +247,114 @@ func (fake *FakeCloudFoundryClient) CountInstancesOfPlanReturnsOnCall(i int, res
 	}{result1, result2}
 }
 
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInOrg(serviceOfferingID string, planID string, orgGUID string, logger *log.Logger) (int, error) {
+	fake.countInstancesOfPlanInOrgMutex.Lock()
+	ret, specificReturn := fake.countInstancesOfPlanInOrgReturnsOnCall[len(fake.countInstancesOfPlanInOrgArgsForCall)]
+	fake.countInstancesOfPlanInOrgArgsForCall = append(fake.countInstancesOfPlanInOrgArgsForCall, struct {
+		serviceOfferingID string
+		planID            string
+		orgGUID           string
+		logger            *log.Logger
+	}{serviceOfferingID, planID, orgGUID, logger})
+	fake.recordInvocation("CountInstancesOfPlanInOrg", []interface{}{serviceOfferingID, planID, orgGUID, logger})
+	fake.countInstancesOfPlanInOrgMutex.Unlock()
+	if fake.CountInstancesOfPlanInOrgStub != nil {
+		return fake.CountInstancesOfPlanInOrgStub(serviceOfferingID, planID, orgGUID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.countInstancesOfPlanInOrgReturns.result1, fake.countInstancesOfPlanInOrgReturns.result2
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInOrgCallCount() int {
+	fake.countInstancesOfPlanInOrgMutex.RLock()
+	defer fake.countInstancesOfPlanInOrgMutex.RUnlock()
+	return len(fake.countInstancesOfPlanInOrgArgsForCall)
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInOrgArgsForCall(i int) (string, string, string, *log.Logger) {
+	fake.countInstancesOfPlanInOrgMutex.RLock()
+	defer fake.countInstancesOfPlanInOrgMutex.RUnlock()
+	return fake.countInstancesOfPlanInOrgArgsForCall[i].serviceOfferingID, fake.countInstancesOfPlanInOrgArgsForCall[i].planID, fake.countInstancesOfPlanInOrgArgsForCall[i].orgGUID, fake.countInstancesOfPlanInOrgArgsForCall[i].logger
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInOrgReturns(result1 int, result2 error) {
+	fake.CountInstancesOfPlanInOrgStub = nil
+	fake.countInstancesOfPlanInOrgReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInOrgReturnsOnCall(i int, result1 int, result2 error) {
+	fake.CountInstancesOfPlanInOrgStub = nil
+	if fake.countInstancesOfPlanInOrgReturnsOnCall == nil {
+		fake.countInstancesOfPlanInOrgReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.countInstancesOfPlanInOrgReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInSpace(serviceOfferingID string, planID string, spaceGUID string, logger *log.Logger) (int, error) {
+	fake.countInstancesOfPlanInSpaceMutex.Lock()
+	ret, specificReturn := fake.countInstancesOfPlanInSpaceReturnsOnCall[len(fake.countInstancesOfPlanInSpaceArgsForCall)]
+	fake.countInstancesOfPlanInSpaceArgsForCall = append(fake.countInstancesOfPlanInSpaceArgsForCall, struct {
+		serviceOfferingID string
+		planID            string
+		spaceGUID         string
+		logger            *log.Logger
+	}{serviceOfferingID, planID, spaceGUID, logger})
+	fake.recordInvocation("CountInstancesOfPlanInSpace", []interface{}{serviceOfferingID, planID, spaceGUID, logger})
+	fake.countInstancesOfPlanInSpaceMutex.Unlock()
+	if fake.CountInstancesOfPlanInSpaceStub != nil {
+		return fake.CountInstancesOfPlanInSpaceStub(serviceOfferingID, planID, spaceGUID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.countInstancesOfPlanInSpaceReturns.result1, fake.countInstancesOfPlanInSpaceReturns.result2
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInSpaceCallCount() int {
+	fake.countInstancesOfPlanInSpaceMutex.RLock()
+	defer fake.countInstancesOfPlanInSpaceMutex.RUnlock()
+	return len(fake.countInstancesOfPlanInSpaceArgsForCall)
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInSpaceArgsForCall(i int) (string, string, string, *log.Logger) {
+	fake.countInstancesOfPlanInSpaceMutex.RLock()
+	defer fake.countInstancesOfPlanInSpaceMutex.RUnlock()
+	return fake.countInstancesOfPlanInSpaceArgsForCall[i].serviceOfferingID, fake.countInstancesOfPlanInSpaceArgsForCall[i].planID, fake.countInstancesOfPlanInSpaceArgsForCall[i].spaceGUID, fake.countInstancesOfPlanInSpaceArgsForCall[i].logger
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInSpaceReturns(result1 int, result2 error) {
+	fake.CountInstancesOfPlanInSpaceStub = nil
+	fake.countInstancesOfPlanInSpaceReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfPlanInSpaceReturnsOnCall(i int, result1 int, result2 error) {
+	fake.CountInstancesOfPlanInSpaceStub = nil
+	if fake.countInstancesOfPlanInSpaceReturnsOnCall == nil {
+		fake.countInstancesOfPlanInSpaceReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.countInstancesOfPlanInSpaceReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeCloudFoundryClient) CountInstancesOfServiceOffering(serviceOfferingID string, logger *log.Logger) (instanceCountByPlanID map[cf.ServicePlan]int, err error) {
 	fake.countInstancesOfServiceOfferingMutex.Lock()
 	ret, specificReturn := fake.countInstancesOfServiceOfferingReturnsOnCall[len(fake.countInstancesOfServiceOfferingArgsForCall)]
@@ -240,6 +407,58 @@ func (fake *FakeCloudFoundryClient) CountInstancesOfServiceOfferingReturnsOnCall
 	}{result1, result2}
 }
 
+func (fake *FakeCloudFoundryClient) CountInstancesOfServiceOfferingByOrg(serviceOfferingID string, logger *log.Logger) (instanceCountByOrgByPlan map[cf.ServicePlan]map[string]int, err error) {
+	fake.countInstancesOfServiceOfferingByOrgMutex.Lock()
+	ret, specificReturn := fake.countInstancesOfServiceOfferingByOrgReturnsOnCall[len(fake.countInstancesOfServiceOfferingByOrgArgsForCall)]
+	fake.countInstancesOfServiceOfferingByOrgArgsForCall = append(fake.countInstancesOfServiceOfferingByOrgArgsForCall, struct {
+		serviceOfferingID string
+		logger            *log.Logger
+	}{serviceOfferingID, logger})
+	fake.recordInvocation("CountInstancesOfServiceOfferingByOrg", []interface{}{serviceOfferingID, logger})
+	fake.countInstancesOfServiceOfferingByOrgMutex.Unlock()
+	if fake.CountInstancesOfServiceOfferingByOrgStub != nil {
+		return fake.CountInstancesOfServiceOfferingByOrgStub(serviceOfferingID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.countInstancesOfServiceOfferingByOrgReturns.result1, fake.countInstancesOfServiceOfferingByOrgReturns.result2
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfServiceOfferingByOrgCallCount() int {
+	fake.countInstancesOfServiceOfferingByOrgMutex.RLock()
+	defer fake.countInstancesOfServiceOfferingByOrgMutex.RUnlock()
+	return len(fake.countInstancesOfServiceOfferingByOrgArgsForCall)
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfServiceOfferingByOrgArgsForCall(i int) (string, *log.Logger) {
+	fake.countInstancesOfServiceOfferingByOrgMutex.RLock()
+	defer fake.countInstancesOfServiceOfferingByOrgMutex.RUnlock()
+	return fake.countInstancesOfServiceOfferingByOrgArgsForCall[i].serviceOfferingID, fake.countInstancesOfServiceOfferingByOrgArgsForCall[i].logger
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfServiceOfferingByOrgReturns(result1 map[cf.ServicePlan]map[string]int, result2 error) {
+	fake.CountInstancesOfServiceOfferingByOrgStub = nil
+	fake.countInstancesOfServiceOfferingByOrgReturns = struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCloudFoundryClient) CountInstancesOfServiceOfferingByOrgReturnsOnCall(i int, result1 map[cf.ServicePlan]map[string]int, result2 error) {
+	fake.CountInstancesOfServiceOfferingByOrgStub = nil
+	if fake.countInstancesOfServiceOfferingByOrgReturnsOnCall == nil {
+		fake.countInstancesOfServiceOfferingByOrgReturnsOnCall = make(map[int]struct {
+			result1 map[cf.ServicePlan]map[string]int
+			result2 error
+		})
+	}
+	fake.countInstancesOfServiceOfferingByOrgReturnsOnCall[i] = struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeCloudFoundryClient) GetInstanceState(serviceInstanceGUID string, logger *log.Logger) (cf.InstanceState, error) {
 	fake.getInstanceStateMutex.Lock()
 	ret, specificReturn := fake.getInstanceStateReturnsOnCall[len(fake.getInstanceStateArgsForCall)]
@@ -344,6 +563,56 @@ func (fake *FakeCloudFoundryClient) GetInstancesOfServiceOfferingReturnsOnCall(i
 	}{result1, result2}
 }
 
+func (fake *FakeCloudFoundryClient) UpdateServiceInstance(serviceInstanceGUID string, parameters map[string]interface{}, logger *log.Logger) error {
+	fake.updateServiceInstanceMutex.Lock()
+	ret, specificReturn := fake.updateServiceInstanceReturnsOnCall[len(fake.updateServiceInstanceArgsForCall)]
+	fake.updateServiceInstanceArgsForCall = append(fake.updateServiceInstanceArgsForCall, struct {
+		serviceInstanceGUID string
+		parameters          map[string]interface{}
+		logger              *log.Logger
+	}{serviceInstanceGUID, parameters, logger})
+	fake.recordInvocation("UpdateServiceInstance", []interface{}{serviceInstanceGUID, parameters, logger})
+	fake.updateServiceInstanceMutex.Unlock()
+	if fake.UpdateServiceInstanceStub != nil {
+		return fake.UpdateServiceInstanceStub(serviceInstanceGUID, parameters, logger)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.updateServiceInstanceReturns.result1
+}
+
+func (fake *FakeCloudFoundryClient) UpdateServiceInstanceCallCount() int {
+	fake.updateServiceInstanceMutex.RLock()
+	defer fake.updateServiceInstanceMutex.RUnlock()
+	return len(fake.updateServiceInstanceArgsForCall)
+}
+
+func (fake *FakeCloudFoundryClient) UpdateServiceInstanceArgsForCall(i int) (string, map[string]interface{}, *log.Logger) {
+	fake.updateServiceInstanceMutex.RLock()
+	defer fake.updateServiceInstanceMutex.RUnlock()
+	return fake.updateServiceInstanceArgsForCall[i].serviceInstanceGUID, fake.updateServiceInstanceArgsForCall[i].parameters, fake.updateServiceInstanceArgsForCall[i].logger
+}
+
+func (fake *FakeCloudFoundryClient) UpdateServiceInstanceReturns(result1 error) {
+	fake.UpdateServiceInstanceStub = nil
+	fake.updateServiceInstanceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCloudFoundryClient) UpdateServiceInstanceReturnsOnCall(i int, result1 error) {
+	fake.UpdateServiceInstanceStub = nil
+	if fake.updateServiceInstanceReturnsOnCall == nil {
+		fake.updateServiceInstanceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.updateServiceInstanceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeCloudFoundryClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -351,12 +620,20 @@ func (fake *FakeCloudFoundryClient) Invocations() map[string][][]interface{} {
 	defer fake.getAPIVersionMutex.RUnlock()
 	fake.countInstancesOfPlanMutex.RLock()
 	defer fake.countInstancesOfPlanMutex.RUnlock()
+	fake.countInstancesOfPlanInOrgMutex.RLock()
+	defer fake.countInstancesOfPlanInOrgMutex.RUnlock()
+	fake.countInstancesOfPlanInSpaceMutex.RLock()
+	defer fake.countInstancesOfPlanInSpaceMutex.RUnlock()
 	fake.countInstancesOfServiceOfferingMutex.RLock()
 	defer fake.countInstancesOfServiceOfferingMutex.RUnlock()
+	fake.countInstancesOfServiceOfferingByOrgMutex.RLock()
+	defer fake.countInstancesOfServiceOfferingByOrgMutex.RUnlock()
 	fake.getInstanceStateMutex.RLock()
 	defer fake.getInstanceStateMutex.RUnlock()
 	fake.getInstancesOfServiceOfferingMutex.RLock()
 	defer fake.getInstancesOfServiceOfferingMutex.RUnlock()
+	fake.updateServiceInstanceMutex.RLock()
+	defer fake.updateServiceInstanceMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"log"
 	"sync"
 
@@ -9,9 +10,10 @@ import (
 )
 
 type FakeDeployer struct {
-	CreateStub        func(deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error)
+	CreateStub        func(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error)
 	createMutex       sync.RWMutex
 	createArgsForCall []struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		requestParams  map[string]interface{}
@@ -28,9 +30,10 @@ type FakeDeployer struct {
 		result2 []byte
 		result3 error
 	}
-	UpdateStub        func(deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	UpdateStub        func(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
 	updateMutex       sync.RWMutex
 	updateArgsForCall []struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		requestParams  map[string]interface{}
@@ -48,9 +51,10 @@ type FakeDeployer struct {
 		result2 []byte
 		result3 error
 	}
-	UpgradeStub        func(deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	UpgradeStub        func(ctx context.Context, deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
 	upgradeMutex       sync.RWMutex
 	upgradeArgsForCall []struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		previousPlanID *string
@@ -67,24 +71,43 @@ type FakeDeployer struct {
 		result2 []byte
 		result3 error
 	}
+	GenerateManifestStub        func(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, logger *log.Logger) ([]byte, error)
+	generateManifestMutex       sync.RWMutex
+	generateManifestArgsForCall []struct {
+		ctx            context.Context
+		deploymentName string
+		planID         string
+		requestParams  map[string]interface{}
+		previousPlanID *string
+		logger         *log.Logger
+	}
+	generateManifestReturns struct {
+		result1 []byte
+		result2 error
+	}
+	generateManifestReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeDeployer) Create(deploymentName string, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error) {
+func (fake *FakeDeployer) Create(ctx context.Context, deploymentName string, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error) {
 	fake.createMutex.Lock()
 	ret, specificReturn := fake.createReturnsOnCall[len(fake.createArgsForCall)]
 	fake.createArgsForCall = append(fake.createArgsForCall, struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		requestParams  map[string]interface{}
 		boshContextID  string
 		logger         *log.Logger
-	}{deploymentName, planID, requestParams, boshContextID, logger})
-	fake.recordInvocation("Create", []interface{}{deploymentName, planID, requestParams, boshContextID, logger})
+	}{ctx, deploymentName, planID, requestParams, boshContextID, logger})
+	fake.recordInvocation("Create", []interface{}{ctx, deploymentName, planID, requestParams, boshContextID, logger})
 	fake.createMutex.Unlock()
 	if fake.CreateStub != nil {
-		return fake.CreateStub(deploymentName, planID, requestParams, boshContextID, logger)
+		return fake.CreateStub(ctx, deploymentName, planID, requestParams, boshContextID, logger)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
@@ -98,10 +121,10 @@ func (fake *FakeDeployer) CreateCallCount() int {
 	return len(fake.createArgsForCall)
 }
 
-func (fake *FakeDeployer) CreateArgsForCall(i int) (string, string, map[string]interface{}, string, *log.Logger) {
+func (fake *FakeDeployer) CreateArgsForCall(i int) (context.Context, string, string, map[string]interface{}, string, *log.Logger) {
 	fake.createMutex.RLock()
 	defer fake.createMutex.RUnlock()
-	return fake.createArgsForCall[i].deploymentName, fake.createArgsForCall[i].planID, fake.createArgsForCall[i].requestParams, fake.createArgsForCall[i].boshContextID, fake.createArgsForCall[i].logger
+	return fake.createArgsForCall[i].ctx, fake.createArgsForCall[i].deploymentName, fake.createArgsForCall[i].planID, fake.createArgsForCall[i].requestParams, fake.createArgsForCall[i].boshContextID, fake.createArgsForCall[i].logger
 }
 
 func (fake *FakeDeployer) CreateReturns(result1 int, result2 []byte, result3 error) {
@@ -129,21 +152,22 @@ func (fake *FakeDeployer) CreateReturnsOnCall(i int, result1 int, result2 []byte
 	}{result1, result2, result3}
 }
 
-func (fake *FakeDeployer) Update(deploymentName string, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error) {
+func (fake *FakeDeployer) Update(ctx context.Context, deploymentName string, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error) {
 	fake.updateMutex.Lock()
 	ret, specificReturn := fake.updateReturnsOnCall[len(fake.updateArgsForCall)]
 	fake.updateArgsForCall = append(fake.updateArgsForCall, struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		requestParams  map[string]interface{}
 		previousPlanID *string
 		boshContextID  string
 		logger         *log.Logger
-	}{deploymentName, planID, requestParams, previousPlanID, boshContextID, logger})
-	fake.recordInvocation("Update", []interface{}{deploymentName, planID, requestParams, previousPlanID, boshContextID, logger})
+	}{ctx, deploymentName, planID, requestParams, previousPlanID, boshContextID, logger})
+	fake.recordInvocation("Update", []interface{}{ctx, deploymentName, planID, requestParams, previousPlanID, boshContextID, logger})
 	fake.updateMutex.Unlock()
 	if fake.UpdateStub != nil {
-		return fake.UpdateStub(deploymentName, planID, requestParams, previousPlanID, boshContextID, logger)
+		return fake.UpdateStub(ctx, deploymentName, planID, requestParams, previousPlanID, boshContextID, logger)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
@@ -157,10 +181,10 @@ func (fake *FakeDeployer) UpdateCallCount() int {
 	return len(fake.updateArgsForCall)
 }
 
-func (fake *FakeDeployer) UpdateArgsForCall(i int) (string, string, map[string]interface{}, *string, string, *log.Logger) {
+func (fake *FakeDeployer) UpdateArgsForCall(i int) (context.Context, string, string, map[string]interface{}, *string, string, *log.Logger) {
 	fake.updateMutex.RLock()
 	defer fake.updateMutex.RUnlock()
-	return fake.updateArgsForCall[i].deploymentName, fake.updateArgsForCall[i].planID, fake.updateArgsForCall[i].requestParams, fake.updateArgsForCall[i].previousPlanID, fake.updateArgsForCall[i].boshContextID, fake.updateArgsForCall[i].logger
+	return fake.updateArgsForCall[i].ctx, fake.updateArgsForCall[i].deploymentName, fake.updateArgsForCall[i].planID, fake.updateArgsForCall[i].requestParams, fake.updateArgsForCall[i].previousPlanID, fake.updateArgsForCall[i].boshContextID, fake.updateArgsForCall[i].logger
 }
 
 func (fake *FakeDeployer) UpdateReturns(result1 int, result2 []byte, result3 error) {
@@ -188,20 +212,21 @@ func (fake *FakeDeployer) UpdateReturnsOnCall(i int, result1 int, result2 []byte
 	}{result1, result2, result3}
 }
 
-func (fake *FakeDeployer) Upgrade(deploymentName string, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error) {
+func (fake *FakeDeployer) Upgrade(ctx context.Context, deploymentName string, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error) {
 	fake.upgradeMutex.Lock()
 	ret, specificReturn := fake.upgradeReturnsOnCall[len(fake.upgradeArgsForCall)]
 	fake.upgradeArgsForCall = append(fake.upgradeArgsForCall, struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		previousPlanID *string
 		boshContextID  string
 		logger         *log.Logger
-	}{deploymentName, planID, previousPlanID, boshContextID, logger})
-	fake.recordInvocation("Upgrade", []interface{}{deploymentName, planID, previousPlanID, boshContextID, logger})
+	}{ctx, deploymentName, planID, previousPlanID, boshContextID, logger})
+	fake.recordInvocation("Upgrade", []interface{}{ctx, deploymentName, planID, previousPlanID, boshContextID, logger})
 	fake.upgradeMutex.Unlock()
 	if fake.UpgradeStub != nil {
-		return fake.UpgradeStub(deploymentName, planID, previousPlanID, boshContextID, logger)
+		return fake.UpgradeStub(ctx, deploymentName, planID, previousPlanID, boshContextID, logger)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
@@ -215,10 +240,10 @@ func (fake *FakeDeployer) UpgradeCallCount() int {
 	return len(fake.upgradeArgsForCall)
 }
 
-func (fake *FakeDeployer) UpgradeArgsForCall(i int) (string, string, *string, string, *log.Logger) {
+func (fake *FakeDeployer) UpgradeArgsForCall(i int) (context.Context, string, string, *string, string, *log.Logger) {
 	fake.upgradeMutex.RLock()
 	defer fake.upgradeMutex.RUnlock()
-	return fake.upgradeArgsForCall[i].deploymentName, fake.upgradeArgsForCall[i].planID, fake.upgradeArgsForCall[i].previousPlanID, fake.upgradeArgsForCall[i].boshContextID, fake.upgradeArgsForCall[i].logger
+	return fake.upgradeArgsForCall[i].ctx, fake.upgradeArgsForCall[i].deploymentName, fake.upgradeArgsForCall[i].planID, fake.upgradeArgsForCall[i].previousPlanID, fake.upgradeArgsForCall[i].boshContextID, fake.upgradeArgsForCall[i].logger
 }
 
 func (fake *FakeDeployer) UpgradeReturns(result1 int, result2 []byte, result3 error) {
@@ -246,6 +271,62 @@ func (fake *FakeDeployer) UpgradeReturnsOnCall(i int, result1 int, result2 []byt
 	}{result1, result2, result3}
 }
 
+func (fake *FakeDeployer) GenerateManifest(ctx context.Context, deploymentName string, planID string, requestParams map[string]interface{}, previousPlanID *string, logger *log.Logger) ([]byte, error) {
+	fake.generateManifestMutex.Lock()
+	ret, specificReturn := fake.generateManifestReturnsOnCall[len(fake.generateManifestArgsForCall)]
+	fake.generateManifestArgsForCall = append(fake.generateManifestArgsForCall, struct {
+		ctx            context.Context
+		deploymentName string
+		planID         string
+		requestParams  map[string]interface{}
+		previousPlanID *string
+		logger         *log.Logger
+	}{ctx, deploymentName, planID, requestParams, previousPlanID, logger})
+	fake.recordInvocation("GenerateManifest", []interface{}{ctx, deploymentName, planID, requestParams, previousPlanID, logger})
+	fake.generateManifestMutex.Unlock()
+	if fake.GenerateManifestStub != nil {
+		return fake.GenerateManifestStub(ctx, deploymentName, planID, requestParams, previousPlanID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.generateManifestReturns.result1, fake.generateManifestReturns.result2
+}
+
+func (fake *FakeDeployer) GenerateManifestCallCount() int {
+	fake.generateManifestMutex.RLock()
+	defer fake.generateManifestMutex.RUnlock()
+	return len(fake.generateManifestArgsForCall)
+}
+
+func (fake *FakeDeployer) GenerateManifestArgsForCall(i int) (context.Context, string, string, map[string]interface{}, *string, *log.Logger) {
+	fake.generateManifestMutex.RLock()
+	defer fake.generateManifestMutex.RUnlock()
+	return fake.generateManifestArgsForCall[i].ctx, fake.generateManifestArgsForCall[i].deploymentName, fake.generateManifestArgsForCall[i].planID, fake.generateManifestArgsForCall[i].requestParams, fake.generateManifestArgsForCall[i].previousPlanID, fake.generateManifestArgsForCall[i].logger
+}
+
+func (fake *FakeDeployer) GenerateManifestReturns(result1 []byte, result2 error) {
+	fake.GenerateManifestStub = nil
+	fake.generateManifestReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDeployer) GenerateManifestReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.GenerateManifestStub = nil
+	if fake.generateManifestReturnsOnCall == nil {
+		fake.generateManifestReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.generateManifestReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeDeployer) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -255,6 +336,8 @@ func (fake *FakeDeployer) Invocations() map[string][][]interface{} {
 	defer fake.updateMutex.RUnlock()
 	fake.upgradeMutex.RLock()
 	defer fake.upgradeMutex.RUnlock()
+	fake.generateManifestMutex.RLock()
+	defer fake.generateManifestMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
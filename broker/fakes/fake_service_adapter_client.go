@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"log"
 	"sync"
 
@@ -11,9 +12,10 @@ import (
 )
 
 type FakeServiceAdapterClient struct {
-	CreateBindingStub        func(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (serviceadapter.Binding, error)
+	CreateBindingStub        func(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (serviceadapter.Binding, error)
 	createBindingMutex       sync.RWMutex
 	createBindingArgsForCall []struct {
+		ctx                context.Context
 		bindingID          string
 		deploymentTopology bosh.BoshVMs
 		manifest           []byte
@@ -28,13 +30,15 @@ type FakeServiceAdapterClient struct {
 		result1 serviceadapter.Binding
 		result2 error
 	}
-	DeleteBindingStub        func(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) error
+	DeleteBindingStub        func(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams, bindingCredentials map[string]interface{}, logger *log.Logger) error
 	deleteBindingMutex       sync.RWMutex
 	deleteBindingArgsForCall []struct {
+		ctx                context.Context
 		bindingID          string
 		deploymentTopology bosh.BoshVMs
 		manifest           []byte
 		requestParams      map[string]interface{}
+		bindingCredentials map[string]interface{}
 		logger             *log.Logger
 	}
 	deleteBindingReturns struct {
@@ -43,9 +47,10 @@ type FakeServiceAdapterClient struct {
 	deleteBindingReturnsOnCall map[int]struct {
 		result1 error
 	}
-	GenerateDashboardUrlStub        func(instanceID string, plan serviceadapter.Plan, manifest []byte, logger *log.Logger) (string, error)
+	GenerateDashboardUrlStub        func(ctx context.Context, instanceID string, plan serviceadapter.Plan, manifest []byte, logger *log.Logger) (string, error)
 	generateDashboardUrlMutex       sync.RWMutex
 	generateDashboardUrlArgsForCall []struct {
+		ctx        context.Context
 		instanceID string
 		plan       serviceadapter.Plan
 		manifest   []byte
@@ -63,7 +68,7 @@ type FakeServiceAdapterClient struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeServiceAdapterClient) CreateBinding(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (serviceadapter.Binding, error) {
+func (fake *FakeServiceAdapterClient) CreateBinding(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (serviceadapter.Binding, error) {
 	var manifestCopy []byte
 	if manifest != nil {
 		manifestCopy = make([]byte, len(manifest))
@@ -72,16 +77,17 @@ func (fake *FakeServiceAdapterClient) CreateBinding(bindingID string, deployment
 	fake.createBindingMutex.Lock()
 	ret, specificReturn := fake.createBindingReturnsOnCall[len(fake.createBindingArgsForCall)]
 	fake.createBindingArgsForCall = append(fake.createBindingArgsForCall, struct {
+		ctx                context.Context
 		bindingID          string
 		deploymentTopology bosh.BoshVMs
 		manifest           []byte
 		requestParams      map[string]interface{}
 		logger             *log.Logger
-	}{bindingID, deploymentTopology, manifestCopy, requestParams, logger})
-	fake.recordInvocation("CreateBinding", []interface{}{bindingID, deploymentTopology, manifestCopy, requestParams, logger})
+	}{ctx, bindingID, deploymentTopology, manifestCopy, requestParams, logger})
+	fake.recordInvocation("CreateBinding", []interface{}{ctx, bindingID, deploymentTopology, manifestCopy, requestParams, logger})
 	fake.createBindingMutex.Unlock()
 	if fake.CreateBindingStub != nil {
-		return fake.CreateBindingStub(bindingID, deploymentTopology, manifest, requestParams, logger)
+		return fake.CreateBindingStub(ctx, bindingID, deploymentTopology, manifest, requestParams, logger)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -95,10 +101,10 @@ func (fake *FakeServiceAdapterClient) CreateBindingCallCount() int {
 	return len(fake.createBindingArgsForCall)
 }
 
-func (fake *FakeServiceAdapterClient) CreateBindingArgsForCall(i int) (string, bosh.BoshVMs, []byte, map[string]interface{}, *log.Logger) {
+func (fake *FakeServiceAdapterClient) CreateBindingArgsForCall(i int) (context.Context, string, bosh.BoshVMs, []byte, map[string]interface{}, *log.Logger) {
 	fake.createBindingMutex.RLock()
 	defer fake.createBindingMutex.RUnlock()
-	return fake.createBindingArgsForCall[i].bindingID, fake.createBindingArgsForCall[i].deploymentTopology, fake.createBindingArgsForCall[i].manifest, fake.createBindingArgsForCall[i].requestParams, fake.createBindingArgsForCall[i].logger
+	return fake.createBindingArgsForCall[i].ctx, fake.createBindingArgsForCall[i].bindingID, fake.createBindingArgsForCall[i].deploymentTopology, fake.createBindingArgsForCall[i].manifest, fake.createBindingArgsForCall[i].requestParams, fake.createBindingArgsForCall[i].logger
 }
 
 func (fake *FakeServiceAdapterClient) CreateBindingReturns(result1 serviceadapter.Binding, result2 error) {
@@ -123,7 +129,7 @@ func (fake *FakeServiceAdapterClient) CreateBindingReturnsOnCall(i int, result1
 	}{result1, result2}
 }
 
-func (fake *FakeServiceAdapterClient) DeleteBinding(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) error {
+func (fake *FakeServiceAdapterClient) DeleteBinding(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, bindingCredentials map[string]interface{}, logger *log.Logger) error {
 	var manifestCopy []byte
 	if manifest != nil {
 		manifestCopy = make([]byte, len(manifest))
@@ -132,16 +138,18 @@ func (fake *FakeServiceAdapterClient) DeleteBinding(bindingID string, deployment
 	fake.deleteBindingMutex.Lock()
 	ret, specificReturn := fake.deleteBindingReturnsOnCall[len(fake.deleteBindingArgsForCall)]
 	fake.deleteBindingArgsForCall = append(fake.deleteBindingArgsForCall, struct {
+		ctx                context.Context
 		bindingID          string
 		deploymentTopology bosh.BoshVMs
 		manifest           []byte
 		requestParams      map[string]interface{}
+		bindingCredentials map[string]interface{}
 		logger             *log.Logger
-	}{bindingID, deploymentTopology, manifestCopy, requestParams, logger})
-	fake.recordInvocation("DeleteBinding", []interface{}{bindingID, deploymentTopology, manifestCopy, requestParams, logger})
+	}{ctx, bindingID, deploymentTopology, manifestCopy, requestParams, bindingCredentials, logger})
+	fake.recordInvocation("DeleteBinding", []interface{}{ctx, bindingID, deploymentTopology, manifestCopy, requestParams, bindingCredentials, logger})
 	fake.deleteBindingMutex.Unlock()
 	if fake.DeleteBindingStub != nil {
-		return fake.DeleteBindingStub(bindingID, deploymentTopology, manifest, requestParams, logger)
+		return fake.DeleteBindingStub(ctx, bindingID, deploymentTopology, manifest, requestParams, bindingCredentials, logger)
 	}
 	if specificReturn {
 		return ret.result1
@@ -155,10 +163,10 @@ func (fake *FakeServiceAdapterClient) DeleteBindingCallCount() int {
 	return len(fake.deleteBindingArgsForCall)
 }
 
-func (fake *FakeServiceAdapterClient) DeleteBindingArgsForCall(i int) (string, bosh.BoshVMs, []byte, map[string]interface{}, *log.Logger) {
+func (fake *FakeServiceAdapterClient) DeleteBindingArgsForCall(i int) (context.Context, string, bosh.BoshVMs, []byte, map[string]interface{}, map[string]interface{}, *log.Logger) {
 	fake.deleteBindingMutex.RLock()
 	defer fake.deleteBindingMutex.RUnlock()
-	return fake.deleteBindingArgsForCall[i].bindingID, fake.deleteBindingArgsForCall[i].deploymentTopology, fake.deleteBindingArgsForCall[i].manifest, fake.deleteBindingArgsForCall[i].requestParams, fake.deleteBindingArgsForCall[i].logger
+	return fake.deleteBindingArgsForCall[i].ctx, fake.deleteBindingArgsForCall[i].bindingID, fake.deleteBindingArgsForCall[i].deploymentTopology, fake.deleteBindingArgsForCall[i].manifest, fake.deleteBindingArgsForCall[i].requestParams, fake.deleteBindingArgsForCall[i].bindingCredentials, fake.deleteBindingArgsForCall[i].logger
 }
 
 func (fake *FakeServiceAdapterClient) DeleteBindingReturns(result1 error) {
@@ -180,7 +188,7 @@ func (fake *FakeServiceAdapterClient) DeleteBindingReturnsOnCall(i int, result1
 	}{result1}
 }
 
-func (fake *FakeServiceAdapterClient) GenerateDashboardUrl(instanceID string, plan serviceadapter.Plan, manifest []byte, logger *log.Logger) (string, error) {
+func (fake *FakeServiceAdapterClient) GenerateDashboardUrl(ctx context.Context, instanceID string, plan serviceadapter.Plan, manifest []byte, logger *log.Logger) (string, error) {
 	var manifestCopy []byte
 	if manifest != nil {
 		manifestCopy = make([]byte, len(manifest))
@@ -189,15 +197,16 @@ func (fake *FakeServiceAdapterClient) GenerateDashboardUrl(instanceID string, pl
 	fake.generateDashboardUrlMutex.Lock()
 	ret, specificReturn := fake.generateDashboardUrlReturnsOnCall[len(fake.generateDashboardUrlArgsForCall)]
 	fake.generateDashboardUrlArgsForCall = append(fake.generateDashboardUrlArgsForCall, struct {
+		ctx        context.Context
 		instanceID string
 		plan       serviceadapter.Plan
 		manifest   []byte
 		logger     *log.Logger
-	}{instanceID, plan, manifestCopy, logger})
-	fake.recordInvocation("GenerateDashboardUrl", []interface{}{instanceID, plan, manifestCopy, logger})
+	}{ctx, instanceID, plan, manifestCopy, logger})
+	fake.recordInvocation("GenerateDashboardUrl", []interface{}{ctx, instanceID, plan, manifestCopy, logger})
 	fake.generateDashboardUrlMutex.Unlock()
 	if fake.GenerateDashboardUrlStub != nil {
-		return fake.GenerateDashboardUrlStub(instanceID, plan, manifest, logger)
+		return fake.GenerateDashboardUrlStub(ctx, instanceID, plan, manifest, logger)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -211,10 +220,10 @@ func (fake *FakeServiceAdapterClient) GenerateDashboardUrlCallCount() int {
 	return len(fake.generateDashboardUrlArgsForCall)
 }
 
-func (fake *FakeServiceAdapterClient) GenerateDashboardUrlArgsForCall(i int) (string, serviceadapter.Plan, []byte, *log.Logger) {
+func (fake *FakeServiceAdapterClient) GenerateDashboardUrlArgsForCall(i int) (context.Context, string, serviceadapter.Plan, []byte, *log.Logger) {
 	fake.generateDashboardUrlMutex.RLock()
 	defer fake.generateDashboardUrlMutex.RUnlock()
-	return fake.generateDashboardUrlArgsForCall[i].instanceID, fake.generateDashboardUrlArgsForCall[i].plan, fake.generateDashboardUrlArgsForCall[i].manifest, fake.generateDashboardUrlArgsForCall[i].logger
+	return fake.generateDashboardUrlArgsForCall[i].ctx, fake.generateDashboardUrlArgsForCall[i].instanceID, fake.generateDashboardUrlArgsForCall[i].plan, fake.generateDashboardUrlArgsForCall[i].manifest, fake.generateDashboardUrlArgsForCall[i].logger
 }
 
 func (fake *FakeServiceAdapterClient) GenerateDashboardUrlReturns(result1 string, result2 error) {
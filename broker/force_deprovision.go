@@ -0,0 +1,89 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/auditlog"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+)
+
+// A forced deprovision gets rid of an instance that cannot be deleted
+// normally: a pre-delete errand that fails is skipped, a missing deployment
+// counts as deleted and the deployment is deleted with BOSH's force option.
+
+// ForceDeprovision is a deprovision requested with the force parameter by a
+// user the API has already checked is an admin.
+func (b *Broker) ForceDeprovision(
+	ctx context.Context,
+	instanceID string,
+	details brokerapi.DeprovisionDetails,
+	asyncAllowed bool,
+	requestedBy string,
+) (brokerapi.DeprovisionServiceSpec, error) {
+	requestID := uuid.New()
+	ctx = brokercontext.New(ctx, string(OperationTypeDelete), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
+	logger := b.loggerFactory.NewWithContext(ctx)
+
+	if !asyncAllowed {
+		return brokerapi.DeprovisionServiceSpec{}, brokerapi.ErrAsyncRequired
+	}
+
+	b.auditForcedAction(instanceID, "force deprovision requested by user "+requestedBy, logger)
+
+	operationData, err := b.deprovisionInstance(ctx, instanceID, true, logger)
+	if err != NilError {
+		return deprovisionErr(err, logger)
+	}
+
+	return deprovisionSpec(ctx, operationData, logger)
+}
+
+// PurgeInstance force deprovisions an instance on behalf of the operator.
+func (b *Broker) PurgeInstance(ctx context.Context, instanceID string, logger *log.Logger) (OperationData, error) {
+	b.auditForcedAction(instanceID, "purge requested by the operator", logger)
+
+	operationData, err := b.deprovisionInstance(ctx, instanceID, true, logger)
+	if err == NilError {
+		return operationData, nil
+	}
+
+	logger.Printf("error purging instance %s: %s\n", instanceID, err)
+	if err.ErrorForCFUser() == brokerapi.ErrInstanceDoesNotExist {
+		return OperationData{}, brokerapi.ErrInstanceDoesNotExist
+	}
+	if inProgressErr, ok := err.errorForOperator.(OperationInProgressError); ok {
+		return OperationData{}, inProgressErr
+	}
+	return OperationData{}, err.errorForOperator
+}
+
+func (b *Broker) auditForcedAction(instanceID, action string, logger *log.Logger) {
+	recordForcedAction(b.auditLog, instanceID, action, logger)
+}
+
+func (l LifeCycleRunner) auditForcedAction(instanceID, action string, logger *log.Logger) {
+	recordForcedAction(l.auditLog, instanceID, action, logger)
+}
+
+// recordForcedAction logs a forced action and records it in the audit log.
+// Failing to record it does not stop the action.
+func recordForcedAction(auditLog AuditLog, instanceID, action string, logger *log.Logger) {
+	logger.Printf("audit: forced deprovision of instance %s: %s\n", instanceID, action)
+	if auditLog == nil {
+		return
+	}
+
+	if err := auditLog.Record(auditlog.Entry{Time: time.Now(), InstanceID: instanceID, Action: action}); err != nil {
+		logger.Printf("error recording forced action on instance %s in the audit log: %s\n", instanceID, err)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+)
+
+var _ = Describe("forced deprovisioning", func() {
+	const (
+		instanceID   = "an-instance-to-be-purged"
+		deleteTaskID = 88
+		errandTaskID = 123
+	)
+
+	BeforeEach(func() {
+		boshClient.GetDeploymentReturns([]byte(`manifest: true`), true, nil)
+		cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: existingPlanID}, nil)
+		boshClient.ForceDeleteDeploymentReturns(deleteTaskID, nil)
+		boshClient.RunErrandReturns(errandTaskID, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	auditedActions := func() []string {
+		var actions []string
+		for i := 0; i < fakeAuditLog.RecordCallCount(); i++ {
+			entry := fakeAuditLog.RecordArgsForCall(i)
+			Expect(entry.InstanceID).To(Equal(instanceID))
+			Expect(entry.Time).To(BeTemporally("~", time.Now(), time.Minute))
+			actions = append(actions, entry.Action)
+		}
+		return actions
+	}
+
+	Describe("a deprovision forced by an admin", func() {
+		var (
+			asyncAllowed    bool
+			deprovisionSpec brokerapi.DeprovisionServiceSpec
+			deprovisionErr  error
+		)
+
+		BeforeEach(func() {
+			asyncAllowed = true
+		})
+
+		JustBeforeEach(func() {
+			deprovisionSpec, deprovisionErr = b.ForceDeprovision(
+				context.Background(),
+				instanceID,
+				brokerapi.DeprovisionDetails{},
+				asyncAllowed,
+				"some-admin-user-id",
+			)
+		})
+
+		It("force deletes the deployment", func() {
+			Expect(deprovisionErr).NotTo(HaveOccurred())
+			Expect(boshClient.DeleteDeploymentCallCount()).To(Equal(0))
+			Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(1))
+			actualDeploymentName, _, _ := boshClient.ForceDeleteDeploymentArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+
+			var operationData broker.OperationData
+			Expect(json.Unmarshal([]byte(deprovisionSpec.OperationData), &operationData)).To(Succeed())
+			Expect(operationData).To(Equal(broker.OperationData{
+				BoshTaskID:    deleteTaskID,
+				OperationType: broker.OperationTypeDelete,
+				Force:         true,
+			}))
+		})
+
+		It("records the forced actions for audit", func() {
+			Expect(logBuffer.String()).To(ContainSubstring(
+				"audit: forced deprovision of instance %s: force deprovision requested by user some-admin-user-id", instanceID,
+			))
+			Expect(logBuffer.String()).To(ContainSubstring(
+				"audit: forced deprovision of instance %s: force deleting the deployment", instanceID,
+			))
+
+			Expect(auditedActions()).To(Equal([]string{
+				"force deprovision requested by user some-admin-user-id",
+				"force deleting the deployment",
+			}))
+
+			Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(1))
+			_, operation := fakeOperationStore.StartOperationArgsForCall(0)
+			Expect(operation.Forced).To(BeTrue())
+		})
+
+		Context("when the audit log cannot be written", func() {
+			BeforeEach(func() {
+				fakeAuditLog.RecordReturns(errors.New("disk full"))
+			})
+
+			It("still force deletes the deployment and logs the error", func() {
+				Expect(deprovisionErr).NotTo(HaveOccurred())
+				Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(1))
+				Expect(logBuffer.String()).To(ContainSubstring(
+					"error recording forced action on instance %s in the audit log: disk full", instanceID,
+				))
+			})
+		})
+
+		Context("when the deployment does not exist", func() {
+			BeforeEach(func() {
+				boshClient.GetDeploymentReturns(nil, false, nil)
+			})
+
+			It("reports that the instance is gone", func() {
+				Expect(deprovisionErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(0))
+				Expect(logBuffer.String()).To(ContainSubstring(
+					"audit: forced deprovision of instance %s: deployment not found, treating the instance as deleted", instanceID,
+				))
+				Expect(auditedActions()).To(ContainElement("deployment not found, treating the instance as deleted"))
+			})
+		})
+
+		Context("when the plan has a pre-delete errand", func() {
+			BeforeEach(func() {
+				cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: preDeleteErrandPlanID}, nil)
+			})
+
+			It("runs the errand as part of a forced deletion", func() {
+				Expect(deprovisionErr).NotTo(HaveOccurred())
+				Expect(boshClient.RunErrandCallCount()).To(Equal(1))
+				Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(0))
+
+				var operationData broker.OperationData
+				Expect(json.Unmarshal([]byte(deprovisionSpec.OperationData), &operationData)).To(Succeed())
+				Expect(operationData.BoshTaskID).To(Equal(errandTaskID))
+				Expect(operationData.Force).To(BeTrue())
+			})
+
+			Context("and the errand cannot be started", func() {
+				BeforeEach(func() {
+					boshClient.RunErrandReturns(0, errors.New("errand not found"))
+				})
+
+				It("skips the errand and force deletes the deployment", func() {
+					Expect(deprovisionErr).NotTo(HaveOccurred())
+					Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(1))
+					Expect(logBuffer.String()).To(ContainSubstring(
+						"audit: forced deprovision of instance %s: pre-delete errand cleanup-resources failed to start, skipping the pre-delete errands", instanceID,
+					))
+				})
+			})
+		})
+
+		Context("when the instance state cannot be retrieved from Cloud Foundry", func() {
+			BeforeEach(func() {
+				cfClient.GetInstanceStateReturns(cf.InstanceState{}, errors.New("cc is down"))
+			})
+
+			It("skips the pre-delete errand and force deletes the deployment", func() {
+				Expect(deprovisionErr).NotTo(HaveOccurred())
+				Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+				Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when a bosh task is in flight for the deployment", func() {
+			BeforeEach(func() {
+				boshClient.GetTasksReturns(boshdirector.BoshTasks{{State: boshdirector.TaskProcessing}}, nil)
+			})
+
+			It("does not delete the deployment", func() {
+				Expect(deprovisionErr).To(MatchError(broker.OperationInProgressMessage))
+				Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the async allowed flag is false", func() {
+			BeforeEach(func() {
+				asyncAllowed = false
+			})
+
+			It("returns an async required error", func() {
+				Expect(deprovisionErr).To(Equal(brokerapi.ErrAsyncRequired))
+			})
+		})
+	})
+
+	Describe("polling a forced deletion whose pre-delete errand failed", func() {
+		It("records the skipped errand for audit", func() {
+			boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+				{ID: errandTaskID, State: boshdirector.TaskError},
+			}, nil)
+			boshClient.GetTaskReturns(boshdirector.BoshTask{ID: deleteTaskID, State: boshdirector.TaskProcessing}, nil)
+
+			operationData, err := json.Marshal(broker.OperationData{
+				BoshTaskID:    errandTaskID,
+				BoshContextID: "some-context-id",
+				OperationType: broker.OperationTypeDelete,
+				Force:         true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = b.LastOperation(context.Background(), instanceID, string(operationData))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(1))
+			Expect(auditedActions()).To(Equal([]string{
+				fmt.Sprintf("pre-delete errand task %d finished in state error, skipping it", errandTaskID),
+				"force deleting the deployment",
+			}))
+		})
+	})
+
+	Describe("purging an instance", func() {
+		var (
+			operationData broker.OperationData
+			purgeErr      error
+		)
+
+		JustBeforeEach(func() {
+			operationData, purgeErr = b.PurgeInstance(context.Background(), instanceID, loggerFactory.New())
+		})
+
+		It("force deletes the deployment", func() {
+			Expect(purgeErr).NotTo(HaveOccurred())
+			Expect(operationData).To(Equal(broker.OperationData{
+				BoshTaskID:    deleteTaskID,
+				OperationType: broker.OperationTypeDelete,
+				Force:         true,
+			}))
+			Expect(logBuffer.String()).To(ContainSubstring(
+				"audit: forced deprovision of instance %s: purge requested by the operator", instanceID,
+			))
+			Expect(auditedActions()).To(Equal([]string{
+				"purge requested by the operator",
+				"force deleting the deployment",
+			}))
+		})
+
+		Context("when the deployment does not exist", func() {
+			BeforeEach(func() {
+				boshClient.GetDeploymentReturns(nil, false, nil)
+			})
+
+			It("reports that the instance is gone", func() {
+				Expect(purgeErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+			})
+		})
+
+		Context("when a bosh task is in flight for the deployment", func() {
+			BeforeEach(func() {
+				boshClient.GetTasksReturns(boshdirector.BoshTasks{{State: boshdirector.TaskProcessing}}, nil)
+			})
+
+			It("returns an operation in progress error", func() {
+				Expect(purgeErr).To(BeAssignableToTypeOf(broker.OperationInProgressError{}))
+			})
+		})
+
+		Context("when the deployment cannot be deleted", func() {
+			BeforeEach(func() {
+				boshClient.ForceDeleteDeploymentReturns(0, errors.New("bosh is down"))
+			})
+
+			It("returns the error for the operator", func() {
+				Expect(purgeErr).To(MatchError("error deprovisioning: deleting bosh deployment: bosh is down"))
+			})
+		})
+	})
+})
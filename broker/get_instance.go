@@ -0,0 +1,119 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/pborman/uuid"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+)
+
+type InstanceDetails struct {
+	ServiceID    string                 `json:"service_id"`
+	PlanID       string                 `json:"plan_id"`
+	DashboardURL string                 `json:"dashboard_url,omitempty"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+}
+
+func (b *Broker) GetInstance(ctx context.Context, instanceID string) (InstanceDetails, error) {
+	requestID := uuid.New()
+	ctx = brokercontext.New(ctx, "", requestID, b.serviceName("", ""), instanceID)
+	logger := b.loggerFactory.NewWithContext(ctx)
+
+	errs := func(err DisplayableError) (InstanceDetails, error) {
+		logger.Println(err)
+		return InstanceDetails{}, err.ErrorForCFUser()
+	}
+
+	planID, parameters, err := b.lastAppliedPlanAndParameters(ctx, instanceID, logger)
+	if err != NilError {
+		return errs(err)
+	}
+
+	manifest, found, getDeploymentErr := b.boshClient.GetDeployment(b.deploymentName(instanceID), logger)
+	switch getDeploymentErr.(type) {
+	case boshdirector.RequestError:
+		return errs(NewBoshRequestError("get", fmt.Errorf("could not get manifest: %s", getDeploymentErr)))
+	case error:
+		return errs(NewGenericError(ctx, fmt.Errorf("could not get manifest: %s", getDeploymentErr)))
+	}
+
+	if !found {
+		return errs(NewDisplayableError(
+			brokerapi.ErrInstanceDoesNotExist,
+			fmt.Errorf("getting instance: deployment %s not found", b.deploymentName(instanceID)),
+		))
+	}
+
+	offering, plan, found := b.offeringForPlan(planID)
+	if !found {
+		return errs(NewGenericError(ctx, fmt.Errorf("getting instance: plan %s not found", planID)))
+	}
+
+	abridgedPlan := plan.AdapterPlan(offering.Catalog.GlobalProperties)
+
+	dashboardURL, dashboardErr := offering.AdapterClient.GenerateDashboardUrl(ctx, instanceID, abridgedPlan, manifest, logger)
+	switch dashboardErr.(type) {
+	case nil, serviceadapter.NotImplementedError:
+	default:
+		logger.Printf("generating dashboard: %v\n", dashboardErr)
+		return InstanceDetails{}, adapterToAPIError(ctx, dashboardErr)
+	}
+
+	return InstanceDetails{
+		ServiceID:    offering.Catalog.ID,
+		PlanID:       planID,
+		DashboardURL: dashboardURL,
+		Parameters:   parameters,
+	}, nil
+}
+
+// lastAppliedPlanAndParameters prefers the operation store, which knows the
+// parameters, and falls back to Cloud Controller for instances created before
+// the store was in use.
+func (b *Broker) lastAppliedPlanAndParameters(
+	ctx context.Context,
+	instanceID string,
+	logger *log.Logger,
+) (string, map[string]interface{}, DisplayableError) {
+	notFound := NewDisplayableError(
+		brokerapi.ErrInstanceDoesNotExist,
+		fmt.Errorf("getting instance: instance %s not found", instanceID),
+	)
+
+	instance, found, err := b.operationStore.GetInstance(instanceID)
+	if err != nil {
+		return "", nil, NewGenericError(ctx, fmt.Errorf("getting instance from operation store: %s", err))
+	}
+
+	if found {
+		switch instance.State {
+		case operationstore.InstanceCreating, operationstore.InstanceFailed, operationstore.InstanceDeleted:
+			return "", nil, notFound
+		}
+		return instance.PlanID, instance.Parameters, NilError
+	}
+
+	instanceState, err := b.cfClient.GetInstanceState(instanceID, logger)
+	switch err.(type) {
+	case nil:
+	case cf.ResourceNotFoundError:
+		return "", nil, notFound
+	default:
+		return "", nil, NewGenericError(ctx, fmt.Errorf("getting instance from cloud controller: %s", err))
+	}
+
+	return instanceState.PlanID, nil, NilError
+}
@@ -0,0 +1,183 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("GetInstance", func() {
+	var (
+		instanceID = "some-instance-id"
+		manifest   = []byte("some-manifest")
+
+		instanceDetails broker.InstanceDetails
+		getInstanceErr  error
+	)
+
+	BeforeEach(func() {
+		fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+			ID:         instanceID,
+			PlanID:     existingPlanID,
+			Parameters: map[string]interface{}{"foo": "bar"},
+			State:      operationstore.InstanceActive,
+		}, true, nil)
+		boshClient.GetDeploymentReturns(manifest, true, nil)
+		serviceAdapter.GenerateDashboardUrlReturns("https://dashboard.example.com", nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+		instanceDetails, getInstanceErr = b.GetInstance(context.Background(), instanceID)
+	})
+
+	It("returns the service, plan, dashboard url and last applied parameters", func() {
+		Expect(getInstanceErr).NotTo(HaveOccurred())
+		Expect(instanceDetails).To(Equal(broker.InstanceDetails{
+			ServiceID:    serviceOfferingID,
+			PlanID:       existingPlanID,
+			DashboardURL: "https://dashboard.example.com",
+			Parameters:   map[string]interface{}{"foo": "bar"},
+		}))
+	})
+
+	It("advertises in the catalog that instances can be fetched", func() {
+		services := b.Services(context.Background())
+		Expect(services).To(HaveLen(1))
+		Expect(services[0].InstancesRetrievable).To(BeTrue())
+	})
+
+	It("regenerates the dashboard url from the current manifest", func() {
+		Expect(serviceAdapter.GenerateDashboardUrlCallCount()).To(Equal(1))
+		_, actualInstanceID, plan, actualManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
+		Expect(actualInstanceID).To(Equal(instanceID))
+		Expect(plan.Properties).To(Equal(sdk.Properties{
+			"super":                      "no",
+			"a_global_property":          "global_value",
+			"some_other_global_property": "other_global_value",
+		}))
+		Expect(actualManifest).To(Equal(manifest))
+	})
+
+	It("does not ask Cloud Controller for the plan", func() {
+		Expect(cfClient.GetInstanceStateCallCount()).To(Equal(0))
+	})
+
+	Context("when the operation store does not know the instance", func() {
+		BeforeEach(func() {
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{}, false, nil)
+			cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: secondPlanID}, nil)
+		})
+
+		It("returns the plan from Cloud Controller without parameters", func() {
+			Expect(getInstanceErr).NotTo(HaveOccurred())
+			Expect(instanceDetails.PlanID).To(Equal(secondPlanID))
+			Expect(instanceDetails.Parameters).To(BeNil())
+		})
+
+		Context("and Cloud Controller does not know the instance either", func() {
+			BeforeEach(func() {
+				cfClient.GetInstanceStateReturns(cf.InstanceState{}, cf.NewResourceNotFoundError("not found"))
+			})
+
+			It("returns an instance does not exist error", func() {
+				Expect(getInstanceErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+			})
+		})
+
+		Context("and Cloud Controller returns an error", func() {
+			BeforeEach(func() {
+				cfClient.GetInstanceStateReturns(cf.InstanceState{}, errors.New("cc is down"))
+			})
+
+			It("returns a generic error", func() {
+				Expect(getInstanceErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+				Expect(logBuffer.String()).To(ContainSubstring("cc is down"))
+			})
+		})
+	})
+
+	Context("when the instance is still being provisioned", func() {
+		BeforeEach(func() {
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+				ID:     instanceID,
+				PlanID: existingPlanID,
+				State:  operationstore.InstanceCreating,
+			}, true, nil)
+		})
+
+		It("returns an instance does not exist error", func() {
+			Expect(getInstanceErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+		})
+	})
+
+	Context("when the instance has been deleted", func() {
+		BeforeEach(func() {
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+				ID:     instanceID,
+				PlanID: existingPlanID,
+				State:  operationstore.InstanceDeleted,
+			}, true, nil)
+		})
+
+		It("returns an instance does not exist error", func() {
+			Expect(getInstanceErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+		})
+	})
+
+	Context("when the deployment does not exist", func() {
+		BeforeEach(func() {
+			boshClient.GetDeploymentReturns(nil, false, nil)
+		})
+
+		It("returns an instance does not exist error", func() {
+			Expect(getInstanceErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+		})
+	})
+
+	Context("when BOSH cannot be reached", func() {
+		BeforeEach(func() {
+			boshClient.GetDeploymentReturns(nil, false, boshdirector.NewRequestError(errors.New("connection refused")))
+		})
+
+		It("returns a try again later error", func() {
+			Expect(getInstanceErr).To(MatchError(ContainSubstring("Currently unable to get service instance, please try again later")))
+		})
+	})
+
+	Context("when the adapter does not implement the dashboard url", func() {
+		BeforeEach(func() {
+			serviceAdapter.GenerateDashboardUrlReturns("", serviceadapter.NewNotImplementedError("not implemented"))
+		})
+
+		It("returns the instance without a dashboard url", func() {
+			Expect(getInstanceErr).NotTo(HaveOccurred())
+			Expect(instanceDetails.DashboardURL).To(BeEmpty())
+		})
+	})
+
+	Context("when the adapter fails to generate the dashboard url", func() {
+		BeforeEach(func() {
+			serviceAdapter.GenerateDashboardUrlReturns("", serviceadapter.NewUnknownFailureError("adapter says no"))
+		})
+
+		It("returns the adapter error", func() {
+			Expect(getInstanceErr).To(MatchError("adapter says no"))
+		})
+	})
+})
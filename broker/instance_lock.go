@@ -0,0 +1,145 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+)
+
+// instanceLocker serialises operations on a single service instance, while
+// operations on different instances proceed in parallel. Requests waiting on
+// the same instance are granted the lock in the order they arrived.
+type instanceLocker struct {
+	mutex       sync.Mutex
+	instances   map[string]*instanceLock
+	waitTimeout time.Duration
+	grants      int
+}
+
+// instanceLock records which grant holds the lock, so that a holder whose
+// lock was forcibly released cannot release it for the next holder, and the
+// ID of the request it was granted to.
+type instanceLock struct {
+	holder  int
+	owner   string
+	waiters []*lockWaiter
+}
+
+type lockWaiter struct {
+	turn  chan struct{}
+	grant int
+	owner string
+}
+
+func newInstanceLocker(waitTimeout time.Duration) *instanceLocker {
+	return &instanceLocker{
+		instances:   map[string]*instanceLock{},
+		waitTimeout: waitTimeout,
+	}
+}
+
+// Lock blocks until the lock for instanceID is held, the wait timeout expires
+// or ctx is done. The returned bool is false when the lock was not acquired.
+func (l *instanceLocker) Lock(ctx context.Context, instanceID string) (func(), bool) {
+	owner := brokercontext.GetReqID(ctx)
+
+	l.mutex.Lock()
+	lock, held := l.instances[instanceID]
+	if !held {
+		l.grants++
+		grant := l.grants
+		l.instances[instanceID] = &instanceLock{holder: grant, owner: owner}
+		l.mutex.Unlock()
+		return l.unlocker(instanceID, grant), true
+	}
+
+	if l.waitTimeout <= 0 {
+		l.mutex.Unlock()
+		return nil, false
+	}
+
+	waiter := &lockWaiter{turn: make(chan struct{}), owner: owner}
+	lock.waiters = append(lock.waiters, waiter)
+	l.mutex.Unlock()
+
+	timer := time.NewTimer(l.waitTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-waiter.turn:
+		return l.unlocker(instanceID, waiter.grant), true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	select {
+	case <-waiter.turn:
+		// the lock was handed over while we were giving up
+		return l.unlocker(instanceID, waiter.grant), true
+	default:
+	}
+
+	for i, w := range lock.waiters {
+		if w == waiter {
+			lock.waiters = append(lock.waiters[:i], lock.waiters[i+1:]...)
+			break
+		}
+	}
+
+	return nil, false
+}
+
+func (l *instanceLocker) unlocker(instanceID string, grant int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+
+			if lock, held := l.instances[instanceID]; held && lock.holder == grant {
+				l.handOver(instanceID, lock)
+			}
+		})
+	}
+}
+
+// ForceUnlock releases the lock for instanceID when the request with the
+// given ID holds it, and reports whether it did. It is for operations that
+// hang while holding the lock.
+func (l *instanceLocker) ForceUnlock(instanceID, owner string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	lock, held := l.instances[instanceID]
+	if !held || owner == "" || lock.owner != owner {
+		return false
+	}
+	l.handOver(instanceID, lock)
+	return true
+}
+
+func (l *instanceLocker) handOver(instanceID string, lock *instanceLock) {
+	if len(lock.waiters) == 0 {
+		delete(l.instances, instanceID)
+		return
+	}
+
+	l.grants++
+	next := lock.waiters[0]
+	lock.waiters = lock.waiters[1:]
+	next.grant = l.grants
+	lock.holder = next.grant
+	lock.owner = next.owner
+	close(next.turn)
+}
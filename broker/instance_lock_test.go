@@ -0,0 +1,200 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"log"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var _ = Describe("per-instance operation locking", func() {
+	const (
+		blockedInstanceID = "blocked-instance-id"
+		otherInstanceID   = "other-instance-id"
+	)
+
+	var (
+		createStarted    chan string
+		releaseCreate    chan struct{}
+		blockedRequestID string
+	)
+
+	provision := func(instanceID string) error {
+		_, err := b.Provision(
+			context.Background(),
+			instanceID,
+			brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+			true,
+		)
+		return err
+	}
+
+	provisionInBackground := func(instanceID string) chan error {
+		result := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			result <- provision(instanceID)
+		}()
+		return result
+	}
+
+	BeforeEach(func() {
+		createStarted = make(chan string, 10)
+		releaseCreate = make(chan struct{})
+
+		boshClient.GetDeploymentReturns(nil, false, nil)
+		fakeDeployer.CreateStub = func(ctx context.Context, deploymentName, _ string, _ map[string]interface{}, _ string, _ *log.Logger) (int, []byte, error) {
+			if deploymentName == "service-instance_"+blockedInstanceID {
+				blockedRequestID = brokercontext.GetReqID(ctx)
+			}
+			createStarted <- deploymentName
+			if deploymentName == "service-instance_"+blockedInstanceID {
+				<-releaseCreate
+			}
+			return 42, []byte("manifest"), nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	Context("when an operation is in progress for an instance", func() {
+		var firstProvision chan error
+
+		JustBeforeEach(func() {
+			firstProvision = provisionInBackground(blockedInstanceID)
+			Eventually(createStarted).Should(Receive(Equal(deploymentName(blockedInstanceID))))
+		})
+
+		AfterEach(func() {
+			close(releaseCreate)
+			Eventually(firstProvision).Should(Receive(BeNil()))
+		})
+
+		It("allows operations on other instances to proceed", func() {
+			Expect(provision(otherInstanceID)).To(Succeed())
+			Expect(createStarted).To(Receive(Equal(deploymentName(otherInstanceID))))
+		})
+
+		It("rejects a provision for the same instance with an operation in progress error", func() {
+			err := provision(blockedInstanceID)
+			Expect(err).To(MatchError(broker.OperationInProgressMessage))
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+		})
+
+		It("rejects an update for the same instance with an operation in progress error", func() {
+			_, err := b.Update(
+				context.Background(),
+				blockedInstanceID,
+				brokerapi.UpdateDetails{PlanID: existingPlanID, PreviousValues: brokerapi.PreviousValues{PlanID: existingPlanID}},
+				true,
+			)
+			Expect(err).To(MatchError(broker.OperationInProgressMessage))
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+		})
+
+		It("rejects a deprovision for the same instance with an operation in progress error", func() {
+			_, err := b.Deprovision(context.Background(), blockedInstanceID, brokerapi.DeprovisionDetails{}, true)
+			Expect(err).To(MatchError(broker.OperationInProgressMessage))
+			Expect(boshClient.DeleteDeploymentCallCount()).To(Equal(0))
+		})
+
+		It("rejects an upgrade for the same instance with an operation in progress error", func() {
+			cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: existingPlanID}, nil)
+			_, _, err := b.Upgrade(context.Background(), blockedInstanceID, loggerFactory.New())
+			Expect(err).To(BeAssignableToTypeOf(broker.OperationInProgressError{}))
+			Expect(fakeDeployer.UpgradeCallCount()).To(Equal(0))
+		})
+
+		It("lets the operation be cancelled and releases its lock", func() {
+			boshClient.GetTasksReturns(boshdirector.BoshTasks{{ID: 42, State: boshdirector.TaskProcessing}}, nil)
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+				ID: blockedInstanceID,
+				Operations: []operationstore.Operation{
+					{Type: "create", BoshTaskIDs: []int{42}, State: operationstore.OperationInProgress, RequestID: blockedRequestID},
+				},
+			}, true, nil)
+
+			cancelledTaskIDs, err := b.CancelOperation(context.Background(), blockedInstanceID, loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cancelledTaskIDs).To(Equal([]int{42}))
+
+			nextProvision := provisionInBackground(blockedInstanceID)
+			Eventually(createStarted).Should(Receive(Equal(deploymentName(blockedInstanceID))))
+
+			releaseCreate <- struct{}{}
+			releaseCreate <- struct{}{}
+			Eventually(nextProvision).Should(Receive(BeNil()))
+		})
+
+		It("does not release the lock when another request holds it", func() {
+			boshClient.GetTasksReturns(boshdirector.BoshTasks{{ID: 42, State: boshdirector.TaskProcessing}}, nil)
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+				ID: blockedInstanceID,
+				Operations: []operationstore.Operation{
+					{Type: "create", BoshTaskIDs: []int{42}, State: operationstore.OperationInProgress, RequestID: "some-other-request-id"},
+				},
+			}, true, nil)
+
+			_, err := b.CancelOperation(context.Background(), blockedInstanceID, loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(provision(blockedInstanceID)).To(MatchError(broker.OperationInProgressMessage))
+		})
+
+		It("logs that the instance is locked", func() {
+			provision(blockedInstanceID)
+			Expect(logBuffer.String()).To(ContainSubstring("broker: operation in progress for instance " + blockedInstanceID))
+		})
+
+		Context("and an operation lock timeout is configured", func() {
+			BeforeEach(func() {
+				operationLockTimeout = time.Minute
+			})
+
+			It("waits for the lock and then proceeds", func() {
+				secondProvision := provisionInBackground(blockedInstanceID)
+				Consistently(secondProvision).ShouldNot(Receive())
+
+				releaseCreate <- struct{}{}
+
+				Eventually(createStarted).Should(Receive(Equal(deploymentName(blockedInstanceID))))
+				releaseCreate <- struct{}{}
+				Eventually(secondProvision).Should(Receive(BeNil()))
+			})
+		})
+
+		Context("and the operation lock timeout expires", func() {
+			BeforeEach(func() {
+				operationLockTimeout = 10 * time.Millisecond
+			})
+
+			It("returns an operation in progress error", func() {
+				Expect(provision(blockedInstanceID)).To(MatchError(broker.OperationInProgressMessage))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	It("releases the lock when an operation finishes", func() {
+		close(releaseCreate)
+		Expect(provision(blockedInstanceID)).To(Succeed())
+		Expect(provision(blockedInstanceID)).To(Succeed())
+		Expect(fakeDeployer.CreateCallCount()).To(Equal(2))
+	})
+})
@@ -8,37 +8,176 @@ package broker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
 	"github.com/pivotal-cf/on-demand-service-broker/config"
 )
 
 func (b *Broker) Instances(logger *log.Logger) ([]string, error) {
-	instanceIDs, err := b.cfClient.GetInstancesOfServiceOffering(b.serviceOffering.ID, logger)
-	if err != nil {
-		logger.Printf("error listing instances: %s", err)
-		return nil, err
+	var instanceIDs []string
+	for _, offering := range b.serviceOfferings {
+		offeringInstanceIDs, err := b.cfClient.GetInstancesOfServiceOffering(offering.Catalog.ID, logger)
+		if err != nil {
+			logger.Printf("error listing instances: %s", err)
+			return nil, err
+		}
+		instanceIDs = append(instanceIDs, offeringInstanceIDs...)
 	}
 
 	return instanceIDs, nil
 }
 
-func (b *Broker) validatePlanQuota(ctx context.Context, serviceID string, plan config.Plan, logger *log.Logger) DisplayableError {
+func (b *Broker) CountInstancesOfPlansByOrg(logger *log.Logger) (map[cf.ServicePlan]map[string]int, error) {
+	counts := map[cf.ServicePlan]map[string]int{}
+	for _, offering := range b.serviceOfferings {
+		offeringCounts, err := b.cfClient.CountInstancesOfServiceOfferingByOrg(offering.Catalog.ID, logger)
+		if err != nil {
+			return nil, err
+		}
+		for plan, orgCounts := range offeringCounts {
+			counts[plan] = orgCounts
+		}
+	}
+
+	return counts, nil
+}
+
+func (b *Broker) validateGlobalQuota(ctx context.Context, catalog config.ServiceOffering, counts *instanceCounts, reserved quotaReservations, logger *log.Logger) DisplayableError {
+	if catalog.GlobalQuotas.ServiceInstanceLimit == nil {
+		return NilError
+	}
+	limit := *catalog.GlobalQuotas.ServiceInstanceLimit
+
+	planCounts, err := counts.byPlan("global", func() (map[cf.ServicePlan]int, error) {
+		return b.cfClient.CountInstancesOfServiceOffering(catalog.ID, logger)
+	})
+	if err != nil {
+		return NewGenericError(ctx, err)
+	}
+
+	count := reserved.ofService(catalog.ID)
+	for _, planCount := range planCounts {
+		count += planCount
+	}
+
+	if count >= limit {
+		return NewDisplayableError(
+			brokerapi.ErrServiceQuotaExceeded,
+			fmt.Errorf("service quota exceeded for service ID %s", catalog.ID),
+		)
+	}
+
+	return NilError
+}
+
+func (b *Broker) validatePlanQuota(ctx context.Context, serviceID string, plan config.Plan, counts *instanceCounts, reserved quotaReservations, logger *log.Logger) DisplayableError {
 	if plan.Quotas.ServiceInstanceLimit == nil {
 		return NilError
 	}
 	limit := *plan.Quotas.ServiceInstanceLimit
 
-	count, err := b.cfClient.CountInstancesOfPlan(serviceID, plan.ID, logger)
+	count, err := counts.of("plan", func() (int, error) {
+		return b.cfClient.CountInstancesOfPlan(serviceID, plan.ID, logger)
+	})
 	if err != nil {
 		return NewGenericError(ctx, fmt.Errorf("error counting instances of plan: %s", err))
 	}
 
-	if count >= limit {
+	if count+reserved.ofPlan(plan.ID) >= limit {
 		return NewDisplayableError(brokerapi.ErrPlanQuotaExceeded, fmt.Errorf("plan quota exceeded for plan ID %s", plan.ID))
 	}
 
 	return NilError
 }
+
+func (b *Broker) validateTenantQuotas(ctx context.Context, serviceID string, plan config.Plan, counts *instanceCounts, reserved quotaReservations, logger *log.Logger) DisplayableError {
+	tenant := brokercontext.GetTenant(ctx)
+
+	if limit, found := plan.Quotas.Orgs.LimitFor(tenant.OrganizationGUID); found {
+		count, err := counts.of("org", func() (int, error) {
+			return b.cfClient.CountInstancesOfPlanInOrg(serviceID, plan.ID, tenant.OrganizationGUID, logger)
+		})
+		if err != nil {
+			return NewGenericError(ctx, fmt.Errorf("error counting instances of plan in org: %s", err))
+		}
+
+		if count+reserved.ofPlanInOrg(plan.ID, tenant.OrganizationGUID) >= limit {
+			return NewDisplayableError(
+				errors.New(OrgQuotaExceededMessage),
+				fmt.Errorf("org quota exceeded for plan ID %s in org %s", plan.ID, tenant.OrganizationGUID),
+			)
+		}
+	}
+
+	if limit, found := plan.Quotas.Spaces.LimitFor(tenant.SpaceGUID); found {
+		count, err := counts.of("space", func() (int, error) {
+			return b.cfClient.CountInstancesOfPlanInSpace(serviceID, plan.ID, tenant.SpaceGUID, logger)
+		})
+		if err != nil {
+			return NewGenericError(ctx, fmt.Errorf("error counting instances of plan in space: %s", err))
+		}
+
+		if count+reserved.ofPlanInSpace(plan.ID, tenant.SpaceGUID) >= limit {
+			return NewDisplayableError(
+				errors.New(SpaceQuotaExceededMessage),
+				fmt.Errorf("space quota exceeded for plan ID %s in space %s", plan.ID, tenant.SpaceGUID),
+			)
+		}
+	}
+
+	return NilError
+}
+
+// validateResourceQuotas checks the resources used by all deployments once
+// the instance is on the given plan. previousPlanID is empty for new instances.
+func (b *Broker) validateResourceQuotas(ctx context.Context, offering ServiceOffering, plan config.Plan, previousPlanID string, counts *instanceCounts, reserved quotaReservations, logger *log.Logger) DisplayableError {
+	catalog := offering.Catalog
+	if !catalog.HasResourceQuotas() {
+		return NilError
+	}
+
+	planCounts, err := counts.byPlan("resources", func() (map[cf.ServicePlan]int, error) {
+		return b.cfClient.CountInstancesOfServiceOffering(catalog.ID, logger)
+	})
+	if err != nil {
+		return NewGenericError(ctx, fmt.Errorf("error counting instances of service offering: %s", err))
+	}
+
+	countsByPlanID := reserved.countsByPlanID(catalog.ID)
+	for cfPlan, count := range planCounts {
+		countsByPlanID[cfPlan.ServicePlanEntity.UniqueID] += count
+	}
+
+	requested := plan.ResourceUsage(catalog.PersistentDiskTypeSizesGB)
+
+	planUsage := config.ResourceUsage{}.Plus(requested, countsByPlanID[plan.ID]+1)
+	if resource, exceeded := plan.Quotas.Resources.ExceededBy(planUsage); exceeded {
+		return NewDisplayableError(
+			fmt.Errorf(PlanResourceQuotaExceededMessage, resource),
+			fmt.Errorf("plan %s quota exceeded for plan ID %s", resource, plan.ID),
+		)
+	}
+
+	// Cloud Foundry still counts instances changing plan in their previous
+	// plans, which they are leaving
+	for previousPlanID, count := range reserved.leavingByPlanID(catalog.ID) {
+		countsByPlanID[previousPlanID] -= count
+	}
+	serviceUsage := catalog.ResourceUsage(countsByPlanID).Plus(requested, 1)
+	if previousPlan, found := catalog.FindPlanByID(previousPlanID); found {
+		serviceUsage = serviceUsage.Plus(previousPlan.ResourceUsage(catalog.PersistentDiskTypeSizesGB), -1)
+	}
+	if resource, exceeded := catalog.GlobalQuotas.Resources.ExceededBy(serviceUsage); exceeded {
+		return NewDisplayableError(
+			fmt.Errorf(ServiceResourceQuotaExceededMessage, resource),
+			fmt.Errorf("service %s quota exceeded for service ID %s", resource, catalog.ID),
+		)
+	}
+
+	return NilError
+}
@@ -25,18 +25,24 @@ var descriptions = map[brokerapi.LastOperationState]map[OperationType]string{
 		OperationTypeUpdate:  "Instance update in progress",
 		OperationTypeUpgrade: "Instance upgrade in progress",
 		OperationTypeDelete:  "Instance deletion in progress",
+		OperationTypeBind:    "Binding in progress",
+		OperationTypeUnbind:  "Unbinding in progress",
 	},
 	brokerapi.Succeeded: {
 		OperationTypeCreate:  "Instance provisioning completed",
 		OperationTypeUpdate:  "Instance update completed",
 		OperationTypeUpgrade: "Instance upgrade completed",
 		OperationTypeDelete:  "Instance deletion completed",
+		OperationTypeBind:    "Binding completed",
+		OperationTypeUnbind:  "Unbinding completed",
 	},
 	brokerapi.Failed: {
 		OperationTypeCreate:  "Instance provisioning failed",
 		OperationTypeUpdate:  "Instance update failed",
 		OperationTypeUpgrade: "Failed for bosh task",
 		OperationTypeDelete:  "Instance deletion failed",
+		OperationTypeBind:    "Binding failed",
+		OperationTypeUnbind:  "Unbinding failed",
 	},
 }
 
@@ -44,7 +50,7 @@ func (b *Broker) LastOperation(ctx context.Context, instanceID, operationDataRaw
 ) (brokerapi.LastOperation, error) {
 
 	requestID := uuid.New()
-	ctx = brokercontext.New(ctx, "", requestID, b.serviceOffering.Name, instanceID)
+	ctx = brokercontext.New(ctx, "", requestID, b.serviceName("", ""), instanceID)
 	logger := b.loggerFactory.NewWithContext(ctx)
 
 	errs := func(err DisplayableError) (brokerapi.LastOperation, error) {
@@ -64,9 +70,25 @@ func (b *Broker) LastOperation(ctx context.Context, instanceID, operationDataRaw
 		))
 	}
 
+	if operationData.OperationType == OperationTypeCreate {
+		// Cloud Foundry only polls for instances it has recorded, so the
+		// instance now counts towards its quotas
+		b.releaseQuota(instanceID, logger)
+	}
+
 	ctx = brokercontext.WithOperation(ctx, string(operationData.OperationType))
+	ctx = brokercontext.WithServiceName(ctx, b.serviceName("", operationData.PlanID))
+
+	if operationData.Queued || len(operationData.PreErrands) > 0 {
+		operationData.RequestParams = b.deferredRequestParams(instanceID, operationData.BoshContextID, logger)
+	}
 
-	if operationData.BoshTaskID == 0 {
+	if operationData.Queued {
+		if lastOperation, waiting := b.queuedLastOperation(ctx, instanceID, operationData, logger); waiting {
+			b.recordOperationStatus(instanceID, operationData, 0, lastOperation, logger)
+			return lastOperation, nil
+		}
+	} else if operationData.BoshTaskID == 0 {
 		return errs(NewGenericError(
 			ctx, errors.New("no task ID found in operation data"),
 		))
@@ -74,27 +96,73 @@ func (b *Broker) LastOperation(ctx context.Context, instanceID, operationDataRaw
 
 	ctx = brokercontext.WithBoshTaskID(ctx, operationData.BoshTaskID)
 
-	lifeCycleRunner := NewLifeCycleRunner(b.boshClient, b.serviceOffering.Plans)
-
-	lastBoshTask, err := lifeCycleRunner.GetTask(deploymentName(instanceID), operationData, logger)
+	lastBoshTask, err := b.lifeCycleRunner(operationData.PlanID).GetTask(ctx, b.deploymentName(instanceID), operationData, logger)
+	if err == errQueuedOperationNotStarted {
+		return b.requeue(instanceID, operationData, logger), nil
+	}
+	if err == errWaitingForTaskSlot {
+		lastOperation := brokerapi.LastOperation{
+			State:       brokerapi.InProgress,
+			Description: fmt.Sprintf("%s: %s", descriptions[brokerapi.InProgress][operationData.OperationType], err),
+		}
+		b.recordOperationStatus(instanceID, operationData, 0, lastOperation, logger)
+		return lastOperation, nil
+	}
+	if err == errDeferredRequestNotRecorded {
+		logger.Printf("not deploying instance %s: %s\n", instanceID, err)
+		lastOperation := brokerapi.LastOperation{
+			State:       brokerapi.Failed,
+			Description: descriptionForOperationTask(ctx, brokerapi.Failed, operationData, 0, ""),
+		}
+		b.recordOperationStatus(instanceID, operationData, 0, lastOperation, logger)
+		return lastOperation, nil
+	}
 	if err != nil {
 		return errs(NewGenericError(ctx, fmt.Errorf(
 			"error retrieving tasks from bosh, for deployment '%s': %s",
-			deploymentName(instanceID), err,
+			b.deploymentName(instanceID), err,
 		)))
 	}
 
 	ctx = brokercontext.WithBoshTaskID(ctx, lastBoshTask.ID)
 
-	lastOperation := constructLastOperation(ctx, lastBoshTask, operationData, logger)
+	details := b.taskDetails(lastBoshTask, operationData, logger)
+	lastOperation := constructLastOperation(ctx, lastBoshTask, operationData, details, b.cancelledByBroker(instanceID, lastBoshTask, logger), logger)
 	logLastOperation(instanceID, lastBoshTask, operationData, logger)
+	b.recordOperationStatus(instanceID, operationData, lastBoshTask.ID, lastOperation, logger)
+
+	if lastOperation.State != brokerapi.InProgress {
+		// the operation's last task has finished, freeing a BOSH task slot
+		b.operationQueue.signal()
+	}
+
+	if operationData.OperationType == OperationTypeUpdate && lastOperation.State != brokerapi.InProgress {
+		b.releaseQuota(instanceID, logger)
+	}
 
 	return lastOperation, nil
 }
 
-func constructLastOperation(ctx context.Context, boshTask boshdirector.BoshTask, operationData OperationData, logger *log.Logger) brokerapi.LastOperation {
+func (b *Broker) lifeCycleRunner(planID string) LifeCycleRunner {
+	var deployer Deployer
+	if offering, _, found := b.offeringForPlan(planID); found {
+		deployer = offering.Deployer
+	}
+	runner := NewLifeCycleRunner(b.boshClient, b.allPlans(), deployer, b.deploymentNames)
+	runner.takeTaskSlot = func(logger *log.Logger) (func(), bool, error) {
+		return b.takeTaskSlot(true, logger)
+	}
+	runner.auditLog = b.auditLog
+	return runner
+}
+
+func constructLastOperation(ctx context.Context, boshTask boshdirector.BoshTask, operationData OperationData, details string, cancelledByBroker bool, logger *log.Logger) brokerapi.LastOperation {
 	taskState := lastOperationState(boshTask, logger)
-	description := descriptionForOperationTask(ctx, taskState, operationData, boshTask.ID)
+	description := descriptionForOperationTask(ctx, taskState, operationData, boshTask.ID, details)
+
+	if cancelledByBroker && operationData.OperationType != OperationTypeUpgrade {
+		description = fmt.Sprintf("%s: %s", descriptions[brokerapi.Failed][operationData.OperationType], OperationCancelledMessage)
+	}
 
 	return brokerapi.LastOperation{State: taskState, Description: description}
 }
@@ -113,12 +181,18 @@ func lastOperationState(task boshdirector.BoshTask, logger *log.Logger) brokerap
 	}
 }
 
-func descriptionForOperationTask(ctx context.Context, taskState brokerapi.LastOperationState, operationData OperationData, taskID int) string {
+func descriptionForOperationTask(ctx context.Context, taskState brokerapi.LastOperationState, operationData OperationData, taskID int, details string) string {
 	description := descriptions[taskState][operationData.OperationType]
 
+	if taskState == brokerapi.InProgress && details != "" {
+		description = fmt.Sprintf("%s: %s", description, details)
+	}
+
 	if taskState == brokerapi.Failed {
 		if operationData.OperationType == OperationTypeUpgrade {
 			description = fmt.Sprintf(description+": %d", taskID) // Allows upgrader to log BOSH task ID when an upgrade fails
+		} else if details != "" {
+			description = fmt.Sprintf("%s: %s. %s", description, details, NewGenericError(ctx, nil).ErrorForCFUser())
 		} else {
 			description = fmt.Sprintf(description+": %s", NewGenericError(ctx, nil).ErrorForCFUser())
 		}
@@ -17,6 +17,7 @@ import (
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
 )
 
 var _ = Describe("LastOperation", func() {
@@ -311,6 +312,18 @@ var _ = Describe("LastOperation", func() {
 					Expect(logBuffer.String()).To(ContainSubstring(testCase.LogContains))
 				})
 
+				It("records the operation status in the operation store", func() {
+					Expect(fakeOperationStore.UpdateOperationCallCount()).To(Equal(1))
+					actualInstanceID, actualTaskID, status := fakeOperationStore.UpdateOperationArgsForCall(0)
+					Expect(actualInstanceID).To(Equal(instanceID))
+					Expect(actualTaskID).To(Equal(taskID))
+					Expect(status).To(Equal(operationstore.OperationStatus{
+						BoshTaskID:  taskID,
+						State:       operationstore.OperationState(actualLastOperation.State),
+						Description: actualLastOperation.Description,
+					}))
+				})
+
 				It(fmt.Sprintf("logs the deployment, type: %s, state: %s", testCase.ActualOperationType, testCase.ActualBoshTask.State), testLogMessage(testCase))
 			}
 		}
@@ -821,4 +834,39 @@ var _ = Describe("LastOperation", func() {
 			)
 		})
 	})
+
+	Context("when the broker cancelled the operation", func() {
+		const (
+			instanceID = "a-cancelled-instance"
+			taskID     = 42
+		)
+
+		var lastOperation brokerapi.LastOperation
+
+		BeforeEach(func() {
+			boshClient.GetTaskReturns(boshdirector.BoshTask{ID: taskID, State: boshdirector.TaskCancelled, ContextID: "some-context-id"}, nil)
+			fakeOperationStore.GetInstanceReturns(operationstore.Instance{
+				ID: instanceID,
+				Operations: []operationstore.Operation{
+					{Type: "create", BoshTaskIDs: []int{taskID}, BoshContextID: "some-context-id", State: operationstore.OperationInProgress, Cancelled: true},
+				},
+			}, true, nil)
+		})
+
+		JustBeforeEach(func() {
+			operationData, err := json.Marshal(broker.OperationData{OperationType: broker.OperationTypeCreate, BoshTaskID: taskID})
+			Expect(err).NotTo(HaveOccurred())
+
+			b = createDefaultBroker()
+			lastOperation, err = b.LastOperation(context.Background(), instanceID, string(operationData))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports that the operator cancelled it", func() {
+			Expect(lastOperation).To(Equal(brokerapi.LastOperation{
+				State:       brokerapi.Failed,
+				Description: "Instance provisioning failed: cancelled by operator",
+			}))
+		})
+	})
 })
@@ -0,0 +1,220 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var _ = Describe("chained lifecycle errands", func() {
+	const (
+		instanceID   = "some-instance-id"
+		errandTaskID = 77
+		deployTaskID = 88
+	)
+
+	BeforeEach(func() {
+		serviceCatalog.Plans[2].LifecycleErrands = &config.LifecycleErrands{
+			PreDeploy:   config.Errands{{Name: "backup", Instances: []string{"redis-server/0"}}},
+			PostDeploy:  config.Errands{{Name: "smoke-tests"}, {Name: "register", ContinueOnFailure: true}},
+			PreUpgrade:  config.Errands{{Name: "drain"}},
+			PostUpgrade: config.Errands{{Name: "migrate-data"}},
+		}
+		boshClient.RunErrandReturns(errandTaskID, nil)
+		boshClient.GetDeploymentReturns(nil, false, nil)
+		fakeDeployer.CreateReturns(deployTaskID, nil, nil)
+		fakeDeployer.UpdateReturns(deployTaskID, nil, nil)
+		fakeDeployer.UpgradeReturns(deployTaskID, nil, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	Describe("provisioning", func() {
+		It("deploys straight away and chains the post-deploy errands", func() {
+			spec, err := b.Provision(
+				context.Background(),
+				instanceID,
+				brokerapi.ProvisionDetails{PlanID: postDeployErrandPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+
+			var operationData broker.OperationData
+			Expect(json.Unmarshal([]byte(spec.OperationData), &operationData)).To(Succeed())
+			Expect(operationData.BoshTaskID).To(Equal(deployTaskID))
+			Expect(operationData.PreErrands).To(BeEmpty())
+			Expect(operationData.PostErrands).To(Equal(config.Errands{{Name: "smoke-tests"}, {Name: "register", ContinueOnFailure: true}}))
+		})
+	})
+
+	Describe("updating", func() {
+		var (
+			updateSpec brokerapi.UpdateServiceSpec
+			updateErr  error
+		)
+
+		JustBeforeEach(func() {
+			updateSpec, updateErr = b.Update(
+				context.Background(),
+				instanceID,
+				brokerapi.UpdateDetails{
+					PlanID:         postDeployErrandPlanID,
+					ServiceID:      serviceOfferingID,
+					PreviousValues: brokerapi.PreviousValues{PlanID: existingPlanID},
+					RawParameters:  []byte(`{"foo":"bar"}`),
+				},
+				true,
+			)
+		})
+
+		It("runs the first pre-deploy errand instead of deploying", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+
+			Expect(boshClient.RunErrandCallCount()).To(Equal(1))
+			actualDeploymentName, errandName, instances, contextID, _ := boshClient.RunErrandArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+			Expect(errandName).To(Equal("backup"))
+			Expect(instances).To(Equal([]string{"redis-server/0"}))
+			Expect(contextID).NotTo(BeEmpty())
+
+			operationData := unmarshalOperationData(updateSpec)
+			Expect(operationData.BoshTaskID).To(Equal(errandTaskID))
+			Expect(operationData.BoshContextID).To(Equal(contextID))
+		})
+
+		It("keeps the plans the deploy needs in the operation data", func() {
+			operationData := unmarshalOperationData(updateSpec)
+			Expect(operationData.PlanID).To(Equal(postDeployErrandPlanID))
+			Expect(operationData.PreviousPlanID).To(Equal(existingPlanID))
+		})
+
+		It("keeps the request in the operation store rather than the operation data", func() {
+			Expect(updateSpec.OperationData).NotTo(ContainSubstring("foo"))
+
+			Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(2))
+			for i := 0; i < 2; i++ {
+				_, operation := fakeOperationStore.StartOperationArgsForCall(i)
+				Expect(operation.BoshContextID).To(Equal(unmarshalOperationData(updateSpec).BoshContextID))
+				Expect(operation.RequestParams).To(HaveKeyWithValue("parameters", map[string]interface{}{"foo": "bar"}))
+			}
+		})
+
+		It("records the request before running the errand", func() {
+			_, operation := fakeOperationStore.StartOperationArgsForCall(0)
+			Expect(operation.BoshTaskIDs).To(Equal([]int{0}))
+
+			_, operation = fakeOperationStore.StartOperationArgsForCall(1)
+			Expect(operation.BoshTaskIDs).To(Equal([]int{errandTaskID}))
+		})
+
+		Context("when the request cannot be recorded", func() {
+			BeforeEach(func() {
+				fakeOperationStore.StartOperationReturns(errors.New("disk full"))
+			})
+
+			It("fails without running the errand", func() {
+				Expect(updateErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+				Expect(logBuffer.String()).To(ContainSubstring("disk full"))
+				Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the errand has finished", func() {
+			BeforeEach(func() {
+				store, err := operationstore.NewFileStore("")
+				Expect(err).NotTo(HaveOccurred())
+				fakeOperationStore.StartOperationStub = store.StartOperation
+				fakeOperationStore.UpdateOperationStub = store.UpdateOperation
+				fakeOperationStore.GetInstanceStub = store.GetInstance
+
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{{ID: errandTaskID, State: boshdirector.TaskDone}}, nil)
+				boshClient.GetTaskReturns(boshdirector.BoshTask{ID: deployTaskID, State: boshdirector.TaskProcessing}, nil)
+			})
+
+			It("deploys with the request from the operation store", func() {
+				_, err := b.LastOperation(context.Background(), instanceID, updateSpec.OperationData)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+				_, _, _, requestParams, _, _, _ := fakeDeployer.UpdateArgsForCall(0)
+				Expect(requestParams).To(HaveKeyWithValue("parameters", map[string]interface{}{"foo": "bar"}))
+			})
+		})
+
+		Context("when the errand has finished but the request is not in the operation store", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{{ID: errandTaskID, State: boshdirector.TaskDone}}, nil)
+			})
+
+			It("fails the operation instead of deploying without the request", func() {
+				lastOperation, err := b.LastOperation(context.Background(), instanceID, updateSpec.OperationData)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lastOperation.State).To(Equal(brokerapi.Failed))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+				Expect(logBuffer.String()).To(ContainSubstring("the request of the operation is not in the operation store"))
+			})
+		})
+
+		Context("when the errand cannot be started", func() {
+			BeforeEach(func() {
+				boshClient.RunErrandReturns(0, errors.New("errand not found"))
+			})
+
+			It("returns an error", func() {
+				Expect(updateErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+				Expect(logBuffer.String()).To(ContainSubstring("errand not found"))
+			})
+		})
+	})
+
+	Describe("upgrading", func() {
+		var operationData broker.OperationData
+
+		BeforeEach(func() {
+			cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: postDeployErrandPlanID}, nil)
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			operationData, _, err = b.Upgrade(context.Background(), instanceID, loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("runs the upgrade errands outside the deploy errands", func() {
+			Expect(operationData.PreErrands).To(Equal(config.Errands{
+				{Name: "drain"},
+				{Name: "backup", Instances: []string{"redis-server/0"}},
+			}))
+			Expect(operationData.PostErrands).To(Equal(config.Errands{
+				{Name: "smoke-tests"},
+				{Name: "register", ContinueOnFailure: true},
+				{Name: "migrate-data"},
+			}))
+
+			Expect(fakeDeployer.UpgradeCallCount()).To(Equal(0))
+			_, errandName, _, _, _ := boshClient.RunErrandArgsForCall(0)
+			Expect(errandName).To(Equal("drain"))
+			Expect(operationData.PlanID).To(Equal(postDeployErrandPlanID))
+		})
+	})
+})
@@ -7,6 +7,7 @@
 package broker
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -15,25 +16,41 @@ import (
 )
 
 type LifeCycleRunner struct {
-	boshClient BoshClient
-	plans      config.Plans
+	boshClient      BoshClient
+	plans           config.Plans
+	deployer        Deployer
+	deploymentNames *DeploymentNames
+
+	// takeTaskSlot, when set, holds back the next task of an operation
+	// while the broker has as many BOSH tasks in flight as it may start
+	takeTaskSlot func(logger *log.Logger) (func(), bool, error)
+
+	// auditLog, when set, records the actions forced through by forced
+	// deletes
+	auditLog AuditLog
 }
 
 func NewLifeCycleRunner(
 	boshClient BoshClient,
 	plans config.Plans,
+	deployer Deployer,
+	deploymentNames *DeploymentNames,
 ) LifeCycleRunner {
 	return LifeCycleRunner{
-		boshClient,
-		plans,
+		boshClient:      boshClient,
+		plans:           plans,
+		deployer:        deployer,
+		deploymentNames: deploymentNames,
 	}
 }
 
-func (l LifeCycleRunner) GetTask(deploymentName string, operationData OperationData, logger *log.Logger,
+func (l LifeCycleRunner) GetTask(ctx context.Context, deploymentName string, operationData OperationData, logger *log.Logger,
 ) (boshdirector.BoshTask, error) {
 	switch {
 	case operationData.BoshContextID == "":
 		return l.boshClient.GetTask(operationData.BoshTaskID, logger)
+	case operationData.hasLifecycleErrands() || operationData.Queued:
+		return l.processErrandChain(ctx, deploymentName, operationData, logger)
 	case validPostDeployOpType(operationData.OperationType):
 		return l.processPostDeployment(deploymentName, operationData, logger)
 	case validPreDeleteOpType(operationData.OperationType):
@@ -75,7 +92,9 @@ func (l LifeCycleRunner) processPostDeployment(
 		}
 
 		if errand := operationData.PostDeployErrandName; errand != "" {
-			return l.runErrand(deploymentName, errand, operationData.BoshContextID, logger)
+			return l.withTaskSlot(operationData, logger, func() (boshdirector.BoshTask, error) {
+				return l.runErrand(deploymentName, config.Errand{Name: errand}, operationData.BoshContextID, logger)
+			})
 		}
 
 		if operationData.PlanID == "" {
@@ -109,14 +128,29 @@ func (l LifeCycleRunner) processPreDelete(
 	case 1:
 		task := boshTasks[0]
 		if task.StateType() != boshdirector.TaskComplete {
-			return task, nil
+			if task.StateType() == boshdirector.TaskIncomplete || !operationData.Force {
+				return task, nil
+			}
+			l.auditForcedAction(
+				l.instanceID(deploymentName),
+				fmt.Sprintf("pre-delete errand task %d finished in state %s, skipping it", task.ID, task.State),
+				logger,
+			)
 		}
 
-		taskID, err := l.boshClient.DeleteDeployment(deploymentName, operationData.BoshContextID, logger)
-		if err != nil {
-			return boshdirector.BoshTask{}, err
-		}
-		return l.boshClient.GetTask(taskID, logger)
+		return l.withTaskSlot(operationData, logger, func() (boshdirector.BoshTask, error) {
+			deleteDeployment := l.boshClient.DeleteDeployment
+			if operationData.Force {
+				l.auditForcedAction(l.instanceID(deploymentName), "force deleting the deployment", logger)
+				deleteDeployment = l.boshClient.ForceDeleteDeployment
+			}
+
+			taskID, err := deleteDeployment(deploymentName, operationData.BoshContextID, logger)
+			if err != nil {
+				return boshdirector.BoshTask{}, err
+			}
+			return l.boshClient.GetTask(taskID, logger)
+		})
 	case 2:
 		// there must be a delete deployment and it must be the first in the task list
 		return boshTasks[0], nil
@@ -126,8 +160,153 @@ func (l LifeCycleRunner) processPreDelete(
 	}
 }
 
-func (l LifeCycleRunner) runErrand(deploymentName, errand, contextID string, log *log.Logger) (boshdirector.BoshTask, error) {
-	taskID, err := l.boshClient.RunErrand(deploymentName, errand, contextID, log)
+// processErrandChain works through the steps of an operation: its
+// pre-deployment errands, the deploy or delete itself, and its
+// post-deployment errands. Every step runs as a BOSH task with the
+// operation's context ID, so the number of tasks found tells how far the
+// operation has got.
+func (l LifeCycleRunner) processErrandChain(
+	ctx context.Context,
+	deploymentName string,
+	operationData OperationData,
+	logger *log.Logger,
+) (boshdirector.BoshTask, error) {
+	boshTasks, err := l.boshClient.GetNormalisedTasksByContext(deploymentName, operationData.BoshContextID, logger)
+	if err != nil {
+		return boshdirector.BoshTask{}, err
+	}
+
+	steps := operationData.lifecycleSteps()
+	if len(boshTasks) == 0 {
+		if operationData.Queued {
+			return boshdirector.BoshTask{}, errQueuedOperationNotStarted
+		}
+		return boshdirector.BoshTask{}, fmt.Errorf("no tasks found for context id: %s", operationData.BoshContextID)
+	}
+	if len(boshTasks) > len(steps) {
+		return boshdirector.BoshTask{},
+			fmt.Errorf("unexpected tasks found with context id: %s, tasks: %s", operationData.BoshContextID, boshTasks.ToLog())
+	}
+
+	task := boshTasks[0]
+	current := steps[len(boshTasks)-1]
+	switch task.StateType() {
+	case boshdirector.TaskComplete:
+	case boshdirector.TaskFailed:
+		if current == nil || task.State == boshdirector.TaskCancelled {
+			return task, nil
+		}
+		switch {
+		case current.ContinueOnFailure:
+			logger.Printf("errand %s failed in task %d, continuing as configured\n", current.Name, task.ID)
+		case operationData.Force && operationData.OperationType == OperationTypeDelete:
+			l.auditForcedAction(
+				l.instanceID(deploymentName),
+				fmt.Sprintf("pre-delete errand %s task %d finished in state %s, skipping it", current.Name, task.ID, task.State),
+				logger,
+			)
+		default:
+			return task, nil
+		}
+		task.State = boshdirector.TaskDone
+	default:
+		return task, nil
+	}
+
+	if len(boshTasks) == len(steps) {
+		return task, nil
+	}
+
+	return l.withTaskSlot(operationData, logger, func() (boshdirector.BoshTask, error) {
+		if next := steps[len(boshTasks)]; next != nil {
+			return l.runErrand(deploymentName, *next, operationData.BoshContextID, logger)
+		}
+
+		taskID, err := l.startDeploymentStep(ctx, deploymentName, operationData, logger)
+		if err != nil {
+			return boshdirector.BoshTask{}, err
+		}
+		return l.boshClient.GetTask(taskID, logger)
+	})
+}
+
+// withTaskSlot starts the next task of an operation once the broker has a
+// BOSH task slot for it. Forced deletes are not held back, as they are not
+// when they start.
+func (l LifeCycleRunner) withTaskSlot(operationData OperationData, logger *log.Logger, start func() (boshdirector.BoshTask, error)) (boshdirector.BoshTask, error) {
+	if l.takeTaskSlot == nil || operationData.Force {
+		return start()
+	}
+
+	release, free, err := l.takeTaskSlot(logger)
+	if err != nil {
+		return boshdirector.BoshTask{}, err
+	}
+	if !free {
+		return boshdirector.BoshTask{}, errWaitingForTaskSlot
+	}
+	defer release()
+
+	return start()
+}
+
+func (l LifeCycleRunner) startDeploymentStep(ctx context.Context, deploymentName string, operationData OperationData, logger *log.Logger) (int, error) {
+	switch operationData.OperationType {
+	case OperationTypeDelete:
+		if operationData.Force {
+			l.auditForcedAction(l.instanceID(deploymentName), "force deleting the deployment", logger)
+			return l.boshClient.ForceDeleteDeployment(deploymentName, operationData.BoshContextID, logger)
+		}
+		return l.boshClient.DeleteDeployment(deploymentName, operationData.BoshContextID, logger)
+	case OperationTypeCreate, OperationTypeUpdate, OperationTypeUpgrade:
+		if l.deployer == nil {
+			return 0, fmt.Errorf("can't deploy %s, plan with id %s not found", deploymentName, operationData.PlanID)
+		}
+		// deploying without the request would drop the parameters it set
+		if operationData.OperationType != OperationTypeUpgrade && operationData.RequestParams == nil {
+			return 0, errDeferredRequestNotRecorded
+		}
+	default:
+		return 0, fmt.Errorf("can't deploy %s for a %s operation", deploymentName, operationData.OperationType)
+	}
+
+	var taskID int
+	var err error
+	switch operationData.OperationType {
+	case OperationTypeCreate:
+		taskID, _, err = l.deployer.Create(
+			ctx,
+			deploymentName,
+			operationData.PlanID,
+			operationData.RequestParams,
+			operationData.BoshContextID,
+			logger,
+		)
+	case OperationTypeUpdate:
+		taskID, _, err = l.deployer.Update(
+			ctx,
+			deploymentName,
+			operationData.PlanID,
+			operationData.RequestParams,
+			&operationData.PreviousPlanID,
+			operationData.BoshContextID,
+			logger,
+		)
+	default:
+		taskID, _, err = l.deployer.Upgrade(
+			ctx,
+			deploymentName,
+			operationData.PlanID,
+			&operationData.PlanID,
+			operationData.BoshContextID,
+			logger,
+		)
+	}
+	return taskID, err
+}
+
+func (l LifeCycleRunner) runErrand(deploymentName string, errand config.Errand, contextID string, log *log.Logger) (boshdirector.BoshTask, error) {
+	taskID, err := l.boshClient.RunErrand(deploymentName, errand.Name, errand.Instances, contextID, log)
 	if err != nil {
 		return boshdirector.BoshTask{}, err
 	}
@@ -147,10 +326,37 @@ func (l LifeCycleRunner) runErrandFromConfig(task boshdirector.BoshTask, deploym
 		return task, nil
 	}
 
-	errand := plan.PostDeployErrand()
-	if errand == "" {
+	errands := plan.PostDeployErrands()
+	if len(errands) == 0 {
 		return task, nil
 	}
 
-	return l.runErrand(deploymentName, errand, operationData.BoshContextID, logger)
+	return l.withTaskSlot(operationData, logger, func() (boshdirector.BoshTask, error) {
+		return l.runErrand(deploymentName, errands[0], operationData.BoshContextID, logger)
+	})
+}
+
+func (o OperationData) hasLifecycleErrands() bool {
+	return len(o.PreErrands) > 0 || len(o.PostErrands) > 0
+}
+
+// lifecycleSteps lists the errands of an operation in the order they run,
+// with nil in place of the deploy or delete itself.
+func (o OperationData) lifecycleSteps() []*config.Errand {
+	var steps []*config.Errand
+	for i := range o.PreErrands {
+		steps = append(steps, &o.PreErrands[i])
+	}
+	steps = append(steps, nil)
+	for i := range o.PostErrands {
+		steps = append(steps, &o.PostErrands[i])
+	}
+	return steps
+}
+
+func (l LifeCycleRunner) instanceID(deploymentName string) string {
+	if instanceID, ours := l.deploymentNames.InstanceID(deploymentName); ours {
+		return instanceID
+	}
+	return deploymentName
 }
@@ -7,6 +7,7 @@
 package broker_test
 
 import (
+	"context"
 	"errors"
 	"log"
 
@@ -33,13 +34,13 @@ var _ = Describe("Lifecycle runner", func() {
 		config.Plan{
 			ID: planID,
 			LifecycleErrands: &config.LifecycleErrands{
-				PostDeploy: errand1,
+				PostDeploy: config.Errands{{Name: errand1}},
 			},
 		},
 		config.Plan{
 			ID: anotherPlanID,
 			LifecycleErrands: &config.LifecycleErrands{
-				PostDeploy: errand2,
+				PostDeploy: config.Errands{{Name: errand2}},
 			},
 		},
 		config.Plan{
@@ -52,13 +53,20 @@ var _ = Describe("Lifecycle runner", func() {
 	taskComplete := boshdirector.BoshTask{ID: 3, State: boshdirector.TaskDone, Description: "snapshot deployment", Result: "result-1", ContextID: contextID}
 
 	var deployRunner broker.LifeCycleRunner
+	var deploymentNames *broker.DeploymentNames
 	var logger *log.Logger
 	var operationData broker.OperationData
 
 	BeforeEach(func() {
+		var err error
+		deploymentNames, err = broker.NewDeploymentNames("", nil)
+		Expect(err).NotTo(HaveOccurred())
+
 		deployRunner = broker.NewLifeCycleRunner(
 			boshClient,
 			plans,
+			fakeDeployer,
+			deploymentNames,
 		)
 
 		logger = loggerFactory.NewWithRequestID()
@@ -80,7 +88,7 @@ var _ = Describe("Lifecycle runner", func() {
 				})
 
 				It("returns the processing task", func() {
-					task, _ := deployRunner.GetTask(deploymentName, operationData, logger)
+					task, _ := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 					Expect(task.State).To(Equal(boshdirector.TaskProcessing))
 				})
 			})
@@ -91,7 +99,7 @@ var _ = Describe("Lifecycle runner", func() {
 				})
 
 				It("returns the errored task", func() {
-					task, _ := deployRunner.GetTask(deploymentName, operationData, logger)
+					task, _ := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 					Expect(task.State).To(Equal(boshdirector.TaskError))
 				})
 			})
@@ -102,7 +110,7 @@ var _ = Describe("Lifecycle runner", func() {
 				})
 
 				It("returns an error", func() {
-					_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+					_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 					Expect(err).To(MatchError("no tasks found for context id: " + contextID))
 				})
 			})
@@ -117,7 +125,7 @@ var _ = Describe("Lifecycle runner", func() {
 					BeforeEach(func() {
 						operationData.PlanID = ""
 						operationData.PostDeployErrandName = ""
-						task, _ = deployRunner.GetTask(deploymentName, operationData, logger)
+						task, _ = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 					})
 					It("logs that the plan id and errand are absent", func() {
 						Expect(logBuffer.String()).To(ContainSubstring("can't determine lifecycle errands, neither PlanID nor PostDeployErrandName is present"))
@@ -135,20 +143,20 @@ var _ = Describe("Lifecycle runner", func() {
 				Context("and the post-deploy errand is present in the operation data", func() {
 					BeforeEach(func() {
 						var err error
-						deployRunner = broker.NewLifeCycleRunner(boshClient, plans)
+						deployRunner = broker.NewLifeCycleRunner(boshClient, plans, fakeDeployer, deploymentNames)
 						operationData = broker.OperationData{
 							BoshContextID:        contextID,
 							OperationType:        broker.OperationTypeCreate,
 							PostDeployErrandName: errand1,
 						}
 
-						task, err = deployRunner.GetTask(deploymentName, operationData, logger)
+						task, err = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 						Expect(err).NotTo(HaveOccurred())
 					})
 
 					It("runs the post-deploy errand set in the operation data", func() {
 						Expect(boshClient.RunErrandCallCount()).To(Equal(1))
-						name, expectedErrand, context, _ := boshClient.RunErrandArgsForCall(0)
+						name, expectedErrand, _, context, _ := boshClient.RunErrandArgsForCall(0)
 						Expect(name).To(Equal(deploymentName))
 						Expect(expectedErrand).To(Equal(errand1))
 						Expect(context).To(Equal(contextID))
@@ -159,19 +167,19 @@ var _ = Describe("Lifecycle runner", func() {
 					Context("and the plan is configured with post deploy errand", func() {
 						BeforeEach(func() {
 							var err error
-							deployRunner = broker.NewLifeCycleRunner(boshClient, plans)
+							deployRunner = broker.NewLifeCycleRunner(boshClient, plans, fakeDeployer, deploymentNames)
 							operationData = broker.OperationData{
 								BoshContextID: contextID,
 								OperationType: broker.OperationTypeCreate,
 								PlanID:        planID,
 							}
 
-							task, err = deployRunner.GetTask(deploymentName, operationData, logger)
+							task, err = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 							Expect(err).NotTo(HaveOccurred())
 						})
 						It("uses the config to determine which errand to run", func() {
 							Expect(boshClient.RunErrandCallCount()).To(Equal(1))
-							name, expectedErrand, context, _ := boshClient.RunErrandArgsForCall(0)
+							name, expectedErrand, _, context, _ := boshClient.RunErrandArgsForCall(0)
 							Expect(name).To(Equal(deploymentName))
 							Expect(expectedErrand).To(Equal(errand1))
 							Expect(context).To(Equal(contextID))
@@ -183,7 +191,7 @@ var _ = Describe("Lifecycle runner", func() {
 					BeforeEach(func() {
 						boshClient.RunErrandReturns(taskProcessing.ID, nil)
 						boshClient.GetTaskReturns(taskProcessing, nil)
-						task, _ = deployRunner.GetTask(deploymentName, operationData, logger)
+						task, _ = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 					})
 
 					It("runs the post deploy errand", func() {
@@ -191,12 +199,12 @@ var _ = Describe("Lifecycle runner", func() {
 					})
 
 					It("runs the correct errand", func() {
-						_, errandName, _, _ := boshClient.RunErrandArgsForCall(0)
+						_, errandName, _, _, _ := boshClient.RunErrandArgsForCall(0)
 						Expect(errandName).To(Equal(errand1))
 					})
 
 					It("runs the errand with the correct contextID", func() {
-						_, _, ctxID, _ := boshClient.RunErrandArgsForCall(0)
+						_, _, _, ctxID, _ := boshClient.RunErrandArgsForCall(0)
 						Expect(ctxID).To(Equal(contextID))
 					})
 
@@ -208,7 +216,7 @@ var _ = Describe("Lifecycle runner", func() {
 					Context("and a post deploy errand is incomplete", func() {
 						BeforeEach(func() {
 							boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskProcessing, taskComplete}, nil)
-							task, _ = deployRunner.GetTask(deploymentName, operationData, logger)
+							task, _ = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 						})
 
 						It("returns the processing task", func() {
@@ -223,7 +231,7 @@ var _ = Describe("Lifecycle runner", func() {
 					Context("and a post deploy errand is complete", func() {
 						BeforeEach(func() {
 							boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskComplete, taskComplete}, nil)
-							task, _ = deployRunner.GetTask(deploymentName, operationData, logger)
+							task, _ = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 						})
 
 						It("returns the complete task", func() {
@@ -238,7 +246,7 @@ var _ = Describe("Lifecycle runner", func() {
 					Context("and the post deploy errand fails", func() {
 						BeforeEach(func() {
 							boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskErrored, taskComplete}, nil)
-							task, _ = deployRunner.GetTask(deploymentName, operationData, logger)
+							task, _ = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 						})
 
 						It("returns the failed task", func() {
@@ -256,7 +264,7 @@ var _ = Describe("Lifecycle runner", func() {
 						})
 
 						It("returns an error", func() {
-							_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+							_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 							Expect(err).To(MatchError("some errand err"))
 						})
 					})
@@ -267,7 +275,7 @@ var _ = Describe("Lifecycle runner", func() {
 						})
 
 						It("returns an error", func() {
-							_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+							_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 							Expect(err).To(MatchError("some err"))
 						})
 					})
@@ -277,7 +285,7 @@ var _ = Describe("Lifecycle runner", func() {
 					BeforeEach(func() {
 						opData := operationData
 						opData.PlanID = "non-existent-plan"
-						task, _ = deployRunner.GetTask(deploymentName, opData, logger)
+						task, _ = deployRunner.GetTask(context.Background(), deploymentName, opData, logger)
 					})
 
 					It("logs that it can't find plan", func() {
@@ -300,7 +308,7 @@ var _ = Describe("Lifecycle runner", func() {
 				})
 
 				It("returns an error", func() {
-					_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+					_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 					Expect(err).To(MatchError("some err"))
 				})
 			})
@@ -314,7 +322,7 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("calls get tasks with the correct id", func() {
-				deployRunner.GetTask(deploymentName, operationData, logger)
+				deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 
 				Expect(boshClient.GetTaskCallCount()).To(Equal(1))
 				actualTaskID, _ := boshClient.GetTaskArgsForCall(0)
@@ -322,13 +330,13 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("returns the processing task", func() {
-				task, _ := deployRunner.GetTask(deploymentName, operationData, logger)
+				task, _ := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 
 				Expect(task).To(Equal(taskProcessing))
 			})
 
 			It("does not error", func() {
-				_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+				_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -339,7 +347,7 @@ var _ = Describe("Lifecycle runner", func() {
 				})
 
 				It("returns the error", func() {
-					_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+					_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 
 					Expect(err).To(MatchError("error getting tasks"))
 				})
@@ -350,7 +358,7 @@ var _ = Describe("Lifecycle runner", func() {
 			func(operationType broker.OperationType, errandRuns bool) {
 				operationData := broker.OperationData{OperationType: operationType, BoshContextID: contextID, PlanID: planID}
 				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskComplete}, nil)
-				deployRunner.GetTask(deploymentName, operationData, logger)
+				deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 
 				if errandRuns {
 					Expect(boshClient.RunErrandCallCount()).To(Equal(1))
@@ -380,7 +388,7 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("returns the processing task", func() {
-				task, _ := deployRunner.GetTask(deploymentName, operationData, logger)
+				task, _ := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 				Expect(task.State).To(Equal(boshdirector.TaskProcessing))
 			})
 		})
@@ -391,7 +399,7 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("returns the errored task", func() {
-				task, _ := deployRunner.GetTask(deploymentName, operationData, logger)
+				task, _ := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 				Expect(task.State).To(Equal(boshdirector.TaskError))
 			})
 		})
@@ -402,7 +410,7 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+				_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 				Expect(err).To(MatchError("no tasks found for context id: " + contextID))
 			})
 		})
@@ -414,7 +422,7 @@ var _ = Describe("Lifecycle runner", func() {
 				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskComplete}, nil)
 				boshClient.DeleteDeploymentReturns(taskProcessing.ID, nil)
 				boshClient.GetTaskReturns(taskProcessing, nil)
-				task, _ = deployRunner.GetTask(deploymentName, operationData, logger)
+				task, _ = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 			})
 
 			It("runs bosh delete deployment ", func() {
@@ -442,7 +450,7 @@ var _ = Describe("Lifecycle runner", func() {
 				})
 
 				It("returns an error", func() {
-					_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+					_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 					Expect(err).To(MatchError("some err"))
 				})
 			})
@@ -454,7 +462,7 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("returns the latest task", func() {
-				task, _ := deployRunner.GetTask(deploymentName, operationData, logger)
+				task, _ := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 				Expect(task).To(Equal(taskProcessing))
 			})
 		})
@@ -469,7 +477,7 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+				_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -480,9 +488,281 @@ var _ = Describe("Lifecycle runner", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := deployRunner.GetTask(deploymentName, operationData, logger)
+				_, err := deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
 				Expect(err).To(MatchError("some err"))
 			})
 		})
+
+		Context("when the deletion is forced", func() {
+			var task boshdirector.BoshTask
+
+			BeforeEach(func() {
+				operationData.Force = true
+				boshClient.ForceDeleteDeploymentReturns(taskProcessing.ID, nil)
+				boshClient.GetTaskReturns(taskProcessing, nil)
+			})
+
+			JustBeforeEach(func() {
+				task, _ = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
+			})
+
+			Context("and the errand has errored", func() {
+				BeforeEach(func() {
+					boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskErrored}, nil)
+				})
+
+				It("skips the errand and force deletes the deployment", func() {
+					Expect(boshClient.DeleteDeploymentCallCount()).To(Equal(0))
+					Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(1))
+					deletedDeploymentName, ctxID, _ := boshClient.ForceDeleteDeploymentArgsForCall(0)
+					Expect(deletedDeploymentName).To(Equal(deploymentName))
+					Expect(ctxID).To(Equal(contextID))
+					Expect(task).To(Equal(taskProcessing))
+				})
+
+				It("records the skipped errand for audit", func() {
+					Expect(logBuffer.String()).To(ContainSubstring(
+						"audit: forced deprovision of instance %s: pre-delete errand task %d finished in state error, skipping it",
+						deploymentName, taskErrored.ID,
+					))
+				})
+			})
+
+			Context("and the errand is still running", func() {
+				BeforeEach(func() {
+					boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskProcessing}, nil)
+				})
+
+				It("waits for the errand", func() {
+					Expect(boshClient.ForceDeleteDeploymentCallCount()).To(Equal(0))
+					Expect(task).To(Equal(taskProcessing))
+				})
+			})
+		})
+	})
+
+	Describe("chained errands", func() {
+		const (
+			previousPlanID = "previous-plan-id"
+			deployTaskID   = 42
+		)
+
+		completeTasks := func(count int) boshdirector.BoshTasks {
+			var tasks boshdirector.BoshTasks
+			for id := count; id > 0; id-- {
+				tasks = append(tasks, boshdirector.BoshTask{ID: id, State: boshdirector.TaskDone, ContextID: contextID})
+			}
+			return tasks
+		}
+
+		var (
+			task    boshdirector.BoshTask
+			taskErr error
+		)
+
+		BeforeEach(func() {
+			operationData = broker.OperationData{
+				BoshContextID:  contextID,
+				OperationType:  broker.OperationTypeUpdate,
+				PlanID:         planID,
+				PreviousPlanID: previousPlanID,
+				RequestParams:  map[string]interface{}{"parameters": map[string]interface{}{"foo": "bar"}},
+				PreErrands:     config.Errands{{Name: "backup"}},
+				PostErrands: config.Errands{
+					{Name: "smoke-tests"},
+					{Name: "register", Instances: []string{"proxy/0"}, ContinueOnFailure: true},
+				},
+			}
+			fakeDeployer.UpdateReturns(deployTaskID, nil, nil)
+			boshClient.RunErrandReturns(taskProcessing.ID, nil)
+			boshClient.GetTaskReturns(taskProcessing, nil)
+		})
+
+		JustBeforeEach(func() {
+			task, taskErr = deployRunner.GetTask(context.Background(), deploymentName, operationData, logger)
+		})
+
+		Context("when the first pre-deploy errand is running", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskProcessing}, nil)
+			})
+
+			It("waits for it before deploying", func() {
+				Expect(taskErr).NotTo(HaveOccurred())
+				Expect(task).To(Equal(taskProcessing))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the pre-deploy errands have finished", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(completeTasks(1), nil)
+			})
+
+			It("starts the deploy with the update's arguments", func() {
+				Expect(taskErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+				_, actualDeploymentName, actualPlanID, actualRequestParams, actualPreviousPlanID, actualContextID, _ := fakeDeployer.UpdateArgsForCall(0)
+				Expect(actualDeploymentName).To(Equal(deploymentName))
+				Expect(actualPlanID).To(Equal(planID))
+				Expect(actualRequestParams).To(Equal(operationData.RequestParams))
+				Expect(*actualPreviousPlanID).To(Equal(previousPlanID))
+				Expect(actualContextID).To(Equal(contextID))
+
+				actualTaskID, _ := boshClient.GetTaskArgsForCall(0)
+				Expect(actualTaskID).To(Equal(deployTaskID))
+				Expect(task).To(Equal(taskProcessing))
+			})
+
+			Context("and the deploy cannot be started", func() {
+				BeforeEach(func() {
+					fakeDeployer.UpdateReturns(0, nil, errors.New("adapter failed"))
+				})
+
+				It("returns the error", func() {
+					Expect(taskErr).To(MatchError("adapter failed"))
+				})
+			})
+
+			Context("and the operation is an upgrade", func() {
+				BeforeEach(func() {
+					operationData.OperationType = broker.OperationTypeUpgrade
+					fakeDeployer.UpgradeReturns(deployTaskID, nil, nil)
+				})
+
+				It("starts the upgrade on the instance's plan", func() {
+					Expect(fakeDeployer.UpgradeCallCount()).To(Equal(1))
+					_, _, actualPlanID, actualPreviousPlanID, actualContextID, _ := fakeDeployer.UpgradeArgsForCall(0)
+					Expect(actualPlanID).To(Equal(planID))
+					Expect(*actualPreviousPlanID).To(Equal(planID))
+					Expect(actualContextID).To(Equal(contextID))
+				})
+			})
+
+			Context("and the operation is a delete", func() {
+				BeforeEach(func() {
+					operationData = broker.OperationData{
+						BoshContextID: contextID,
+						OperationType: broker.OperationTypeDelete,
+						PreErrands:    config.Errands{{Name: "cleanup-resources"}},
+					}
+					boshClient.DeleteDeploymentReturns(deployTaskID, nil)
+				})
+
+				It("deletes the deployment", func() {
+					Expect(boshClient.DeleteDeploymentCallCount()).To(Equal(1))
+					actualDeploymentName, actualContextID, _ := boshClient.DeleteDeploymentArgsForCall(0)
+					Expect(actualDeploymentName).To(Equal(deploymentName))
+					Expect(actualContextID).To(Equal(contextID))
+				})
+			})
+
+			Context("and no deployer is available for the plan", func() {
+				BeforeEach(func() {
+					deployRunner = broker.NewLifeCycleRunner(boshClient, plans, nil, deploymentNames)
+				})
+
+				It("returns an error", func() {
+					Expect(taskErr).To(MatchError(ContainSubstring("plan with id %s not found", planID)))
+				})
+			})
+		})
+
+		Context("when a pre-deploy errand has failed", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{taskErrored}, nil)
+			})
+
+			It("fails the operation without deploying", func() {
+				Expect(task).To(Equal(taskErrored))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the deploy has finished", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(completeTasks(2), nil)
+			})
+
+			It("runs the first post-deploy errand", func() {
+				Expect(boshClient.RunErrandCallCount()).To(Equal(1))
+				actualDeploymentName, errandName, instances, actualContextID, _ := boshClient.RunErrandArgsForCall(0)
+				Expect(actualDeploymentName).To(Equal(deploymentName))
+				Expect(errandName).To(Equal("smoke-tests"))
+				Expect(instances).To(BeEmpty())
+				Expect(actualContextID).To(Equal(contextID))
+				Expect(task).To(Equal(taskProcessing))
+			})
+		})
+
+		Context("when a post-deploy errand has finished", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(completeTasks(3), nil)
+			})
+
+			It("runs the next one on its instances", func() {
+				Expect(boshClient.RunErrandCallCount()).To(Equal(1))
+				_, errandName, instances, _, _ := boshClient.RunErrandArgsForCall(0)
+				Expect(errandName).To(Equal("register"))
+				Expect(instances).To(Equal([]string{"proxy/0"}))
+			})
+		})
+
+		Context("when a post-deploy errand has failed", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(append(boshdirector.BoshTasks{taskErrored}, completeTasks(2)...), nil)
+			})
+
+			It("fails the operation without running the rest", func() {
+				Expect(task).To(Equal(taskErrored))
+				Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the last errand has failed but may continue on failure", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(append(boshdirector.BoshTasks{taskErrored}, completeTasks(3)...), nil)
+			})
+
+			It("completes the operation", func() {
+				Expect(taskErr).NotTo(HaveOccurred())
+				Expect(task.ID).To(Equal(taskErrored.ID))
+				Expect(task.State).To(Equal(boshdirector.TaskDone))
+				Expect(logBuffer.String()).To(ContainSubstring("errand register failed in task %d, continuing as configured", taskErrored.ID))
+			})
+		})
+
+		Context("when an errand is cancelled", func() {
+			BeforeEach(func() {
+				cancelled := boshdirector.BoshTask{ID: 9, State: boshdirector.TaskCancelled, ContextID: contextID}
+				boshClient.GetNormalisedTasksByContextReturns(append(boshdirector.BoshTasks{cancelled}, completeTasks(3)...), nil)
+			})
+
+			It("does not continue even if it may continue on failure", func() {
+				Expect(task.State).To(Equal(boshdirector.TaskCancelled))
+			})
+		})
+
+		Context("when every step has finished", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(completeTasks(4), nil)
+			})
+
+			It("returns the last task", func() {
+				Expect(task.ID).To(Equal(4))
+				Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when there are more tasks than steps", func() {
+			BeforeEach(func() {
+				boshClient.GetNormalisedTasksByContextReturns(completeTasks(5), nil)
+			})
+
+			It("returns an error", func() {
+				Expect(taskErr).To(MatchError(ContainSubstring("unexpected tasks found with context id: %s", contextID)))
+			})
+		})
 	})
 })
@@ -0,0 +1,184 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+)
+
+var _ = Describe("maintenance_info", func() {
+	const (
+		instanceID         = "some-instance-id"
+		maintenanceVersion = "1.2.0+abcdef"
+	)
+
+	var maintenanceVersionForBroker string
+
+	BeforeEach(func() {
+		maintenanceVersionForBroker = maintenanceVersion
+		serviceCatalog.MaintenanceInfo = &config.MaintenanceInfo{Version: "1.2.0", Description: "redis 4.0.11 on xenial 170.1"}
+	})
+
+	JustBeforeEach(func() {
+		b, brokerCreationErr = broker.New(
+			createBOSHInfoWithMajorVersion(boshdirector.MinimumMajorSemverDirectorVersionForLifecycleErrands, "semver"),
+			boshClient,
+			cfClient,
+			[]broker.ServiceOffering{{
+				Catalog:            serviceCatalog,
+				AdapterClient:      serviceAdapter,
+				Deployer:           fakeDeployer,
+				MaintenanceVersion: maintenanceVersionForBroker,
+			}},
+			fakeOperationStore,
+			fakeBindingStore,
+			fakeReservationStore,
+			fakeAuditLog,
+			"",
+			config.LastOperationDetails{},
+			0,
+			false,
+			operationLockTimeout,
+			loggerFactory,
+		)
+		Expect(brokerCreationErr).NotTo(HaveOccurred())
+	})
+
+	Describe("the catalog", func() {
+		It("advertises the maintenance_info on every plan", func() {
+			plans := b.Services(context.Background())[0].Plans
+			Expect(plans).To(HaveLen(len(serviceCatalog.Plans)))
+			for _, plan := range plans {
+				Expect(plan.MaintenanceInfo).To(Equal(&brokerapi.MaintenanceInfo{
+					Version:     maintenanceVersion,
+					Description: "redis 4.0.11 on xenial 170.1",
+				}))
+			}
+		})
+
+		Context("when the offering has no maintenance version", func() {
+			BeforeEach(func() {
+				maintenanceVersionForBroker = ""
+			})
+
+			It("does not advertise maintenance_info", func() {
+				for _, plan := range b.Services(context.Background())[0].Plans {
+					Expect(plan.MaintenanceInfo).To(BeNil())
+				}
+			})
+		})
+	})
+
+	Describe("updating an instance", func() {
+		var (
+			updateDetails brokerapi.UpdateDetails
+			updateSpec    brokerapi.UpdateServiceSpec
+			updateErr     error
+		)
+
+		BeforeEach(func() {
+			updateDetails = brokerapi.UpdateDetails{
+				PlanID:          existingPlanID,
+				ServiceID:       serviceOfferingID,
+				MaintenanceInfo: &brokerapi.MaintenanceInfo{Version: maintenanceVersion},
+				PreviousValues: brokerapi.PreviousValues{
+					PlanID:          existingPlanID,
+					ServiceID:       serviceOfferingID,
+					MaintenanceInfo: &brokerapi.MaintenanceInfo{Version: "1.1.0+123456"},
+				},
+			}
+			fakeDeployer.UpgradeReturns(42, []byte("upgraded-manifest"), nil)
+			fakeDeployer.UpdateReturns(43, []byte("updated-manifest"), nil)
+		})
+
+		JustBeforeEach(func() {
+			updateSpec, updateErr = b.Update(context.Background(), instanceID, updateDetails, true)
+		})
+
+		Context("when only the maintenance_info version changes", func() {
+			It("upgrades the instance", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+				Expect(fakeDeployer.UpgradeCallCount()).To(Equal(1))
+
+				_, actualDeploymentName, actualPlanID, actualPreviousPlanID, _, _ := fakeDeployer.UpgradeArgsForCall(0)
+				Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+				Expect(actualPlanID).To(Equal(existingPlanID))
+				Expect(*actualPreviousPlanID).To(Equal(existingPlanID))
+			})
+
+			It("returns upgrade operation data", func() {
+				Expect(updateSpec.IsAsync).To(BeTrue())
+				operationData := unmarshalOperationData(updateSpec)
+				Expect(operationData.BoshTaskID).To(Equal(42))
+				Expect(operationData.OperationType).To(Equal(broker.OperationTypeUpgrade))
+			})
+		})
+
+		Context("when the instance has never had maintenance_info", func() {
+			BeforeEach(func() {
+				updateDetails.PreviousValues.MaintenanceInfo = nil
+			})
+
+			It("upgrades the instance", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpgradeCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the maintenance_info version is unchanged", func() {
+			BeforeEach(func() {
+				updateDetails.PreviousValues.MaintenanceInfo = &brokerapi.MaintenanceInfo{Version: maintenanceVersion}
+				updateDetails.RawParameters = []byte(`{"foo":"bar"}`)
+			})
+
+			It("updates the instance as usual", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpgradeCallCount()).To(Equal(0))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+				Expect(unmarshalOperationData(updateSpec).OperationType).To(Equal(broker.OperationTypeUpdate))
+			})
+		})
+
+		Context("when the plan changes as well", func() {
+			BeforeEach(func() {
+				updateDetails.PreviousValues.PlanID = secondPlanID
+			})
+
+			It("updates the instance to the new plan", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpgradeCallCount()).To(Equal(0))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the maintenance_info version does not match the catalog", func() {
+			BeforeEach(func() {
+				updateDetails.MaintenanceInfo = &brokerapi.MaintenanceInfo{Version: "1.3.0+fedcba"}
+			})
+
+			It("rejects the update with 422", func() {
+				Expect(updateErr).To(Equal(brokerapi.NewFailureResponse(
+					errors.New(broker.MaintenanceInfoConflictMessage),
+					http.StatusUnprocessableEntity,
+					broker.UpdateLoggerAction,
+				)))
+				Expect(fakeDeployer.UpgradeCallCount()).To(Equal(0))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
@@ -0,0 +1,384 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var (
+	errQueuedOperationNotStarted = errors.New("queued operation has not started")
+	errWaitingForTaskSlot        = errors.New("waiting for a BOSH task slot")
+)
+
+// operationQueue holds the operations that wait for a BOSH task slot while
+// the broker has as many tasks in flight as it may start. It is only kept in
+// memory: operations queued before a restart rejoin it, in the order they
+// were first queued, when Cloud Foundry next polls them.
+//
+// Every task the broker starts takes a slot while it is being started, as
+// BOSH only counts it once it exists. generation changes whenever a slot is
+// released, so that a count of the tasks in flight taken before then is not
+// trusted.
+type operationQueue struct {
+	maxInFlight int
+	wake        chan struct{}
+
+	mutex       sync.Mutex
+	starting    int
+	generation  int
+	queued      []queuedOperation
+	dispatching map[string]bool
+	failed      map[string]error
+}
+
+type queuedOperation struct {
+	instanceID    string
+	operationData OperationData
+}
+
+func newOperationQueue(maxInFlight int) *operationQueue {
+	return &operationQueue{
+		maxInFlight: maxInFlight,
+		wake:        make(chan struct{}, 1),
+		dispatching: map[string]bool{},
+		failed:      map[string]error{},
+	}
+}
+
+// enqueue must be called with the mutex held.
+func (q *operationQueue) enqueue(instanceID string, operationData OperationData) int {
+	if position, found := q.position(operationData.BoshContextID); found {
+		return position
+	}
+
+	i := len(q.queued)
+	for i > 0 && q.queued[i-1].operationData.QueuedAt > operationData.QueuedAt {
+		i--
+	}
+	q.queued = append(q.queued, queuedOperation{})
+	copy(q.queued[i+1:], q.queued[i:])
+	q.queued[i] = queuedOperation{instanceID: instanceID, operationData: operationData}
+	return i + 1
+}
+
+func (q *operationQueue) position(contextID string) (int, bool) {
+	for i, queued := range q.queued {
+		if queued.operationData.BoshContextID == contextID {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func (q *operationQueue) release() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.starting--
+	q.generation++
+}
+
+// signal asks the dispatcher to look for a free slot without waiting for its
+// next tick.
+func (q *operationQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// takeTaskSlot takes a BOSH task slot for a task the broker is about to
+// start, unless the broker already has as many tasks in flight as it may
+// start. New operations also wait while others are queued before them; the
+// next steps of operations already under way, and the queued operations
+// themselves, do not. release frees the slot once the task has been started.
+func (b *Broker) takeTaskSlot(underWay bool, logger *log.Logger) (func(), bool, error) {
+	q := b.operationQueue
+	if q.maxInFlight == 0 {
+		return func() {}, true, nil
+	}
+
+	for {
+		q.mutex.Lock()
+		generation := q.generation
+		q.mutex.Unlock()
+
+		// BOSH is asked without holding the mutex, so that polls are not
+		// held up behind it
+		inFlight, err := b.inFlightTaskCount(logger)
+		if err != nil {
+			return nil, false, err
+		}
+
+		q.mutex.Lock()
+		if q.generation != generation {
+			// a task was started while counting and may not have been counted
+			q.mutex.Unlock()
+			continue
+		}
+		free := inFlight+q.starting < q.maxInFlight && (underWay || len(q.queued) == 0)
+		if free {
+			q.starting++
+		}
+		q.mutex.Unlock()
+
+		if !free {
+			return nil, false, nil
+		}
+		return q.release, true, nil
+	}
+}
+
+// queueIfThrottled queues the operation when the broker already has as many
+// BOSH tasks in flight as it may start, or when others are queued before it.
+// Otherwise the operation takes a slot, which release frees once its first
+// task has been started.
+func (b *Broker) queueIfThrottled(ctx context.Context, instanceID string, operationData OperationData, logger *log.Logger) (OperationData, bool, func(), error) {
+	release, free, err := b.takeTaskSlot(false, logger)
+	if err != nil {
+		return OperationData{}, false, nil, err
+	}
+	if free {
+		return operationData, false, release, nil
+	}
+
+	operationData.Queued = true
+	if operationData.BoshContextID == "" {
+		operationData.BoshContextID = uuid.New()
+	}
+	if operationData.QueuedAt == 0 {
+		operationData.QueuedAt = time.Now().UnixNano()
+	}
+	if operationData.RequestParams != nil {
+		if err := b.recordDeferredRequest(ctx, instanceID, operationData); err != nil {
+			return OperationData{}, false, nil, err
+		}
+	}
+
+	q := b.operationQueue
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	position := q.enqueue(instanceID, operationData)
+	q.signal()
+	logger.Printf("queued %s of instance %s at position %d, as %d BOSH tasks may be in flight\n", operationData.OperationType, instanceID, position, q.maxInFlight)
+
+	return operationData, true, nil, nil
+}
+
+// RunOperationQueue starts queued operations as BOSH task slots free up,
+// until stop is closed. Tasks finishing are only seen by counting them, so it
+// looks every interval, as well as whenever an operation is queued.
+func (b *Broker) RunOperationQueue(interval time.Duration, stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := b.loggerFactory.New()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-b.operationQueue.wake:
+		}
+
+		b.dispatchQueuedOperations(ctx, logger)
+	}
+}
+
+// dispatchQueuedOperations starts queued operations, in the order they were
+// queued, for as long as there are BOSH task slots free. An operation that
+// fails to start is remembered until its last operation reports it.
+func (b *Broker) dispatchQueuedOperations(ctx context.Context, logger *log.Logger) {
+	for {
+		next, release, found := b.takeQueuedOperation(logger)
+		if !found {
+			return
+		}
+		if !b.startQueuedOperation(ctx, next, release, logger) {
+			return
+		}
+	}
+}
+
+// takeQueuedOperation takes the first operation off the queue when there is
+// a BOSH task slot free for it. The slot is held until the operation has been
+// started.
+func (b *Broker) takeQueuedOperation(logger *log.Logger) (queuedOperation, func(), bool) {
+	q := b.operationQueue
+	q.mutex.Lock()
+	empty := len(q.queued) == 0
+	q.mutex.Unlock()
+	if empty {
+		return queuedOperation{}, nil, false
+	}
+
+	release, free, err := b.takeTaskSlot(true, logger)
+	if err != nil {
+		logger.Printf("error dispatching queued operations: %s\n", err)
+		return queuedOperation{}, nil, false
+	}
+	if !free {
+		return queuedOperation{}, nil, false
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.queued) == 0 {
+		release()
+		return queuedOperation{}, nil, false
+	}
+
+	next := q.queued[0]
+	q.queued = q.queued[1:]
+	q.dispatching[next.operationData.BoshContextID] = true
+	return next, release, true
+}
+
+// startQueuedOperation starts an operation taken off the queue while holding
+// the lock of its instance, on behalf of the request that queued it. It
+// reports whether the next queued operation may be started.
+func (b *Broker) startQueuedOperation(ctx context.Context, queued queuedOperation, release func(), logger *log.Logger) bool {
+	q := b.operationQueue
+	contextID := queued.operationData.BoshContextID
+	defer release()
+
+	if operation, found := b.recordedOperation(queued.instanceID, contextID, logger); found {
+		ctx = brokercontext.WithReqID(ctx, operation.RequestID)
+	}
+
+	unlock, locked := b.instanceLocker.Lock(ctx, queued.instanceID)
+	if !locked {
+		logger.Printf("leaving queued %s of instance %s in the queue, as another operation holds the instance lock\n", queued.operationData.OperationType, queued.instanceID)
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		delete(q.dispatching, contextID)
+		q.enqueue(queued.instanceID, queued.operationData)
+		return false
+	}
+	defer unlock()
+
+	logger.Printf("starting queued %s of instance %s\n", queued.operationData.OperationType, queued.instanceID)
+	err := b.startQueuedDeploymentStep(ctx, queued, logger)
+	if err != nil {
+		logger.Printf("error starting queued %s of instance %s: %s\n", queued.operationData.OperationType, queued.instanceID, err)
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	delete(q.dispatching, contextID)
+	if err != nil {
+		q.failed[contextID] = err
+	}
+	return true
+}
+
+func (b *Broker) startQueuedDeploymentStep(ctx context.Context, queued queuedOperation, logger *log.Logger) error {
+	if len(queued.operationData.PreErrands) > 0 {
+		_, err := b.runFirstPreErrand(queued.instanceID, queued.operationData, logger)
+		return err
+	}
+
+	_, err := b.lifeCycleRunner(queued.operationData.PlanID).startDeploymentStep(
+		ctx,
+		b.deploymentName(queued.instanceID),
+		queued.operationData,
+		logger,
+	)
+	return err
+}
+
+// queuedLastOperation describes an operation that is waiting in the queue,
+// that is being started or that failed to start when it left the queue. A
+// failure to start is only kept until it has been reported; the operation
+// store remembers it after that.
+func (b *Broker) queuedLastOperation(ctx context.Context, instanceID string, operationData OperationData, logger *log.Logger) (brokerapi.LastOperation, bool) {
+	q := b.operationQueue
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if err, found := q.failed[operationData.BoshContextID]; found {
+		delete(q.failed, operationData.BoshContextID)
+		logger.Printf("queued %s of instance %s failed to start: %s\n", operationData.OperationType, instanceID, err)
+		return brokerapi.LastOperation{
+			State:       brokerapi.Failed,
+			Description: descriptionForOperationTask(ctx, brokerapi.Failed, operationData, 0, ""),
+		}, true
+	}
+
+	if q.dispatching[operationData.BoshContextID] {
+		return brokerapi.LastOperation{
+			State:       brokerapi.InProgress,
+			Description: descriptions[brokerapi.InProgress][operationData.OperationType],
+		}, true
+	}
+
+	position, found := q.position(operationData.BoshContextID)
+	if !found {
+		return brokerapi.LastOperation{}, false
+	}
+	return queuedDescription(operationData, position), true
+}
+
+// requeue puts back a queued operation that had not started before the
+// broker restarted, unless the operation store shows that it already failed
+// to start.
+func (b *Broker) requeue(instanceID string, operationData OperationData, logger *log.Logger) brokerapi.LastOperation {
+	if operation, found := b.recordedOperation(instanceID, operationData.BoshContextID, logger); found && operation.State == operationstore.OperationFailed {
+		return brokerapi.LastOperation{State: brokerapi.Failed, Description: operation.Description}
+	}
+
+	q := b.operationQueue
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	position := q.enqueue(instanceID, operationData)
+	q.signal()
+	logger.Printf("requeued %s of instance %s at position %d\n", operationData.OperationType, instanceID, position)
+	return queuedDescription(operationData, position)
+}
+
+func queuedDescription(operationData OperationData, position int) brokerapi.LastOperation {
+	return brokerapi.LastOperation{
+		State: brokerapi.InProgress,
+		Description: fmt.Sprintf(
+			"%s: queued, position %d",
+			descriptions[brokerapi.InProgress][operationData.OperationType],
+			position,
+		),
+	}
+}
+
+// inFlightTaskCount counts the queued and running BOSH tasks of the
+// deployments of this broker.
+func (b *Broker) inFlightTaskCount(logger *log.Logger) (int, error) {
+	tasks, err := b.boshClient.GetInFlightTasks(logger)
+	if err != nil {
+		return 0, fmt.Errorf("error counting BOSH tasks in flight: %s", err)
+	}
+
+	count := 0
+	for _, task := range tasks {
+		if _, ours := b.deploymentNames.InstanceID(task.Deployment); ours {
+			count++
+		}
+	}
+	return count, nil
+}
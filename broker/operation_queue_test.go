@@ -0,0 +1,474 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/loggerfactory"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var _ = Describe("queueing operations", func() {
+	var (
+		stopQueue   chan struct{}
+		queueExited chan struct{}
+		queueLog    *gbytes.Buffer
+	)
+
+	var busyDirector = boshdirector.BoshTasks{
+		{ID: 1, State: boshdirector.TaskProcessing, Deployment: deploymentName("busy-instance-1")},
+		{ID: 2, State: boshdirector.TaskQueued, Deployment: deploymentName("busy-instance-2")},
+		{ID: 3, State: boshdirector.TaskProcessing, Deployment: "some-other-deployment"},
+	}
+
+	parseOperationData := func(operationDataJSON string) broker.OperationData {
+		var operationData broker.OperationData
+		Expect(json.Unmarshal([]byte(operationDataJSON), &operationData)).To(Succeed())
+		return operationData
+	}
+
+	provision := func(instanceID string) broker.OperationData {
+		spec, err := b.Provision(
+			context.Background(),
+			instanceID,
+			brokerapi.ProvisionDetails{
+				PlanID:        existingPlanID,
+				ServiceID:     serviceOfferingID,
+				RawParameters: []byte(`{"foo": "bar"}`),
+			},
+			true,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		return parseOperationData(spec.OperationData)
+	}
+
+	lastOperation := func(instanceID string, operationData broker.OperationData) brokerapi.LastOperation {
+		operationDataJSON, err := json.Marshal(operationData)
+		Expect(err).NotTo(HaveOccurred())
+		lastOperation, err := b.LastOperation(context.Background(), instanceID, string(operationDataJSON))
+		Expect(err).NotTo(HaveOccurred())
+		return lastOperation
+	}
+
+	BeforeEach(func() {
+		store, err := operationstore.NewFileStore("")
+		Expect(err).NotTo(HaveOccurred())
+		fakeOperationStore.StartOperationStub = store.StartOperation
+		fakeOperationStore.UpdateOperationStub = store.UpdateOperation
+		fakeOperationStore.GetInstanceStub = store.GetInstance
+
+		maxInFlightBoshTasks = 2
+		stopQueue = make(chan struct{})
+		queueExited = nil
+
+		// the queue logs from its own goroutine
+		queueLog = gbytes.NewBuffer()
+		loggerFactory = loggerfactory.New(io.MultiWriter(GinkgoWriter, queueLog), "broker-unit-tests", log.LstdFlags)
+		boshClient.GetDeploymentReturns(nil, false, nil)
+		fakeDeployer.CreateReturns(42, []byte("manifest"), nil)
+	})
+
+	runQueue := func(interval time.Duration) {
+		queueExited = make(chan struct{})
+		go func(b *broker.Broker) {
+			defer close(queueExited)
+			b.RunOperationQueue(interval, stopQueue)
+		}(b)
+	}
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	AfterEach(func() {
+		close(stopQueue)
+		if queueExited != nil {
+			Eventually(queueExited).Should(BeClosed())
+		}
+	})
+
+	Context("when there are BOSH task slots free", func() {
+		BeforeEach(func() {
+			boshClient.GetInFlightTasksReturns(busyDirector[1:], nil)
+		})
+
+		It("starts the operation straight away", func() {
+			operationData := provision("some-instance-id")
+
+			Expect(operationData.Queued).To(BeFalse())
+			Expect(operationData.BoshTaskID).To(Equal(42))
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+		})
+
+		It("does not count the tasks of other deployments", func() {
+			boshClient.GetInFlightTasksReturns(append(busyDirector[2:], busyDirector[2:]...), nil)
+
+			Expect(provision("some-instance-id").Queued).To(BeFalse())
+		})
+	})
+
+	Context("when the broker has as many BOSH tasks in flight as it may start", func() {
+		BeforeEach(func() {
+			boshClient.GetInFlightTasksReturns(busyDirector, nil)
+		})
+
+		It("queues provisions in the order they arrive", func() {
+			first := provision("first-instance-id")
+			second := provision("second-instance-id")
+
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			Expect(first.Queued).To(BeTrue())
+			Expect(first.BoshContextID).NotTo(BeEmpty())
+			Expect(first.RequestParams).To(BeNil())
+			Expect(first.PlanID).To(Equal(existingPlanID))
+
+			Expect(lastOperation("first-instance-id", first)).To(Equal(brokerapi.LastOperation{
+				State:       brokerapi.InProgress,
+				Description: "Instance provisioning in progress: queued, position 1",
+			}))
+			Expect(lastOperation("second-instance-id", second).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 2",
+			))
+		})
+
+		It("queues deprovisions", func() {
+			boshClient.GetDeploymentReturns([]byte("manifest"), true, nil)
+			cfClient.GetInstanceStateReturns(cf.InstanceState{PlanID: existingPlanID}, nil)
+
+			spec, err := b.Deprovision(
+				context.Background(),
+				"some-instance-id",
+				brokerapi.DeprovisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parseOperationData(spec.OperationData).Queued).To(BeTrue())
+			Expect(boshClient.DeleteDeploymentCallCount()).To(Equal(0))
+		})
+
+		Context("and a slot frees up", func() {
+			It("starts the first queued operation without waiting for a poll", func() {
+				first := provision("first-instance-id")
+				second := provision("second-instance-id")
+
+				boshClient.GetInFlightTasksStub = func(*log.Logger) (boshdirector.BoshTasks, error) {
+					if fakeDeployer.CreateCallCount() > 0 {
+						return busyDirector, nil
+					}
+					return busyDirector[1:], nil
+				}
+				runQueue(time.Millisecond)
+
+				Eventually(fakeDeployer.CreateCallCount).Should(Equal(1))
+				Consistently(fakeDeployer.CreateCallCount).Should(Equal(1))
+				_, actualDeploymentName, actualPlanID, actualRequestParams, actualContextID, _ := fakeDeployer.CreateArgsForCall(0)
+				Expect(actualDeploymentName).To(Equal(deploymentName("first-instance-id")))
+				Expect(actualPlanID).To(Equal(existingPlanID))
+				Expect(actualRequestParams).To(HaveKeyWithValue("parameters", map[string]interface{}{"foo": "bar"}))
+				Expect(actualContextID).To(Equal(first.BoshContextID))
+
+				Expect(lastOperation("second-instance-id", second).Description).To(Equal(
+					"Instance provisioning in progress: queued, position 1",
+				))
+
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{
+					{ID: 42, State: boshdirector.TaskQueued},
+				}, nil)
+				Expect(lastOperation("first-instance-id", first)).To(Equal(brokerapi.LastOperation{
+					State:       brokerapi.InProgress,
+					Description: "Instance provisioning in progress",
+				}))
+				actualDeploymentName, actualContextID, _ = boshClient.GetNormalisedTasksByContextArgsForCall(0)
+				Expect(actualDeploymentName).To(Equal(deploymentName("first-instance-id")))
+				Expect(actualContextID).To(Equal(first.BoshContextID))
+			})
+
+			It("does not start queued operations from a poll", func() {
+				provision("first-instance-id")
+
+				boshClient.GetInFlightTasksReturns(nil, nil)
+				boshClient.GetTaskReturns(boshdirector.BoshTask{ID: 7, State: boshdirector.TaskProcessing}, nil)
+				lastOperation("busy-instance-1", broker.OperationData{BoshTaskID: 7, OperationType: broker.OperationTypeUpdate})
+
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+
+			It("looks for a free slot as soon as a polled operation has finished", func() {
+				first := provision("first-instance-id")
+				runQueue(time.Hour)
+
+				boshClient.GetInFlightTasksReturns(busyDirector[1:], nil)
+				boshClient.GetTaskReturns(boshdirector.BoshTask{ID: 7, State: boshdirector.TaskDone}, nil)
+				lastOperation("busy-instance-1", broker.OperationData{BoshTaskID: 7, OperationType: broker.OperationTypeUpdate})
+
+				Eventually(fakeDeployer.CreateCallCount).Should(Equal(1))
+				_, _, _, _, actualContextID, _ := fakeDeployer.CreateArgsForCall(0)
+				Expect(actualContextID).To(Equal(first.BoshContextID))
+			})
+
+			It("reports operations that fail to start", func() {
+				first := provision("first-instance-id")
+
+				boshClient.GetInFlightTasksReturns(nil, nil)
+				fakeDeployer.CreateReturns(0, nil, errors.New("adapter is broken"))
+				runQueue(time.Millisecond)
+
+				Eventually(func() brokerapi.LastOperationState {
+					return lastOperation("first-instance-id", first).State
+				}).Should(Equal(brokerapi.Failed))
+				Expect(string(queueLog.Contents())).To(ContainSubstring("error starting queued create of instance first-instance-id: adapter is broken"))
+			})
+
+			It("keeps reporting an operation that failed to start without starting it again", func() {
+				first := provision("first-instance-id")
+
+				boshClient.GetInFlightTasksReturns(nil, nil)
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{}, nil)
+				fakeDeployer.CreateReturns(0, nil, errors.New("adapter is broken"))
+				runQueue(time.Millisecond)
+
+				Eventually(func() brokerapi.LastOperationState {
+					return lastOperation("first-instance-id", first).State
+				}).Should(Equal(brokerapi.Failed))
+				reported := lastOperation("first-instance-id", first)
+				Expect(reported.Description).To(HavePrefix("Instance provisioning failed: " + broker.GenericErrorPrefix))
+				Consistently(func() brokerapi.LastOperation {
+					return lastOperation("first-instance-id", first)
+				}).Should(Equal(reported))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			})
+
+			It("starts a queued operation holding the lock of its instance", func() {
+				first := provision("first-instance-id")
+
+				createStarted := make(chan struct{})
+				releaseCreate := make(chan struct{})
+				fakeDeployer.CreateStub = func(context.Context, string, string, map[string]interface{}, string, *log.Logger) (int, []byte, error) {
+					close(createStarted)
+					<-releaseCreate
+					return 42, []byte("manifest"), nil
+				}
+				boshClient.GetInFlightTasksReturns(nil, nil)
+				runQueue(time.Millisecond)
+				Eventually(createStarted).Should(BeClosed())
+
+				Expect(lastOperation("first-instance-id", first)).To(Equal(brokerapi.LastOperation{
+					State:       brokerapi.InProgress,
+					Description: "Instance provisioning in progress",
+				}))
+				_, err := b.Deprovision(context.Background(), "first-instance-id", brokerapi.DeprovisionDetails{}, true)
+				Expect(err).To(MatchError(broker.OperationInProgressMessage))
+
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{{ID: 42, State: boshdirector.TaskQueued}}, nil)
+				close(releaseCreate)
+				Eventually(func() brokerapi.LastOperation {
+					return lastOperation("first-instance-id", first)
+				}).Should(Equal(brokerapi.LastOperation{
+					State:       brokerapi.InProgress,
+					Description: "Instance provisioning in progress",
+				}))
+			})
+		})
+
+		It("requeues operations that were queued before a restart", func() {
+			queued := provision("first-instance-id")
+
+			b = createDefaultBroker()
+			boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{}, nil)
+
+			Expect(lastOperation("first-instance-id", queued).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 1",
+			))
+			Expect(lastOperation("first-instance-id", queued).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 1",
+			))
+		})
+
+		It("requeues operations that were queued before a restart in their original order", func() {
+			first := provision("first-instance-id")
+			second := provision("second-instance-id")
+
+			b = createDefaultBroker()
+			boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{}, nil)
+			third := provision("third-instance-id")
+
+			Expect(lastOperation("second-instance-id", second).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 1",
+			))
+			Expect(lastOperation("first-instance-id", first).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 1",
+			))
+			Expect(lastOperation("second-instance-id", second).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 2",
+			))
+			Expect(lastOperation("third-instance-id", third).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 3",
+			))
+		})
+
+		It("starts operations queued before a restart with the request from the operation store", func() {
+			queued := provision("first-instance-id")
+
+			b = createDefaultBroker()
+			boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{}, nil)
+			lastOperation("first-instance-id", queued)
+
+			boshClient.GetInFlightTasksReturns(nil, nil)
+			runQueue(time.Millisecond)
+
+			Eventually(fakeDeployer.CreateCallCount).Should(Equal(1))
+			_, _, _, actualRequestParams, _, _ := fakeDeployer.CreateArgsForCall(0)
+			Expect(actualRequestParams).To(HaveKeyWithValue("parameters", map[string]interface{}{"foo": "bar"}))
+		})
+
+		It("starts operations queued before a restart that removed the limit", func() {
+			queued := provision("first-instance-id")
+
+			maxInFlightBoshTasks = 0
+			b = createDefaultBroker()
+			runQueue(time.Hour)
+			boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{}, nil)
+			lastOperation("first-instance-id", queued)
+
+			Eventually(fakeDeployer.CreateCallCount).Should(Equal(1))
+			_, _, _, _, actualContextID, _ := fakeDeployer.CreateArgsForCall(0)
+			Expect(actualContextID).To(Equal(queued.BoshContextID))
+		})
+
+		Context("and an operation under way is due to start its next task", func() {
+			var operationData broker.OperationData
+
+			BeforeEach(func() {
+				operationData = broker.OperationData{
+					OperationType: broker.OperationTypeUpdate,
+					BoshTaskID:    5,
+					BoshContextID: "some-context-id",
+					PlanID:        existingPlanID,
+					PreErrands:    config.Errands{{Name: "backup"}},
+				}
+				boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{{ID: 5, State: boshdirector.TaskDone}}, nil)
+			})
+
+			It("waits for a slot", func() {
+				Expect(lastOperation("some-instance-id", operationData)).To(Equal(brokerapi.LastOperation{
+					State:       brokerapi.InProgress,
+					Description: "Instance update in progress: waiting for a BOSH task slot",
+				}))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("does not wait behind the queued operations once a slot is free", func() {
+				provision("queued-instance-id")
+				Expect(fakeOperationStore.StartOperation("some-instance-id", operationstore.Operation{
+					BoshTaskIDs:   []int{5},
+					BoshContextID: "some-context-id",
+					RequestParams: map[string]interface{}{"parameters": map[string]interface{}{}},
+				})).To(Succeed())
+				boshClient.GetInFlightTasksReturns(busyDirector[1:], nil)
+
+				lastOperation("some-instance-id", operationData)
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+			})
+		})
+
+		It("does not run errands from the management API", func() {
+			boshClient.GetDeploymentReturns([]byte("manifest"), true, nil)
+
+			_, err := b.RunErrand(context.Background(), "some-instance-id", config.Errand{Name: "smoke-tests"}, loggerFactory.New())
+
+			Expect(err).To(BeAssignableToTypeOf(broker.OperationInProgressError{}))
+			Expect(err).To(MatchError(ContainSubstring("waiting for a BOSH task slot")))
+			Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the request of a queued operation cannot be recorded", func() {
+		BeforeEach(func() {
+			boshClient.GetInFlightTasksReturns(busyDirector, nil)
+			fakeOperationStore.StartOperationReturns(errors.New("disk full"))
+			fakeOperationStore.StartOperationStub = nil
+		})
+
+		It("fails the operation without queueing it", func() {
+			_, err := b.Provision(
+				context.Background(),
+				"some-instance-id",
+				brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+
+			Expect(err).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+			Expect(string(queueLog.Contents())).To(ContainSubstring("disk full"))
+
+			fakeOperationStore.StartOperationReturns(nil)
+			next := provision("next-instance-id")
+			Expect(lastOperation("next-instance-id", next).Description).To(Equal(
+				"Instance provisioning in progress: queued, position 1",
+			))
+		})
+	})
+
+	Context("when the request of a queued operation was lost in a restart", func() {
+		BeforeEach(func() {
+			boshClient.GetInFlightTasksReturns(busyDirector, nil)
+		})
+
+		It("fails the operation instead of deploying without the request", func() {
+			queued := provision("first-instance-id")
+
+			store, err := operationstore.NewFileStore("")
+			Expect(err).NotTo(HaveOccurred())
+			fakeOperationStore.StartOperationStub = store.StartOperation
+			fakeOperationStore.UpdateOperationStub = store.UpdateOperation
+			fakeOperationStore.GetInstanceStub = store.GetInstance
+			b = createDefaultBroker()
+			boshClient.GetNormalisedTasksByContextReturns(boshdirector.BoshTasks{}, nil)
+			lastOperation("first-instance-id", queued)
+
+			boshClient.GetInFlightTasksReturns(nil, nil)
+			runQueue(time.Millisecond)
+			Eventually(func() brokerapi.LastOperationState {
+				return lastOperation("first-instance-id", queued).State
+			}).Should(Equal(brokerapi.Failed))
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the tasks in flight cannot be counted", func() {
+		BeforeEach(func() {
+			boshClient.GetInFlightTasksReturns(nil, errors.New("bosh is down"))
+		})
+
+		It("fails the operation", func() {
+			_, err := b.Provision(
+				context.Background(),
+				"some-instance-id",
+				brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+
+			Expect(err).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+			Expect(string(queueLog.Contents())).To(ContainSubstring("error counting BOSH tasks in flight: bosh is down"))
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+		})
+	})
+})
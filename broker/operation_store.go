@@ -0,0 +1,114 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var errDeferredRequestNotRecorded = errors.New("the request of the operation is not in the operation store")
+
+// The operation store is a record of what the broker has done; failing to
+// write to it is logged but never fails the operation itself, as the BOSH task
+// has already been started by then.
+
+func (b *Broker) recordOperationStarted(
+	ctx context.Context,
+	instanceID, planID string,
+	requestParams map[string]interface{},
+	operationData OperationData,
+	logger *log.Logger,
+) {
+	operation := operationRecord(ctx, planID, requestParams, operationData)
+	if err := b.operationStore.StartOperation(instanceID, operation); err != nil {
+		logger.Printf("error recording %s operation for instance %s: %s\n", operationData.OperationType, instanceID, err)
+	}
+}
+
+// recordDeferredRequest records an operation whose deploy starts after its
+// request has been accepted, before any of its tasks start. The deploy needs
+// the request from the operation store, so failing to record it fails the
+// request.
+func (b *Broker) recordDeferredRequest(ctx context.Context, instanceID string, operationData OperationData) error {
+	operation := operationRecord(ctx, operationData.PlanID, operationData.RequestParams, operationData)
+	if err := b.operationStore.StartOperation(instanceID, operation); err != nil {
+		return fmt.Errorf("error recording the request of %s operation for instance %s: %s", operationData.OperationType, instanceID, err)
+	}
+	return nil
+}
+
+func operationRecord(ctx context.Context, planID string, requestParams map[string]interface{}, operationData OperationData) operationstore.Operation {
+	return operationstore.Operation{
+		Type:          string(operationData.OperationType),
+		PlanID:        planID,
+		Parameters:    arbitraryParams(requestParams),
+		BoshTaskIDs:   []int{operationData.BoshTaskID},
+		BoshContextID: operationData.BoshContextID,
+		Forced:        operationData.Force,
+		RequestParams: operationData.RequestParams,
+		RequestID:     brokercontext.GetReqID(ctx),
+	}
+}
+
+func (b *Broker) recordOperationStatus(
+	instanceID string,
+	operationData OperationData,
+	boshTaskID int,
+	lastOperation brokerapi.LastOperation,
+	logger *log.Logger,
+) {
+	status := operationstore.OperationStatus{
+		BoshTaskID:  boshTaskID,
+		State:       operationstore.OperationState(lastOperation.State),
+		Description: lastOperation.Description,
+	}
+
+	if err := b.operationStore.UpdateOperation(instanceID, operationData.BoshTaskID, status); err != nil {
+		logger.Printf("error recording status of %s operation for instance %s: %s\n", operationData.OperationType, instanceID, err)
+	}
+}
+
+// deferredRequestParams finds the request of an operation whose deploy had
+// not started when it was accepted.
+func (b *Broker) deferredRequestParams(instanceID, boshContextID string, logger *log.Logger) map[string]interface{} {
+	operation, _ := b.recordedOperation(instanceID, boshContextID, logger)
+	return operation.RequestParams
+}
+
+func (b *Broker) recordedOperation(instanceID, boshContextID string, logger *log.Logger) (operationstore.Operation, bool) {
+	if boshContextID == "" {
+		return operationstore.Operation{}, false
+	}
+
+	instance, found, err := b.operationStore.GetInstance(instanceID)
+	if err != nil {
+		logger.Printf("error reading operations of instance %s: %s\n", instanceID, err)
+		return operationstore.Operation{}, false
+	}
+	if !found {
+		return operationstore.Operation{}, false
+	}
+
+	for i := len(instance.Operations) - 1; i >= 0; i-- {
+		if instance.Operations[i].BoshContextID == boshContextID {
+			return instance.Operations[i], true
+		}
+	}
+	return operationstore.Operation{}, false
+}
+
+func arbitraryParams(requestParams map[string]interface{}) map[string]interface{} {
+	params, _ := requestParams["parameters"].(map[string]interface{})
+	return params
+}
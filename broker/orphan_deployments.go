@@ -8,7 +8,6 @@ package broker
 
 import (
 	"log"
-	"strings"
 )
 
 func (b *Broker) OrphanDeployments(logger *log.Logger) ([]string, error) {
@@ -31,11 +30,12 @@ func (b *Broker) OrphanDeployments(logger *log.Logger) ([]string, error) {
 
 	var orphanDeploymentNames []string
 	for _, deployment := range deployments {
-		if !strings.HasPrefix(deployment.Name, InstancePrefix) {
+		instanceID, ours := b.deploymentNames.InstanceID(deployment.Name)
+		if !ours {
 			continue
 		}
 
-		if !instanceIDs[instanceID(deployment.Name)] {
+		if !instanceIDs[instanceID] {
 			orphanDeploymentNames = append(orphanDeploymentNames, deployment.Name)
 		}
 	}
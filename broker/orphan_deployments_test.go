@@ -13,6 +13,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
 )
 
 var _ = Describe("Orphan Deployments", func() {
@@ -92,6 +93,34 @@ var _ = Describe("Orphan Deployments", func() {
 		})
 	})
 
+	Context("when deployments are named by a template", func() {
+		BeforeEach(func() {
+			deploymentNameTemplate = "redis-{instance_id}-staging"
+			cfClient.GetInstancesOfServiceOfferingReturns([]string{"one", "four"}, nil)
+			fakeOperationStore.GetInstanceStub = func(instanceID string) (operationstore.Instance, bool, error) {
+				return operationstore.Instance{ID: instanceID}, instanceID == "two", nil
+			}
+			deployments := []boshdirector.Deployment{
+				{Name: "service-instance_one"},
+				{Name: "service-instance_two"},
+				{Name: "service-instance_another-brokers-instance"},
+				{Name: "redis-three-staging"},
+				{Name: "redis-four-staging"},
+				{Name: "redis-five-production"},
+			}
+			boshClient.GetDeploymentsReturns(deployments, nil)
+		})
+
+		It("returns the orphans with templated and legacy names", func() {
+			Expect(orphanDeploymentsErr).NotTo(HaveOccurred())
+			Expect(orphans).To(ConsistOf("service-instance_two", "redis-three-staging"))
+		})
+
+		It("does not claim legacy deployments of instances it has no record of", func() {
+			Expect(orphans).NotTo(ContainElement("service-instance_another-brokers-instance"))
+		})
+	})
+
 	Context("when the getting the list of instances fails", func() {
 		BeforeEach(func() {
 			cfClient.GetInstancesOfServiceOfferingReturns([]string{}, errors.New("error listing instances: listing error"))
@@ -0,0 +1,63 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+)
+
+const PlanTransitionLoggerAction = "plan-transition"
+
+// validatePlanTransition checks a plan change against the transitions the
+// previous plan allows. The confirmation parameter is removed from the
+// request, so it reaches neither the plan schemas nor the adapter.
+func validatePlanTransition(
+	offering ServiceOffering,
+	previousPlanID string,
+	plan config.Plan,
+	requestParams map[string]interface{},
+) (config.PlanTransition, DisplayableError) {
+	previousPlan, found := offering.Catalog.FindPlanByID(previousPlanID)
+	if !found {
+		return config.PlanTransition{PlanID: plan.ID}, NilError
+	}
+
+	transition, allowed := previousPlan.TransitionTo(plan.ID)
+	if !allowed {
+		return config.PlanTransition{}, NewDisplayableError(
+			brokerapi.NewFailureResponse(
+				fmt.Errorf(PlanTransitionNotAllowedMessage, previousPlan.Name, plan.Name),
+				http.StatusUnprocessableEntity,
+				PlanTransitionLoggerAction,
+			),
+			fmt.Errorf("plan %s does not allow transitions to plan %s", previousPlan.ID, plan.ID),
+		)
+	}
+
+	if transition.ConfirmationParameter == "" {
+		return transition, NilError
+	}
+
+	parameters, _ := requestParams["parameters"].(map[string]interface{})
+	if confirmed, _ := parameters[transition.ConfirmationParameter].(bool); !confirmed {
+		return config.PlanTransition{}, NewDisplayableError(
+			brokerapi.NewFailureResponse(
+				fmt.Errorf(PlanTransitionUnconfirmedMessage, previousPlan.Name, plan.Name, transition.ConfirmationParameter),
+				http.StatusUnprocessableEntity,
+				PlanTransitionLoggerAction,
+			),
+			fmt.Errorf("transition from plan %s to plan %s was not confirmed with parameter %s", previousPlan.ID, plan.ID, transition.ConfirmationParameter),
+		)
+	}
+	delete(parameters, transition.ConfirmationParameter)
+
+	return transition, NilError
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+)
+
+var _ = Describe("plan transitions", func() {
+	const (
+		instanceID       = "some-instance-id"
+		secondPlanName   = "second-plan"
+		migrationTaskID  = 55
+		confirmationFlag = "confirm_data_loss"
+	)
+
+	var (
+		rawParameters []byte
+		updateSpec    brokerapi.UpdateServiceSpec
+		updateErr     error
+	)
+
+	BeforeEach(func() {
+		serviceCatalog.Plans[1].Name = secondPlanName
+		rawParameters = nil
+		boshClient.RunErrandReturns(migrationTaskID, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+		updateSpec, updateErr = b.Update(
+			context.Background(),
+			instanceID,
+			brokerapi.UpdateDetails{
+				PlanID:         secondPlanID,
+				ServiceID:      serviceOfferingID,
+				PreviousValues: brokerapi.PreviousValues{PlanID: existingPlanID},
+				RawParameters:  rawParameters,
+			},
+			true,
+		)
+	})
+
+	Context("when the previous plan does not restrict transitions", func() {
+		It("changes the plan", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the previous plan does not allow the transition", func() {
+		BeforeEach(func() {
+			serviceCatalog.Plans[0].PlanTransitions = &config.PlanTransitions{
+				AllowedTargets: []config.PlanTransition{{PlanID: postDeployErrandPlanID}},
+			}
+		})
+
+		It("rejects the update with 422 before deploying", func() {
+			Expect(updateErr).To(Equal(brokerapi.NewFailureResponse(
+				fmt.Errorf(broker.PlanTransitionNotAllowedMessage, existingPlanName, secondPlanName),
+				http.StatusUnprocessableEntity,
+				broker.PlanTransitionLoggerAction,
+			)))
+			Expect(logBuffer.String()).To(ContainSubstring("plan %s does not allow transitions to plan %s", existingPlanID, secondPlanID))
+			Expect(fakeReservationStore.ReserveCallCount()).To(Equal(0))
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the transition must be confirmed", func() {
+		BeforeEach(func() {
+			serviceCatalog.Plans[0].PlanTransitions = &config.PlanTransitions{
+				AllowedTargets: []config.PlanTransition{{PlanID: secondPlanID, ConfirmationParameter: confirmationFlag}},
+			}
+		})
+
+		It("rejects the update without the confirmation", func() {
+			Expect(updateErr).To(Equal(brokerapi.NewFailureResponse(
+				fmt.Errorf(broker.PlanTransitionUnconfirmedMessage, existingPlanName, secondPlanName, confirmationFlag),
+				http.StatusUnprocessableEntity,
+				broker.PlanTransitionLoggerAction,
+			)))
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+		})
+
+		Context("and the confirmation is not true", func() {
+			BeforeEach(func() {
+				rawParameters = []byte(`{"confirm_data_loss": "yes"}`)
+			})
+
+			It("rejects the update", func() {
+				Expect(updateErr).To(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("and the update is confirmed", func() {
+			BeforeEach(func() {
+				rawParameters = []byte(`{"confirm_data_loss": true, "maxclients": 10}`)
+			})
+
+			It("changes the plan without passing on the confirmation", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+
+				_, _, planID, requestParams, _, _, _ := fakeDeployer.UpdateArgsForCall(0)
+				Expect(planID).To(Equal(secondPlanID))
+				Expect(requestParams["parameters"]).To(Equal(map[string]interface{}{"maxclients": float64(10)}))
+			})
+		})
+	})
+
+	Context("when the transition runs an errand", func() {
+		BeforeEach(func() {
+			serviceCatalog.Plans[0].PlanTransitions = &config.PlanTransitions{
+				AllowedTargets: []config.PlanTransition{{PlanID: secondPlanID, Errands: config.Errands{{Name: "shrink-cluster"}}}},
+			}
+		})
+
+		It("runs the errand before deploying", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+
+			Expect(boshClient.RunErrandCallCount()).To(Equal(1))
+			actualDeploymentName, errandName, _, _, _ := boshClient.RunErrandArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+			Expect(errandName).To(Equal("shrink-cluster"))
+
+			operationData := unmarshalOperationData(updateSpec)
+			Expect(operationData.BoshTaskID).To(Equal(migrationTaskID))
+			Expect(operationData.PreErrands).To(Equal(config.Errands{{Name: "shrink-cluster"}}))
+			Expect(operationData.PreviousPlanID).To(Equal(existingPlanID))
+		})
+	})
+})
@@ -22,23 +22,31 @@ import (
 func (b *Broker) Provision(ctx context.Context, instanceID string, details brokerapi.ProvisionDetails,
 	asyncAllowed bool) (brokerapi.ProvisionedServiceSpec, error) {
 
-	b.deploymentLock.Lock()
-	defer b.deploymentLock.Unlock()
-
 	requestID := uuid.New()
-	ctx = brokercontext.New(ctx, string(OperationTypeCreate), requestID, b.serviceOffering.Name, instanceID)
+	ctx = brokercontext.New(ctx, string(OperationTypeCreate), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
 	logger := b.loggerFactory.NewWithContext(ctx)
 
 	if !asyncAllowed {
 		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrAsyncRequired
 	}
 
+	unlock, locked := b.instanceLocker.Lock(ctx, instanceID)
+	if !locked {
+		err := newInstanceLockedError(instanceID)
+		logger.Println(err)
+		return brokerapi.ProvisionedServiceSpec{}, err.ErrorForCFUser()
+	}
+	defer unlock()
+
 	detailsWithRawParameters := brokerapi.DetailsWithRawParameters(details)
 	requestParams, err := convertDetailsToMap(detailsWithRawParameters)
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
+	ctx = brokercontext.WithTenant(ctx, brokercontext.TenantFromRequestParams(requestParams))
+	logger = b.loggerFactory.NewWithContext(ctx)
+
 	operationData, dashboardURL, err := b.provisionInstance(
 		ctx,
 		instanceID,
@@ -62,6 +70,8 @@ func (b *Broker) Provision(ctx context.Context, instanceID string, details broke
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
+	b.recordOperationStarted(ctx, instanceID, details.PlanID, requestParams, operationData, logger)
+
 	return brokerapi.ProvisionedServiceSpec{
 		IsAsync:       true,
 		DashboardURL:  dashboardURL,
@@ -76,7 +86,7 @@ func (b *Broker) provisionInstance(ctx context.Context, instanceID string, planI
 		return OperationData{}, "", err
 	}
 
-	plan, found := b.serviceOffering.FindPlanByID(planID)
+	offering, plan, found := b.offeringForPlan(planID)
 	if !found {
 		return errs(NewDisplayableError(
 			fmt.Errorf("plan %s not found", planID),
@@ -84,7 +94,11 @@ func (b *Broker) provisionInstance(ctx context.Context, instanceID string, planI
 		))
 	}
 
-	_, found, err := b.boshClient.GetDeployment(deploymentName(instanceID), logger)
+	if err := validateParameters(b.planSchemas[plan.ID].instanceCreate, requestParams); err != NilError {
+		return errs(err)
+	}
+
+	_, found, err := b.boshClient.GetDeployment(b.deploymentName(instanceID), logger)
 	switch err := err.(type) {
 	case boshdirector.RequestError:
 		return errs(NewBoshRequestError("create", fmt.Errorf("could not get manifest: %s", err)))
@@ -99,44 +113,50 @@ func (b *Broker) provisionInstance(ctx context.Context, instanceID string, planI
 		))
 	}
 
-	var planCounts map[string]int
-	if b.serviceOffering.GlobalQuotas.ServiceInstanceLimit != nil {
-		var displayableError DisplayableError
-		planCounts, displayableError = b.checkGlobalQuota(ctx, b.serviceOffering.ID, logger)
-		if displayableError.Occurred() {
-			return errs(displayableError)
-		}
+	if err := b.reserveQuota(ctx, instanceID, offering, plan, "", logger); err != NilError {
+		return errs(err)
 	}
 
-	if plan.Quotas.ServiceInstanceLimit != nil {
-		limit := *plan.Quotas.ServiceInstanceLimit
-		planCount, displayableError := b.getPlanCount(ctx, planID, planCounts, logger)
-		if displayableError.Occurred() {
-			return errs(displayableError)
-		}
+	// there is no deployment yet for pre-deploy errands to run on
+	postErrands := plan.PostDeployErrands()
+	var boshContextID string
+	if len(postErrands) > 0 {
+		boshContextID = uuid.New()
+	}
 
-		if planCount >= limit {
-			return errs(NewDisplayableError(
-				brokerapi.ErrPlanQuotaExceeded,
-				fmt.Errorf("plan quota exceeded for plan ID %s", planID),
-			))
-		}
+	queuedOperationData, queued, release, err := b.queueIfThrottled(ctx, instanceID, OperationData{
+		OperationType: OperationTypeCreate,
+		PlanID:        plan.ID,
+		RequestParams: requestParams,
+		PostErrands:   postErrands,
+		BoshContextID: boshContextID,
+	}, logger)
+	if err != nil {
+		b.releaseQuota(instanceID, logger)
+		return errs(NewGenericError(ctx, err))
+	}
+	if queued {
+		// the dashboard URL is unknown until the manifest is generated
+		return queuedOperationData, "", DisplayableError{}
 	}
+	defer release()
 
-	var boshContextID string
-	var operationPostDeployErrand string
-	if plan.PostDeployErrand() != "" {
-		boshContextID = uuid.New()
-		operationPostDeployErrand = plan.PostDeployErrand()
+	boshTaskID, manifest, err := offering.Deployer.Create(ctx, b.deploymentName(instanceID), plan.ID, requestParams, boshContextID, logger)
+	if err != nil {
+		b.releaseQuota(instanceID, logger)
 	}
 
-	boshTaskID, manifest, err := b.deployer.Create(deploymentName(instanceID), plan.ID, requestParams, boshContextID, logger)
 	switch err := err.(type) {
 	case boshdirector.RequestError:
 		return errs(NewBoshRequestError("create", err))
 	case DisplayableError:
 		return errs(err)
 	case serviceadapter.UnknownFailureError:
+		if err.Structured() {
+			return errs(NewAdapterError(ctx, err))
+		}
+		return errs(adapterToAPIError(ctx, err))
+	case serviceadapter.TimeoutError:
 		return errs(adapterToAPIError(ctx, err))
 	case error:
 		return errs(NewGenericError(ctx, err))
@@ -144,18 +164,18 @@ func (b *Broker) provisionInstance(ctx context.Context, instanceID string, planI
 
 	ctx = brokercontext.WithBoshTaskID(ctx, boshTaskID)
 
-	abridgedPlan := plan.AdapterPlan(b.serviceOffering.GlobalProperties)
+	abridgedPlan := plan.AdapterPlan(offering.Catalog.GlobalProperties)
 
-	dashboardUrl, err := b.adapterClient.GenerateDashboardUrl(instanceID, abridgedPlan, manifest, logger)
+	dashboardUrl, err := offering.AdapterClient.GenerateDashboardUrl(ctx, instanceID, abridgedPlan, manifest, logger)
 	if err != nil {
 		logger.Printf("generating dashboard: %v\n", err)
 	}
 
 	operationData := OperationData{
-		BoshTaskID:           boshTaskID,
-		OperationType:        OperationTypeCreate,
-		BoshContextID:        boshContextID,
-		PostDeployErrandName: operationPostDeployErrand,
+		BoshTaskID:    boshTaskID,
+		OperationType: OperationTypeCreate,
+		BoshContextID: boshContextID,
+		PostErrands:   postErrands,
 	}
 
 	//Dashboard url optional
@@ -164,50 +184,10 @@ func (b *Broker) provisionInstance(ctx context.Context, instanceID string, planI
 	}
 
 	if err := adapterToAPIError(ctx, err); err != nil {
+		// Cloud Foundry records no instance when provisioning fails
+		b.releaseQuota(instanceID, logger)
 		return operationData, dashboardUrl, err
 	}
 
 	return operationData, dashboardUrl, DisplayableError{}
 }
-
-func (b *Broker) getPlanCount(ctx context.Context, planID string, planCounts map[string]int, logger *log.Logger) (int, DisplayableError) {
-	var planCount int
-
-	if planCounts != nil {
-		planCount = planCounts[planID]
-	} else {
-		var countErr error
-		planCount, countErr = b.cfClient.CountInstancesOfPlan(b.serviceOffering.ID, planID, logger)
-		if countErr != nil {
-			return 0, NewGenericError(ctx, fmt.Errorf("could not count instances of plan: %s", countErr))
-		}
-	}
-
-	return planCount, DisplayableError{}
-}
-
-func (b *Broker) checkGlobalQuota(
-	ctx context.Context,
-	serviceOfferingID string,
-	logger *log.Logger,
-) (map[string]int, DisplayableError) {
-
-	planCounts, err := b.cfClient.CountInstancesOfServiceOffering(serviceOfferingID, logger)
-	if err != nil {
-		return nil, NewGenericError(ctx, err)
-	}
-
-	var totalServiceInstances = 0
-	for _, count := range planCounts {
-		totalServiceInstances += count
-	}
-
-	if b.serviceOffering.GlobalQuotas.ServiceInstanceLimit != nil && totalServiceInstances >= *b.serviceOffering.GlobalQuotas.ServiceInstanceLimit {
-		return nil, NewDisplayableError(
-			brokerapi.ErrServiceQuotaExceeded,
-			fmt.Errorf("service quota exceeded for service ID %s", b.serviceOffering.ID),
-		)
-	}
-
-	return nil, DisplayableError{}
-}
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,6 +21,7 @@ import (
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
 	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
@@ -87,7 +89,7 @@ var _ = Describe("provisioning", func() {
 
 		It("invokes the deployer", func() {
 			Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
-			actualDeploymentName, actualPlan, actualRequestParams, actualBoshContextID, _ := fakeDeployer.CreateArgsForCall(0)
+			_, actualDeploymentName, actualPlan, actualRequestParams, actualBoshContextID, _ := fakeDeployer.CreateArgsForCall(0)
 			Expect(actualRequestParams).To(Equal(map[string]interface{}{
 				"plan_id":           planID,
 				"parameters":        arbParams,
@@ -100,6 +102,11 @@ var _ = Describe("provisioning", func() {
 			Expect(actualBoshContextID).To(BeEmpty())
 		})
 
+		It("gives the deployer a logger that includes the organization and space", func() {
+			_, _, _, _, _, actualLogger := fakeDeployer.CreateArgsForCall(0)
+			Expect(actualLogger.Prefix()).To(HaveSuffix("[organization_guid=a-cf-org space_guid=a-cf-space] "))
+		})
+
 		It("returns operation data with bosh task ID and operation type", func() {
 			var operationData broker.OperationData
 			Expect(json.Unmarshal([]byte(serviceSpec.OperationData), &operationData)).To(Succeed())
@@ -107,6 +114,34 @@ var _ = Describe("provisioning", func() {
 			Expect(operationData.OperationType).To(Equal(broker.OperationTypeCreate))
 		})
 
+		It("records the operation in the operation store", func() {
+			Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(1))
+			actualInstanceID, operation := fakeOperationStore.StartOperationArgsForCall(0)
+			Expect(actualInstanceID).To(Equal(instanceID))
+			Expect(operation.RequestID).NotTo(BeEmpty())
+			operation.RequestID = ""
+			Expect(operation).To(Equal(operationstore.Operation{
+				Type:        "create",
+				PlanID:      planID,
+				Parameters:  arbParams,
+				BoshTaskIDs: []int{deployTaskID},
+			}))
+		})
+
+		Context("and the operation cannot be recorded", func() {
+			BeforeEach(func() {
+				fakeOperationStore.StartOperationReturns(errors.New("disk full"))
+			})
+
+			It("does not error", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+			})
+
+			It("logs the error", func() {
+				Expect(logBuffer.String()).To(ContainSubstring("error recording create operation for instance some-instance-id: disk full"))
+			})
+		})
+
 		It("return operation data without add bosh context ID or plan ID", func() {
 			var operationData broker.OperationData
 			Expect(json.Unmarshal([]byte(serviceSpec.OperationData), &operationData)).To(Succeed())
@@ -116,7 +151,7 @@ var _ = Describe("provisioning", func() {
 
 		It("invokes the adapter for the dashboard url, merging global and plan properties", func() {
 			Expect(serviceAdapter.GenerateDashboardUrlCallCount()).To(Equal(1))
-			instanceID, plan, boshManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
+			_, instanceID, plan, boshManifest, _ := serviceAdapter.GenerateDashboardUrlArgsForCall(0)
 			Expect(instanceID).To(Equal(instanceID))
 			expectedProperties := sdk.Properties{"super": "no", "a_global_property": "global_value", "some_other_global_property": "other_global_value"}
 			Expect(plan).To(Equal(sdk.Plan{
@@ -234,7 +269,7 @@ var _ = Describe("provisioning", func() {
 			postDeployErrandPlan := config.Plan{
 				ID: planID,
 				LifecycleErrands: &config.LifecycleErrands{
-					PostDeploy: errandName,
+					PostDeploy: config.Errands{{Name: errandName}},
 				},
 				InstanceGroups: []sdk.InstanceGroup{
 					{
@@ -260,12 +295,12 @@ var _ = Describe("provisioning", func() {
 			err := json.Unmarshal([]byte(serviceSpec.OperationData), &data)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(data.BoshContextID).NotTo(BeEmpty())
-			Expect(data.PostDeployErrandName).To(Equal(errandName))
+			Expect(data.PostErrands).To(Equal(config.Errands{{Name: errandName}}))
 		})
 
 		It("calls the deployer with a bosh context id", func() {
 			Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
-			_, _, _, actualBoshContextID, _ := fakeDeployer.CreateArgsForCall(0)
+			_, _, _, _, actualBoshContextID, _ := fakeDeployer.CreateArgsForCall(0)
 			Expect(actualBoshContextID).NotTo(BeEmpty())
 		})
 
@@ -295,10 +330,10 @@ var _ = Describe("provisioning", func() {
 
 			It("calls the deployer with a different bosh context id", func() {
 				Expect(fakeDeployer.CreateCallCount()).To(Equal(2))
-				_, _, _, firstBoshContextID, _ := fakeDeployer.CreateArgsForCall(0)
+				_, _, _, _, firstBoshContextID, _ := fakeDeployer.CreateArgsForCall(0)
 				Expect(firstBoshContextID).NotTo(BeNil())
 
-				_, _, _, secondBoshContextID, _ := fakeDeployer.CreateArgsForCall(1)
+				_, _, _, _, secondBoshContextID, _ := fakeDeployer.CreateArgsForCall(1)
 				Expect(secondBoshContextID).NotTo(Equal(firstBoshContextID))
 			})
 		})
@@ -324,7 +359,7 @@ var _ = Describe("provisioning", func() {
 		})
 
 		It("no arbitrary params are passed to the adapter", func() {
-			_, _, actualRequestParams, _, _ := fakeDeployer.CreateArgsForCall(0)
+			_, _, _, actualRequestParams, _, _ := fakeDeployer.CreateArgsForCall(0)
 			Expect(actualRequestParams["parameters"]).To(BeNil())
 		})
 
@@ -453,6 +488,51 @@ var _ = Describe("provisioning", func() {
 		})
 	})
 
+	Context("when the deploy returns a structured adapter error", func() {
+		BeforeEach(func() {
+			fakeDeployer.CreateReturns(0, nil, serviceadapter.ErrorForExitCode(
+				sdk.ErrorExitCode,
+				`{"user_message": "the service is unavailable", "operator_message": "credhub at 10.0.0.5 refused the connection", "error_code": "internal"}`,
+			))
+		})
+
+		It("returns the user message with an internal error status", func() {
+			Expect(provisionErr).To(Equal(brokerapi.NewFailureResponse(
+				errors.New("the service is unavailable"),
+				http.StatusInternalServerError,
+				broker.AdapterErrorLoggerAction,
+			)))
+		})
+
+		It("logs the operator message", func() {
+			Expect(logBuffer.String()).To(ContainSubstring(`service adapter failed with error code "internal": credhub at 10.0.0.5 refused the connection`))
+		})
+	})
+
+	Context("when the deploy returns a structured adapter error without a user message", func() {
+		BeforeEach(func() {
+			fakeDeployer.CreateReturns(0, nil, serviceadapter.ErrorForExitCode(
+				sdk.ErrorExitCode,
+				`{"operator_message": "credhub at 10.0.0.5 refused the connection"}`,
+			))
+		})
+
+		It("returns a generic error", func() {
+			Expect(provisionErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+			Expect(provisionErr).NotTo(MatchError(ContainSubstring("credhub")))
+		})
+	})
+
+	Context("when the service adapter times out generating the manifest", func() {
+		BeforeEach(func() {
+			fakeDeployer.CreateReturns(0, nil, serviceadapter.NewTimeoutError("stopped after running generate-manifest for longer than 5m0s"))
+		})
+
+		It("tells the user the service took too long", func() {
+			Expect(provisionErr).To(MatchError(broker.ServiceAdapterTimeoutMessage))
+		})
+	})
+
 	Context("when a provision of an already provisioned instance is triggered", func() {
 		BeforeEach(func() {
 			boshClient.GetDeploymentReturns([]byte(`manifest: true`), true, nil)
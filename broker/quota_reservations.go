@@ -0,0 +1,278 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/reservationstore"
+)
+
+// Cloud Foundry only counts an instance once it has recorded it, and only
+// moves it to a new plan once the update has succeeded. Until then the
+// instance holds a reservation that counts against the quotas of its plan.
+// Quotas are checked again and reserved under quotaMutex so that concurrent
+// requests cannot all pass the same check. Cloud Foundry is asked before
+// then, so that a slow Cloud Controller does not hold up every other quota
+// check; quotaReleases changes whenever a reservation is released, so that
+// an instance that was not counted in time is not missed.
+
+// Reservations the broker never hears back about, for example because Cloud
+// Foundry gave up on the request, stop counting after this long.
+const quotaReservationTimeout = time.Hour
+
+type quotaReservations []reservationstore.Reservation
+
+func (r quotaReservations) count(match func(reservationstore.Reservation) bool) int {
+	count := 0
+	for _, reservation := range r {
+		if match(reservation) {
+			count++
+		}
+	}
+	return count
+}
+
+// ofService leaves out plan changes, whose instances Cloud Foundry already
+// counts.
+func (r quotaReservations) ofService(serviceID string) int {
+	return r.count(func(reservation reservationstore.Reservation) bool {
+		return reservation.ServiceID == serviceID && !reservation.IsPlanChange()
+	})
+}
+
+func (r quotaReservations) ofPlan(planID string) int {
+	return r.count(func(reservation reservationstore.Reservation) bool {
+		return reservation.PlanID == planID
+	})
+}
+
+func (r quotaReservations) ofPlanInOrg(planID, orgGUID string) int {
+	return r.count(func(reservation reservationstore.Reservation) bool {
+		return reservation.PlanID == planID && reservation.OrganizationGUID == orgGUID
+	})
+}
+
+func (r quotaReservations) ofPlanInSpace(planID, spaceGUID string) int {
+	return r.count(func(reservation reservationstore.Reservation) bool {
+		return reservation.PlanID == planID && reservation.SpaceGUID == spaceGUID
+	})
+}
+
+func (r quotaReservations) countsByPlanID(serviceID string) map[string]int {
+	counts := map[string]int{}
+	for _, reservation := range r {
+		if reservation.ServiceID == serviceID {
+			counts[reservation.PlanID]++
+		}
+	}
+	return counts
+}
+
+// leavingByPlanID counts the instances changing plan by the plan they are
+// leaving.
+func (r quotaReservations) leavingByPlanID(serviceID string) map[string]int {
+	counts := map[string]int{}
+	for _, reservation := range r {
+		if reservation.ServiceID == serviceID && reservation.IsPlanChange() {
+			counts[reservation.PreviousPlanID]++
+		}
+	}
+	return counts
+}
+
+// instanceCounts keeps what Cloud Foundry counted for a quota check, so
+// that the check can be made again under quotaMutex without asking again.
+type instanceCounts struct {
+	counts     map[string]int
+	planCounts map[string]map[cf.ServicePlan]int
+}
+
+func newInstanceCounts() *instanceCounts {
+	return &instanceCounts{
+		counts:     map[string]int{},
+		planCounts: map[string]map[cf.ServicePlan]int{},
+	}
+}
+
+func (c *instanceCounts) of(quota string, count func() (int, error)) (int, error) {
+	if counted, found := c.counts[quota]; found {
+		return counted, nil
+	}
+	counted, err := count()
+	if err != nil {
+		return 0, err
+	}
+	c.counts[quota] = counted
+	return counted, nil
+}
+
+func (c *instanceCounts) byPlan(quota string, count func() (map[cf.ServicePlan]int, error)) (map[cf.ServicePlan]int, error) {
+	if counted, found := c.planCounts[quota]; found {
+		return counted, nil
+	}
+	counted, err := count()
+	if err != nil {
+		return nil, err
+	}
+	c.planCounts[quota] = counted
+	return counted, nil
+}
+
+// reserveQuota reserves quota for a new instance, or for an instance moving
+// to plan from previousPlanID.
+func (b *Broker) reserveQuota(ctx context.Context, instanceID string, offering ServiceOffering, plan config.Plan, previousPlanID string, logger *log.Logger) DisplayableError {
+	for {
+		releases := b.quotaReleaseCount()
+		counts := newInstanceCounts()
+
+		reserved, _, err := b.currentReservations(instanceID)
+		if err != nil {
+			return NewGenericError(ctx, fmt.Errorf("error reading quota reservations: %s", err))
+		}
+		if err := b.checkQuotas(ctx, offering, plan, previousPlanID, counts, reserved, logger); err != NilError {
+			return err
+		}
+
+		if reserveErr, counted := b.reserveCounted(ctx, instanceID, offering, plan, previousPlanID, counts, releases, logger); counted {
+			return reserveErr
+		}
+	}
+}
+
+// reserveCounted checks the quotas again against the reservations held now,
+// and reserves quota when they pass. It reports false when a reservation has
+// been released since Cloud Foundry was asked, as the instance may not have
+// been counted by it.
+func (b *Broker) reserveCounted(
+	ctx context.Context,
+	instanceID string,
+	offering ServiceOffering,
+	plan config.Plan,
+	previousPlanID string,
+	counts *instanceCounts,
+	releases int,
+	logger *log.Logger,
+) (DisplayableError, bool) {
+	b.quotaMutex.Lock()
+	defer b.quotaMutex.Unlock()
+
+	if b.quotaReleases != releases {
+		return NilError, false
+	}
+
+	reserved, expired, err := b.currentReservations(instanceID)
+	if err != nil {
+		return NewGenericError(ctx, fmt.Errorf("error reading quota reservations: %s", err)), true
+	}
+	for _, expiredInstanceID := range expired {
+		logger.Printf("quota reservation for instance %s expired\n", expiredInstanceID)
+		// nothing counts an expired reservation, so quotaReleases is left
+		// alone
+		if err := b.reservationStore.Release(expiredInstanceID); err != nil {
+			logger.Printf("error releasing quota reservation for instance %s: %s\n", expiredInstanceID, err)
+		}
+	}
+	if err := b.checkQuotas(ctx, offering, plan, previousPlanID, counts, reserved, logger); err != NilError {
+		return err, true
+	}
+
+	return b.reserve(ctx, instanceID, offering, plan, previousPlanID), true
+}
+
+func (b *Broker) checkQuotas(
+	ctx context.Context,
+	offering ServiceOffering,
+	plan config.Plan,
+	previousPlanID string,
+	counts *instanceCounts,
+	reserved quotaReservations,
+	logger *log.Logger,
+) DisplayableError {
+	// an instance changing plan is already counted by the service
+	if previousPlanID == "" {
+		if err := b.validateGlobalQuota(ctx, offering.Catalog, counts, reserved, logger); err != NilError {
+			return err
+		}
+	}
+
+	if err := b.validatePlanQuota(ctx, offering.Catalog.ID, plan, counts, reserved, logger); err != NilError {
+		return err
+	}
+
+	if err := b.validateTenantQuotas(ctx, offering.Catalog.ID, plan, counts, reserved, logger); err != NilError {
+		return err
+	}
+
+	return b.validateResourceQuotas(ctx, offering, plan, previousPlanID, counts, reserved, logger)
+}
+
+func (b *Broker) reserve(ctx context.Context, instanceID string, offering ServiceOffering, plan config.Plan, previousPlanID string) DisplayableError {
+	tenant := brokercontext.GetTenant(ctx)
+	reservation := reservationstore.Reservation{
+		InstanceID:       instanceID,
+		ServiceID:        offering.Catalog.ID,
+		PlanID:           plan.ID,
+		PreviousPlanID:   previousPlanID,
+		OrganizationGUID: tenant.OrganizationGUID,
+		SpaceGUID:        tenant.SpaceGUID,
+		ReservedAt:       time.Now(),
+	}
+
+	if err := b.reservationStore.Reserve(reservation); err != nil {
+		return NewGenericError(ctx, fmt.Errorf("error reserving quota for instance %s: %s", instanceID, err))
+	}
+
+	return NilError
+}
+
+// currentReservations leaves out the instance being checked, which must not
+// count against itself, and the reservations that have expired.
+func (b *Broker) currentReservations(instanceID string) (quotaReservations, []string, error) {
+	reservations, err := b.reservationStore.Reservations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		current quotaReservations
+		expired []string
+	)
+	for _, reservation := range reservations {
+		if time.Since(reservation.ReservedAt) > quotaReservationTimeout {
+			expired = append(expired, reservation.InstanceID)
+			continue
+		}
+		if reservation.InstanceID != instanceID {
+			current = append(current, reservation)
+		}
+	}
+
+	return current, expired, nil
+}
+
+func (b *Broker) quotaReleaseCount() int {
+	b.quotaMutex.Lock()
+	defer b.quotaMutex.Unlock()
+	return b.quotaReleases
+}
+
+func (b *Broker) releaseQuota(instanceID string, logger *log.Logger) {
+	b.quotaMutex.Lock()
+	defer b.quotaMutex.Unlock()
+
+	b.quotaReleases++
+	if err := b.reservationStore.Release(instanceID); err != nil {
+		logger.Printf("error releasing quota reservation for instance %s: %s\n", instanceID, err)
+	}
+}
@@ -0,0 +1,424 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/loggerfactory"
+	"github.com/pivotal-cf/on-demand-service-broker/reservationstore"
+)
+
+var _ = Describe("quota reservations", func() {
+	const (
+		instanceID = "some-instance-id"
+		orgGUID    = "some-org-guid"
+		spaceGUID  = "some-space-guid"
+	)
+
+	reservationOf := func(instanceID, planID string, reservedAt time.Time) reservationstore.Reservation {
+		return reservationstore.Reservation{
+			InstanceID: instanceID,
+			ServiceID:  serviceOfferingID,
+			PlanID:     planID,
+			ReservedAt: reservedAt,
+		}
+	}
+
+	BeforeEach(func() {
+		boshClient.GetDeploymentReturns(nil, false, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	Describe("provisioning", func() {
+		var provisionErr error
+
+		JustBeforeEach(func() {
+			_, provisionErr = b.Provision(
+				context.Background(),
+				instanceID,
+				brokerapi.ProvisionDetails{
+					PlanID:           existingPlanID,
+					ServiceID:        serviceOfferingID,
+					OrganizationGUID: orgGUID,
+					SpaceGUID:        spaceGUID,
+				},
+				true,
+			)
+		})
+
+		It("reserves quota for the instance", func() {
+			Expect(provisionErr).NotTo(HaveOccurred())
+			Expect(fakeReservationStore.ReserveCallCount()).To(Equal(1))
+
+			reservation := fakeReservationStore.ReserveArgsForCall(0)
+			Expect(reservation.InstanceID).To(Equal(instanceID))
+			Expect(reservation.ServiceID).To(Equal(serviceOfferingID))
+			Expect(reservation.PlanID).To(Equal(existingPlanID))
+			Expect(reservation.OrganizationGUID).To(Equal(orgGUID))
+			Expect(reservation.SpaceGUID).To(Equal(spaceGUID))
+			Expect(reservation.ReservedAt).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		Context("when other instances of the plan hold reservations", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfPlanReturns(existingPlanServiceInstanceLimit-1, nil)
+				fakeReservationStore.ReservationsReturns([]reservationstore.Reservation{
+					reservationOf("other-instance-id", existingPlanID, time.Now()),
+				}, nil)
+			})
+
+			It("counts them against the plan quota", func() {
+				Expect(provisionErr).To(Equal(brokerapi.ErrPlanQuotaExceeded))
+				Expect(fakeReservationStore.ReserveCallCount()).To(Equal(0))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when other instances of the service hold reservations", func() {
+			BeforeEach(func() {
+				var reservations []reservationstore.Reservation
+				for i := 0; i < serviceOfferingServiceInstanceLimit; i++ {
+					reservations = append(reservations, reservationOf(fmt.Sprintf("other-instance-%d", i), secondPlanID, time.Now()))
+				}
+				fakeReservationStore.ReservationsReturns(reservations, nil)
+			})
+
+			It("counts them against the global quota", func() {
+				Expect(provisionErr).To(Equal(brokerapi.ErrServiceQuotaExceeded))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when other instances of the service are changing plan", func() {
+			BeforeEach(func() {
+				var reservations []reservationstore.Reservation
+				for i := 0; i < serviceOfferingServiceInstanceLimit; i++ {
+					reservation := reservationOf(fmt.Sprintf("other-instance-%d", i), secondPlanID, time.Now())
+					reservation.PreviousPlanID = existingPlanID
+					reservations = append(reservations, reservation)
+				}
+				fakeReservationStore.ReservationsReturns(reservations, nil)
+			})
+
+			It("does not count them against the global quota, as Cloud Foundry already does", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the instance itself holds a reservation", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfPlanReturns(existingPlanServiceInstanceLimit-1, nil)
+				fakeReservationStore.ReservationsReturns([]reservationstore.Reservation{
+					reservationOf(instanceID, existingPlanID, time.Now()),
+				}, nil)
+			})
+
+			It("does not count it against the instance", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when a reservation has expired", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfPlanReturns(existingPlanServiceInstanceLimit-1, nil)
+				fakeReservationStore.ReservationsReturns([]reservationstore.Reservation{
+					reservationOf("stale-instance-id", existingPlanID, time.Now().Add(-2*time.Hour)),
+				}, nil)
+			})
+
+			It("releases it and does not count it", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeReservationStore.ReleaseArgsForCall(0)).To(Equal("stale-instance-id"))
+				Expect(logBuffer.String()).To(ContainSubstring("quota reservation for instance stale-instance-id expired"))
+			})
+		})
+
+		Context("when the deployment cannot be created", func() {
+			BeforeEach(func() {
+				fakeDeployer.CreateReturns(0, nil, errors.New("bosh is down"))
+			})
+
+			It("releases the reservation", func() {
+				Expect(provisionErr).To(HaveOccurred())
+				Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeReservationStore.ReleaseArgsForCall(0)).To(Equal(instanceID))
+			})
+		})
+
+		Context("when the dashboard URL cannot be generated", func() {
+			BeforeEach(func() {
+				serviceAdapter.GenerateDashboardUrlReturns("", errors.New("adapter crashed"))
+			})
+
+			It("releases the reservation", func() {
+				Expect(provisionErr).To(HaveOccurred())
+				Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeReservationStore.ReleaseArgsForCall(0)).To(Equal(instanceID))
+			})
+		})
+
+		Context("when the reservations cannot be read", func() {
+			BeforeEach(func() {
+				fakeReservationStore.ReservationsReturns(nil, errors.New("disk is full"))
+			})
+
+			It("returns a generic error", func() {
+				Expect(provisionErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+				Expect(logBuffer.String()).To(ContainSubstring("error reading quota reservations: disk is full"))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the quota cannot be reserved", func() {
+			BeforeEach(func() {
+				fakeReservationStore.ReserveReturns(errors.New("disk is full"))
+			})
+
+			It("returns a generic error", func() {
+				Expect(provisionErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+				Expect(logBuffer.String()).To(ContainSubstring("error reserving quota for instance %s: disk is full", instanceID))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("changing plan", func() {
+		var updateErr error
+
+		JustBeforeEach(func() {
+			_, updateErr = b.Update(
+				context.Background(),
+				instanceID,
+				brokerapi.UpdateDetails{
+					PlanID:         existingPlanID,
+					ServiceID:      serviceOfferingID,
+					PreviousValues: brokerapi.PreviousValues{PlanID: secondPlanID},
+				},
+				true,
+			)
+		})
+
+		It("reserves quota in the new plan", func() {
+			Expect(updateErr).NotTo(HaveOccurred())
+			Expect(fakeReservationStore.ReserveCallCount()).To(Equal(1))
+			Expect(fakeReservationStore.ReserveArgsForCall(0).PlanID).To(Equal(existingPlanID))
+			Expect(fakeReservationStore.ReserveArgsForCall(0).PreviousPlanID).To(Equal(secondPlanID))
+		})
+
+		Context("when the service's instances have reached the global quota", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingReturns(map[cf.ServicePlan]int{
+					cfServicePlan("1234", secondPlanID, "url", "name"): serviceOfferingServiceInstanceLimit,
+				}, nil)
+			})
+
+			It("changes the plan, as the instance is already counted", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when other instances hold the rest of the new plan's quota", func() {
+			BeforeEach(func() {
+				var reservations []reservationstore.Reservation
+				for i := 0; i < existingPlanServiceInstanceLimit; i++ {
+					reservations = append(reservations, reservationOf(fmt.Sprintf("other-instance-%d", i), existingPlanID, time.Now()))
+				}
+				fakeReservationStore.ReservationsReturns(reservations, nil)
+			})
+
+			It("rejects the update", func() {
+				Expect(updateErr).To(Equal(brokerapi.ErrPlanQuotaExceeded))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the deployment cannot be updated", func() {
+			BeforeEach(func() {
+				fakeDeployer.UpdateReturns(0, nil, errors.New("bosh is down"))
+			})
+
+			It("releases the reservation", func() {
+				Expect(updateErr).To(HaveOccurred())
+				Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeReservationStore.ReleaseArgsForCall(0)).To(Equal(instanceID))
+			})
+		})
+	})
+
+	Describe("polling the last operation", func() {
+		var (
+			operationData string
+			boshTask      boshdirector.BoshTask
+		)
+
+		JustBeforeEach(func() {
+			boshClient.GetTaskReturns(boshTask, nil)
+			_, err := b.LastOperation(context.Background(), instanceID, operationData)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("of a provision", func() {
+			BeforeEach(func() {
+				operationData = `{"BoshTaskID": 42, "OperationType": "create"}`
+				boshTask = boshdirector.BoshTask{ID: 42, State: boshdirector.TaskProcessing}
+			})
+
+			It("releases the reservation", func() {
+				Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeReservationStore.ReleaseArgsForCall(0)).To(Equal(instanceID))
+			})
+		})
+
+		Context("of a plan change that is still in progress", func() {
+			BeforeEach(func() {
+				operationData = `{"BoshTaskID": 42, "OperationType": "update"}`
+				boshTask = boshdirector.BoshTask{ID: 42, State: boshdirector.TaskProcessing}
+			})
+
+			It("keeps the reservation", func() {
+				Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("of a plan change that has finished", func() {
+			BeforeEach(func() {
+				operationData = `{"BoshTaskID": 42, "OperationType": "update"}`
+				boshTask = boshdirector.BoshTask{ID: 42, State: boshdirector.TaskDone}
+			})
+
+			It("releases the reservation", func() {
+				Expect(fakeReservationStore.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeReservationStore.ReleaseArgsForCall(0)).To(Equal(instanceID))
+			})
+		})
+	})
+
+	Describe("counting instances", func() {
+		It("does not hold up other quota checks while Cloud Foundry counts", func() {
+			countStarted := make(chan struct{})
+			releaseCount := make(chan struct{})
+			cfClient.CountInstancesOfPlanStub = func(string, string, *log.Logger) (int, error) {
+				if cfClient.CountInstancesOfPlanCallCount() == 1 {
+					close(countStarted)
+					<-releaseCount
+				}
+				return 0, nil
+			}
+
+			firstProvision := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := b.Provision(context.Background(), "first-instance-id", brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID}, true)
+				firstProvision <- err
+			}()
+			Eventually(countStarted).Should(BeClosed())
+
+			_, err := b.Provision(context.Background(), "second-instance-id", brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID}, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			close(releaseCount)
+			Eventually(firstProvision).Should(Receive(BeNil()))
+		})
+
+		It("counts again when a reservation is released while Cloud Foundry counts", func() {
+			store, err := reservationstore.NewFileStore("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Reserve(reservationOf("recorded-instance-id", existingPlanID, time.Now()))).To(Succeed())
+			fakeReservationStore.ReservationsStub = store.Reservations
+			fakeReservationStore.ReleaseStub = store.Release
+
+			cfClient.CountInstancesOfPlanStub = func(string, string, *log.Logger) (int, error) {
+				if cfClient.CountInstancesOfPlanCallCount() > 1 {
+					return existingPlanServiceInstanceLimit - 1, nil
+				}
+				// Cloud Foundry records an instance after it has been counted, while
+				// another instance is reserved
+				_, err := b.LastOperation(context.Background(), "recorded-instance-id", `{"BoshTaskID": 42, "OperationType": "create"}`)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(store.Reserve(reservationOf("other-instance-id", existingPlanID, time.Now()))).To(Succeed())
+				return existingPlanServiceInstanceLimit - 2, nil
+			}
+
+			_, err = b.Provision(context.Background(), instanceID, brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID}, true)
+			Expect(err).To(Equal(brokerapi.ErrPlanQuotaExceeded))
+			Expect(cfClient.CountInstancesOfPlanCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("concurrent provisions", func() {
+		It("admits no more instances than the plan quota", func() {
+			store, err := reservationstore.NewFileStore("")
+			Expect(err).NotTo(HaveOccurred())
+
+			concurrentBroker, err := broker.New(
+				boshInfo,
+				boshClient,
+				cfClient,
+				[]broker.ServiceOffering{{Catalog: serviceCatalog, AdapterClient: serviceAdapter, Deployer: fakeDeployer}},
+				fakeOperationStore,
+				fakeBindingStore,
+				store,
+				fakeAuditLog,
+				"",
+				config.LastOperationDetails{},
+				0,
+				false,
+				operationLockTimeout,
+				loggerfactory.New(ioutil.Discard, "broker-unit-tests", log.LstdFlags),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			var (
+				wg        sync.WaitGroup
+				mutex     sync.Mutex
+				succeeded int
+			)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					_, err := concurrentBroker.Provision(
+						context.Background(),
+						fmt.Sprintf("instance-%d", i),
+						brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+						true,
+					)
+					if err == nil {
+						mutex.Lock()
+						succeeded++
+						mutex.Unlock()
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			Expect(succeeded).To(Equal(existingPlanServiceInstanceLimit))
+		})
+	})
+})
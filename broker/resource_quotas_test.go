@@ -0,0 +1,179 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/reservationstore"
+)
+
+var _ = Describe("resource quotas", func() {
+	const instanceID = "some-instance-id"
+
+	var (
+		planInstanceLimit   = 200
+		globalDiskLimitInGB = 1000
+	)
+
+	instanceCounts := func(existingPlanCount, secondPlanCount int) map[cf.ServicePlan]int {
+		return map[cf.ServicePlan]int{
+			cfServicePlan("1234", existingPlanID, "url", "existing-plan"): existingPlanCount,
+			cfServicePlan("5678", secondPlanID, "url", "second-plan"):     secondPlanCount,
+		}
+	}
+
+	BeforeEach(func() {
+		// a deployment of existingPlan has 97 instances and 420GB of disk,
+		// one of secondPlan has 44 instances and 880GB
+		serviceCatalog.PersistentDiskTypeSizesGB = map[string]int{"disk-type": 10, "disk-type1": 20}
+		serviceCatalog.GlobalQuotas.Resources = &config.ResourceLimits{PersistentDiskGB: &globalDiskLimitInGB}
+		serviceCatalog.Plans[0].Quotas.Resources = &config.ResourceLimits{Instances: &planInstanceLimit}
+		boshClient.GetDeploymentReturns(nil, false, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	Describe("provisioning", func() {
+		var provisionErr error
+
+		JustBeforeEach(func() {
+			_, provisionErr = b.Provision(
+				context.Background(),
+				instanceID,
+				brokerapi.ProvisionDetails{PlanID: existingPlanID, ServiceID: serviceOfferingID},
+				true,
+			)
+		})
+
+		Context("when the resources stay within the quotas", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingReturns(instanceCounts(1, 0), nil)
+			})
+
+			It("provisions the instance", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the plan would use more instances than its quota", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingReturns(instanceCounts(2, 0), nil)
+			})
+
+			It("rejects the provision naming the resource", func() {
+				Expect(provisionErr).To(MatchError(fmt.Sprintf(broker.PlanResourceQuotaExceededMessage, config.InstancesResource)))
+				Expect(logBuffer.String()).To(ContainSubstring("plan instances quota exceeded for plan ID %s", existingPlanID))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the service would use more disk than its global quota", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingReturns(instanceCounts(0, 1), nil)
+			})
+
+			It("rejects the provision naming the resource", func() {
+				Expect(provisionErr).To(MatchError(fmt.Sprintf(broker.ServiceResourceQuotaExceededMessage, config.PersistentDiskGBResource)))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when another instance is leaving a plan for this one", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingReturns(instanceCounts(0, 1), nil)
+				fakeReservationStore.ReservationsReturns([]reservationstore.Reservation{{
+					InstanceID:     "moving-instance-id",
+					ServiceID:      serviceOfferingID,
+					PlanID:         existingPlanID,
+					PreviousPlanID: secondPlanID,
+					ReservedAt:     time.Now(),
+				}}, nil)
+			})
+
+			It("only counts the resources the move adds", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the instances cannot be counted", func() {
+			BeforeEach(func() {
+				serviceCatalog.GlobalQuotas.ServiceInstanceLimit = nil
+				cfClient.CountInstancesOfServiceOfferingReturnsOnCall(1, nil, errors.New("cc is down"))
+			})
+
+			It("returns a generic error", func() {
+				Expect(provisionErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+				Expect(logBuffer.String()).To(ContainSubstring("error counting instances of service offering: cc is down"))
+			})
+		})
+
+		Context("when no resource quotas are configured", func() {
+			BeforeEach(func() {
+				serviceCatalog.GlobalQuotas = config.Quotas{}
+				serviceCatalog.Plans[0].Quotas.Resources = nil
+			})
+
+			It("does not count the instances of the service offering", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(cfClient.CountInstancesOfServiceOfferingCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("changing plan", func() {
+		var updateErr error
+
+		JustBeforeEach(func() {
+			_, updateErr = b.Update(
+				context.Background(),
+				instanceID,
+				brokerapi.UpdateDetails{
+					PlanID:         secondPlanID,
+					ServiceID:      serviceOfferingID,
+					PreviousValues: brokerapi.PreviousValues{PlanID: existingPlanID},
+				},
+				true,
+			)
+		})
+
+		Context("when the instance's current resources make room for the new plan", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingReturns(instanceCounts(1, 0), nil)
+			})
+
+			It("updates the instance", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the new plan would take the service over its quota", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingReturns(instanceCounts(2, 0), nil)
+			})
+
+			It("rejects the update naming the resource", func() {
+				Expect(updateErr).To(MatchError(fmt.Sprintf(broker.ServiceResourceQuotaExceededMessage, config.PersistentDiskGBResource)))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
@@ -0,0 +1,46 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+)
+
+// RunErrand runs an errand of an instance's deployment outside of any
+// operation, for operators to re-run a lifecycle errand by hand.
+func (b *Broker) RunErrand(ctx context.Context, instanceID string, errand config.Errand, logger *log.Logger) (int, error) {
+	unlock, locked := b.instanceLocker.Lock(ctx, instanceID)
+	if !locked {
+		return 0, NewOperationInProgressError(fmt.Errorf("broker: operation in progress for instance %s", instanceID))
+	}
+	defer unlock()
+
+	_, found, err := b.boshClient.GetDeployment(b.deploymentName(instanceID), logger)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	release, free, err := b.takeTaskSlot(false, logger)
+	if err != nil {
+		return 0, err
+	}
+	if !free {
+		return 0, NewOperationInProgressError(fmt.Errorf("broker: %s for instance %s", errWaitingForTaskSlot, instanceID))
+	}
+	defer release()
+
+	logger.Printf("running errand %s on instance %s\n", errand.Name, instanceID)
+	return b.boshClient.RunErrand(b.deploymentName(instanceID), errand.Name, errand.Instances, "", logger)
+}
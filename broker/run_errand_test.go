@@ -0,0 +1,74 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+)
+
+var _ = Describe("running an errand", func() {
+	const (
+		instanceID   = "some-instance-id"
+		errandTaskID = 55
+	)
+
+	var (
+		errand    config.Errand
+		taskID    int
+		errandErr error
+	)
+
+	BeforeEach(func() {
+		errand = config.Errand{Name: "cluster-init", Instances: []string{"redis-server/first"}}
+		boshClient.GetDeploymentReturns(nil, true, nil)
+		boshClient.RunErrandReturns(errandTaskID, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+		taskID, errandErr = b.RunErrand(context.Background(), instanceID, errand, loggerFactory.New())
+	})
+
+	It("runs the errand on the given instances of the deployment", func() {
+		Expect(errandErr).NotTo(HaveOccurred())
+		Expect(taskID).To(Equal(errandTaskID))
+
+		Expect(boshClient.RunErrandCallCount()).To(Equal(1))
+		actualDeploymentName, errandName, instances, contextID, _ := boshClient.RunErrandArgsForCall(0)
+		Expect(actualDeploymentName).To(Equal(deploymentName(instanceID)))
+		Expect(errandName).To(Equal("cluster-init"))
+		Expect(instances).To(Equal([]string{"redis-server/first"}))
+		Expect(contextID).To(BeEmpty())
+	})
+
+	Context("when the deployment does not exist", func() {
+		BeforeEach(func() {
+			boshClient.GetDeploymentReturns(nil, false, nil)
+		})
+
+		It("returns that the instance does not exist", func() {
+			Expect(errandErr).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+			Expect(boshClient.RunErrandCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the errand cannot be run", func() {
+		BeforeEach(func() {
+			boshClient.RunErrandReturns(0, errors.New("no instances found in instance group redis-server"))
+		})
+
+		It("returns the error", func() {
+			Expect(errandErr).To(MatchError("no instances found in instance group redis-server"))
+		})
+	})
+})
@@ -0,0 +1,110 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const InvalidParametersLoggerAction = "invalid-parameters"
+
+type planSchemas struct {
+	instanceCreate *gojsonschema.Schema
+	instanceUpdate *gojsonschema.Schema
+	bindingCreate  *gojsonschema.Schema
+}
+
+func compilePlanSchemas(plans []config.Plan) (map[string]planSchemas, error) {
+	compiled := map[string]planSchemas{}
+	for _, plan := range plans {
+		if plan.Schemas == nil {
+			continue
+		}
+
+		var schemas planSchemas
+		var err error
+		if schemas.instanceCreate, err = compileSchema(plan.Schemas.ServiceInstance.Create); err != nil {
+			return nil, fmt.Errorf("plan %s has an invalid service_instance create schema: %s", plan.ID, err)
+		}
+		if schemas.instanceUpdate, err = compileSchema(plan.Schemas.ServiceInstance.Update); err != nil {
+			return nil, fmt.Errorf("plan %s has an invalid service_instance update schema: %s", plan.ID, err)
+		}
+		if schemas.bindingCreate, err = compileSchema(plan.Schemas.ServiceBinding.Create); err != nil {
+			return nil, fmt.Errorf("plan %s has an invalid service_binding create schema: %s", plan.ID, err)
+		}
+		compiled[plan.ID] = schemas
+	}
+	return compiled, nil
+}
+
+func compileSchema(schema config.Schema) (*gojsonschema.Schema, error) {
+	if schema.Parameters == nil {
+		return nil, nil
+	}
+	return gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema.Parameters))
+}
+
+func brokerapiSchemas(schemas *config.PlanSchemas) *brokerapi.ServiceSchemas {
+	if schemas == nil {
+		return nil
+	}
+
+	return &brokerapi.ServiceSchemas{
+		Instance: brokerapi.ServiceInstanceSchema{
+			Create: brokerapi.Schema{Parameters: schemas.ServiceInstance.Create.Parameters},
+			Update: brokerapi.Schema{Parameters: schemas.ServiceInstance.Update.Parameters},
+		},
+		Binding: brokerapi.ServiceBindingSchema{
+			Create: brokerapi.Schema{Parameters: schemas.ServiceBinding.Create.Parameters},
+		},
+	}
+}
+
+// validateParameters checks the arbitrary parameters of a request against the
+// plan's schema. Requests without parameters are validated as an empty object.
+func validateParameters(schema *gojsonschema.Schema, requestParams map[string]interface{}) DisplayableError {
+	if schema == nil {
+		return NilError
+	}
+
+	parameters, _ := requestParams["parameters"].(map[string]interface{})
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+	}
+
+	result, err := schema.Validate(gojsonschema.NewGoLoader(parameters))
+	if err != nil {
+		return NewDisplayableError(
+			brokerapi.NewFailureResponse(errors.New("parameters could not be validated"), http.StatusBadRequest, InvalidParametersLoggerAction),
+			fmt.Errorf("validating parameters: %s", err),
+		)
+	}
+
+	if result.Valid() {
+		return NilError
+	}
+
+	violations := []string{}
+	for _, violation := range result.Errors() {
+		violations = append(violations, violation.String())
+	}
+	sort.Strings(violations)
+
+	message := fmt.Sprintf("parameters are invalid: %s", strings.Join(violations, "; "))
+	return NewDisplayableError(
+		brokerapi.NewFailureResponse(errors.New(message), http.StatusBadRequest, InvalidParametersLoggerAction),
+		errors.New(message),
+	)
+}
@@ -0,0 +1,211 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("plan schemas", func() {
+	const (
+		schemaPlanID = "schema-plan-id"
+		instanceID   = "some-instance-id"
+	)
+
+	var (
+		instanceSchema = map[string]interface{}{
+			"$schema": "http://json-schema.org/draft-04/schema#",
+			"type":    "object",
+			"properties": map[string]interface{}{
+				"maxclients": map[string]interface{}{"type": "integer", "minimum": 1},
+				"persistent": map[string]interface{}{"type": "boolean"},
+			},
+			"required":             []interface{}{"maxclients"},
+			"additionalProperties": false,
+		}
+		updateSchema = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"maxclients": map[string]interface{}{"type": "integer", "minimum": 1},
+			},
+			"additionalProperties": false,
+		}
+		bindingSchema = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"role": map[string]interface{}{"type": "string", "enum": []interface{}{"read", "write"}},
+			},
+		}
+
+		expectedInvalidParametersError = func(message string) error {
+			return brokerapi.NewFailureResponse(errors.New(message), http.StatusBadRequest, broker.InvalidParametersLoggerAction)
+		}
+	)
+
+	BeforeEach(func() {
+		serviceCatalog.Plans = append(serviceCatalog.Plans, config.Plan{
+			ID:             schemaPlanID,
+			InstanceGroups: []serviceadapter.InstanceGroup{},
+			Schemas: &config.PlanSchemas{
+				ServiceInstance: config.ServiceInstanceSchemas{
+					Create: config.Schema{Parameters: instanceSchema},
+					Update: config.Schema{Parameters: updateSchema},
+				},
+				ServiceBinding: config.ServiceBindingSchemas{
+					Create: config.Schema{Parameters: bindingSchema},
+				},
+			},
+		})
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	It("advertises the schemas in the catalog", func() {
+		var advertisedPlan brokerapi.ServicePlan
+		for _, plan := range b.Services(context.Background())[0].Plans {
+			if plan.ID == schemaPlanID {
+				advertisedPlan = plan
+			}
+		}
+
+		Expect(advertisedPlan.Schemas).To(Equal(&brokerapi.ServiceSchemas{
+			Instance: brokerapi.ServiceInstanceSchema{
+				Create: brokerapi.Schema{Parameters: instanceSchema},
+				Update: brokerapi.Schema{Parameters: updateSchema},
+			},
+			Binding: brokerapi.ServiceBindingSchema{
+				Create: brokerapi.Schema{Parameters: bindingSchema},
+			},
+		}))
+	})
+
+	It("does not advertise schemas for plans without them", func() {
+		for _, plan := range b.Services(context.Background())[0].Plans {
+			if plan.ID == existingPlanID {
+				Expect(plan.Schemas).To(BeNil())
+			}
+		}
+	})
+
+	Describe("provisioning", func() {
+		provision := func(rawParameters string) error {
+			_, err := b.Provision(context.Background(), instanceID, brokerapi.ProvisionDetails{
+				PlanID:        schemaPlanID,
+				ServiceID:     serviceOfferingID,
+				RawParameters: []byte(rawParameters),
+			}, true)
+			return err
+		}
+
+		It("accepts parameters that match the schema", func() {
+			Expect(provision(`{"maxclients": 10, "persistent": true}`)).To(Succeed())
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+		})
+
+		It("rejects parameters that violate the schema, listing every violation", func() {
+			err := provision(`{"maxclients": "lots", "persistent": "yes", "colour": "red"}`)
+			Expect(err).To(Equal(expectedInvalidParametersError(
+				"parameters are invalid: (root): Additional property colour is not allowed; maxclients: Invalid type. Expected: integer, given: string; persistent: Invalid type. Expected: boolean, given: string",
+			)))
+		})
+
+		It("validates missing parameters as an empty object", func() {
+			err := provision("")
+			Expect(err).To(Equal(expectedInvalidParametersError("parameters are invalid: (root): maxclients is required")))
+		})
+
+		It("does not call BOSH or the adapter when the parameters are invalid", func() {
+			Expect(provision(`{"maxclients": 0}`)).To(HaveOccurred())
+			Expect(boshClient.GetDeploymentCallCount()).To(Equal(0))
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+		})
+
+		It("logs the violations", func() {
+			Expect(provision(`{"maxclients": 0}`)).To(HaveOccurred())
+			Expect(logBuffer.String()).To(ContainSubstring("parameters are invalid: maxclients: Must be greater than or equal to 1"))
+		})
+	})
+
+	Describe("updating", func() {
+		update := func(rawParameters string) error {
+			_, err := b.Update(context.Background(), instanceID, brokerapi.UpdateDetails{
+				PlanID:         schemaPlanID,
+				ServiceID:      serviceOfferingID,
+				RawParameters:  []byte(rawParameters),
+				PreviousValues: brokerapi.PreviousValues{PlanID: schemaPlanID},
+			}, true)
+			return err
+		}
+
+		It("accepts parameters that match the update schema", func() {
+			Expect(update(`{"maxclients": 10}`)).To(Succeed())
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("rejects parameters that violate the update schema without deploying", func() {
+			err := update(`{"persistent": true}`)
+			Expect(err).To(Equal(expectedInvalidParametersError("parameters are invalid: (root): Additional property persistent is not allowed")))
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("binding", func() {
+		bind := func(rawParameters string) error {
+			_, err := b.Bind(context.Background(), instanceID, "some-binding-id", brokerapi.BindDetails{
+				PlanID:        schemaPlanID,
+				ServiceID:     serviceOfferingID,
+				RawParameters: []byte(rawParameters),
+			})
+			return err
+		}
+
+		BeforeEach(func() {
+			boshClient.GetDeploymentReturns([]byte("a manifest"), true, nil)
+		})
+
+		It("accepts parameters that match the binding schema", func() {
+			Expect(bind(`{"role": "read"}`)).To(Succeed())
+			Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(1))
+		})
+
+		It("rejects parameters that violate the binding schema without calling BOSH or the adapter", func() {
+			err := bind(`{"role": "admin"}`)
+			Expect(err).To(Equal(expectedInvalidParametersError(`parameters are invalid: role: role must be one of the following: "read", "write"`)))
+			Expect(boshClient.VMsCallCount()).To(Equal(0))
+			Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(0))
+		})
+	})
+})
+
+var _ = Describe("invalid plan schemas", func() {
+	BeforeEach(func() {
+		serviceCatalog.Plans = append(serviceCatalog.Plans, config.Plan{
+			ID: "broken-schema-plan",
+			Schemas: &config.PlanSchemas{
+				ServiceBinding: config.ServiceBindingSchemas{
+					Create: config.Schema{Parameters: map[string]interface{}{"type": 42}},
+				},
+			},
+		})
+	})
+
+	It("refuses to create the broker", func() {
+		_, err := createBroker(boshInfo)
+		Expect(err).To(MatchError(ContainSubstring("plan broken-schema-plan has an invalid service_binding create schema")))
+	})
+})
@@ -0,0 +1,73 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import "github.com/pivotal-cf/on-demand-service-broker/config"
+
+// ServiceOffering is a service in the catalog together with the adapter and
+// deployer used for its instances. MaintenanceVersion is advertised as the
+// plans' maintenance_info version when set. BindingsRetrievable is set when
+// bindings are kept in a store that survives restarts.
+type ServiceOffering struct {
+	Catalog             config.ServiceOffering
+	AdapterClient       ServiceAdapterClient
+	Deployer            Deployer
+	MaintenanceVersion  string
+	BindingsRetrievable bool
+}
+
+// offeringForPlan finds the offering a plan belongs to. Plan IDs are unique
+// across offerings.
+func (b *Broker) offeringForPlan(planID string) (ServiceOffering, config.Plan, bool) {
+	for _, offering := range b.serviceOfferings {
+		if plan, found := offering.Catalog.FindPlanByID(planID); found {
+			return offering, plan, true
+		}
+	}
+	return ServiceOffering{}, config.Plan{}, false
+}
+
+// offeringForRequest finds the offering for a request that names its service
+// and plan, falling back to the only offering when neither is recognised.
+func (b *Broker) offeringForRequest(serviceID, planID string) (ServiceOffering, bool) {
+	if offering, _, found := b.offeringForPlan(planID); found {
+		return offering, true
+	}
+
+	for _, offering := range b.serviceOfferings {
+		if offering.Catalog.ID == serviceID {
+			return offering, true
+		}
+	}
+
+	if len(b.serviceOfferings) == 1 {
+		return b.serviceOfferings[0], true
+	}
+
+	return ServiceOffering{}, false
+}
+
+func (b *Broker) serviceName(serviceID, planID string) string {
+	offering, _ := b.offeringForRequest(serviceID, planID)
+	return offering.Catalog.Name
+}
+
+func (b *Broker) allPlans() config.Plans {
+	var plans config.Plans
+	for _, offering := range b.serviceOfferings {
+		plans = append(plans, offering.Catalog.Plans...)
+	}
+	return plans
+}
+
+func (b *Broker) serviceNames() []string {
+	var names []string
+	for _, offering := range b.serviceOfferings {
+		names = append(names, offering.Catalog.Name)
+	}
+	return names
+}
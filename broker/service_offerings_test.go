@@ -0,0 +1,241 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/broker/fakes"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("multiple service offerings", func() {
+	const (
+		otherServiceID = "other-service-id"
+		otherPlanID    = "other-plan-id"
+		instanceID     = "some-instance-id"
+	)
+
+	var (
+		otherServiceAdapter *fakes.FakeServiceAdapterClient
+		otherDeployer       *fakes.FakeDeployer
+		otherCatalog        config.ServiceOffering
+		otherGlobalLimit    = 2
+	)
+
+	BeforeEach(func() {
+		otherServiceAdapter = new(fakes.FakeServiceAdapterClient)
+		otherDeployer = new(fakes.FakeDeployer)
+		otherCatalog = config.ServiceOffering{
+			ID:   otherServiceID,
+			Name: "a-cool-kafka-service",
+			GlobalQuotas: config.Quotas{
+				ServiceInstanceLimit: &otherGlobalLimit,
+			},
+			Plans: []config.Plan{{
+				ID:             otherPlanID,
+				Name:           "other-plan",
+				InstanceGroups: []sdk.InstanceGroup{},
+			}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		b, brokerCreationErr = broker.New(
+			createBOSHInfoWithMajorVersion(boshdirector.MinimumMajorSemverDirectorVersionForLifecycleErrands, "semver"),
+			boshClient,
+			cfClient,
+			[]broker.ServiceOffering{
+				{Catalog: serviceCatalog, AdapterClient: serviceAdapter, Deployer: fakeDeployer},
+				{Catalog: otherCatalog, AdapterClient: otherServiceAdapter, Deployer: otherDeployer, BindingsRetrievable: true},
+			},
+			fakeOperationStore,
+			fakeBindingStore,
+			fakeReservationStore,
+			fakeAuditLog,
+			"",
+			config.LastOperationDetails{},
+			0,
+			false,
+			operationLockTimeout,
+			loggerFactory,
+		)
+		Expect(brokerCreationErr).NotTo(HaveOccurred())
+	})
+
+	It("lists every offering in the catalog", func() {
+		services := b.Services(context.Background())
+		Expect(services).To(HaveLen(2))
+		Expect(services[0].ID).To(Equal(serviceOfferingID))
+		Expect(services[1].ID).To(Equal(otherServiceID))
+		Expect(services[1].Plans).To(HaveLen(1))
+		Expect(services[1].Plans[0].ID).To(Equal(otherPlanID))
+	})
+
+	It("advertises bindings as retrievable only for offerings whose bindings are kept", func() {
+		services := b.Services(context.Background())
+		Expect(services[0].BindingsRetrievable).To(BeFalse())
+		Expect(services[1].BindingsRetrievable).To(BeTrue())
+	})
+
+	It("verifies existing plan IDs for each offering at startup", func() {
+		Expect(cfClient.CountInstancesOfServiceOfferingCallCount()).To(Equal(2))
+		firstID, _ := cfClient.CountInstancesOfServiceOfferingArgsForCall(0)
+		secondID, _ := cfClient.CountInstancesOfServiceOfferingArgsForCall(1)
+		Expect([]string{firstID, secondID}).To(Equal([]string{serviceOfferingID, otherServiceID}))
+	})
+
+	Context("when provisioning a plan of the second offering", func() {
+		var provisionErr error
+
+		JustBeforeEach(func() {
+			boshClient.GetDeploymentReturns(nil, false, nil)
+			_, provisionErr = b.Provision(
+				context.Background(),
+				instanceID,
+				brokerapi.ProvisionDetails{PlanID: otherPlanID, ServiceID: otherServiceID},
+				true,
+			)
+		})
+
+		It("deploys with the second offering's deployer", func() {
+			Expect(provisionErr).NotTo(HaveOccurred())
+			Expect(otherDeployer.CreateCallCount()).To(Equal(1))
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+		})
+
+		It("checks the global quota of the second offering", func() {
+			Expect(cfClient.CountInstancesOfServiceOfferingCallCount()).To(Equal(3))
+			actualServiceID, _ := cfClient.CountInstancesOfServiceOfferingArgsForCall(2)
+			Expect(actualServiceID).To(Equal(otherServiceID))
+		})
+
+		Context("and the second offering's global quota is reached", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingStub = func(serviceID string, _ *log.Logger) (map[cf.ServicePlan]int, error) {
+					if serviceID == otherServiceID {
+						return map[cf.ServicePlan]int{cfServicePlan("1234", otherPlanID, "url", "other-plan"): otherGlobalLimit}, nil
+					}
+					return map[cf.ServicePlan]int{}, nil
+				}
+			})
+
+			It("rejects the provision", func() {
+				Expect(provisionErr).To(Equal(brokerapi.ErrServiceQuotaExceeded))
+				Expect(otherDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when updating to a plan of another offering", func() {
+		var updateErr error
+
+		JustBeforeEach(func() {
+			_, updateErr = b.Update(
+				context.Background(),
+				instanceID,
+				brokerapi.UpdateDetails{
+					PlanID:         otherPlanID,
+					ServiceID:      otherServiceID,
+					PreviousValues: brokerapi.PreviousValues{PlanID: existingPlanID},
+				},
+				true,
+			)
+		})
+
+		It("rejects the update", func() {
+			Expect(updateErr).To(MatchError("Plan other-plan-id does not belong to service a-cool-redis-service"))
+			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			Expect(otherDeployer.UpdateCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when binding to an instance of the second offering", func() {
+		JustBeforeEach(func() {
+			boshClient.GetDeploymentReturns([]byte("manifest"), true, nil)
+			otherServiceAdapter.CreateBindingReturns(sdk.Binding{Credentials: map[string]interface{}{"user": "kafka"}}, nil)
+		})
+
+		It("asks the second offering's adapter for credentials", func() {
+			binding, err := b.Bind(context.Background(), instanceID, "some-binding-id", brokerapi.BindDetails{
+				PlanID:    otherPlanID,
+				ServiceID: otherServiceID,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(binding.Credentials).To(Equal(map[string]interface{}{"user": "kafka"}))
+			Expect(otherServiceAdapter.CreateBindingCallCount()).To(Equal(1))
+			Expect(serviceAdapter.CreateBindingCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when listing instances", func() {
+		BeforeEach(func() {
+			cfClient.GetInstancesOfServiceOfferingStub = func(serviceID string, _ *log.Logger) ([]string, error) {
+				if serviceID == otherServiceID {
+					return []string{"kafka-instance"}, nil
+				}
+				return []string{"redis-instance"}, nil
+			}
+		})
+
+		It("returns the instances of every offering", func() {
+			instances, err := b.Instances(loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instances).To(Equal([]string{"redis-instance", "kafka-instance"}))
+		})
+	})
+
+	Context("when counting instances by plan", func() {
+		BeforeEach(func() {
+			cfClient.CountInstancesOfServiceOfferingStub = func(serviceID string, _ *log.Logger) (map[cf.ServicePlan]int, error) {
+				if serviceID == otherServiceID {
+					return map[cf.ServicePlan]int{cfServicePlan("5678", otherPlanID, "url", "other-plan"): 1}, nil
+				}
+				return map[cf.ServicePlan]int{cfServicePlan("1234", existingPlanID, "url", "plan"): 3}, nil
+			}
+		})
+
+		It("merges the counts of every offering", func() {
+			counts, err := b.CountInstancesOfPlans(loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counts).To(Equal(map[cf.ServicePlan]int{
+				cfServicePlan("1234", existingPlanID, "url", "plan"):    3,
+				cfServicePlan("5678", otherPlanID, "url", "other-plan"): 1,
+			}))
+		})
+	})
+})
+
+var _ = Describe("creating a broker without service offerings", func() {
+	It("returns an error", func() {
+		_, err := broker.New(
+			createBOSHInfoWithMajorVersion(boshdirector.MinimumMajorSemverDirectorVersionForLifecycleErrands, "semver"),
+			boshClient,
+			cfClient,
+			nil,
+			fakeOperationStore,
+			fakeBindingStore,
+			fakeReservationStore,
+			fakeAuditLog,
+			"",
+			config.LastOperationDetails{},
+			0,
+			false,
+			operationLockTimeout,
+			loggerFactory,
+		)
+		Expect(err).To(MatchError("at least one service offering must be configured"))
+	})
+})
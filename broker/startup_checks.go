@@ -14,6 +14,7 @@ import (
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
 )
 
 func (b *Broker) startupChecks() error {
@@ -43,13 +44,22 @@ func (b *Broker) checkAuthentication(logger *log.Logger) error {
 }
 
 func (b *Broker) verifyExistingInstancePlanIDsUnchanged(logger *log.Logger) error {
-	instanceCountByPlanID, err := b.cfClient.CountInstancesOfServiceOffering(b.serviceOffering.ID, logger)
+	for _, offering := range b.serviceOfferings {
+		if err := b.verifyOfferingPlanIDsUnchanged(offering.Catalog, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Broker) verifyOfferingPlanIDsUnchanged(serviceOffering config.ServiceOffering, logger *log.Logger) error {
+	instanceCountByPlanID, err := b.cfClient.CountInstancesOfServiceOffering(serviceOffering.ID, logger)
 	if err != nil {
 		return err
 	}
 
 	for plan, count := range instanceCountByPlanID {
-		_, found := b.serviceOffering.Plans.FindByID(plan.ServicePlanEntity.UniqueID)
+		_, found := serviceOffering.Plans.FindByID(plan.ServicePlanEntity.UniqueID)
 
 		if !found && count > 0 {
 			return fmt.Errorf(
@@ -110,10 +120,19 @@ func (b *Broker) checkBoshDirectorVersion(logger *log.Logger) error {
 		return errors.New("API version is insufficient, ODB requires BOSH v257+.")
 	}
 
-	if b.serviceOffering.HasLifecycleErrands() && !directorVersion.SupportsLifecycleErrands() {
+	if b.hasLifecycleErrands() && !directorVersion.SupportsLifecycleErrands() {
 		errMsg := fmt.Sprintf("API version is insufficient, one or more plans are configured with lifecycle_errands which require BOSH v%d+.", boshdirector.MinimumMajorSemverDirectorVersionForLifecycleErrands)
 		return errors.New(errMsg)
 	}
 
 	return nil
 }
+
+func (b *Broker) hasLifecycleErrands() bool {
+	for _, offering := range b.serviceOfferings {
+		if offering.Catalog.HasLifecycleErrands() {
+			return true
+		}
+	}
+	return false
+}
@@ -71,10 +71,16 @@ var _ = Describe("Initializing the broker", func() {
 				boshInfo,
 				boshClient,
 				cfClient,
-				serviceAdapter,
-				fakeDeployer,
-				serviceCatalog,
+				[]broker.ServiceOffering{{Catalog: serviceCatalog, AdapterClient: serviceAdapter, Deployer: fakeDeployer}},
+				fakeOperationStore,
+				fakeBindingStore,
+				fakeReservationStore,
+				fakeAuditLog,
+				"",
+				config.LastOperationDetails{},
+				0,
 				true,
+				0,
 				loggerFactory,
 			)
 			Expect(brokerCreationErr).NotTo(HaveOccurred())
@@ -129,8 +135,8 @@ var _ = Describe("Initializing the broker", func() {
 			emptyLifecycleErrandsPlan := config.Plan{
 				ID: "empty-lifecycle-errands-plan-id",
 				LifecycleErrands: &config.LifecycleErrands{
-					PostDeploy: "",
-					PreDelete:  "",
+					PostDeploy: nil,
+					PreDelete:  nil,
 				},
 			}
 
@@ -160,10 +166,16 @@ var _ = Describe("Initializing the broker", func() {
 				boshInfo,
 				boshClient,
 				cfClient,
-				serviceAdapter,
-				fakeDeployer,
-				serviceCatalog,
+				[]broker.ServiceOffering{{Catalog: serviceCatalog, AdapterClient: serviceAdapter, Deployer: fakeDeployer}},
+				fakeOperationStore,
+				fakeBindingStore,
+				fakeReservationStore,
+				fakeAuditLog,
+				"",
+				config.LastOperationDetails{},
+				0,
 				true,
+				0,
 				loggerFactory,
 			)
 			Expect(brokerCreationErr).To(HaveOccurred())
@@ -227,10 +239,16 @@ var _ = Describe("Initializing the broker", func() {
 				boshInfo,
 				boshClient,
 				cfClient,
-				serviceAdapter,
-				fakeDeployer,
-				serviceCatalog,
+				[]broker.ServiceOffering{{Catalog: serviceCatalog, AdapterClient: serviceAdapter, Deployer: fakeDeployer}},
+				fakeOperationStore,
+				fakeBindingStore,
+				fakeReservationStore,
+				fakeAuditLog,
+				"",
+				config.LastOperationDetails{},
+				0,
 				true,
+				0,
 				loggerFactory,
 			)
 			Expect(brokerCreationErr).NotTo(HaveOccurred())
@@ -0,0 +1,99 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+)
+
+const errandTaskDescriptionPrefix = "run errand"
+
+func compileFailureMessagePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid failure message pattern %q: %s", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// taskDetails describes how far a BOSH task has got or, when it failed, what
+// went wrong, as far as the operator lets app developers see. It is empty
+// when there is nothing to add to the description of the operation.
+func (b *Broker) taskDetails(task boshdirector.BoshTask, operationData OperationData, logger *log.Logger) string {
+	switch task.StateType() {
+	case boshdirector.TaskIncomplete:
+		if !b.showTaskProgress {
+			return ""
+		}
+		return b.taskProgress(task, logger)
+	case boshdirector.TaskFailed:
+		// upgrade failures are described by their task ID for the upgrader
+		if len(b.failureMessagePatterns) == 0 || task.State == boshdirector.TaskCancelled || operationData.OperationType == OperationTypeUpgrade {
+			return ""
+		}
+		return strings.Join(b.failureSnippets(task, logger), "; ")
+	default:
+		return ""
+	}
+}
+
+func (b *Broker) taskProgress(task boshdirector.BoshTask, logger *log.Logger) string {
+	events, err := b.boshClient.GetTaskEvents(task.ID, logger)
+	if err != nil {
+		logger.Printf("error getting events of BOSH task %d: %s\n", task.ID, err)
+		return ""
+	}
+
+	stage, percent, found := events.CurrentStage()
+	if !found {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d%%)", stage, percent)
+}
+
+// failureSnippets returns the parts of the task's BOSH errors and, for
+// errands, of their output that match the failure message patterns.
+func (b *Broker) failureSnippets(task boshdirector.BoshTask, logger *log.Logger) []string {
+	events, err := b.boshClient.GetTaskEvents(task.ID, logger)
+	if err != nil {
+		logger.Printf("error getting events of BOSH task %d: %s\n", task.ID, err)
+	}
+	candidates := events.ErrorMessages()
+
+	if strings.HasPrefix(task.Description, errandTaskDescriptionPrefix) {
+		outputs, err := b.boshClient.GetTaskOutput(task.ID, logger)
+		if err != nil {
+			logger.Printf("error getting output of BOSH task %d: %s\n", task.ID, err)
+		}
+		for _, output := range outputs {
+			candidates = append(candidates, strings.Split(output.StdOut, "\n")...)
+			candidates = append(candidates, strings.Split(output.StdErr, "\n")...)
+		}
+	}
+
+	var snippets []string
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		for _, pattern := range b.failureMessagePatterns {
+			snippet := strings.TrimSpace(pattern.FindString(candidate))
+			if snippet != "" && !seen[snippet] {
+				seen[snippet] = true
+				snippets = append(snippets, snippet)
+			}
+		}
+	}
+	return snippets
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+)
+
+var _ = Describe("last operation details", func() {
+	const (
+		instanceID = "some-instance-id"
+		taskID     = 42
+	)
+
+	var (
+		operationData string
+		task          boshdirector.BoshTask
+		lastOperation brokerapi.LastOperation
+	)
+
+	stagedEvents := boshdirector.BoshTaskEvents{
+		{Stage: "Updating instance", Total: 2, Index: 1, State: "started"},
+		{Stage: "Updating instance", Total: 2, Index: 1, State: "finished"},
+		{Stage: "Updating instance", Total: 2, Index: 2, State: "started"},
+	}
+
+	BeforeEach(func() {
+		operationData = fmt.Sprintf(`{"BoshTaskID": %d, "OperationType": "create"}`, taskID)
+		boshClient.GetTaskEventsReturns(stagedEvents, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+		boshClient.GetTaskReturns(task, nil)
+
+		var err error
+		lastOperation, err = b.LastOperation(context.Background(), instanceID, operationData)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when the task is in progress", func() {
+		BeforeEach(func() {
+			task = boshdirector.BoshTask{ID: taskID, State: boshdirector.TaskProcessing}
+		})
+
+		It("does not read the task events by default", func() {
+			Expect(lastOperation.Description).To(Equal("Instance provisioning in progress"))
+			Expect(boshClient.GetTaskEventsCallCount()).To(Equal(0))
+		})
+
+		Context("and progress is shown", func() {
+			BeforeEach(func() {
+				lastOperationDetails = config.LastOperationDetails{ShowProgress: true}
+			})
+
+			It("describes the current stage and its progress", func() {
+				Expect(lastOperation.Description).To(Equal("Instance provisioning in progress: Updating instance (50%)"))
+				actualTaskID, _ := boshClient.GetTaskEventsArgsForCall(0)
+				Expect(actualTaskID).To(Equal(taskID))
+			})
+
+			Context("and the events cannot be read", func() {
+				BeforeEach(func() {
+					boshClient.GetTaskEventsReturns(nil, errors.New("bosh is down"))
+				})
+
+				It("falls back to the plain description", func() {
+					Expect(lastOperation.Description).To(Equal("Instance provisioning in progress"))
+					Expect(logBuffer.String()).To(ContainSubstring("error getting events of BOSH task %d: bosh is down", taskID))
+				})
+			})
+		})
+	})
+
+	Context("when the task has failed", func() {
+		BeforeEach(func() {
+			task = boshdirector.BoshTask{ID: taskID, State: boshdirector.TaskError}
+			boshClient.GetTaskEventsReturns(boshdirector.BoshTaskEvents{
+				{Error: &boshdirector.BoshTaskEventError{Message: "Timed out pinging to abc after 600 seconds"}},
+			}, nil)
+		})
+
+		It("only shows the generic error by default", func() {
+			Expect(lastOperation.Description).To(HavePrefix("Instance provisioning failed: " + broker.GenericErrorPrefix))
+			Expect(lastOperation.Description).NotTo(ContainSubstring("Timed out"))
+		})
+
+		Context("and failure messages are whitelisted", func() {
+			BeforeEach(func() {
+				lastOperationDetails = config.LastOperationDetails{
+					FailureMessagePatterns: []string{"Timed out pinging .*", "ODB-ERROR: .*"},
+				}
+			})
+
+			It("includes the matching BOSH errors", func() {
+				Expect(lastOperation.Description).To(HavePrefix(
+					"Instance provisioning failed: Timed out pinging to abc after 600 seconds. " + broker.GenericErrorPrefix,
+				))
+				Expect(boshClient.GetTaskOutputCallCount()).To(Equal(0))
+			})
+
+			Context("and the failed task is an errand", func() {
+				BeforeEach(func() {
+					task.Description = "run errand health-check from deployment service-instance_some-instance-id"
+					boshClient.GetTaskEventsReturns(nil, nil)
+					boshClient.GetTaskOutputReturns([]boshdirector.BoshTaskOutput{{
+						ExitCode: 1,
+						StdOut:   "checking redis\nODB-ERROR: maxmemory is too low for the dataset\n",
+						StdErr:   "secret password is hunter2",
+					}}, nil)
+				})
+
+				It("includes the matching lines of its output", func() {
+					Expect(lastOperation.Description).To(HavePrefix(
+						"Instance provisioning failed: ODB-ERROR: maxmemory is too low for the dataset. " + broker.GenericErrorPrefix,
+					))
+					Expect(lastOperation.Description).NotTo(ContainSubstring("hunter2"))
+				})
+			})
+
+			Context("and the operation is an upgrade", func() {
+				BeforeEach(func() {
+					operationData = fmt.Sprintf(`{"BoshTaskID": %d, "OperationType": "upgrade"}`, taskID)
+				})
+
+				It("keeps describing the failure by its task ID", func() {
+					Expect(lastOperation.Description).To(Equal(fmt.Sprintf("Failed for bosh task: %d", taskID)))
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,217 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+)
+
+var _ = Describe("org and space quotas", func() {
+	const (
+		instanceID = "some-instance-id"
+		orgGUID    = "some-org-guid"
+		spaceGUID  = "some-space-guid"
+	)
+
+	var (
+		orgLimit   = 2
+		spaceLimit = 1
+	)
+
+	BeforeEach(func() {
+		serviceCatalog.Plans[1].Quotas = config.Quotas{
+			Orgs:   &config.ScopeQuota{ServiceInstanceLimit: &orgLimit, Overrides: map[string]int{"big-org-guid": 10}},
+			Spaces: &config.ScopeQuota{ServiceInstanceLimit: &spaceLimit},
+		}
+		boshClient.GetDeploymentReturns(nil, false, nil)
+	})
+
+	JustBeforeEach(func() {
+		b = createDefaultBroker()
+	})
+
+	Describe("provisioning", func() {
+		var (
+			details      brokerapi.ProvisionDetails
+			provisionErr error
+		)
+
+		BeforeEach(func() {
+			details = brokerapi.ProvisionDetails{
+				PlanID:           secondPlanID,
+				ServiceID:        serviceOfferingID,
+				OrganizationGUID: orgGUID,
+				SpaceGUID:        spaceGUID,
+			}
+		})
+
+		JustBeforeEach(func() {
+			_, provisionErr = b.Provision(context.Background(), instanceID, details, true)
+		})
+
+		It("counts the instances of the plan in the org and the space", func() {
+			Expect(provisionErr).NotTo(HaveOccurred())
+			Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+
+			Expect(cfClient.CountInstancesOfPlanInOrgCallCount()).To(Equal(1))
+			actualServiceID, actualPlanID, actualOrgGUID, _ := cfClient.CountInstancesOfPlanInOrgArgsForCall(0)
+			Expect(actualServiceID).To(Equal(serviceOfferingID))
+			Expect(actualPlanID).To(Equal(secondPlanID))
+			Expect(actualOrgGUID).To(Equal(orgGUID))
+
+			Expect(cfClient.CountInstancesOfPlanInSpaceCallCount()).To(Equal(1))
+			_, _, actualSpaceGUID, _ := cfClient.CountInstancesOfPlanInSpaceArgsForCall(0)
+			Expect(actualSpaceGUID).To(Equal(spaceGUID))
+		})
+
+		Context("when the org quota has been reached", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfPlanInOrgReturns(orgLimit, nil)
+			})
+
+			It("rejects the provision naming the org", func() {
+				Expect(provisionErr).To(MatchError(broker.OrgQuotaExceededMessage))
+				Expect(logBuffer.String()).To(ContainSubstring("org quota exceeded for plan ID %s in org %s", secondPlanID, orgGUID))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the org has its own limit", func() {
+			BeforeEach(func() {
+				details.OrganizationGUID = "big-org-guid"
+				cfClient.CountInstancesOfPlanInOrgReturns(orgLimit, nil)
+			})
+
+			It("applies the org's limit instead of the default", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the space quota has been reached", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfPlanInSpaceReturns(spaceLimit, nil)
+			})
+
+			It("rejects the provision naming the space", func() {
+				Expect(provisionErr).To(MatchError(broker.SpaceQuotaExceededMessage))
+				Expect(logBuffer.String()).To(ContainSubstring("space quota exceeded for plan ID %s in space %s", secondPlanID, spaceGUID))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the request does not identify an org or a space", func() {
+			BeforeEach(func() {
+				details.OrganizationGUID = ""
+				details.SpaceGUID = ""
+			})
+
+			It("does not check the org and space quotas", func() {
+				Expect(provisionErr).NotTo(HaveOccurred())
+				Expect(cfClient.CountInstancesOfPlanInOrgCallCount()).To(Equal(0))
+				Expect(cfClient.CountInstancesOfPlanInSpaceCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the instances in the org cannot be counted", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfPlanInOrgReturns(0, errors.New("cc is down"))
+			})
+
+			It("returns a generic error", func() {
+				Expect(provisionErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+				Expect(logBuffer.String()).To(ContainSubstring("error counting instances of plan in org: cc is down"))
+				Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("updating", func() {
+		var (
+			details   brokerapi.UpdateDetails
+			updateErr error
+		)
+
+		BeforeEach(func() {
+			details = brokerapi.UpdateDetails{
+				PlanID:    secondPlanID,
+				ServiceID: serviceOfferingID,
+				PreviousValues: brokerapi.PreviousValues{
+					PlanID:  existingPlanID,
+					OrgID:   orgGUID,
+					SpaceID: spaceGUID,
+				},
+			}
+			cfClient.CountInstancesOfPlanInSpaceReturns(spaceLimit, nil)
+		})
+
+		JustBeforeEach(func() {
+			_, updateErr = b.Update(context.Background(), instanceID, details, true)
+		})
+
+		Context("when changing to a plan whose space quota has been reached", func() {
+			It("rejects the update naming the space", func() {
+				Expect(updateErr).To(MatchError(broker.SpaceQuotaExceededMessage))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the plan does not change", func() {
+			BeforeEach(func() {
+				details.PreviousValues.PlanID = secondPlanID
+			})
+
+			It("does not check the org and space quotas", func() {
+				Expect(updateErr).NotTo(HaveOccurred())
+				Expect(cfClient.CountInstancesOfPlanInOrgCallCount()).To(Equal(0))
+				Expect(cfClient.CountInstancesOfPlanInSpaceCallCount()).To(Equal(0))
+				Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("counting instances by org", func() {
+		BeforeEach(func() {
+			cfClient.CountInstancesOfServiceOfferingByOrgStub = func(serviceID string, _ *log.Logger) (map[cf.ServicePlan]map[string]int, error) {
+				return map[cf.ServicePlan]map[string]int{
+					cfServicePlan("1234", secondPlanID, "url", "second-plan"): {orgGUID: 2},
+				}, nil
+			}
+		})
+
+		It("returns the counts of every offering", func() {
+			counts, err := b.CountInstancesOfPlansByOrg(loggerFactory.New())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counts).To(Equal(map[cf.ServicePlan]map[string]int{
+				cfServicePlan("1234", secondPlanID, "url", "second-plan"): {orgGUID: 2},
+			}))
+			serviceID, _ := cfClient.CountInstancesOfServiceOfferingByOrgArgsForCall(0)
+			Expect(serviceID).To(Equal(serviceOfferingID))
+		})
+
+		Context("when counting fails", func() {
+			BeforeEach(func() {
+				cfClient.CountInstancesOfServiceOfferingByOrgStub = nil
+				cfClient.CountInstancesOfServiceOfferingByOrgReturns(nil, errors.New("cc is down"))
+			})
+
+			It("returns the error", func() {
+				_, err := b.CountInstancesOfPlansByOrg(loggerFactory.New())
+				Expect(err).To(MatchError("cc is down"))
+			})
+		})
+	})
+})
@@ -14,6 +14,7 @@ import (
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 )
 
 func (b *Broker) Unbind(
@@ -22,9 +23,12 @@ func (b *Broker) Unbind(
 	bindingID string,
 	details brokerapi.UnbindDetails,
 ) error {
+	if _, plan, found := b.offeringForPlan(details.PlanID); found && plan.BindingErrand != "" {
+		return bindingAsyncRequiredError()
+	}
 
 	requestID := uuid.New()
-	ctx = brokercontext.New(ctx, string(OperationTypeUnbind), requestID, b.serviceOffering.Name, instanceID)
+	ctx = brokercontext.New(ctx, string(OperationTypeUnbind), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
 	logger := b.loggerFactory.NewWithContext(ctx)
 
 	errs := func(err DisplayableError) error {
@@ -32,6 +36,14 @@ func (b *Broker) Unbind(
 		return err.ErrorForCFUser()
 	}
 
+	offering, found := b.offeringForRequest(details.ServiceID, details.PlanID)
+	if !found {
+		return errs(NewDisplayableError(
+			fmt.Errorf("plan %s not found", details.PlanID),
+			fmt.Errorf("error unbinding: no service offering for service %s and plan %s", details.ServiceID, details.PlanID),
+		))
+	}
+
 	vms, manifest, err := b.getDeploymentInfo(instanceID, logger)
 	switch err.(type) {
 	case boshdirector.RequestError:
@@ -42,18 +54,33 @@ func (b *Broker) Unbind(
 		return errs(NewGenericError(ctx, fmt.Errorf("gathering unbinding info %s", err)))
 	}
 
+	binding, found, err := b.bindingStore.Get(instanceID, bindingID)
+	if err != nil {
+		return errs(NewGenericError(ctx, fmt.Errorf("getting binding from binding store: %s", err)))
+	}
+	if !found {
+		logger.Printf("binding %s is not in the binding store, the service adapter will not be given its credentials\n", bindingID)
+	}
+
 	requestParams := map[string]interface{}{
 		"plan_id":    details.PlanID,
 		"service_id": details.ServiceID,
 	}
 
 	logger.Printf("service adapter will delete binding with ID %s for instance %s\n", bindingID, instanceID)
-	err = b.adapterClient.DeleteBinding(bindingID, vms, manifest, requestParams, logger)
+	err = offering.AdapterClient.DeleteBinding(ctx, bindingID, vms, manifest, requestParams, binding.Credentials, logger)
 
 	if err != nil {
 		logger.Printf("delete binding: %v\n", err)
 	}
 
+	switch err.(type) {
+	case nil, serviceadapter.BindingNotFoundError:
+		if err := b.bindingStore.Delete(instanceID, bindingID); err != nil {
+			logger.Printf("error removing binding %s from binding store: %s\n", bindingID, err)
+		}
+	}
+
 	if err := adapterToAPIError(ctx, err); err != nil {
 		return err
 	}
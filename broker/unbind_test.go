@@ -14,6 +14,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
@@ -51,17 +52,74 @@ var _ = Describe("Unbind", func() {
 
 	It("destroys the binding using the bosh topology and admin credentials", func() {
 		Expect(serviceAdapter.DeleteBindingCallCount()).To(Equal(1))
-		passedBindingID, passedVms, passedManifest, passedRequestParams, _ := serviceAdapter.DeleteBindingArgsForCall(0)
+		_, passedBindingID, passedVms, passedManifest, passedRequestParams, passedCredentials, _ := serviceAdapter.DeleteBindingArgsForCall(0)
 		Expect(passedBindingID).To(Equal(bindingID))
 		Expect(passedVms).To(Equal(boshVms))
 		Expect(passedManifest).To(Equal(actualManifest))
 		Expect(passedRequestParams).To(Equal(map[string]interface{}{"service_id": serviceID, "plan_id": planID}))
+		Expect(passedCredentials).To(BeNil())
 	})
 
 	It("does not error", func() {
 		Expect(unbindErr).NotTo(HaveOccurred())
 	})
 
+	It("removes the binding from the binding store", func() {
+		Expect(fakeBindingStore.DeleteCallCount()).To(Equal(1))
+		actualInstanceID, actualBindingID := fakeBindingStore.DeleteArgsForCall(0)
+		Expect(actualInstanceID).To(Equal(instanceID))
+		Expect(actualBindingID).To(Equal(bindingID))
+	})
+
+	Context("when the binding was stored when it was created", func() {
+		BeforeEach(func() {
+			fakeBindingStore.GetReturns(bindingstore.Binding{
+				InstanceID:  instanceID,
+				BindingID:   bindingID,
+				Created:     true,
+				Credentials: map[string]interface{}{"username": "some-user"},
+			}, true, nil)
+		})
+
+		It("passes the original credentials to the service adapter", func() {
+			_, _, _, _, _, passedCredentials, _ := serviceAdapter.DeleteBindingArgsForCall(0)
+			Expect(passedCredentials).To(Equal(map[string]interface{}{"username": "some-user"}))
+		})
+	})
+
+	Context("when bindings are not kept, as no binding store is configured", func() {
+		BeforeEach(func() {
+			store := bindingstore.DiscardStore{}
+			fakeBindingStore.GetStub = store.Get
+			fakeBindingStore.DeleteStub = store.Delete
+		})
+
+		It("deletes the binding without passing credentials to the service adapter", func() {
+			Expect(unbindErr).NotTo(HaveOccurred())
+			Expect(serviceAdapter.DeleteBindingCallCount()).To(Equal(1))
+			_, _, _, _, _, passedCredentials, _ := serviceAdapter.DeleteBindingArgsForCall(0)
+			Expect(passedCredentials).To(BeNil())
+		})
+
+		It("logs that the service adapter is not given the credentials", func() {
+			Expect(logBuffer.String()).To(ContainSubstring(
+				fmt.Sprintf("binding %s is not in the binding store, the service adapter will not be given its credentials", bindingID),
+			))
+		})
+	})
+
+	Context("when the binding store cannot be read", func() {
+		BeforeEach(func() {
+			fakeBindingStore.GetReturns(bindingstore.Binding{}, false, errors.New("corrupt store"))
+		})
+
+		It("returns a generic error without deleting the binding", func() {
+			Expect(unbindErr).To(MatchError(ContainSubstring(broker.GenericErrorPrefix)))
+			Expect(logBuffer.String()).To(ContainSubstring("getting binding from binding store: corrupt store"))
+			Expect(serviceAdapter.DeleteBindingCallCount()).To(Equal(0))
+		})
+	})
+
 	It("logs using a request ID", func() {
 		Expect(logBuffer.String()).To(MatchRegexp(fmt.Sprintf(`\[[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\] \d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} service adapter will delete binding with ID %s for instance %s`, bindingID, instanceID)))
 	})
@@ -228,5 +286,9 @@ var _ = Describe("Unbind", func() {
 		It("returns a binding not found error", func() {
 			Expect(unbindErr).To(Equal(brokerapi.ErrBindingDoesNotExist))
 		})
+
+		It("removes the binding from the binding store", func() {
+			Expect(fakeBindingStore.DeleteCallCount()).To(Equal(1))
+		})
 	})
 })
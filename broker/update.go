@@ -11,11 +11,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/pborman/uuid"
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 	"github.com/pivotal-cf/on-demand-service-broker/task"
 )
@@ -26,11 +28,8 @@ func (b *Broker) Update(
 	details brokerapi.UpdateDetails,
 	asyncAllowed bool,
 ) (brokerapi.UpdateServiceSpec, error) {
-	b.deploymentLock.Lock()
-	defer b.deploymentLock.Unlock()
-
 	requestID := uuid.New()
-	ctx = brokercontext.New(ctx, string(OperationTypeUpdate), requestID, b.serviceOffering.Name, instanceID)
+	ctx = brokercontext.New(ctx, string(OperationTypeUpdate), requestID, b.serviceName(details.ServiceID, details.PlanID), instanceID)
 	logger := b.loggerFactory.NewWithContext(ctx)
 
 	if !asyncAllowed {
@@ -42,41 +41,85 @@ func (b *Broker) Update(
 		return brokerapi.UpdateServiceSpec{IsAsync: true}, err.ErrorForCFUser()
 	}
 
-	plan, found := b.serviceOffering.FindPlanByID(details.PlanID)
+	unlock, locked := b.instanceLocker.Lock(ctx, instanceID)
+	if !locked {
+		return errs(newInstanceLockedError(instanceID))
+	}
+	defer unlock()
+
+	offering, plan, found := b.offeringForPlan(details.PlanID)
 	if !found {
 		message := fmt.Sprintf("Plan %s not found", details.PlanID)
 		logger.Println(message)
 		return brokerapi.UpdateServiceSpec{IsAsync: true}, errors.New(message)
 	}
 
-	if details.PreviousValues.PlanID != plan.ID {
-		if err := b.validatePlanQuota(ctx, details.ServiceID, plan, logger); err != NilError {
-			return errs(err)
-		}
+	if previousOffering, _, found := b.offeringForPlan(details.PreviousValues.PlanID); found && previousOffering.Catalog.ID != offering.Catalog.ID {
+		message := fmt.Sprintf("Plan %s does not belong to service %s", details.PlanID, previousOffering.Catalog.Name)
+		logger.Println(message)
+		return brokerapi.UpdateServiceSpec{IsAsync: true}, errors.New(message)
+	}
+
+	if dashboardURLResyncRequested(details) {
+		return b.resyncDashboardURL(ctx, instanceID, offering, plan, logger)
+	}
+
+	if details.MaintenanceInfo != nil && details.MaintenanceInfo.Version != offering.MaintenanceVersion {
+		logger.Printf("maintenance_info version %q does not match catalog version %q", details.MaintenanceInfo.Version, offering.MaintenanceVersion)
+		return brokerapi.UpdateServiceSpec{IsAsync: true}, brokerapi.NewFailureResponse(
+			errors.New(MaintenanceInfoConflictMessage),
+			http.StatusUnprocessableEntity,
+			UpdateLoggerAction,
+		)
 	}
 
-	logger.Printf("updating instance %s", instanceID)
 	detailsWithRawParameters := brokerapi.DetailsWithRawParameters(details)
 	detailsMap, err := convertDetailsToMap(detailsWithRawParameters)
 	if err != nil {
 		return errs(NewGenericError(ctx, err))
 	}
 
-	var boshContextID string
-	var operationPostDeployErrandName string
-	if plan.PostDeployErrand() != "" {
-		boshContextID = uuid.New()
-		operationPostDeployErrandName = plan.PostDeployErrand()
+	ctx = brokercontext.WithTenant(ctx, brokercontext.TenantFromRequestParams(detailsMap))
+	logger = b.loggerFactory.NewWithContext(ctx)
+
+	planChanged := details.PreviousValues.PlanID != plan.ID
+	var transition config.PlanTransition
+	if planChanged {
+		var transitionErr DisplayableError
+		transition, transitionErr = validatePlanTransition(offering, details.PreviousValues.PlanID, plan, detailsMap)
+		if transitionErr != NilError {
+			return errs(transitionErr)
+		}
 	}
 
-	boshTaskID, _, err := b.deployer.Update(
-		deploymentName(instanceID),
-		details.PlanID,
-		detailsMap,
-		&details.PreviousValues.PlanID,
-		boshContextID,
-		logger,
+	if err := validateParameters(b.planSchemas[plan.ID].instanceUpdate, detailsMap); err != NilError {
+		return errs(err)
+	}
+
+	if planChanged {
+		if err := b.reserveQuota(ctx, instanceID, offering, plan, details.PreviousValues.PlanID, logger); err != NilError {
+			return errs(err)
+		}
+	}
+
+	var (
+		operationData  OperationData
+		manifest       []byte
+		deployedParams = detailsMap
+		previousPlanID = details.PreviousValues.PlanID
 	)
+	if maintenanceUpgradeRequested(details, detailsMap) {
+		logger.Printf("upgrading instance %s to maintenance_info version %s", instanceID, details.MaintenanceInfo.Version)
+		operationData, manifest, err = b.upgradeDeployment(ctx, instanceID, offering, plan, logger)
+		deployedParams, previousPlanID = nil, plan.ID
+	} else {
+		logger.Printf("updating instance %s", instanceID)
+		operationData, manifest, err = b.updateDeployment(ctx, instanceID, offering, plan, detailsMap, details.PreviousValues.PlanID, transition.Errands, logger)
+	}
+
+	if err != nil && planChanged {
+		b.releaseQuota(instanceID, logger)
+	}
 
 	switch err := err.(type) {
 	case task.ServiceError:
@@ -92,20 +135,118 @@ func (b *Broker) Update(
 	case task.PlanNotFoundError:
 		return brokerapi.UpdateServiceSpec{IsAsync: true}, err
 	case serviceadapter.UnknownFailureError:
+		if err.Structured() {
+			return errs(NewAdapterError(ctx, err))
+		}
+		return brokerapi.UpdateServiceSpec{IsAsync: true}, adapterToAPIError(ctx, err)
+	case serviceadapter.TimeoutError:
 		return brokerapi.UpdateServiceSpec{IsAsync: true}, adapterToAPIError(ctx, err)
 	case error:
 		return errs(NewGenericError(ctx, fmt.Errorf("error deploying instance: %s", err)))
 	}
 
-	operationData, err := json.Marshal(OperationData{
-		BoshTaskID:           boshTaskID,
-		OperationType:        OperationTypeUpdate,
-		BoshContextID:        boshContextID,
-		PostDeployErrandName: operationPostDeployErrandName,
-	})
+	operationDataJSON, err := json.Marshal(operationData)
+	if err != nil {
+		return errs(NewGenericError(brokercontext.WithBoshTaskID(ctx, operationData.BoshTaskID), err))
+	}
+
+	b.recordOperationStarted(ctx, instanceID, details.PlanID, detailsMap, operationData, logger)
+
+	return brokerapi.UpdateServiceSpec{
+		IsAsync:       true,
+		DashboardURL:  b.regenerateDashboardURL(ctx, instanceID, offering, plan, manifest, deployedParams, previousPlanID, logger),
+		OperationData: string(operationDataJSON),
+	}, nil
+}
+
+func (b *Broker) updateDeployment(
+	ctx context.Context,
+	instanceID string,
+	offering ServiceOffering,
+	plan config.Plan,
+	requestParams map[string]interface{},
+	previousPlanID string,
+	transitionErrands config.Errands,
+	logger *log.Logger,
+) (OperationData, []byte, error) {
+	operationData := OperationData{
+		OperationType: OperationTypeUpdate,
+		PreErrands:    concatErrands(plan.PreDeployErrands(), transitionErrands),
+		PostErrands:   plan.PostDeployErrands(),
+	}
+	if operationData.hasLifecycleErrands() {
+		operationData.BoshContextID = uuid.New()
+	}
+
+	deferredOperationData := operationData
+	deferredOperationData.PlanID = plan.ID
+	deferredOperationData.PreviousPlanID = previousPlanID
+	deferredOperationData.RequestParams = requestParams
+
+	queuedOperationData, queued, release, err := b.queueIfThrottled(ctx, instanceID, deferredOperationData, logger)
+	if err != nil || queued {
+		return queuedOperationData, nil, err
+	}
+	defer release()
+
+	if len(operationData.PreErrands) > 0 {
+		if err := b.recordDeferredRequest(ctx, instanceID, deferredOperationData); err != nil {
+			return OperationData{}, nil, err
+		}
+		operationData, err := b.runFirstPreErrand(instanceID, deferredOperationData, logger)
+		return operationData, nil, err
+	}
+
+	boshTaskID, manifest, err := offering.Deployer.Update(
+		ctx,
+		b.deploymentName(instanceID),
+		plan.ID,
+		requestParams,
+		&previousPlanID,
+		operationData.BoshContextID,
+		logger,
+	)
 	if err != nil {
-		return errs(NewGenericError(brokercontext.WithBoshTaskID(ctx, boshTaskID), err))
+		return OperationData{}, nil, err
+	}
+
+	operationData.BoshTaskID = boshTaskID
+	return operationData, manifest, nil
+}
+
+// runFirstPreErrand starts an operation that has errands to run before its
+// deploy or delete, which LifeCycleRunner starts once they have finished.
+func (b *Broker) runFirstPreErrand(instanceID string, operationData OperationData, logger *log.Logger) (OperationData, error) {
+	errand := operationData.PreErrands[0]
+	logger.Printf("running errand %s before the %s of instance %s\n", errand.Name, operationData.OperationType, instanceID)
+
+	taskID, err := b.boshClient.RunErrand(
+		b.deploymentName(instanceID),
+		errand.Name,
+		errand.Instances,
+		operationData.BoshContextID,
+		logger,
+	)
+	if err != nil {
+		return OperationData{}, err
+	}
+
+	operationData.BoshTaskID = taskID
+	return operationData, nil
+}
+
+// maintenanceUpgradeRequested reports whether the platform is only asking for
+// the instance to be brought up to a new maintenance_info version, which is
+// handled the same way as an operator-triggered upgrade.
+func maintenanceUpgradeRequested(details brokerapi.UpdateDetails, requestParams map[string]interface{}) bool {
+	if details.MaintenanceInfo == nil || details.PlanID != details.PreviousValues.PlanID {
+		return false
+	}
+
+	if len(arbitraryParams(requestParams)) > 0 {
+		return false
 	}
 
-	return brokerapi.UpdateServiceSpec{IsAsync: true, OperationData: string(operationData)}, nil
+	previous := details.PreviousValues.MaintenanceInfo
+	return previous == nil || previous.Version != details.MaintenanceInfo.Version
 }
@@ -17,8 +17,11 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 	"github.com/pivotal-cf/on-demand-service-broker/task"
+	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
 
 var _ = Describe("Update", func() {
@@ -85,13 +88,13 @@ var _ = Describe("Update", func() {
 				It("counts the instances of the plan in Cloud Controller", func() {
 					Expect(cfClient.CountInstancesOfPlanCallCount()).To(Equal(1))
 					actualServiceOfferingID, actualPlanID, _ := cfClient.CountInstancesOfPlanArgsForCall(0)
-					Expect(actualServiceOfferingID).To(Equal(serviceID))
+					Expect(actualServiceOfferingID).To(Equal(serviceOfferingID))
 					Expect(actualPlanID).To(Equal(newPlanID))
 				})
 
 				It("calls the deployer without a bosh context id", func() {
 					Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
-					_, _, _, _, actualBoshContextID, _ := fakeDeployer.UpdateArgsForCall(0)
+					_, _, _, _, _, actualBoshContextID, _ := fakeDeployer.UpdateArgsForCall(0)
 					Expect(actualBoshContextID).To(BeEmpty())
 				})
 
@@ -104,8 +107,8 @@ var _ = Describe("Update", func() {
 					Expect(data).To(Equal(broker.OperationData{BoshTaskID: boshTaskID, OperationType: broker.OperationTypeUpdate}))
 				})
 
-				It("logs with a request ID", func() {
-					Expect(logBuffer.String()).To(MatchRegexp(`\[[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\] \d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} updating instance`))
+				It("logs with a request ID and the tenant", func() {
+					Expect(logBuffer.String()).To(MatchRegexp(`\[[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\] \[organization_guid=organizationGUID space_guid=spaceGUID\] \d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} updating instance`))
 				})
 			})
 
@@ -161,12 +164,12 @@ var _ = Describe("Update", func() {
 					data := unmarshalOperationData(updateSpec)
 					Expect(data.OperationType).To(Equal(broker.OperationTypeUpdate))
 					Expect(data.BoshContextID).NotTo(BeEmpty())
-					Expect(data.PostDeployErrandName).To(Equal("health-check"))
+					Expect(data.PostErrands).To(Equal(config.Errands{{Name: "health-check"}}))
 				})
 
 				It("calls the deployer with a bosh context id", func() {
 					Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
-					_, _, _, _, actualBoshContextID, _ := fakeDeployer.UpdateArgsForCall(0)
+					_, _, _, _, _, actualBoshContextID, _ := fakeDeployer.UpdateArgsForCall(0)
 					Expect(actualBoshContextID).NotTo(BeEmpty())
 				})
 			})
@@ -212,6 +215,20 @@ var _ = Describe("Update", func() {
 						data := unmarshalOperationData(updateSpec)
 						Expect(data).To(Equal(broker.OperationData{BoshTaskID: boshTaskID, OperationType: broker.OperationTypeUpdate}))
 					})
+
+					It("records the operation in the operation store", func() {
+						Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(1))
+						actualInstanceID, operation := fakeOperationStore.StartOperationArgsForCall(0)
+						Expect(actualInstanceID).To(Equal(instanceID))
+						Expect(operation.RequestID).NotTo(BeEmpty())
+						operation.RequestID = ""
+						Expect(operation).To(Equal(operationstore.Operation{
+							Type:        "update",
+							PlanID:      newPlanID,
+							Parameters:  arbitraryParams,
+							BoshTaskIDs: []int{boshTaskID},
+						}))
+					})
 				})
 
 				Context("and the plan's quota has been met", func() {
@@ -273,12 +290,12 @@ var _ = Describe("Update", func() {
 						data := unmarshalOperationData(updateSpec)
 						Expect(data.OperationType).To(Equal(broker.OperationTypeUpdate))
 						Expect(data.BoshContextID).NotTo(BeEmpty())
-						Expect(data.PostDeployErrandName).To(Equal("health-check"))
+						Expect(data.PostErrands).To(Equal(config.Errands{{Name: "health-check"}}))
 					})
 
 					It("calls the deployer with a bosh context id", func() {
 						Expect(fakeDeployer.UpdateCallCount()).To(Equal(1))
-						_, _, _, _, actualBoshContextID, _ := fakeDeployer.UpdateArgsForCall(0)
+						_, _, _, _, _, actualBoshContextID, _ := fakeDeployer.UpdateArgsForCall(0)
 						Expect(actualBoshContextID).NotTo(BeEmpty())
 					})
 				})
@@ -384,6 +401,58 @@ var _ = Describe("Update", func() {
 			})
 		})
 
+		Context("when the adapter client fails with a structured error for a bad parameter", func() {
+			BeforeEach(func() {
+				fakeDeployer.UpdateReturns(boshTaskID, nil, serviceadapter.ErrorForExitCode(
+					sdk.ErrorExitCode,
+					`{"user_message": "replicas must be 3 or fewer", "operator_message": "plan allows 3 replicas, 5 requested", "error_code": "bad_parameter"}`,
+				))
+			})
+
+			It("returns the user message with a bad request status", func() {
+				Expect(updateError).To(Equal(brokerapi.NewFailureResponse(
+					errors.New("replicas must be 3 or fewer"),
+					http.StatusBadRequest,
+					broker.AdapterErrorLoggerAction,
+				)))
+			})
+
+			It("logs the operator message", func() {
+				Expect(logBuffer.String()).To(ContainSubstring("plan allows 3 replicas, 5 requested"))
+			})
+		})
+
+		Context("when the adapter client fails with a structured internal error", func() {
+			BeforeEach(func() {
+				fakeDeployer.UpdateReturns(boshTaskID, nil, serviceadapter.ErrorForExitCode(
+					sdk.ErrorExitCode,
+					`{"user_message": "the service could not be updated", "operator_message": "nil pointer dereference", "error_code": "internal"}`,
+				))
+			})
+
+			It("returns the user message with an internal server error status", func() {
+				Expect(updateError).To(Equal(brokerapi.NewFailureResponse(
+					errors.New("the service could not be updated"),
+					http.StatusInternalServerError,
+					broker.AdapterErrorLoggerAction,
+				)))
+			})
+
+			It("logs the operator message", func() {
+				Expect(logBuffer.String()).To(ContainSubstring("nil pointer dereference"))
+			})
+		})
+
+		Context("when the adapter client times out", func() {
+			BeforeEach(func() {
+				fakeDeployer.UpdateReturns(boshTaskID, nil, serviceadapter.NewTimeoutError("stopped after running generate-manifest for longer than 5m0s"))
+			})
+
+			It("tells the user the service took too long", func() {
+				Expect(updateError).To(MatchError(broker.ServiceAdapterTimeoutMessage))
+			})
+		})
+
 		Context("when bosh is blocked", func() {
 			BeforeEach(func() {
 				fakeDeployer.UpdateReturns(boshTaskID, nil, task.TaskInProgressError{})
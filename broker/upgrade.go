@@ -12,65 +12,109 @@ import (
 	"log"
 
 	"github.com/pborman/uuid"
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 	"github.com/pivotal-cf/on-demand-service-broker/task"
 )
 
-func (b *Broker) Upgrade(ctx context.Context, instanceID string, logger *log.Logger) (OperationData, error) {
-	b.deploymentLock.Lock()
-	defer b.deploymentLock.Unlock()
+// Upgrade redeploys an instance and returns the dashboard URL generated from
+// its new manifest.
+func (b *Broker) Upgrade(ctx context.Context, instanceID string, logger *log.Logger) (OperationData, string, error) {
+	unlock, locked := b.instanceLocker.Lock(ctx, instanceID)
+	if !locked {
+		err := NewOperationInProgressError(fmt.Errorf("broker: operation in progress for instance %s", instanceID))
+		logger.Printf("error upgrading instance %s: %s", instanceID, err)
+		return OperationData{}, "", err
+	}
+	defer unlock()
 
 	instance, err := b.cfClient.GetInstanceState(instanceID, logger)
 	if err != nil {
-		return OperationData{}, err
+		return OperationData{}, "", err
 	}
 
 	if instance.OperationInProgress {
-		return OperationData{}, NewOperationInProgressError(fmt.Errorf("cloud controller: operation in progress for instance %s", instanceID))
+		return OperationData{}, "", NewOperationInProgressError(fmt.Errorf("cloud controller: operation in progress for instance %s", instanceID))
 	}
 
 	logger.Printf("upgrading instance %s", instanceID)
 
-	plan, found := b.serviceOffering.FindPlanByID(instance.PlanID)
+	offering, plan, found := b.offeringForPlan(instance.PlanID)
 	if !found {
 		logger.Printf("error: finding plan ID %s", instance.PlanID)
-		return OperationData{}, fmt.Errorf("plan %s not found", instance.PlanID)
-	}
-
-	var boshContextID string
-	var operationPostDeployErrand string
-	if plan.LifecycleErrands != nil {
-		boshContextID = uuid.New()
-		operationPostDeployErrand = plan.PostDeployErrand()
+		return OperationData{}, "", fmt.Errorf("plan %s not found", instance.PlanID)
 	}
+	ctx = brokercontext.WithServiceName(ctx, offering.Catalog.Name)
 
-	taskID, _, err := b.deployer.Upgrade(
-		deploymentName(instanceID),
-		instance.PlanID,
-		&instance.PlanID,
-		boshContextID,
-		logger,
-	)
-
+	operationData, manifest, err := b.upgradeDeployment(ctx, instanceID, offering, plan, logger)
 	if err != nil {
 		logger.Printf("error upgrading instance %s: %s", instanceID, err)
 
 		switch err := err.(type) {
 		case DisplayableError:
-			return OperationData{}, err.ErrorForCFUser()
-		case serviceadapter.UnknownFailureError:
-			return OperationData{}, adapterToAPIError(ctx, err)
+			return OperationData{}, "", err.ErrorForCFUser()
+		case serviceadapter.UnknownFailureError, serviceadapter.TimeoutError:
+			return OperationData{}, "", adapterToAPIError(ctx, err)
 		case task.TaskInProgressError:
-			return OperationData{}, NewOperationInProgressError(err)
+			return OperationData{}, "", NewOperationInProgressError(err)
 		default:
-			return OperationData{}, err
+			return OperationData{}, "", err
 		}
 	}
 
-	return OperationData{
-		BoshContextID:        boshContextID,
-		BoshTaskID:           taskID,
-		PostDeployErrandName: operationPostDeployErrand,
-		OperationType:        OperationTypeUpgrade,
-	}, nil
+	b.recordOperationStarted(ctx, instanceID, instance.PlanID, nil, operationData, logger)
+
+	return operationData, b.regenerateDashboardURL(ctx, instanceID, offering, plan, manifest, nil, plan.ID, logger), nil
+}
+
+// upgradeDeployment redeploys an instance on its current plan with the
+// releases and stemcell the broker is now configured with.
+func (b *Broker) upgradeDeployment(ctx context.Context, instanceID string, offering ServiceOffering, plan config.Plan, logger *log.Logger) (OperationData, []byte, error) {
+	// the upgrade errands run outside those that run around every deploy
+	operationData := OperationData{
+		OperationType: OperationTypeUpgrade,
+		PreErrands:    concatErrands(plan.PreUpgradeErrands(), plan.PreDeployErrands()),
+		PostErrands:   concatErrands(plan.PostDeployErrands(), plan.PostUpgradeErrands()),
+	}
+	if operationData.hasLifecycleErrands() {
+		operationData.BoshContextID = uuid.New()
+	}
+
+	deferredOperationData := operationData
+	deferredOperationData.PlanID = plan.ID
+
+	queuedOperationData, queued, release, err := b.queueIfThrottled(ctx, instanceID, deferredOperationData, logger)
+	if err != nil || queued {
+		return queuedOperationData, nil, err
+	}
+	defer release()
+
+	if len(operationData.PreErrands) > 0 {
+		operationData, err := b.runFirstPreErrand(instanceID, deferredOperationData, logger)
+		return operationData, nil, err
+	}
+
+	taskID, manifest, err := offering.Deployer.Upgrade(
+		ctx,
+		b.deploymentName(instanceID),
+		plan.ID,
+		&plan.ID,
+		operationData.BoshContextID,
+		logger,
+	)
+	if err != nil {
+		return OperationData{}, nil, err
+	}
+
+	operationData.BoshTaskID = taskID
+	return operationData, manifest, nil
+}
+
+func concatErrands(lists ...config.Errands) config.Errands {
+	var errands config.Errands
+	for _, list := range lists {
+		errands = append(errands, list...)
+	}
+	return errands
 }
@@ -16,6 +16,8 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 	"github.com/pivotal-cf/on-demand-service-broker/task"
 )
@@ -41,7 +43,7 @@ var _ = Describe("Upgrade", func() {
 	JustBeforeEach(func() {
 		logger = loggerFactory.NewWithRequestID()
 		b = createDefaultBroker()
-		upgradeOperationData, redeployErr = b.Upgrade(context.Background(), instanceID, logger)
+		upgradeOperationData, _, redeployErr = b.Upgrade(context.Background(), instanceID, logger)
 	})
 
 	Context("when the deployment goes well", func() {
@@ -58,7 +60,7 @@ var _ = Describe("Upgrade", func() {
 			Expect(fakeDeployer.CreateCallCount()).To(Equal(0))
 			Expect(fakeDeployer.UpgradeCallCount()).To(Equal(1))
 			Expect(fakeDeployer.UpdateCallCount()).To(Equal(0))
-			actualDeploymentName, actualPlanID, actualPreviousPlanID, actualBoshContextID, _ := fakeDeployer.UpgradeArgsForCall(0)
+			_, actualDeploymentName, actualPlanID, actualPreviousPlanID, actualBoshContextID, _ := fakeDeployer.UpgradeArgsForCall(0)
 			Expect(actualPlanID).To(Equal(existingPlanID))
 			Expect(actualDeploymentName).To(Equal(broker.InstancePrefix + instanceID))
 			oldPlanIDCopy := existingPlanID
@@ -89,6 +91,17 @@ var _ = Describe("Upgrade", func() {
 			Expect(upgradeOperationData.OperationType).To(Equal(broker.OperationTypeUpgrade))
 		})
 
+		It("records the upgrade in the operation store", func() {
+			Expect(fakeOperationStore.StartOperationCallCount()).To(Equal(1))
+			actualInstanceID, operation := fakeOperationStore.StartOperationArgsForCall(0)
+			Expect(actualInstanceID).To(Equal(instanceID))
+			Expect(operation).To(Equal(operationstore.Operation{
+				Type:        "upgrade",
+				PlanID:      existingPlanID,
+				BoshTaskIDs: []int{boshTaskID},
+			}))
+		})
+
 		It("fetches correct instance", func() {
 			Expect(cfClient.GetInstanceStateCallCount()).To(Equal(1))
 			actualInstanceID, _ := cfClient.GetInstanceStateArgsForCall(0)
@@ -115,15 +128,15 @@ var _ = Describe("Upgrade", func() {
 			})
 
 			It("deploys with a context id", func() {
-				_, _, _, contextID, _ := fakeDeployer.UpgradeArgsForCall(0)
+				_, _, _, _, contextID, _ := fakeDeployer.UpgradeArgsForCall(0)
 				Expect(contextID).NotTo(BeEmpty())
 				Expect(upgradeOperationData.BoshContextID).NotTo(BeEmpty())
 				Expect(upgradeOperationData).To(Equal(
 					broker.OperationData{
-						BoshTaskID:           boshTaskID,
-						PostDeployErrandName: "health-check",
-						OperationType:        broker.OperationTypeUpgrade,
-						BoshContextID:        upgradeOperationData.BoshContextID,
+						BoshTaskID:    boshTaskID,
+						PostErrands:   config.Errands{{Name: "health-check"}},
+						OperationType: broker.OperationTypeUpgrade,
+						BoshContextID: upgradeOperationData.BoshContextID,
 					},
 				))
 			})
@@ -16,8 +16,44 @@ const (
 	serviceNameKey correlationIDType = iota
 	instanceIDKey  correlationIDType = iota
 	boshTaskIDKey  correlationIDType = iota
+	tenantKey      correlationIDType = iota
 )
 
+// Tenant identifies the platform, organization and space a service instance
+// belongs to.
+type Tenant struct {
+	Platform         string
+	OrganizationGUID string
+	SpaceGUID        string
+}
+
+func (t Tenant) IsEmpty() bool {
+	return t == Tenant{}
+}
+
+// TenantFromRequestParams reads the tenant from a provision or update request
+// body. The platform context is preferred over the deprecated top-level and
+// previous_values fields.
+func TenantFromRequestParams(requestParams map[string]interface{}) Tenant {
+	platformContext, _ := requestParams["context"].(map[string]interface{})
+	previousValues, _ := requestParams["previous_values"].(map[string]interface{})
+
+	return Tenant{
+		Platform:         firstString(platformContext["platform"]),
+		OrganizationGUID: firstString(platformContext["organization_guid"], requestParams["organization_guid"], previousValues["organization_id"]),
+		SpaceGUID:        firstString(platformContext["space_guid"], requestParams["space_guid"], previousValues["space_id"]),
+	}
+}
+
+func firstString(values ...interface{}) string {
+	for _, value := range values {
+		if s, ok := value.(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
 func New(ctx context.Context, operation, requestID, serviceName, instanceID string) context.Context {
 	ctx = WithOperation(ctx, operation)
 	ctx = WithReqID(ctx, requestID)
@@ -70,3 +106,12 @@ func GetBoshTaskID(ctx context.Context) int {
 	boshTaskID, _ := ctx.Value(boshTaskIDKey).(int)
 	return boshTaskID
 }
+
+func WithTenant(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+func GetTenant(ctx context.Context) Tenant {
+	tenant, _ := ctx.Value(tenantKey).(Tenant)
+	return tenant
+}
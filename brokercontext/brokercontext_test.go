@@ -77,6 +77,55 @@ var _ = Describe("BrokerContext", func() {
 		})
 	})
 
+	Describe("Tenant", func() {
+		It("can be set and retrieved", func() {
+			tenant := Tenant{Platform: "cloudfoundry", OrganizationGUID: "some-org", SpaceGUID: "some-space"}
+			ctx = WithTenant(ctx, tenant)
+			Expect(GetTenant(ctx)).To(Equal(tenant))
+		})
+
+		It("is empty when not set", func() {
+			Expect(GetTenant(ctx).IsEmpty()).To(BeTrue())
+		})
+	})
+
+	Describe("TenantFromRequestParams", func() {
+		It("reads the tenant from the platform context", func() {
+			tenant := TenantFromRequestParams(map[string]interface{}{
+				"organization_guid": "top-level-org",
+				"space_guid":        "top-level-space",
+				"context": map[string]interface{}{
+					"platform":          "cloudfoundry",
+					"organization_guid": "context-org",
+					"space_guid":        "context-space",
+				},
+			})
+			Expect(tenant).To(Equal(Tenant{Platform: "cloudfoundry", OrganizationGUID: "context-org", SpaceGUID: "context-space"}))
+		})
+
+		It("falls back to the top-level provision fields", func() {
+			tenant := TenantFromRequestParams(map[string]interface{}{
+				"organization_guid": "top-level-org",
+				"space_guid":        "top-level-space",
+			})
+			Expect(tenant).To(Equal(Tenant{OrganizationGUID: "top-level-org", SpaceGUID: "top-level-space"}))
+		})
+
+		It("falls back to the previous values of an update", func() {
+			tenant := TenantFromRequestParams(map[string]interface{}{
+				"previous_values": map[string]interface{}{
+					"organization_id": "previous-org",
+					"space_id":        "previous-space",
+				},
+			})
+			Expect(tenant).To(Equal(Tenant{OrganizationGUID: "previous-org", SpaceGUID: "previous-space"}))
+		})
+
+		It("is empty when the request has no tenant", func() {
+			Expect(TenantFromRequestParams(map[string]interface{}{"parameters": nil}).IsEmpty()).To(BeTrue())
+		})
+	})
+
 	Context("with multiple attributes", func() {
 		It("can set and retrieve all of them", func() {
 			operation := "create"
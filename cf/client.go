@@ -7,6 +7,7 @@
 package cf
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 )
@@ -37,7 +38,7 @@ func (c Client) CountInstancesOfServiceOffering(serviceID string, logger *log.Lo
 
 	output := map[ServicePlan]int{}
 	for _, plan := range plans {
-		count, err := c.countServiceInstancesOfServicePlan(plan.ServicePlanEntity.ServiceInstancesUrl, logger)
+		count, err := c.countServiceInstancesOfServicePlan(plan.ServicePlanEntity.ServiceInstancesUrl, "", logger)
 		if err != nil {
 			return nil, err
 		}
@@ -75,22 +76,48 @@ func (c Client) GetInstance(serviceInstanceGUID string, logger *log.Logger) (Ins
 }
 
 func (c Client) CountInstancesOfPlan(serviceID, servicePlanID string, logger *log.Logger) (int, error) {
+	return c.countInstancesOfPlanMatching(serviceID, servicePlanID, "", logger)
+}
+
+func (c Client) CountInstancesOfPlanInOrg(serviceID, servicePlanID, orgGUID string, logger *log.Logger) (int, error) {
+	return c.countInstancesOfPlanMatching(serviceID, servicePlanID, "organization_guid:"+orgGUID, logger)
+}
+
+func (c Client) CountInstancesOfPlanInSpace(serviceID, servicePlanID, spaceGUID string, logger *log.Logger) (int, error) {
+	return c.countInstancesOfPlanMatching(serviceID, servicePlanID, "space_guid:"+spaceGUID, logger)
+}
+
+func (c Client) CountInstancesOfServiceOfferingByOrg(serviceID string, logger *log.Logger) (map[ServicePlan]map[string]int, error) {
 	plans, err := c.getPlansForServiceID(serviceID, logger)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
+	spaceOrgs := map[string]string{}
+	output := map[ServicePlan]map[string]int{}
 	for _, plan := range plans {
-		if plan.ServicePlanEntity.UniqueID == servicePlanID {
-			count, err := c.countServiceInstancesOfServicePlan(plan.ServicePlanEntity.ServiceInstancesUrl, logger)
-			if err != nil {
-				return 0, err
+		instances, err := c.listServiceInstancesOfPlan(plan.Metadata.GUID, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		counts := map[string]int{}
+		for _, instance := range instances {
+			spaceGUID := instance.Entity.SpaceGUID
+			orgGUID, found := spaceOrgs[spaceGUID]
+			if !found {
+				orgGUID, err = c.getSpaceOrganization(spaceGUID, logger)
+				if err != nil {
+					return nil, err
+				}
+				spaceOrgs[spaceGUID] = orgGUID
 			}
-			return count, nil
+			counts[orgGUID]++
 		}
+		output[plan] = counts
 	}
 
-	return 0, fmt.Errorf("service plan %s not found for service %s", servicePlanID, serviceID)
+	return output, nil
 }
 
 func (c Client) GetInstancesOfServiceOffering(serviceOfferingID string, logger *log.Logger) ([]string, error) {
@@ -101,25 +128,12 @@ func (c Client) GetInstancesOfServiceOffering(serviceOfferingID string, logger *
 
 	var instances []string
 	for _, plan := range plans {
-		path := fmt.Sprintf(
-			"/v2/service_plans/%s/service_instances?results-per-page=%d",
-			plan.Metadata.GUID,
-			defaultPerPage,
-		)
-
-		for path != "" {
-			var serviceInstancesResp serviceInstancesResponse
-
-			instancesURL := fmt.Sprintf("%s%s", c.url, path)
-
-			err := c.get(instancesURL, &serviceInstancesResp, logger)
-			if err != nil {
-				return nil, err
-			}
-			for _, instance := range serviceInstancesResp.ServiceInstances {
-				instances = append(instances, instance.Metadata.GUID)
-			}
-			path = serviceInstancesResp.NextPath
+		planInstances, err := c.listServiceInstancesOfPlan(plan.Metadata.GUID, logger)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range planInstances {
+			instances = append(instances, instance.Metadata.GUID)
 		}
 	}
 	return instances, nil
@@ -215,6 +229,24 @@ func (c Client) DeleteServiceInstance(instanceGUID string, logger *log.Logger) e
 	return c.delete(url, logger)
 }
 
+// UpdateServiceInstance asks Cloud Controller to update an instance with the
+// given parameters. Cloud Controller passes the update on to the broker and
+// records the dashboard URL the broker responds with.
+func (c Client) UpdateServiceInstance(instanceGUID string, parameters map[string]interface{}, logger *log.Logger) error {
+	body, err := json.Marshal(map[string]interface{}{"parameters": parameters})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf(
+		"%s/v2/service_instances/%s?accepts_incomplete=true",
+		c.url,
+		instanceGUID,
+	)
+
+	return c.put(url, string(body), logger)
+}
+
 func (c Client) GetAPIVersion(logger *log.Logger) (string, error) {
 	var infoResponse infoResponse
 	err := c.get(fmt.Sprintf("%s/v2/info", c.url), &infoResponse, logger)
@@ -351,11 +383,64 @@ func (c Client) listPlans(path string, logger *log.Logger) (ServicePlanResponse,
 	return servicePlanResponse, c.get(fmt.Sprintf("%s%s", c.url, path), &servicePlanResponse, logger)
 }
 
-func (c Client) countServiceInstancesOfServicePlan(path string, logger *log.Logger) (int, error) {
+func (c Client) countInstancesOfPlanMatching(serviceID, servicePlanID, filter string, logger *log.Logger) (int, error) {
+	plans, err := c.getPlansForServiceID(serviceID, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, plan := range plans {
+		if plan.ServicePlanEntity.UniqueID == servicePlanID {
+			count, err := c.countServiceInstancesOfServicePlan(plan.ServicePlanEntity.ServiceInstancesUrl, filter, logger)
+			if err != nil {
+				return 0, err
+			}
+			return count, nil
+		}
+	}
+
+	return 0, fmt.Errorf("service plan %s not found for service %s", servicePlanID, serviceID)
+}
+
+func (c Client) countServiceInstancesOfServicePlan(path, filter string, logger *log.Logger) (int, error) {
+	query := fmt.Sprintf("results-per-page=%d", defaultPerPage)
+	if filter != "" {
+		query = fmt.Sprintf("q=%s&%s", filter, query)
+	}
+
 	resp := serviceInstancesResponse{}
-	err := c.get(fmt.Sprintf("%s%s?results-per-page=%d", c.url, path, defaultPerPage), &resp, logger)
+	err := c.get(fmt.Sprintf("%s%s?%s", c.url, path, query), &resp, logger)
 	if err != nil {
 		return 0, err
 	}
 	return resp.TotalResults, nil
 }
+
+func (c Client) listServiceInstancesOfPlan(servicePlanGUID string, logger *log.Logger) ([]serviceInstanceResource, error) {
+	path := fmt.Sprintf(
+		"/v2/service_plans/%s/service_instances?results-per-page=%d",
+		servicePlanGUID,
+		defaultPerPage,
+	)
+
+	var instances []serviceInstanceResource
+	for path != "" {
+		var serviceInstancesResp serviceInstancesResponse
+
+		instancesURL := fmt.Sprintf("%s%s", c.url, path)
+
+		err := c.get(instancesURL, &serviceInstancesResp, logger)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, serviceInstancesResp.ServiceInstances...)
+		path = serviceInstancesResp.NextPath
+	}
+	return instances, nil
+}
+
+func (c Client) getSpaceOrganization(spaceGUID string, logger *log.Logger) (string, error) {
+	var space spaceResource
+	err := c.get(fmt.Sprintf("%s/v2/spaces/%s", c.url, spaceGUID), &space, logger)
+	return space.Entity.OrganizationGUID, err
+}
@@ -474,6 +474,108 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("CountInstancesOfPlanInOrg", func() {
+		It("counts the instances of the plan in the org", func() {
+			server.VerifyAndMock(
+				mockcfapi.ListServiceOfferings().WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_services_response.json")),
+				mockcfapi.ListServicePlans(serviceGUID).WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_plans_response.json")),
+				mockcfapi.ListServiceInstancesInOrg("2777ad05-8114-4169-8188-2ef5f39e0c6b", "some-org-guid").WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_instances_for_plan_2_response.json")),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.CountInstancesOfPlanInOrg("D94A086D-203D-4966-A6F1-60A9E2300F72", "22789210-D743-4C65-9D38-C80B29F4D9C8", "some-org-guid", testLogger)).To(Equal(2))
+		})
+
+		It("fails when it can't retrieve service instances for the plan", func() {
+			server.VerifyAndMock(
+				mockcfapi.ListServiceOfferings().WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_services_response.json")),
+				mockcfapi.ListServicePlans(serviceGUID).WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_plans_response.json")),
+				mockcfapi.ListServiceInstancesInOrg("2777ad05-8114-4169-8188-2ef5f39e0c6b", "some-org-guid").RespondsInternalServerErrorWith("no instances for you"),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.CountInstancesOfPlanInOrg("D94A086D-203D-4966-A6F1-60A9E2300F72", "22789210-D743-4C65-9D38-C80B29F4D9C8", "some-org-guid", testLogger)
+			Expect(err).To(MatchError(ContainSubstring("no instances for you")))
+		})
+	})
+
+	Describe("CountInstancesOfPlanInSpace", func() {
+		It("counts the instances of the plan in the space", func() {
+			server.VerifyAndMock(
+				mockcfapi.ListServiceOfferings().WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_services_response.json")),
+				mockcfapi.ListServicePlans(serviceGUID).WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_plans_response.json")),
+				mockcfapi.ListServiceInstancesInSpace("ff717e7c-afd5-4d0a-bafe-16c7eff546ec", "some-space-guid").WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_instances_for_plan_1_response.json")),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.CountInstancesOfPlanInSpace("D94A086D-203D-4966-A6F1-60A9E2300F72", "11789210-D743-4C65-9D38-C80B29F4D9C8", "some-space-guid", testLogger)).To(Equal(1))
+		})
+
+		It("fails if the plan is not found", func() {
+			server.VerifyAndMock(
+				mockcfapi.ListServiceOfferings().WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_services_response.json")),
+				mockcfapi.ListServicePlans(serviceGUID).WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_plans_response.json")),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.CountInstancesOfPlanInSpace("D94A086D-203D-4966-A6F1-60A9E2300F72", "does-not-exist", "some-space-guid", testLogger)
+			Expect(err).To(MatchError(ContainSubstring("service plan does-not-exist not found")))
+		})
+	})
+
+	Describe("CountInstancesOfServiceOfferingByOrg", func() {
+		It("counts the instances of each plan per org, looking each space up once", func() {
+			server.VerifyAndMock(
+				mockcfapi.ListServiceOfferings().WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_services_response.json")),
+				mockcfapi.ListServicePlans(serviceGUID).WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_plans_response.json")),
+				mockcfapi.ListServiceInstances("ff717e7c-afd5-4d0a-bafe-16c7eff546ec").WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_instances_for_plan_1_response.json")),
+				mockcfapi.GetSpace("a157c861-92bb-4f57-9108-f791260f66ab").RespondsWithOrganization("some-org-guid").WithAuthorizationHeader(cfAuthorizationHeader),
+				mockcfapi.ListServiceInstances("2777ad05-8114-4169-8188-2ef5f39e0c6b").WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_instances_for_plan_2_response.json")),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(client.CountInstancesOfServiceOfferingByOrg("D94A086D-203D-4966-A6F1-60A9E2300F72", testLogger)).To(Equal(map[cf.ServicePlan]map[string]int{
+				servicePlan(
+					"ff717e7c-afd5-4d0a-bafe-16c7eff546ec",
+					"11789210-D743-4C65-9D38-C80B29F4D9C8",
+					"/v2/service_plans/ff717e7c-afd5-4d0a-bafe-16c7eff546ec/service_instances",
+					"small",
+				): {"some-org-guid": 1},
+				servicePlan(
+					"2777ad05-8114-4169-8188-2ef5f39e0c6b",
+					"22789210-D743-4C65-9D38-C80B29F4D9C8",
+					"/v2/service_plans/2777ad05-8114-4169-8188-2ef5f39e0c6b/service_instances",
+					"big",
+				): {"some-org-guid": 2},
+			}))
+		})
+
+		It("fails when it can't look up a space", func() {
+			server.VerifyAndMock(
+				mockcfapi.ListServiceOfferings().WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_services_response.json")),
+				mockcfapi.ListServicePlans(serviceGUID).WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_plans_response.json")),
+				mockcfapi.ListServiceInstances("ff717e7c-afd5-4d0a-bafe-16c7eff546ec").WithAuthorizationHeader(cfAuthorizationHeader).RespondsOKWith(fixture("list_service_instances_for_plan_1_response.json")),
+				mockcfapi.GetSpace("a157c861-92bb-4f57-9108-f791260f66ab").RespondsInternalServerErrorWith("no spaces for you"),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.CountInstancesOfServiceOfferingByOrg("D94A086D-203D-4966-A6F1-60A9E2300F72", testLogger)
+			Expect(err).To(MatchError(ContainSubstring("no spaces for you")))
+		})
+	})
+
 	Describe("GetInstance", func() {
 		It("fetches the instance", func() {
 			server.VerifyAndMock(
@@ -1129,6 +1231,40 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("UpdateServiceInstance", func() {
+		const serviceInstanceGUID = "596736f1-eee4-4249-a201-e21f00a55209"
+
+		It("asks cloud controller to update the instance with the parameters", func() {
+			server.VerifyAndMock(
+				mockcfapi.UpdateServiceInstance(serviceInstanceGUID).
+					WithAuthorizationHeader(cfAuthorizationHeader).
+					WithBody(`{"parameters":{"foo":"bar"}}`).
+					RespondsCreated(),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = client.UpdateServiceInstance(serviceInstanceGUID, map[string]interface{}{"foo": "bar"}, testLogger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logBuffer).To(gbytes.Say("PUT %s/v2/service_instances/%s\\?accepts_incomplete\\=true", server.URL, serviceInstanceGUID))
+		})
+
+		It("returns an error when cloud controller does not update the instance", func() {
+			server.VerifyAndMock(
+				mockcfapi.UpdateServiceInstance(serviceInstanceGUID).
+					WithAuthorizationHeader(cfAuthorizationHeader).
+					RespondsForbiddenWith(`{"foo":"bar"}`),
+			)
+
+			client, err := cf.New(server.URL, authHeaderBuilder, nil, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = client.UpdateServiceInstance(serviceInstanceGUID, map[string]interface{}{"foo": "bar"}, testLogger)
+			Expect(err).To(MatchError(ContainSubstring("Unexpected reponse status 403")))
+		})
+	})
+
 	Describe("DeleteServiceInstance", func() {
 		const serviceInstanceGUID = "596736f1-eee4-4249-a201-e21f00a55209"
 
@@ -97,6 +97,7 @@ func (o LastOperation) IsDelete() bool {
 
 type serviceInstanceEntity struct {
 	ServicePlanURL string        `json:"service_plan_url"`
+	SpaceGUID      string        `json:"space_guid"`
 	LastOperation  LastOperation `json:"last_operation"`
 }
 
@@ -105,6 +106,14 @@ type serviceInstancesResponse struct {
 	ServiceInstances []serviceInstanceResource `json:"resources"`
 }
 
+type spaceResource struct {
+	Entity spaceEntity `json:"entity"`
+}
+
+type spaceEntity struct {
+	OrganizationGUID string `json:"organization_guid"`
+}
+
 type Instance struct {
 	LastOperation LastOperation `json:"last_operation"`
 }
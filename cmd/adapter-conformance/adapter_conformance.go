@@ -0,0 +1,49 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/conformance"
+	"github.com/pivotal-cf/on-demand-service-broker/loggerfactory"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+)
+
+const ConformanceFailedExitCode = 10
+
+func main() {
+	loggerFactory := loggerfactory.New(os.Stderr, "adapter-conformance", loggerfactory.Flags)
+	logger := loggerFactory.New()
+
+	configFilePath := flag.String("configFilePath", "", "path to the broker config file")
+	flag.Parse()
+	if *configFilePath == "" {
+		logger.Fatal("must supply -configFilePath")
+	}
+
+	conf, err := config.Parse(*configFilePath)
+	if err != nil {
+		logger.Fatalf("error parsing config: %s", err)
+	}
+
+	newAdapterClient := func(adapter config.ServiceAdapter) (*serviceadapter.Client, error) {
+		return adapter.NewClient(conf.Broker.DisableSSLCertVerification)
+	}
+	report, err := conformance.Check(conf, newAdapterClient, logger)
+	if err != nil {
+		logger.Fatalf("error checking service adapter: %s", err)
+	}
+
+	report.Write(os.Stdout)
+
+	if report.Failed() {
+		os.Exit(ConformanceFailedExitCode)
+	}
+}
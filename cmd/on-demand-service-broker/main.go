@@ -22,19 +22,34 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pivotal-cf/brokerapi"
 	apiauth "github.com/pivotal-cf/brokerapi/auth"
+	"github.com/pivotal-cf/on-demand-service-broker/auditlog"
 	"github.com/pivotal-cf/on-demand-service-broker/authorizationheader"
+	"github.com/pivotal-cf/on-demand-service-broker/bindingstore"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
 	"github.com/pivotal-cf/on-demand-service-broker/config"
 	"github.com/pivotal-cf/on-demand-service-broker/loggerfactory"
 	"github.com/pivotal-cf/on-demand-service-broker/mgmtapi"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+	"github.com/pivotal-cf/on-demand-service-broker/osbapi"
+	"github.com/pivotal-cf/on-demand-service-broker/reservationstore"
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 	"github.com/pivotal-cf/on-demand-service-broker/task"
 	"github.com/urfave/negroni"
 )
 
-const componentName = "on-demand-service-broker"
+const (
+	componentName = "on-demand-service-broker"
+
+	// a long-running service adapter may start after the broker
+	serviceAdapterStartupTimeout    = 2 * time.Minute
+	serviceAdapterStartupBackoff    = time.Second
+	serviceAdapterHealthCheckPeriod = 30 * time.Second
+
+	// queued operations start as soon as BOSH reports a task slot free
+	operationQueueDispatchPeriod = 10 * time.Second
+)
 
 func main() {
 	loggerFactory := loggerfactory.New(os.Stdout, componentName, loggerfactory.Flags)
@@ -94,21 +109,68 @@ func startBroker(conf config.Config, logger *log.Logger, loggerFactory *loggerfa
 		logger.Fatalf("error creating Cloud Foundry client: %s", err)
 	}
 
-	serviceAdapter := &serviceadapter.Client{
-		ExternalBinPath: conf.ServiceAdapter.Path,
-		CommandRunner:   serviceadapter.NewCommandRunner(),
+	var (
+		serviceOfferings    []broker.ServiceOffering
+		longRunningAdapters []*serviceadapter.Client
+	)
+	for _, offering := range conf.Offerings() {
+		serviceAdapter, err := offering.ServiceAdapter.NewClient(conf.Broker.DisableSSLCertVerification)
+		if err != nil {
+			logger.Fatalf("error creating service adapter client: %s", err)
+		}
+		if offering.ServiceAdapter.Transport == config.ServiceAdapterTransportHTTP {
+			if err := serviceAdapter.WaitUntilHealthy(serviceAdapterStartupBackoff, serviceAdapterStartupTimeout, logger); err != nil {
+				logger.Fatalf("error checking service adapter: %s", err)
+			}
+			longRunningAdapters = append(longRunningAdapters, serviceAdapter)
+		}
+
+		manifestGenerator := task.NewManifestGenerator(
+			serviceAdapter,
+			offering.ServiceCatalog,
+			offering.ServiceDeployment.Stemcell,
+			offering.ServiceDeployment.Releases,
+		)
+
+		serviceOfferings = append(serviceOfferings, broker.ServiceOffering{
+			Catalog:             offering.ServiceCatalog,
+			AdapterClient:       serviceAdapter,
+			Deployer:            task.NewDeployer(boshClient, manifestGenerator),
+			MaintenanceVersion:  offering.MaintenanceVersion(),
+			BindingsRetrievable: conf.Broker.BindingStorePath != "",
+		})
 	}
 
-	manifestGenerator := task.NewManifestGenerator(
-		serviceAdapter,
-		conf.ServiceCatalog,
-		conf.ServiceDeployment.Stemcell,
-		conf.ServiceDeployment.Releases,
-	)
+	var operationStore *operationstore.FileStore
+	if conf.Broker.OperationStoreEncryptionKey != "" {
+		operationStore, err = operationstore.NewEncryptedFileStore(conf.Broker.OperationStorePath, conf.Broker.OperationStoreEncryptionKey)
+	} else {
+		operationStore, err = operationstore.NewFileStore(conf.Broker.OperationStorePath)
+	}
+	if err != nil {
+		logger.Fatalf("error opening operation store: %s", err)
+	}
 
-	deploymentManager := task.NewDeployer(boshClient, manifestGenerator)
+	var bindingStore broker.BindingStore = bindingstore.DiscardStore{}
+	if conf.Broker.BindingStorePath != "" {
+		bindingStore, err = bindingstore.NewEncryptedFileStore(conf.Broker.BindingStorePath, conf.Broker.BindingStoreEncryptionKey)
+		if err != nil {
+			logger.Fatalf("error opening binding store: %s", err)
+		}
+	}
 
-	onDemandBroker, err := broker.New(boshInfo, boshClient, cfClient, serviceAdapter, deploymentManager, conf.ServiceCatalog, conf.Broker.DisableCFStartupChecks, loggerFactory)
+	reservationStore, err := reservationstore.NewFileStore(conf.Broker.QuotaReservationStorePath)
+	if err != nil {
+		logger.Fatalf("error opening quota reservation store: %s", err)
+	}
+
+	auditLog, err := auditlog.NewFileLog(conf.Broker.AuditLogPath)
+	if err != nil {
+		logger.Fatalf("error opening audit log: %s", err)
+	}
+
+	operationLockTimeout := time.Duration(conf.Broker.OperationLockTimeoutSecs) * time.Second
+	onDemandBroker, err := broker.New(boshInfo, boshClient, cfClient, serviceOfferings, operationStore, bindingStore, reservationStore, auditLog, conf.Broker.DeploymentNameTemplate, conf.Broker.LastOperationDetails, conf.Broker.MaxInFlightBoshTasks, conf.Broker.DisableCFStartupChecks, operationLockTimeout, loggerFactory)
 	if err != nil {
 		logger.Fatalf("error starting broker: %s", err)
 	}
@@ -135,6 +197,12 @@ func startBroker(conf config.Config, logger *log.Logger, loggerFactory *loggerfa
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	for _, serviceAdapter := range longRunningAdapters {
+		go serviceAdapter.MonitorHealth(serviceAdapterHealthCheckPeriod, stopped, logger)
+	}
+	// operations queued before a restart that removed the limit still start
+	go onDemandBroker.RunOperationQueue(operationQueueDispatchPeriod, stopped)
+
 	go func() {
 		<-stop
 
@@ -173,8 +241,14 @@ func setupServer(
 	logger *log.Logger,
 ) *http.Server {
 
+	var serviceCatalogs []config.ServiceOffering
+	for _, offering := range conf.Offerings() {
+		serviceCatalogs = append(serviceCatalogs, offering.ServiceCatalog)
+	}
+
 	brokerRouter := mux.NewRouter()
-	mgmtapi.AttachRoutes(brokerRouter, broker, conf.ServiceCatalog, loggerFactory)
+	mgmtapi.AttachRoutes(brokerRouter, broker, serviceCatalogs, loggerFactory)
+	osbapi.AttachRoutes(brokerRouter, broker, conf.Broker.AdminUserIDs, conf.Broker.BindingStorePath != "", loggerFactory)
 	brokerapi.AttachRoutes(brokerRouter, broker, lager.NewLogger(componentName))
 	authProtectedBrokerAPI := apiauth.
 		NewWrapper(conf.Broker.Username, conf.Broker.Password).
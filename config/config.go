@@ -7,15 +7,18 @@
 package config
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"net/http"
 
+	"github.com/coreos/go-semver/semver"
 	"github.com/pivotal-cf/on-demand-service-broker/authorizationheader"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
@@ -26,11 +29,51 @@ type Config struct {
 	Broker            Broker
 	Bosh              Bosh
 	CF                CF
+	ServiceAdapter    ServiceAdapter          `yaml:"service_adapter"`
+	ServiceDeployment ServiceDeployment       `yaml:"service_deployment"`
+	ServiceCatalog    ServiceOffering         `yaml:"service_catalog"`
+	ServiceOfferings  []ServiceOfferingConfig `yaml:"service_offerings,omitempty"`
+}
+
+// ServiceOfferingConfig is everything the broker needs to offer one service:
+// its adapter, the releases and stemcell it deploys, and its catalog entry.
+type ServiceOfferingConfig struct {
 	ServiceAdapter    ServiceAdapter    `yaml:"service_adapter"`
 	ServiceDeployment ServiceDeployment `yaml:"service_deployment"`
 	ServiceCatalog    ServiceOffering   `yaml:"service_catalog"`
 }
 
+// MaintenanceVersion is the maintenance_info version advertised for the
+// offering's plans: the operator's version, with a hash of the releases and
+// stemcell as build metadata. Build metadata does not count towards semver
+// precedence, so operators bump the version to make an upgrade available.
+func (o ServiceOfferingConfig) MaintenanceVersion() string {
+	if o.ServiceCatalog.MaintenanceInfo == nil || o.ServiceCatalog.MaintenanceInfo.Version == "" {
+		return ""
+	}
+
+	hash := sha256.New()
+	for _, release := range o.ServiceDeployment.Releases {
+		fmt.Fprintf(hash, "release:%s/%s\n", release.Name, release.Version)
+	}
+	fmt.Fprintf(hash, "stemcell:%s/%s\n", o.ServiceDeployment.Stemcell.OS, o.ServiceDeployment.Stemcell.Version)
+	return fmt.Sprintf("%s+%x", o.ServiceCatalog.MaintenanceInfo.Version, hash.Sum(nil)[:6])
+}
+
+// Offerings returns the configured service offerings. Configs written before
+// service_offerings existed describe a single offering at the top level.
+func (c Config) Offerings() []ServiceOfferingConfig {
+	if len(c.ServiceOfferings) > 0 {
+		return c.ServiceOfferings
+	}
+
+	return []ServiceOfferingConfig{{
+		ServiceAdapter:    c.ServiceAdapter,
+		ServiceDeployment: c.ServiceDeployment,
+		ServiceCatalog:    c.ServiceCatalog,
+	}}
+}
+
 func (c Config) Validate() error {
 	if err := c.Broker.Validate(); err != nil {
 		return err
@@ -40,31 +83,161 @@ func (c Config) Validate() error {
 		return err
 	}
 
+	if c.Broker.OperationStorePath == "" {
+		if reason, deferred := c.deploysLater(); deferred {
+			return fmt.Errorf("broker.operation_store_path can't be empty when %s", reason)
+		}
+	}
+
+	if c.Broker.BindingStorePath == "" {
+		for _, offering := range c.Offerings() {
+			for _, plan := range offering.ServiceCatalog.Plans {
+				if plan.BindingErrand != "" {
+					return fmt.Errorf("broker.binding_store_path can't be empty when plan %s has a binding_errand", plan.Name)
+				}
+			}
+		}
+	}
+
 	if !c.Broker.DisableCFStartupChecks {
 		if err := c.CF.Validate(); err != nil {
 			return err
 		}
 	}
 
-	if err := checkIsExecutableFile(c.ServiceAdapter.Path); err != nil {
-		return fmt.Errorf("checking for executable service adapter file: %s", err)
+	if len(c.ServiceOfferings) > 0 {
+		if c.ServiceAdapter != (ServiceAdapter{}) || c.ServiceCatalog.ID != "" {
+			return errors.New("service_offerings cannot be combined with a top-level service_adapter or service_catalog")
+		}
+
+		for _, offering := range c.ServiceOfferings {
+			if err := offering.Validate(); err != nil {
+				return fmt.Errorf("service offering %s: %s", offering.ServiceCatalog.ID, err)
+			}
+		}
+
+		return validateUniqueOfferings(c.ServiceOfferings)
+	}
+
+	return c.Offerings()[0].Validate()
+}
+
+// deploysLater reports why some deploys may start after their request has
+// been accepted, as the broker then needs the request in its operation store.
+func (c Config) deploysLater() (string, bool) {
+	if c.Broker.MaxInFlightBoshTasks > 0 {
+		return "broker.max_in_flight_bosh_tasks is set", true
+	}
+
+	for _, offering := range c.Offerings() {
+		for _, plan := range offering.ServiceCatalog.Plans {
+			if len(plan.PreDeployErrands()) > 0 {
+				return fmt.Sprintf("plan %s has pre_deploy errands", plan.Name), true
+			}
+			if plan.PlanTransitions == nil {
+				continue
+			}
+			for _, transition := range plan.PlanTransitions.AllowedTargets {
+				if len(transition.Errands) > 0 {
+					return fmt.Sprintf("plan %s has transition errands", plan.Name), true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+func (o ServiceOfferingConfig) Validate() error {
+	if err := o.ServiceAdapter.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.ServiceDeployment.Validate(); err != nil {
+		return err
+	}
+
+	if o.ServiceCatalog.GlobalQuotas.Orgs != nil || o.ServiceCatalog.GlobalQuotas.Spaces != nil {
+		return errors.New("org and space quotas can only be configured on plans")
 	}
 
-	if err := c.ServiceDeployment.Validate(); err != nil {
+	if err := o.ServiceCatalog.validatePersistentDiskSizes(); err != nil {
 		return err
 	}
 
+	if err := o.ServiceCatalog.validateLifecycleErrands(); err != nil {
+		return err
+	}
+
+	if err := o.ServiceCatalog.validatePlanTransitions(); err != nil {
+		return err
+	}
+
+	if err := o.ServiceCatalog.MaintenanceInfo.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateUniqueOfferings ensures every plan can be traced back to a single
+// offering, as requests for existing instances only identify their plan.
+func validateUniqueOfferings(offerings []ServiceOfferingConfig) error {
+	offeringIDs := map[string]bool{}
+	offeringNames := map[string]bool{}
+	planIDs := map[string]bool{}
+
+	for _, offering := range offerings {
+		catalog := offering.ServiceCatalog
+		if offeringIDs[catalog.ID] {
+			return fmt.Errorf("service offering ID %s is used more than once", catalog.ID)
+		}
+		if offeringNames[catalog.Name] {
+			return fmt.Errorf("service offering name %s is used more than once", catalog.Name)
+		}
+		offeringIDs[catalog.ID] = true
+		offeringNames[catalog.Name] = true
+
+		for _, plan := range catalog.Plans {
+			if planIDs[plan.ID] {
+				return fmt.Errorf("plan ID %s is used by more than one plan", plan.ID)
+			}
+			planIDs[plan.ID] = true
+		}
+	}
+
 	return nil
 }
 
 type Broker struct {
-	Port                       int
-	Username                   string
-	Password                   string
-	DisableSSLCertVerification bool `yaml:"disable_ssl_cert_verification"`
-	StartUpBanner              bool `yaml:"startup_banner"`
-	ShutdownTimeoutSecs        int  `yaml:"shutdown_timeout_in_seconds"`
-	DisableCFStartupChecks     bool `yaml:"disable_cf_startup_checks"`
+	Port                        int
+	Username                    string
+	Password                    string
+	DisableSSLCertVerification  bool     `yaml:"disable_ssl_cert_verification"`
+	StartUpBanner               bool     `yaml:"startup_banner"`
+	ShutdownTimeoutSecs         int      `yaml:"shutdown_timeout_in_seconds"`
+	DisableCFStartupChecks      bool     `yaml:"disable_cf_startup_checks"`
+	OperationLockTimeoutSecs    int      `yaml:"operation_lock_timeout_in_seconds"`
+	OperationStorePath          string   `yaml:"operation_store_path"`
+	OperationStoreEncryptionKey string   `yaml:"operation_store_encryption_key"`
+	QuotaReservationStorePath   string   `yaml:"quota_reservation_store_path"`
+	BindingStorePath            string   `yaml:"binding_store_path"`
+	BindingStoreEncryptionKey   string   `yaml:"binding_store_encryption_key"`
+	AuditLogPath                string   `yaml:"audit_log_path"`
+	AdminUserIDs                []string `yaml:"admin_user_ids"`
+	DeploymentNameTemplate      string   `yaml:"deployment_name_template"`
+	MaxInFlightBoshTasks        int      `yaml:"max_in_flight_bosh_tasks"`
+
+	LastOperationDetails LastOperationDetails `yaml:"last_operation_details"`
+}
+
+// LastOperationDetails adds the progress of the BOSH task to the description
+// of operations in progress. Failed operations are described with the parts
+// of BOSH errors and errand output that match FailureMessagePatterns, as
+// app developers can see them.
+type LastOperationDetails struct {
+	ShowProgress           bool     `yaml:"show_progress"`
+	FailureMessagePatterns []string `yaml:"failure_message_patterns"`
 }
 
 func (b Broker) Validate() error {
@@ -77,6 +250,20 @@ func (b Broker) Validate() error {
 	if b.Password == "" {
 		return errors.New("broker.password can't be empty")
 	}
+	if b.BindingStorePath != "" && b.BindingStoreEncryptionKey == "" {
+		return errors.New("broker.binding_store_encryption_key can't be empty when broker.binding_store_path is set")
+	}
+	if b.OperationStoreEncryptionKey != "" && b.OperationStorePath == "" {
+		return errors.New("broker.operation_store_path can't be empty when broker.operation_store_encryption_key is set")
+	}
+	if b.MaxInFlightBoshTasks < 0 {
+		return errors.New("broker.max_in_flight_bosh_tasks can't be negative")
+	}
+	for _, pattern := range b.LastOperationDetails.FailureMessagePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("broker.last_operation_details.failure_message_patterns: invalid pattern %q: %s", pattern, err)
+		}
+	}
 
 	return nil
 }
@@ -264,10 +451,6 @@ func (a BOSHAuthentication) Validate() error {
 	return err
 }
 
-type ServiceAdapter struct {
-	Path string
-}
-
 func Parse(configFilePath string) (Config, error) {
 	configFileBytes, err := ioutil.ReadFile(configFilePath)
 	if err != nil {
@@ -313,7 +496,33 @@ type ServiceOffering struct {
 	Tags             []string
 	GlobalProperties serviceadapter.Properties `yaml:"global_properties"`
 	GlobalQuotas     Quotas                    `yaml:"global_quotas"`
+	MaintenanceInfo  *MaintenanceInfo          `yaml:"maintenance_info,omitempty"`
 	Plans            Plans
+
+	PersistentDiskTypeSizesGB map[string]int `yaml:"persistent_disk_type_sizes_gb,omitempty"`
+}
+
+type MaintenanceInfo struct {
+	Version     string `yaml:"version"`
+	Description string `yaml:"description,omitempty"`
+}
+
+func (m *MaintenanceInfo) validate() error {
+	if m == nil {
+		return nil
+	}
+	if m.Version == "" {
+		return errors.New("service_catalog.maintenance_info.version can't be empty")
+	}
+
+	version, err := semver.NewVersion(m.Version)
+	if err != nil {
+		return fmt.Errorf("service_catalog.maintenance_info.version must be a semantic version: %s", err)
+	}
+	if version.Metadata != "" {
+		return fmt.Errorf("service_catalog.maintenance_info.version %s can't have build metadata, the broker adds its own", m.Version)
+	}
+	return nil
 }
 
 func (s ServiceOffering) FindPlanByID(id string) (Plan, bool) {
@@ -322,16 +531,56 @@ func (s ServiceOffering) FindPlanByID(id string) (Plan, bool) {
 
 func (s ServiceOffering) HasLifecycleErrands() bool {
 	for _, plan := range s.Plans {
-		if plan.LifecycleErrands != nil {
-			if plan.LifecycleErrands.PostDeploy != "" || plan.LifecycleErrands.PreDelete != "" {
-				return true
-			}
+		if plan.LifecycleErrands != nil && !plan.LifecycleErrands.empty() {
+			return true
 		}
 	}
 
 	return false
 }
 
+func (s ServiceOffering) validateLifecycleErrands() error {
+	for _, plan := range s.Plans {
+		for _, errand := range plan.lifecycleErrands().all() {
+			if errand.Name == "" {
+				return fmt.Errorf("plan %s has a lifecycle errand without a name", plan.ID)
+			}
+			for _, instance := range errand.Instances {
+				if _, err := boshdirector.ParseErrandInstance(instance); err != nil {
+					return fmt.Errorf("plan %s, lifecycle errand %s: %s", plan.ID, errand.Name, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s ServiceOffering) validatePlanTransitions() error {
+	for _, plan := range s.Plans {
+		if plan.PlanTransitions == nil {
+			continue
+		}
+		for _, transition := range plan.PlanTransitions.AllowedTargets {
+			if _, found := s.FindPlanByID(transition.PlanID); !found {
+				return fmt.Errorf("plan %s allows transitions to plan %q, which does not exist", plan.ID, transition.PlanID)
+			}
+			for _, errand := range transition.Errands {
+				if errand.Name == "" {
+					return fmt.Errorf("plan %s has a transition errand without a name", plan.ID)
+				}
+				for _, instance := range errand.Instances {
+					if _, err := boshdirector.ParseErrandInstance(instance); err != nil {
+						return fmt.Errorf("plan %s, transition errand %s: %s", plan.ID, errand.Name, err)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 type Plans []Plan
 
 func (p Plans) FindByID(id string) (Plan, bool) {
@@ -355,6 +604,9 @@ type Plan struct {
 	InstanceGroups   []serviceadapter.InstanceGroup `yaml:"instance_groups,omitempty"`
 	Update           *serviceadapter.Update         `yaml:"update,omitempty"`
 	LifecycleErrands *LifecycleErrands              `yaml:"lifecycle_errands,omitempty"`
+	BindingErrand    string                         `yaml:"binding_errand,omitempty"`
+	Schemas          *PlanSchemas                   `yaml:"schemas,omitempty"`
+	PlanTransitions  *PlanTransitions               `yaml:"plan_transitions,omitempty"`
 }
 
 func (p Plan) AdapterPlan(globalProperties serviceadapter.Properties) serviceadapter.Plan {
@@ -376,25 +628,190 @@ func mergeProperties(planProperties, globalProperties serviceadapter.Properties)
 	return properties
 }
 
-func (p Plan) PostDeployErrand() string {
-	if p.LifecycleErrands == nil {
-		return ""
+func (p Plan) PreDeployErrands() Errands {
+	return p.lifecycleErrands().PreDeploy
+}
+
+func (p Plan) PostDeployErrands() Errands {
+	return p.lifecycleErrands().PostDeploy
+}
+
+func (p Plan) PreUpgradeErrands() Errands {
+	return p.lifecycleErrands().PreUpgrade
+}
+
+func (p Plan) PostUpgradeErrands() Errands {
+	return p.lifecycleErrands().PostUpgrade
+}
+
+func (p Plan) PreDeleteErrands() Errands {
+	return p.lifecycleErrands().PreDelete
+}
+
+// TransitionTo returns how instances of the plan can move to the plan with
+// the given ID. Plans without PlanTransitions can move to any plan.
+func (p Plan) TransitionTo(planID string) (PlanTransition, bool) {
+	if p.PlanTransitions == nil {
+		return PlanTransition{PlanID: planID}, true
 	}
 
-	return p.LifecycleErrands.PostDeploy
+	for _, transition := range p.PlanTransitions.AllowedTargets {
+		if transition.PlanID == planID {
+			return transition, true
+		}
+	}
+	return PlanTransition{}, false
 }
 
-func (p Plan) PreDeleteErrand() string {
+func (p Plan) lifecycleErrands() LifecycleErrands {
 	if p.LifecycleErrands == nil {
-		return ""
+		return LifecycleErrands{}
 	}
 
-	return p.LifecycleErrands.PreDelete
+	return *p.LifecycleErrands
+}
+
+type PlanTransitions struct {
+	AllowedTargets []PlanTransition `yaml:"allowed_targets"`
+}
+
+// PlanTransition is a plan that instances can be moved to. A move needs the
+// ConfirmationParameter, when set, to be passed as true, and runs Errands
+// before the deploy.
+type PlanTransition struct {
+	PlanID                string  `yaml:"plan_id"`
+	ConfirmationParameter string  `yaml:"confirmation_parameter,omitempty"`
+	Errands               Errands `yaml:"errands,omitempty"`
 }
 
+// LifecycleErrands lists, for each hook, the errands that run in order
+// around the BOSH deployment of an instance.
 type LifecycleErrands struct {
-	PostDeploy string `yaml:"post_deploy"`
-	PreDelete  string `yaml:"pre_delete"`
+	PreDeploy   Errands `yaml:"pre_deploy,omitempty"`
+	PostDeploy  Errands `yaml:"post_deploy,omitempty"`
+	PreUpgrade  Errands `yaml:"pre_upgrade,omitempty"`
+	PostUpgrade Errands `yaml:"post_upgrade,omitempty"`
+	PreDelete   Errands `yaml:"pre_delete,omitempty"`
+}
+
+func (l LifecycleErrands) empty() bool {
+	return len(l.all()) == 0
+}
+
+func (l LifecycleErrands) all() Errands {
+	var errands Errands
+	for _, hook := range []Errands{l.PreDeploy, l.PostDeploy, l.PreUpgrade, l.PostUpgrade, l.PreDelete} {
+		errands = append(errands, hook...)
+	}
+	return errands
+}
+
+// Errand is a lifecycle errand. Instances limits where it runs, each being an
+// instance group, a group/index or group/first; see
+// boshdirector.ParseErrandInstance.
+type Errand struct {
+	Name              string   `yaml:"name"`
+	Instances         []string `yaml:"instances,omitempty" json:",omitempty"`
+	ContinueOnFailure bool     `yaml:"continue_on_failure,omitempty" json:",omitempty"`
+}
+
+// UnmarshalYAML accepts either a bare errand name or the full settings.
+func (e *Errand) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*e = Errand{Name: name}
+		return nil
+	}
+
+	type plainErrand Errand
+	return unmarshal((*plainErrand)(e))
+}
+
+type Errands []Errand
+
+// UnmarshalYAML accepts a single errand name, as hooks were configured
+// before they could hold more than one errand, as well as a list.
+func (e *Errands) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*e = nil
+		if name != "" {
+			*e = Errands{{Name: name}}
+		}
+		return nil
+	}
+
+	var errands []Errand
+	if err := unmarshal(&errands); err != nil {
+		return err
+	}
+	*e = errands
+	return nil
+}
+
+// PlanSchemas holds the JSON schemas that the arbitrary parameters of each
+// request must conform to, as described by the Open Service Broker API.
+type PlanSchemas struct {
+	ServiceInstance ServiceInstanceSchemas `yaml:"service_instance,omitempty"`
+	ServiceBinding  ServiceBindingSchemas  `yaml:"service_binding,omitempty"`
+}
+
+type ServiceInstanceSchemas struct {
+	Create Schema `yaml:"create,omitempty"`
+	Update Schema `yaml:"update,omitempty"`
+}
+
+type ServiceBindingSchemas struct {
+	Create Schema `yaml:"create,omitempty"`
+}
+
+type Schema struct {
+	Parameters map[string]interface{} `yaml:"parameters,omitempty"`
+}
+
+// UnmarshalYAML converts the nested maps produced by the YAML decoder into
+// maps with string keys, so that the schema can be marshalled to JSON.
+func (s *Schema) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		Parameters map[string]interface{} `yaml:"parameters"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	if raw.Parameters != nil {
+		s.Parameters = stringKeyedMap(raw.Parameters)
+	}
+	return nil
+}
+
+func stringKeyedMap(m map[string]interface{}) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for k, v := range m {
+		converted[k] = stringKeyedValue(v)
+	}
+	return converted
+}
+
+func stringKeyedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for k, item := range v {
+			converted[fmt.Sprintf("%v", k)] = stringKeyedValue(item)
+		}
+		return converted
+	case map[string]interface{}:
+		return stringKeyedMap(v)
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = stringKeyedValue(item)
+		}
+		return converted
+	default:
+		return v
+	}
 }
 
 type PlanMetadata struct {
@@ -424,5 +841,32 @@ type DashboardClient struct {
 }
 
 type Quotas struct {
-	ServiceInstanceLimit *int `yaml:"service_instance_limit,omitempty"`
+	ServiceInstanceLimit *int            `yaml:"service_instance_limit,omitempty"`
+	Orgs                 *ScopeQuota     `yaml:"orgs,omitempty"`
+	Spaces               *ScopeQuota     `yaml:"spaces,omitempty"`
+	Resources            *ResourceLimits `yaml:"resources,omitempty"`
+}
+
+// ScopeQuota limits the instances of a plan within each org or space.
+// Overrides are keyed by org or space GUID and take precedence over the
+// default limit.
+type ScopeQuota struct {
+	ServiceInstanceLimit *int           `yaml:"service_instance_limit,omitempty"`
+	Overrides            map[string]int `yaml:"overrides,omitempty"`
+}
+
+func (q *ScopeQuota) LimitFor(guid string) (int, bool) {
+	if q == nil || guid == "" {
+		return 0, false
+	}
+
+	if limit, found := q.Overrides[guid]; found {
+		return limit, true
+	}
+
+	if q.ServiceInstanceLimit != nil {
+		return *q.ServiceInstanceLimit, true
+	}
+
+	return 0, false
 }
@@ -7,15 +7,18 @@
 package config_test
 
 import (
+	"encoding/pem"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"net/http"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
 	"github.com/pivotal-cf/on-demand-service-broker/authorizationheader"
 	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/config"
@@ -48,12 +51,26 @@ var _ = Describe("Config", func() {
 				instanceLimit := 1
 				expected := config.Config{
 					Broker: config.Broker{
-						Port:                       8080,
-						Username:                   "username",
-						Password:                   "password",
-						DisableSSLCertVerification: true,
-						StartUpBanner:              false,
-						ShutdownTimeoutSecs:        10,
+						Port:                        8080,
+						Username:                    "username",
+						Password:                    "password",
+						DisableSSLCertVerification:  true,
+						StartUpBanner:               false,
+						ShutdownTimeoutSecs:         10,
+						OperationLockTimeoutSecs:    5,
+						OperationStorePath:          "/var/vcap/store/broker/operations.json",
+						OperationStoreEncryptionKey: "some-operation-store-key",
+						QuotaReservationStorePath:   "/var/vcap/store/broker/quota_reservations.json",
+						BindingStorePath:            "/var/vcap/store/broker/bindings",
+						BindingStoreEncryptionKey:   "some-encryption-key",
+						AuditLogPath:                "/var/vcap/store/broker/audit.log",
+						AdminUserIDs:                []string{"some-admin-user-id"},
+						DeploymentNameTemplate:      "staging-{service_name}_{instance_id}",
+						MaxInFlightBoshTasks:        5,
+						LastOperationDetails: config.LastOperationDetails{
+							ShowProgress:           true,
+							FailureMessagePatterns: []string{"ODB-ERROR: .*"},
+						},
 					},
 					Bosh: config.Bosh{
 						URL:         "some-url",
@@ -129,7 +146,24 @@ var _ = Describe("Config", func() {
 									"persistence": true,
 								},
 								LifecycleErrands: &config.LifecycleErrands{
-									PostDeploy: "health-check",
+									PostDeploy: config.Errands{{Name: "health-check"}},
+								},
+								Schemas: &config.PlanSchemas{
+									ServiceInstance: config.ServiceInstanceSchemas{
+										Create: config.Schema{
+											Parameters: map[string]interface{}{
+												"$schema": "http://json-schema.org/draft-04/schema#",
+												"type":    "object",
+												"properties": map[string]interface{}{
+													"maxclients": map[string]interface{}{
+														"type":    "integer",
+														"minimum": 1,
+													},
+												},
+												"required": []interface{}{"maxclients"},
+											},
+										},
+									},
 								},
 								InstanceGroups: []serviceadapter.InstanceGroup{
 									{
@@ -222,6 +256,104 @@ var _ = Describe("Config", func() {
 			})
 		})
 
+		Context("when the service adapter is called over HTTP", func() {
+			BeforeEach(func() {
+				configFileName = "service_adapter_http_config.yml"
+			})
+
+			It("does not need an executable path", func() {
+				Expect(parseErr).NotTo(HaveOccurred())
+				Expect(conf.ServiceAdapter).To(Equal(config.ServiceAdapter{
+					Transport: config.ServiceAdapterTransportHTTP,
+					URL:       "http://localhost:8090",
+				}))
+			})
+		})
+
+		Context("when the service adapter is called over HTTPS with basic auth", func() {
+			BeforeEach(func() {
+				configFileName = "service_adapter_https_config.yml"
+			})
+
+			It("returns the CA certificate, credentials and request timeout", func() {
+				Expect(parseErr).NotTo(HaveOccurred())
+				Expect(conf.ServiceAdapter).To(Equal(config.ServiceAdapter{
+					Transport:   config.ServiceAdapterTransportHTTP,
+					URL:         "https://localhost:8090",
+					TrustedCert: "some-adapter-cert",
+					Authentication: config.ServiceAdapterAuthentication{
+						Basic: config.UserCredentials{Username: "some-adapter-username", Password: "some-adapter-password"},
+					},
+					RequestTimeoutSecs: 60,
+				}))
+			})
+		})
+
+		Context("when the service adapter's basic auth credentials are incomplete", func() {
+			BeforeEach(func() {
+				configFileName = "service_adapter_http_incomplete_basic_auth_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("service_adapter.authentication.basic.password can't be empty"))
+			})
+		})
+
+		Context("when the service adapter has timeouts", func() {
+			BeforeEach(func() {
+				configFileName = "service_adapter_timeouts_config.yml"
+			})
+
+			It("returns the timeouts", func() {
+				Expect(parseErr).NotTo(HaveOccurred())
+				Expect(conf.ServiceAdapter.Timeouts).To(Equal(config.ServiceAdapterTimeouts{
+					GenerateManifestSecs: 300,
+					CreateBindingSecs:    60,
+					DeleteBindingSecs:    60,
+				}))
+			})
+
+			It("gives the adapter client the timeouts", func() {
+				client, err := conf.ServiceAdapter.NewClient(false)
+				Expect(err).NotTo(HaveOccurred())
+				timeouts := client.Timeouts
+				Expect(timeouts.GenerateManifest).To(Equal(5 * time.Minute))
+				Expect(timeouts.CreateBinding).To(Equal(time.Minute))
+				Expect(timeouts.DeleteBinding).To(Equal(time.Minute))
+				Expect(timeouts.DashboardURL).To(BeZero())
+			})
+		})
+
+		Context("when a service adapter timeout is negative", func() {
+			BeforeEach(func() {
+				configFileName = "negative_service_adapter_timeout_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("service_adapter.timeouts can't be negative"))
+			})
+		})
+
+		Context("when the service adapter is called over HTTP without a URL", func() {
+			BeforeEach(func() {
+				configFileName = "service_adapter_http_no_url_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("service_adapter.url can't be empty when service_adapter.transport is http"))
+			})
+		})
+
+		Context("when the service adapter transport is not supported", func() {
+			BeforeEach(func() {
+				configFileName = "service_adapter_unknown_transport_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError(`service_adapter.transport "grpc" is not supported, must be exec or http`))
+			})
+		})
+
 		Context("when the configuration contains an empty service adapter path", func() {
 			BeforeEach(func() {
 				configFileName = "config_with_missing_adapter_path.yml"
@@ -232,6 +364,188 @@ var _ = Describe("Config", func() {
 			})
 		})
 
+		Context("when a failure message pattern is not a valid regular expression", func() {
+			BeforeEach(func() {
+				configFileName = "invalid_failure_message_pattern_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError(ContainSubstring(`broker.last_operation_details.failure_message_patterns: invalid pattern "ODB-ERROR: (.*"`)))
+			})
+		})
+
+		Context("when the maximum number of BOSH tasks in flight is negative", func() {
+			BeforeEach(func() {
+				configFileName = "negative_max_in_flight_bosh_tasks_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("broker.max_in_flight_bosh_tasks can't be negative"))
+			})
+		})
+
+		Context("when operations may be queued but are not stored on disk", func() {
+			BeforeEach(func() {
+				configFileName = "queue_without_operation_store_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("broker.operation_store_path can't be empty when broker.max_in_flight_bosh_tasks is set"))
+			})
+		})
+
+		Context("when a plan has pre-deploy errands but operations are not stored on disk", func() {
+			BeforeEach(func() {
+				configFileName = "pre_deploy_errand_without_operation_store_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("broker.operation_store_path can't be empty when plan some-dedicated-name has pre_deploy errands"))
+			})
+		})
+
+		Context("when the operation store has an encryption key but no path", func() {
+			BeforeEach(func() {
+				configFileName = "operation_store_encryption_key_without_path_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("broker.operation_store_path can't be empty when broker.operation_store_encryption_key is set"))
+			})
+		})
+
+		Context("when the binding store has no encryption key", func() {
+			BeforeEach(func() {
+				configFileName = "binding_store_no_encryption_key_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("broker.binding_store_encryption_key can't be empty when broker.binding_store_path is set"))
+			})
+		})
+
+		Context("when a plan has a binding errand but bindings are not stored on disk", func() {
+			BeforeEach(func() {
+				configFileName = "binding_errand_without_binding_store_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("broker.binding_store_path can't be empty when plan some-dedicated-name has a binding_errand"))
+			})
+		})
+
+		Context("when the maintenance_info version is not a semantic version", func() {
+			BeforeEach(func() {
+				configFileName = "invalid_maintenance_version_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError(ContainSubstring("service_catalog.maintenance_info.version must be a semantic version")))
+			})
+		})
+
+		Context("when the maintenance_info version has build metadata", func() {
+			BeforeEach(func() {
+				configFileName = "maintenance_version_with_build_metadata_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("service_catalog.maintenance_info.version 1.2.0+abc can't have build metadata, the broker adds its own"))
+			})
+		})
+
+		Context("when the configuration lists several service offerings", func() {
+			BeforeEach(func() {
+				configFileName = "multiple_service_offerings_config.yml"
+			})
+
+			It("returns each offering with its own adapter, deployment and catalog", func() {
+				Expect(parseErr).NotTo(HaveOccurred())
+				offerings := conf.Offerings()
+				Expect(offerings).To(HaveLen(2))
+				Expect(offerings[0].ServiceAdapter.Path).To(Equal("test_assets/executable.sh"))
+				Expect(offerings[0].ServiceDeployment.Releases[0].Name).To(Equal("redis-release"))
+				Expect(offerings[0].ServiceCatalog.ID).To(Equal("redis-id"))
+				Expect(offerings[1].ServiceDeployment.Releases[0].Name).To(Equal("kafka-release"))
+				Expect(offerings[1].ServiceCatalog.ID).To(Equal("kafka-id"))
+				Expect(offerings[1].ServiceCatalog.Plans[0].ID).To(Equal("kafka-plan-id"))
+			})
+		})
+
+		Context("when the configuration only has a top-level service offering", func() {
+			BeforeEach(func() {
+				configFileName = "good_config.yml"
+			})
+
+			It("returns it as the only offering", func() {
+				Expect(conf.Offerings()).To(Equal([]config.ServiceOfferingConfig{{
+					ServiceAdapter:    conf.ServiceAdapter,
+					ServiceDeployment: conf.ServiceDeployment,
+					ServiceCatalog:    conf.ServiceCatalog,
+				}}))
+			})
+		})
+
+		Context("when the configuration combines service offerings with a top-level service adapter", func() {
+			BeforeEach(func() {
+				configFileName = "service_offerings_with_top_level_adapter_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("service_offerings cannot be combined with a top-level service_adapter or service_catalog"))
+			})
+		})
+
+		Context("when two service offerings share a plan ID", func() {
+			BeforeEach(func() {
+				configFileName = "service_offerings_duplicate_plan_id_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("plan ID shared-plan-id is used by more than one plan"))
+			})
+		})
+
+		Context("when the global quotas limit instances per org", func() {
+			BeforeEach(func() {
+				configFileName = "global_org_quota_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("org and space quotas can only be configured on plans"))
+			})
+		})
+
+		Context("when a persistent disk quota is set but a plan's disk type has no size", func() {
+			BeforeEach(func() {
+				configFileName = "resource_quota_without_disk_sizes_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError("persistent disk type some-disk of plan some-dedicated-name has no size in persistent_disk_type_sizes_gb"))
+			})
+		})
+
+		Context("when a lifecycle errand targets an invalid instance", func() {
+			BeforeEach(func() {
+				configFileName = "invalid_errand_instance_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError(ContainSubstring(`lifecycle errand health-check: errand instance "redis-server/0/1" must be a group, group/index or group/first`)))
+			})
+		})
+
+		Context("when a plan allows transitions to a plan that does not exist", func() {
+			BeforeEach(func() {
+				configFileName = "invalid_plan_transition_config.yml"
+			})
+
+			It("returns an error", func() {
+				Expect(parseErr).To(MatchError(`plan some-dedicated-plan-id allows transitions to plan "some-missing-plan-id", which does not exist`))
+			})
+		})
+
 		Context("BOSH configuration", func() {
 			Context("when the configuration does not specify a BOSH url", func() {
 				BeforeEach(func() {
@@ -564,6 +878,67 @@ var _ = Describe("Config", func() {
 	})
 })
 
+var _ = Describe("ServiceAdapter", func() {
+	var server *ghttp.Server
+
+	BeforeEach(func() {
+		server = ghttp.NewTLSServer()
+		server.AppendHandlers(ghttp.RespondWith(http.StatusOK, ""))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	trustedCert := func() string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.HTTPTestServer.Certificate().Raw}))
+	}
+
+	It("calls an adapter over HTTPS that presents the trusted certificate", func() {
+		adapter := config.ServiceAdapter{Transport: config.ServiceAdapterTransportHTTP, URL: server.URL(), TrustedCert: trustedCert()}
+		client, err := adapter.NewClient(false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.CheckHealth()).To(Succeed())
+	})
+
+	It("does not trust other certificates", func() {
+		adapter := config.ServiceAdapter{Transport: config.ServiceAdapterTransportHTTP, URL: server.URL()}
+		client, err := adapter.NewClient(false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.CheckHealth()).To(MatchError(ContainSubstring("certificate")))
+	})
+
+	It("sends the basic auth credentials", func() {
+		server.SetHandler(0, ghttp.CombineHandlers(
+			ghttp.VerifyBasicAuth("adapter-user", "adapter-password"),
+			ghttp.RespondWith(http.StatusOK, ""),
+		))
+		adapter := config.ServiceAdapter{
+			Transport:      config.ServiceAdapterTransportHTTP,
+			URL:            server.URL(),
+			TrustedCert:    trustedCert(),
+			Authentication: config.ServiceAdapterAuthentication{Basic: config.UserCredentials{Username: "adapter-user", Password: "adapter-password"}},
+		}
+		client, err := adapter.NewClient(false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.CheckHealth()).To(Succeed())
+	})
+
+	It("gives up on requests that take longer than the request timeout", func() {
+		server.SetHandler(0, func(http.ResponseWriter, *http.Request) {
+			time.Sleep(1500 * time.Millisecond)
+		})
+		adapter := config.ServiceAdapter{Transport: config.ServiceAdapterTransportHTTP, URL: server.URL(), TrustedCert: trustedCert(), RequestTimeoutSecs: 1}
+		client, err := adapter.NewClient(false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.CheckHealth()).To(MatchError(ContainSubstring("Client.Timeout exceeded")))
+	})
+})
+
 var _ = Describe("ServiceOffering", func() {
 	Context("FindPlanByID", func() {
 		var offering = config.ServiceOffering{
@@ -590,6 +965,209 @@ var _ = Describe("ServiceOffering", func() {
 	})
 })
 
+var _ = Describe("LifecycleErrands", func() {
+	It("parses a single errand name for a hook", func() {
+		var errands config.LifecycleErrands
+		Expect(yaml.Unmarshal([]byte(`
+post_deploy: health-check
+pre_delete: cleanup-resources
+`), &errands)).To(Succeed())
+
+		Expect(errands.PostDeploy).To(Equal(config.Errands{{Name: "health-check"}}))
+		Expect(errands.PreDelete).To(Equal(config.Errands{{Name: "cleanup-resources"}}))
+	})
+
+	It("parses an ordered list of errands for each hook", func() {
+		var errands config.LifecycleErrands
+		Expect(yaml.Unmarshal([]byte(`
+pre_deploy: [backup]
+post_deploy:
+- smoke-tests
+- name: register-dashboard
+  continue_on_failure: true
+pre_upgrade:
+- name: drain
+  instances: [proxy, redis-server/0]
+post_upgrade: [migrate-data, smoke-tests]
+`), &errands)).To(Succeed())
+
+		Expect(errands).To(Equal(config.LifecycleErrands{
+			PreDeploy: config.Errands{{Name: "backup"}},
+			PostDeploy: config.Errands{
+				{Name: "smoke-tests"},
+				{Name: "register-dashboard", ContinueOnFailure: true},
+			},
+			PreUpgrade:  config.Errands{{Name: "drain", Instances: []string{"proxy", "redis-server/0"}}},
+			PostUpgrade: config.Errands{{Name: "migrate-data"}, {Name: "smoke-tests"}},
+		}))
+	})
+
+	It("treats an empty errand name as no errands", func() {
+		var errands config.LifecycleErrands
+		Expect(yaml.Unmarshal([]byte(`post_deploy: ""`), &errands)).To(Succeed())
+
+		Expect(errands.PostDeploy).To(BeEmpty())
+	})
+
+	It("round trips through yaml", func() {
+		errands := config.LifecycleErrands{
+			PostDeploy: config.Errands{{Name: "smoke-tests", ContinueOnFailure: true}},
+		}
+		marshalled, err := yaml.Marshal(errands)
+		Expect(err).NotTo(HaveOccurred())
+
+		var unmarshalled config.LifecycleErrands
+		Expect(yaml.Unmarshal(marshalled, &unmarshalled)).To(Succeed())
+		Expect(unmarshalled).To(Equal(errands))
+	})
+})
+
+var _ = Describe("PlanTransitions", func() {
+	It("allows any transition when none are configured", func() {
+		transition, allowed := config.Plan{ID: "small"}.TransitionTo("large")
+		Expect(allowed).To(BeTrue())
+		Expect(transition).To(Equal(config.PlanTransition{PlanID: "large"}))
+	})
+
+	Context("when transitions are configured", func() {
+		var plan config.Plan
+
+		BeforeEach(func() {
+			Expect(yaml.Unmarshal([]byte(`
+plan_id: cluster
+plan_transitions:
+  allowed_targets:
+  - plan_id: large-cluster
+  - plan_id: single-node
+    confirmation_parameter: confirm_data_loss
+    errands: shrink-cluster
+`), &plan)).To(Succeed())
+		})
+
+		It("allows the listed transitions with their settings", func() {
+			transition, allowed := plan.TransitionTo("single-node")
+			Expect(allowed).To(BeTrue())
+			Expect(transition).To(Equal(config.PlanTransition{
+				PlanID:                "single-node",
+				ConfirmationParameter: "confirm_data_loss",
+				Errands:               config.Errands{{Name: "shrink-cluster"}},
+			}))
+		})
+
+		It("forbids the other transitions", func() {
+			_, allowed := plan.TransitionTo("small-cluster")
+			Expect(allowed).To(BeFalse())
+		})
+	})
+
+	It("forbids every transition when no targets are allowed", func() {
+		var plan config.Plan
+		Expect(yaml.Unmarshal([]byte(`
+plan_id: cluster
+plan_transitions:
+  allowed_targets: []
+`), &plan)).To(Succeed())
+
+		_, allowed := plan.TransitionTo("large-cluster")
+		Expect(allowed).To(BeFalse())
+	})
+})
+
+var _ = Describe("Quotas", func() {
+	It("parses org and space limits", func() {
+		var quotas config.Quotas
+		Expect(yaml.Unmarshal([]byte(`
+service_instance_limit: 20
+orgs:
+  service_instance_limit: 5
+  overrides:
+    big-org-guid: 10
+spaces:
+  service_instance_limit: 2
+`), &quotas)).To(Succeed())
+
+		orgLimit, found := quotas.Orgs.LimitFor("big-org-guid")
+		Expect(found).To(BeTrue())
+		Expect(orgLimit).To(Equal(10))
+
+		spaceLimit, found := quotas.Spaces.LimitFor("some-space-guid")
+		Expect(found).To(BeTrue())
+		Expect(spaceLimit).To(Equal(2))
+	})
+
+	Context("ScopeQuota#LimitFor", func() {
+		var defaultLimit = 5
+
+		It("falls back to the default limit", func() {
+			quota := &config.ScopeQuota{ServiceInstanceLimit: &defaultLimit, Overrides: map[string]int{"big-org-guid": 10}}
+			limit, found := quota.LimitFor("some-org-guid")
+			Expect(found).To(BeTrue())
+			Expect(limit).To(Equal(5))
+		})
+
+		It("has no limit without a default or an override", func() {
+			quota := &config.ScopeQuota{Overrides: map[string]int{"big-org-guid": 10}}
+			_, found := quota.LimitFor("some-org-guid")
+			Expect(found).To(BeFalse())
+		})
+
+		It("has no limit when the GUID is unknown", func() {
+			quota := &config.ScopeQuota{ServiceInstanceLimit: &defaultLimit}
+			_, found := quota.LimitFor("")
+			Expect(found).To(BeFalse())
+		})
+
+		It("has no limit when not configured", func() {
+			var quota *config.ScopeQuota
+			_, found := quota.LimitFor("some-org-guid")
+			Expect(found).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("ServiceOfferingConfig", func() {
+	Context("MaintenanceVersion", func() {
+		var offering config.ServiceOfferingConfig
+
+		BeforeEach(func() {
+			offering = config.ServiceOfferingConfig{
+				ServiceDeployment: config.ServiceDeployment{
+					Releases: serviceadapter.ServiceReleases{{Name: "redis", Version: "1.2.3", Jobs: []string{"redis-server"}}},
+					Stemcell: serviceadapter.Stemcell{OS: "ubuntu-xenial", Version: "170.1"},
+				},
+				ServiceCatalog: config.ServiceOffering{
+					MaintenanceInfo: &config.MaintenanceInfo{Version: "1.2.0"},
+				},
+			}
+		})
+
+		It("is the operator's version with the deployment hash as build metadata", func() {
+			Expect(offering.MaintenanceVersion()).To(MatchRegexp(`^1\.2\.0\+[0-9a-f]{12}$`))
+		})
+
+		It("is empty when the operator has not set a version", func() {
+			offering.ServiceCatalog.MaintenanceInfo = nil
+			Expect(offering.MaintenanceVersion()).To(BeEmpty())
+		})
+
+		It("is stable for the same releases and stemcell", func() {
+			Expect(offering.MaintenanceVersion()).To(Equal(offering.MaintenanceVersion()))
+		})
+
+		It("changes when a release version changes", func() {
+			before := offering.MaintenanceVersion()
+			offering.ServiceDeployment.Releases[0].Version = "1.2.4"
+			Expect(offering.MaintenanceVersion()).NotTo(Equal(before))
+		})
+
+		It("changes when the stemcell version changes", func() {
+			before := offering.MaintenanceVersion()
+			offering.ServiceDeployment.Stemcell.Version = "170.2"
+			Expect(offering.MaintenanceVersion()).NotTo(Equal(before))
+		})
+	})
+})
+
 var _ = Describe("CF#NewAuthHeaderBuilder", func() {
 	const tokenToReturn = "auth-token"
 	var logger *log.Logger
@@ -0,0 +1,146 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	InstancesResource        = "instances"
+	PersistentDiskGBResource = "persistent_disk_gb"
+)
+
+func InstanceGroupResource(instanceGroupName string) string {
+	return "instance_groups/" + instanceGroupName
+}
+
+// ResourceLimits caps the resources used by all deployments of a plan, or of
+// a whole service offering when set in global_quotas.
+type ResourceLimits struct {
+	Instances        *int           `yaml:"instances,omitempty"`
+	InstanceGroups   map[string]int `yaml:"instance_groups,omitempty"`
+	PersistentDiskGB *int           `yaml:"persistent_disk_gb,omitempty"`
+}
+
+func (l *ResourceLimits) Limits() map[string]int {
+	limits := map[string]int{}
+	if l == nil {
+		return limits
+	}
+
+	if l.Instances != nil {
+		limits[InstancesResource] = *l.Instances
+	}
+	for name, limit := range l.InstanceGroups {
+		limits[InstanceGroupResource(name)] = limit
+	}
+	if l.PersistentDiskGB != nil {
+		limits[PersistentDiskGBResource] = *l.PersistentDiskGB
+	}
+
+	return limits
+}
+
+// ExceededBy returns the first resource, in name order, for which the usage
+// is over the limit.
+func (l *ResourceLimits) ExceededBy(usage ResourceUsage) (string, bool) {
+	limits := l.Limits()
+
+	var resources []string
+	for resource := range limits {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	for _, resource := range resources {
+		if usage[resource] > limits[resource] {
+			return resource, true
+		}
+	}
+
+	return "", false
+}
+
+type ResourceUsage map[string]int
+
+func (u ResourceUsage) Plus(other ResourceUsage, times int) ResourceUsage {
+	sum := ResourceUsage{}
+	for resource, amount := range u {
+		sum[resource] += amount
+	}
+	for resource, amount := range other {
+		sum[resource] += amount * times
+	}
+	return sum
+}
+
+// ResourceUsage is what a single deployment of the plan uses.
+func (p Plan) ResourceUsage(persistentDiskTypeSizesGB map[string]int) ResourceUsage {
+	usage := ResourceUsage{}
+	for _, instanceGroup := range p.InstanceGroups {
+		usage[InstancesResource] += instanceGroup.Instances
+		usage[InstanceGroupResource(instanceGroup.Name)] += instanceGroup.Instances
+		if instanceGroup.PersistentDiskType != "" {
+			usage[PersistentDiskGBResource] += instanceGroup.Instances * persistentDiskTypeSizesGB[instanceGroup.PersistentDiskType]
+		}
+	}
+	return usage
+}
+
+// ResourceUsage is what all deployments of the offering use, given the number
+// of instances of each plan.
+func (s ServiceOffering) ResourceUsage(instanceCountsByPlanID map[string]int) ResourceUsage {
+	usage := ResourceUsage{}
+	for _, plan := range s.Plans {
+		usage = usage.Plus(plan.ResourceUsage(s.PersistentDiskTypeSizesGB), instanceCountsByPlanID[plan.ID])
+	}
+	return usage
+}
+
+func (s ServiceOffering) HasResourceQuotas() bool {
+	if s.GlobalQuotas.Resources != nil {
+		return true
+	}
+	for _, plan := range s.Plans {
+		if plan.Quotas.Resources != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ServiceOffering) validatePersistentDiskSizes() error {
+	diskQuotaSet := s.GlobalQuotas.Resources != nil && s.GlobalQuotas.Resources.PersistentDiskGB != nil
+	for _, plan := range s.Plans {
+		if plan.Quotas.Resources != nil && plan.Quotas.Resources.PersistentDiskGB != nil {
+			diskQuotaSet = true
+		}
+	}
+
+	if !diskQuotaSet {
+		return nil
+	}
+
+	for _, plan := range s.Plans {
+		for _, instanceGroup := range plan.InstanceGroups {
+			if instanceGroup.PersistentDiskType == "" {
+				continue
+			}
+			if _, found := s.PersistentDiskTypeSizesGB[instanceGroup.PersistentDiskType]; !found {
+				return fmt.Errorf(
+					"persistent disk type %s of plan %s has no size in persistent_disk_type_sizes_gb",
+					instanceGroup.PersistentDiskType,
+					plan.Name,
+				)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package config_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("resource quotas", func() {
+	var (
+		smallPlan = config.Plan{
+			ID: "small",
+			InstanceGroups: []serviceadapter.InstanceGroup{
+				{Name: "redis", Instances: 1, PersistentDiskType: "ten"},
+			},
+		}
+		clusterPlan = config.Plan{
+			ID: "cluster",
+			InstanceGroups: []serviceadapter.InstanceGroup{
+				{Name: "redis", Instances: 3, PersistentDiskType: "fifty"},
+				{Name: "sentinel", Instances: 2},
+			},
+		}
+		diskSizes = map[string]int{"ten": 10, "fifty": 50}
+	)
+
+	Describe("Plan#ResourceUsage", func() {
+		It("adds up the instances and persistent disk of every instance group", func() {
+			Expect(clusterPlan.ResourceUsage(diskSizes)).To(Equal(config.ResourceUsage{
+				config.InstancesResource:                 5,
+				config.InstanceGroupResource("redis"):    3,
+				config.InstanceGroupResource("sentinel"): 2,
+				config.PersistentDiskGBResource:          150,
+			}))
+		})
+	})
+
+	Describe("ServiceOffering#ResourceUsage", func() {
+		It("multiplies each plan's usage by its instance count", func() {
+			offering := config.ServiceOffering{
+				Plans:                     []config.Plan{smallPlan, clusterPlan},
+				PersistentDiskTypeSizesGB: diskSizes,
+			}
+
+			Expect(offering.ResourceUsage(map[string]int{"small": 4, "cluster": 2})).To(Equal(config.ResourceUsage{
+				config.InstancesResource:                 14,
+				config.InstanceGroupResource("redis"):    10,
+				config.InstanceGroupResource("sentinel"): 4,
+				config.PersistentDiskGBResource:          340,
+			}))
+		})
+	})
+
+	Describe("ResourceLimits", func() {
+		var limits *config.ResourceLimits
+
+		BeforeEach(func() {
+			Expect(yaml.Unmarshal([]byte(`
+instances: 10
+instance_groups:
+  sentinel: 4
+persistent_disk_gb: 100
+`), &limits)).To(Succeed())
+		})
+
+		It("flattens the limits by resource", func() {
+			Expect(limits.Limits()).To(Equal(map[string]int{
+				config.InstancesResource:                 10,
+				config.InstanceGroupResource("sentinel"): 4,
+				config.PersistentDiskGBResource:          100,
+			}))
+		})
+
+		It("is not exceeded when the usage is at the limit", func() {
+			_, exceeded := limits.ExceededBy(config.ResourceUsage{config.InstancesResource: 10, config.PersistentDiskGBResource: 100})
+			Expect(exceeded).To(BeFalse())
+		})
+
+		It("names the resource that is over its limit", func() {
+			resource, exceeded := limits.ExceededBy(config.ResourceUsage{config.InstancesResource: 6, config.InstanceGroupResource("sentinel"): 5})
+			Expect(exceeded).To(BeTrue())
+			Expect(resource).To(Equal("instance_groups/sentinel"))
+		})
+
+		It("is never exceeded when not configured", func() {
+			var noLimits *config.ResourceLimits
+			_, exceeded := noLimits.ExceededBy(config.ResourceUsage{config.InstancesResource: 1000})
+			Expect(exceeded).To(BeFalse())
+		})
+	})
+})
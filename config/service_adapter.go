@@ -0,0 +1,152 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/craigfurman/herottp"
+	"github.com/pivotal-cf/on-demand-service-broker/network"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+)
+
+const (
+	ServiceAdapterTransportExec = "exec"
+	ServiceAdapterTransportHTTP = "http"
+
+	defaultServiceAdapterRequestTimeout = 5 * time.Minute
+)
+
+// ServiceAdapter is executed at Path for every call by default. With the
+// http transport the broker calls a long-running adapter process at URL
+// instead, trusting TrustedCert and sending Authentication if they are set.
+type ServiceAdapter struct {
+	Path               string
+	Transport          string
+	URL                string
+	TrustedCert        string `yaml:"root_ca_cert"`
+	Authentication     ServiceAdapterAuthentication
+	RequestTimeoutSecs int `yaml:"request_timeout_in_seconds"`
+	Timeouts           ServiceAdapterTimeouts
+}
+
+type ServiceAdapterAuthentication struct {
+	Basic UserCredentials
+}
+
+// ServiceAdapterTimeouts limit how long each adapter subcommand may run for.
+// A subcommand without a timeout runs for as long as its request lasts.
+type ServiceAdapterTimeouts struct {
+	GenerateManifestSecs int `yaml:"generate_manifest_in_seconds"`
+	CreateBindingSecs    int `yaml:"create_binding_in_seconds"`
+	DeleteBindingSecs    int `yaml:"delete_binding_in_seconds"`
+	DashboardURLSecs     int `yaml:"dashboard_url_in_seconds"`
+}
+
+func (t ServiceAdapterTimeouts) Validate() error {
+	if t.GenerateManifestSecs < 0 || t.CreateBindingSecs < 0 || t.DeleteBindingSecs < 0 || t.DashboardURLSecs < 0 {
+		return errors.New("service_adapter.timeouts can't be negative")
+	}
+	return nil
+}
+
+func (t ServiceAdapterTimeouts) clientTimeouts() serviceadapter.Timeouts {
+	return serviceadapter.Timeouts{
+		GenerateManifest: time.Duration(t.GenerateManifestSecs) * time.Second,
+		CreateBinding:    time.Duration(t.CreateBindingSecs) * time.Second,
+		DeleteBinding:    time.Duration(t.DeleteBindingSecs) * time.Second,
+		DashboardURL:     time.Duration(t.DashboardURLSecs) * time.Second,
+	}
+}
+
+func (a ServiceAdapter) Validate() error {
+	switch a.Transport {
+	case "", ServiceAdapterTransportExec:
+		if err := checkIsExecutableFile(a.Path); err != nil {
+			return fmt.Errorf("checking for executable service adapter file: %s", err)
+		}
+	case ServiceAdapterTransportHTTP:
+		if a.URL == "" {
+			return errors.New("service_adapter.url can't be empty when service_adapter.transport is http")
+		}
+		if _, err := url.ParseRequestURI(a.URL); err != nil {
+			return fmt.Errorf("service_adapter.url is invalid: %s", err)
+		}
+		if a.Authentication.Basic.IsSet() {
+			if err := validateNoFieldsEmptyString(a.Authentication.Basic, "service_adapter.authentication.basic"); err != nil {
+				return err
+			}
+		}
+		if a.RequestTimeoutSecs < 0 {
+			return errors.New("service_adapter.request_timeout_in_seconds can't be negative")
+		}
+	default:
+		return fmt.Errorf("service_adapter.transport %q is not supported, must be %s or %s", a.Transport, ServiceAdapterTransportExec, ServiceAdapterTransportHTTP)
+	}
+
+	return a.Timeouts.Validate()
+}
+
+func (a ServiceAdapter) NewClient(disableSSLCertVerification bool) (*serviceadapter.Client, error) {
+	if a.Transport == ServiceAdapterTransportHTTP {
+		doer, err := a.httpClient(disableSSLCertVerification)
+		if err != nil {
+			return nil, err
+		}
+		return &serviceadapter.Client{
+			CommandRunner: serviceadapter.NewHTTPCommandRunner(doer, a.URL),
+			Timeouts:      a.Timeouts.clientTimeouts(),
+		}, nil
+	}
+
+	return &serviceadapter.Client{
+		ExternalBinPath: a.Path,
+		CommandRunner:   serviceadapter.NewCommandRunner(),
+		Timeouts:        a.Timeouts.clientTimeouts(),
+	}, nil
+}
+
+// httpClient gives up on a request to the adapter after the request
+// timeout, which bounds subcommands that have no timeout of their own.
+func (a ServiceAdapter) httpClient(disableSSLCertVerification bool) (network.Doer, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+	rootCAs.AppendCertsFromPEM([]byte(a.TrustedCert))
+
+	timeout := defaultServiceAdapterRequestTimeout
+	if a.RequestTimeoutSecs > 0 {
+		timeout = time.Duration(a.RequestTimeoutSecs) * time.Second
+	}
+
+	var doer network.Doer = herottp.New(herottp.Config{
+		NoFollowRedirect:                  true,
+		DisableTLSCertificateVerification: disableSSLCertVerification,
+		RootCAs:                           rootCAs,
+		Timeout:                           timeout,
+	})
+	if a.Authentication.Basic.IsSet() {
+		doer = basicAuthDoer{doer: doer, credentials: a.Authentication.Basic}
+	}
+	return doer, nil
+}
+
+type basicAuthDoer struct {
+	doer        network.Doer
+	credentials UserCredentials
+}
+
+func (d basicAuthDoer) Do(request *http.Request) (*http.Response, error) {
+	request.SetBasicAuth(d.credentials.Username, d.credentials.Password)
+	return d.doer.Do(request)
+}
@@ -0,0 +1,293 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+// Package conformance runs a service adapter the way the broker does, for
+// every configured plan, and reports the outputs the broker would reject.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+	"github.com/pivotal-cf/on-demand-service-broker/task"
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	InstanceID       = "conformance-instance-id"
+	BindingID        = "conformance-binding-id"
+	AppGUID          = "conformance-app-guid"
+	OrganizationGUID = "conformance-organization-guid"
+	SpaceGUID        = "conformance-space-guid"
+)
+
+const (
+	Pass = Outcome("PASS")
+	Fail = Outcome("FAIL")
+	Skip = Outcome("SKIP")
+)
+
+type Outcome string
+
+type Result struct {
+	ServiceOffering string
+	Plan            string
+	Check           string
+	Outcome         Outcome
+	Message         string `json:",omitempty"`
+}
+
+type Report []Result
+
+func (r Report) Failed() bool {
+	for _, result := range r {
+		if result.Outcome == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Report) Write(w io.Writer) {
+	counts := map[Outcome]int{}
+	for _, result := range r {
+		counts[result.Outcome]++
+		fmt.Fprintf(w, "%s %s/%s: %s\n", result.Outcome, result.ServiceOffering, result.Plan, result.Check)
+		if result.Message != "" {
+			fmt.Fprintf(w, "    %s\n", result.Message)
+		}
+	}
+	fmt.Fprintf(w, "\n%d checks: %d passed, %d failed, %d skipped\n", len(r), counts[Pass], counts[Fail], counts[Skip])
+}
+
+// Check runs the adapter of every service offering in the config through
+// the calls the broker makes over the life of an instance of each plan.
+func Check(conf config.Config, newAdapterClient func(config.ServiceAdapter) (*serviceadapter.Client, error), logger *log.Logger) (Report, error) {
+	var serviceNames []string
+	for _, offering := range conf.Offerings() {
+		serviceNames = append(serviceNames, offering.ServiceCatalog.Name)
+	}
+
+	deploymentNames, err := broker.NewDeploymentNames(conf.Broker.DeploymentNameTemplate, serviceNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var report Report
+	for _, offering := range conf.Offerings() {
+		adapter, err := newAdapterClient(offering.ServiceAdapter)
+		if err != nil {
+			return nil, err
+		}
+		if err := adapter.CheckHealth(); err != nil {
+			return nil, err
+		}
+
+		c := checker{
+			offering: offering.ServiceCatalog,
+			adapter:  adapter,
+			manifestGenerator: task.NewManifestGenerator(
+				adapter,
+				offering.ServiceCatalog,
+				offering.ServiceDeployment.Stemcell,
+				offering.ServiceDeployment.Releases,
+			),
+			deploymentName: deploymentNames.DeploymentName(InstanceID),
+			logger:         logger,
+		}
+
+		for _, plan := range offering.ServiceCatalog.Plans {
+			c.checkPlan(plan)
+		}
+		report = append(report, c.report...)
+	}
+
+	return report, nil
+}
+
+type checker struct {
+	offering          config.ServiceOffering
+	adapter           *serviceadapter.Client
+	manifestGenerator task.ManifestGenerator
+	deploymentName    string
+	logger            *log.Logger
+
+	report Report
+}
+
+func (c *checker) checkPlan(plan config.Plan) {
+	manifest, generated := c.generateManifest(plan, "generate-manifest for a new instance", plan.ID, c.provisionParams(plan), nil, nil)
+	if !generated {
+		c.record(plan, "create-binding", Skip, "needs the manifest of a new instance")
+		c.record(plan, "delete-binding", Skip, "needs the manifest of a new instance")
+		c.record(plan, "dashboard-url", Skip, "needs the manifest of a new instance")
+		return
+	}
+
+	c.generateManifest(plan, "generate-manifest for an upgrade", plan.ID, map[string]interface{}{}, manifest, &plan.ID)
+	c.generateManifest(plan, "generate-manifest for an update", plan.ID, c.updateParams(plan.ID, plan.ID), manifest, &plan.ID)
+
+	for _, target := range c.offering.Plans {
+		if target.ID == plan.ID {
+			continue
+		}
+		if _, allowed := plan.TransitionTo(target.ID); !allowed {
+			continue
+		}
+		check := fmt.Sprintf("generate-manifest for a plan change to %s", target.Name)
+		c.generateManifest(plan, check, target.ID, c.updateParams(target.ID, plan.ID), manifest, &plan.ID)
+	}
+
+	c.checkBindings(plan, manifest)
+	c.checkDashboardURL(plan, manifest)
+}
+
+func (c *checker) generateManifest(
+	plan config.Plan,
+	check string,
+	planID string,
+	requestParams map[string]interface{},
+	previousManifest []byte,
+	previousPlanID *string,
+) ([]byte, bool) {
+	manifest, err := c.manifestGenerator.GenerateManifest(context.Background(), c.deploymentName, planID, requestParams, previousManifest, previousPlanID, c.logger)
+	if err != nil {
+		c.record(plan, check, Fail, err.Error())
+		return nil, false
+	}
+
+	c.record(plan, check, Pass, "")
+	return manifest, true
+}
+
+func (c *checker) checkBindings(plan config.Plan, manifest []byte) {
+	topology, err := topologyOf(manifest)
+	if err != nil {
+		c.record(plan, "create-binding", Fail, err.Error())
+		c.record(plan, "delete-binding", Skip, "needs a binding")
+		return
+	}
+
+	binding, err := c.adapter.CreateBinding(context.Background(), BindingID, topology, manifest, c.bindParams(plan), c.logger)
+	if err != nil {
+		c.record(plan, "create-binding", Fail, err.Error())
+		c.record(plan, "delete-binding", Skip, "needs a binding")
+		return
+	}
+	c.record(plan, "create-binding", Pass, "")
+
+	unbindParams := map[string]interface{}{
+		"plan_id":    plan.ID,
+		"service_id": c.offering.ID,
+	}
+	if err := c.adapter.DeleteBinding(context.Background(), BindingID, topology, manifest, unbindParams, binding.Credentials, c.logger); err != nil {
+		c.record(plan, "delete-binding", Fail, err.Error())
+		return
+	}
+	c.record(plan, "delete-binding", Pass, "")
+}
+
+func (c *checker) checkDashboardURL(plan config.Plan, manifest []byte) {
+	dashboardURL, err := c.adapter.GenerateDashboardUrl(context.Background(), InstanceID, plan.AdapterPlan(c.offering.GlobalProperties), manifest, c.logger)
+	switch err.(type) {
+	case nil:
+	case serviceadapter.NotImplementedError:
+		c.record(plan, "dashboard-url", Skip, "not implemented by the adapter")
+		return
+	default:
+		c.record(plan, "dashboard-url", Fail, err.Error())
+		return
+	}
+
+	if parsed, err := url.Parse(dashboardURL); err != nil || !parsed.IsAbs() {
+		c.record(plan, "dashboard-url", Fail, fmt.Sprintf("dashboard URL %q is not an absolute URL", dashboardURL))
+		return
+	}
+	c.record(plan, "dashboard-url", Pass, "")
+}
+
+func (c *checker) record(plan config.Plan, check string, outcome Outcome, message string) {
+	c.report = append(c.report, Result{
+		ServiceOffering: c.offering.Name,
+		Plan:            plan.Name,
+		Check:           check,
+		Outcome:         outcome,
+		Message:         message,
+	})
+}
+
+func (c *checker) provisionParams(plan config.Plan) map[string]interface{} {
+	return map[string]interface{}{
+		"service_id":        c.offering.ID,
+		"plan_id":           plan.ID,
+		"organization_guid": OrganizationGUID,
+		"space_guid":        SpaceGUID,
+		"parameters":        nil,
+		"context": map[string]interface{}{
+			"platform":          "cloudfoundry",
+			"organization_guid": OrganizationGUID,
+			"space_guid":        SpaceGUID,
+		},
+	}
+}
+
+func (c *checker) updateParams(planID, previousPlanID string) map[string]interface{} {
+	return map[string]interface{}{
+		"service_id": c.offering.ID,
+		"plan_id":    planID,
+		"parameters": nil,
+		"previous_values": map[string]interface{}{
+			"service_id":      c.offering.ID,
+			"plan_id":         previousPlanID,
+			"organization_id": OrganizationGUID,
+			"space_id":        SpaceGUID,
+		},
+	}
+}
+
+func (c *checker) bindParams(plan config.Plan) map[string]interface{} {
+	return map[string]interface{}{
+		"service_id": c.offering.ID,
+		"plan_id":    plan.ID,
+		"app_guid":   AppGUID,
+		"parameters": nil,
+		"bind_resource": map[string]interface{}{
+			"app_guid": AppGUID,
+		},
+	}
+}
+
+// topologyOf gives every instance of the manifest's instance groups an
+// address, as BOSH would once the instance is deployed.
+func topologyOf(manifest []byte) (bosh.BoshVMs, error) {
+	var deployment struct {
+		InstanceGroups []struct {
+			Name      string `yaml:"name"`
+			Instances int    `yaml:"instances"`
+		} `yaml:"instance_groups"`
+	}
+	if err := yaml.Unmarshal(manifest, &deployment); err != nil {
+		return nil, fmt.Errorf("reading instance groups from the manifest: %s", err)
+	}
+
+	topology := bosh.BoshVMs{}
+	address := 0
+	for _, group := range deployment.InstanceGroups {
+		topology[group.Name] = []string{}
+		for i := 0; i < group.Instances; i++ {
+			address++
+			topology[group.Name] = append(topology[group.Name], fmt.Sprintf("10.0.%d.%d", address/256, address%256))
+		}
+	}
+	return topology, nil
+}
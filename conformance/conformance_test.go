@@ -0,0 +1,231 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package conformance_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	"github.com/pivotal-cf/on-demand-service-broker/conformance"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter/fakes"
+	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("checking a service adapter", func() {
+	const (
+		adapterPath    = "/path/to/adapter"
+		deploymentName = "service-instance_" + conformance.InstanceID
+	)
+
+	var (
+		conf          config.Config
+		commandRunner *fakes.FakeCommandRunner
+		outputs       map[string]string
+		exitCodes     map[string]int
+
+		report   conformance.Report
+		checkErr error
+	)
+
+	BeforeEach(func() {
+		conf = config.Config{
+			ServiceAdapter: config.ServiceAdapter{Path: adapterPath},
+			ServiceDeployment: config.ServiceDeployment{
+				Releases: sdk.ServiceReleases{{Name: "redis", Version: "1.2.3", Jobs: []string{"redis-server"}}},
+				Stemcell: sdk.Stemcell{OS: "ubuntu-trusty", Version: "3421.11"},
+			},
+			ServiceCatalog: config.ServiceOffering{
+				ID:   "redis-id",
+				Name: "redis",
+				Plans: []config.Plan{
+					{ID: "small-id", Name: "small"},
+					{ID: "large-id", Name: "large", PlanTransitions: &config.PlanTransitions{}},
+				},
+			},
+		}
+
+		outputs = map[string]string{
+			"generate-manifest": "name: " + deploymentName + "\ninstance_groups:\n- name: redis-server\n  instances: 2\n",
+			"create-binding":    `{"credentials": {"password": "secret"}}`,
+			"delete-binding":    "",
+			"dashboard-url":     `{"dashboard_url": "https://dashboard.example.com/instance"}`,
+		}
+		exitCodes = map[string]int{}
+
+		commandRunner = new(fakes.FakeCommandRunner)
+		commandRunner.RunStub = func(ctx context.Context, stdin []byte, args ...string) ([]byte, []byte, *int, error) {
+			exitCode := exitCodes[args[1]]
+			return []byte(outputs[args[1]]), nil, &exitCode, nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		newAdapterClient := func(adapter config.ServiceAdapter) (*serviceadapter.Client, error) {
+			return &serviceadapter.Client{ExternalBinPath: adapter.Path, CommandRunner: commandRunner}, nil
+		}
+		report, checkErr = conformance.Check(conf, newAdapterClient, log.New(ioutil.Discard, "", 0))
+	})
+
+	checks := func(plan string) map[string]conformance.Outcome {
+		outcomes := map[string]conformance.Outcome{}
+		for _, result := range report {
+			if result.Plan == plan {
+				outcomes[result.Check] = result.Outcome
+			}
+		}
+		return outcomes
+	}
+
+	Context("when the adapter conforms", func() {
+		It("passes every check", func() {
+			Expect(checkErr).NotTo(HaveOccurred())
+			Expect(report.Failed()).To(BeFalse())
+
+			Expect(checks("small")).To(Equal(map[string]conformance.Outcome{
+				"generate-manifest for a new instance":         conformance.Pass,
+				"generate-manifest for an upgrade":             conformance.Pass,
+				"generate-manifest for an update":              conformance.Pass,
+				"generate-manifest for a plan change to large": conformance.Pass,
+				"create-binding":                               conformance.Pass,
+				"delete-binding":                               conformance.Pass,
+				"dashboard-url":                                conformance.Pass,
+			}))
+		})
+
+		It("only changes plans where the plan allows it", func() {
+			Expect(checks("large")).NotTo(HaveKey("generate-manifest for a plan change to small"))
+		})
+
+		It("calls the adapter as the broker does", func() {
+			_, _, args := commandRunner.RunArgsForCall(0)
+			Expect(args[0]).To(Equal(adapterPath))
+			Expect(args[1]).To(Equal("generate-manifest"))
+
+			var serviceDeployment sdk.ServiceDeployment
+			Expect(json.Unmarshal([]byte(args[2]), &serviceDeployment)).To(Succeed())
+			Expect(serviceDeployment.DeploymentName).To(Equal(deploymentName))
+			Expect(serviceDeployment.Releases[0].Version).To(Equal("1.2.3"))
+
+			for i := 0; i < commandRunner.RunCallCount(); i++ {
+				if _, _, args := commandRunner.RunArgsForCall(i); args[1] == "create-binding" {
+					Expect(args[3]).To(MatchJSON(`{"redis-server": ["10.0.0.1", "10.0.0.2"]}`))
+					return
+				}
+			}
+			Fail("create-binding was not called")
+		})
+
+		Context("and the deployment name template is customised", func() {
+			BeforeEach(func() {
+				conf.Broker.DeploymentNameTemplate = "{service_name}-{instance_id}"
+				outputs["generate-manifest"] = "name: redis-" + conformance.InstanceID
+			})
+
+			It("expects the deployment name from the template", func() {
+				Expect(report.Failed()).To(BeFalse())
+			})
+		})
+	})
+
+	Context("when the adapter generates a manifest with the wrong deployment name", func() {
+		BeforeEach(func() {
+			outputs["generate-manifest"] = "name: some-other-name"
+		})
+
+		It("fails and skips the checks that need the manifest", func() {
+			Expect(report.Failed()).To(BeTrue())
+			Expect(report[0].Outcome).To(Equal(conformance.Fail))
+			Expect(report[0].Message).To(ContainSubstring("incorrect deployment name"))
+			Expect(checks("small")).To(Equal(map[string]conformance.Outcome{
+				"generate-manifest for a new instance": conformance.Fail,
+				"create-binding":                       conformance.Skip,
+				"delete-binding":                       conformance.Skip,
+				"dashboard-url":                        conformance.Skip,
+			}))
+		})
+	})
+
+	Context("when the adapter returns a binding that is not valid JSON", func() {
+		BeforeEach(func() {
+			outputs["create-binding"] = "password=secret"
+		})
+
+		It("fails the binding check", func() {
+			Expect(report.Failed()).To(BeTrue())
+			Expect(checks("small")["create-binding"]).To(Equal(conformance.Fail))
+			Expect(checks("small")["delete-binding"]).To(Equal(conformance.Skip))
+		})
+	})
+
+	Context("when the adapter exits with an unexpected code", func() {
+		BeforeEach(func() {
+			exitCodes["delete-binding"] = 3
+		})
+
+		It("fails the check", func() {
+			Expect(checks("small")["delete-binding"]).To(Equal(conformance.Fail))
+		})
+	})
+
+	Context("when the adapter does not implement dashboard URLs", func() {
+		BeforeEach(func() {
+			exitCodes["dashboard-url"] = sdk.NotImplementedExitCode
+		})
+
+		It("skips the check", func() {
+			Expect(report.Failed()).To(BeFalse())
+			Expect(checks("small")["dashboard-url"]).To(Equal(conformance.Skip))
+		})
+	})
+
+	Context("when the dashboard URL is not absolute", func() {
+		BeforeEach(func() {
+			outputs["dashboard-url"] = `{"dashboard_url": "dashboard/instance"}`
+		})
+
+		It("fails the check", func() {
+			Expect(checks("small")["dashboard-url"]).To(Equal(conformance.Fail))
+		})
+	})
+
+	Context("when the deployment name template is invalid", func() {
+		BeforeEach(func() {
+			conf.Broker.DeploymentNameTemplate = "no-instance-id"
+		})
+
+		It("returns an error", func() {
+			Expect(checkErr).To(HaveOccurred())
+		})
+	})
+
+	It("writes a report for CI", func() {
+		report := conformance.Report{
+			{ServiceOffering: "redis", Plan: "small", Check: "create-binding", Outcome: conformance.Pass},
+			{ServiceOffering: "redis", Plan: "small", Check: "delete-binding", Outcome: conformance.Fail, Message: "binding not found"},
+			{ServiceOffering: "redis", Plan: "small", Check: "dashboard-url", Outcome: conformance.Skip, Message: "not implemented by the adapter"},
+		}
+
+		output := new(bytes.Buffer)
+		report.Write(output)
+
+		Expect(output.String()).To(Equal(`PASS redis/small: create-binding
+FAIL redis/small: delete-binding
+    binding not found
+SKIP redis/small: dashboard-url
+    not implemented by the adapter
+
+3 checks: 1 passed, 1 failed, 1 skipped
+`))
+	})
+})
@@ -77,11 +77,12 @@ var _ = Describe("Catalog", func() {
 			Expect(catalog).To(Equal(map[string][]brokerapi.Service{
 				"services": {
 					{
-						ID:            serviceID,
-						Name:          serviceName,
-						Description:   serviceDescription,
-						Bindable:      serviceBindable,
-						PlanUpdatable: servicePlanUpdatable,
+						ID:                   serviceID,
+						Name:                 serviceName,
+						Description:          serviceDescription,
+						Bindable:             serviceBindable,
+						PlanUpdatable:        servicePlanUpdatable,
+						InstancesRetrievable: true,
 						Metadata: &brokerapi.ServiceMetadata{
 							DisplayName:         serviceMetadataDisplayName,
 							ImageUrl:            serviceMetadataImageURL,
@@ -148,11 +149,12 @@ var _ = Describe("Catalog", func() {
 			Expect(catalog).To(Equal(map[string][]brokerapi.Service{
 				"services": {
 					{
-						ID:            serviceID,
-						Name:          serviceName,
-						Description:   serviceDescription,
-						Bindable:      serviceBindable,
-						PlanUpdatable: servicePlanUpdatable,
+						ID:                   serviceID,
+						Name:                 serviceName,
+						Description:          serviceDescription,
+						Bindable:             serviceBindable,
+						PlanUpdatable:        servicePlanUpdatable,
+						InstancesRetrievable: true,
 						Metadata: &brokerapi.ServiceMetadata{
 							DisplayName:         serviceMetadataDisplayName,
 							ImageUrl:            serviceMetadataImageURL,
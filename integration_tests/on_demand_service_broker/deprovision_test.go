@@ -141,7 +141,7 @@ var _ = Describe("deprovisioning service instances", func() {
 						},
 					},
 					LifecycleErrands: &config.LifecycleErrands{
-						PreDelete: errandName,
+						PreDelete: config.Errands{{Name: errandName}},
 					},
 				}
 				conf.ServiceCatalog.Plans = config.Plans{preDeleteErrandPlan}
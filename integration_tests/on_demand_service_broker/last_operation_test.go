@@ -251,7 +251,7 @@ var _ = Describe("last operation", func() {
 				ID:   planID,
 				Name: "post-deploy-plan",
 				LifecycleErrands: &config.LifecycleErrands{
-					PostDeploy: errandName,
+					PostDeploy: config.Errands{{Name: errandName}},
 				},
 			}
 
@@ -521,7 +521,7 @@ var _ = Describe("last operation", func() {
 				ID:   planID,
 				Name: "pre-delete-plan",
 				LifecycleErrands: &config.LifecycleErrands{
-					PreDelete: errandName,
+					PreDelete: config.Errands{{Name: errandName}},
 				},
 			}
 
@@ -788,7 +788,7 @@ var _ = Describe("last operation", func() {
 				ID:   planID,
 				Name: "post-deploy-plan",
 				LifecycleErrands: &config.LifecycleErrands{
-					PostDeploy: "health-check",
+					PostDeploy: config.Errands{{Name: "health-check"}},
 				},
 			}
 
@@ -395,7 +395,7 @@ var _ = Describe("Management API", func() {
 						},
 					},
 					LifecycleErrands: &config.LifecycleErrands{
-						PostDeploy: postDeployErrandName,
+						PostDeploy: config.Errands{{Name: postDeployErrandName}},
 					},
 				}
 
@@ -422,10 +422,10 @@ var _ = Describe("Management API", func() {
 				operationData := decodeOperationDataFromResponseBody(upgradeResp.Body)
 				Expect(operationData.BoshContextID).NotTo(BeEmpty())
 				Expect(operationData).To(Equal(broker.OperationData{
-					OperationType:        broker.OperationTypeUpgrade,
-					BoshTaskID:           upgradingTaskID,
-					BoshContextID:        operationData.BoshContextID,
-					PostDeployErrandName: postDeployErrandName,
+					OperationType: broker.OperationTypeUpgrade,
+					BoshTaskID:    upgradingTaskID,
+					BoshContextID: operationData.BoshContextID,
+					PostErrands:   config.Errands{{Name: postDeployErrandName}},
 				}))
 			})
 		})
@@ -389,7 +389,7 @@ var _ = Describe("provision service instance", func() {
 					},
 				},
 				LifecycleErrands: &config.LifecycleErrands{
-					PostDeploy: "health-check",
+					PostDeploy: config.Errands{{Name: "health-check"}},
 				},
 			}
 			conf.ServiceCatalog.Plans = config.Plans{postDeployErrandPlan}
@@ -428,7 +428,7 @@ var _ = Describe("provision service instance", func() {
 			By("including a context ID")
 			Expect(operationData.BoshContextID).NotTo(BeEmpty())
 			By("including the post deploy errand name")
-			Expect(operationData.PostDeployErrandName).To(Equal("health-check"))
+			Expect(operationData.PostErrands).To(Equal(config.Errands{{Name: "health-check"}}))
 		})
 	})
 
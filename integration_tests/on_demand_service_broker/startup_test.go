@@ -394,7 +394,7 @@ var _ = Describe("Startup", func() {
 							},
 						},
 						LifecycleErrands: &config.LifecycleErrands{
-							PostDeploy: "health-check",
+							PostDeploy: config.Errands{{Name: "health-check"}},
 						},
 					}
 
@@ -449,7 +449,7 @@ var _ = Describe("Startup", func() {
 							},
 						},
 						LifecycleErrands: &config.LifecycleErrands{
-							PostDeploy: "health-check",
+							PostDeploy: config.Errands{{Name: "health-check"}},
 						},
 					}
 
@@ -507,7 +507,7 @@ var _ = Describe("Startup", func() {
 							},
 						},
 						LifecycleErrands: &config.LifecycleErrands{
-							PostDeploy: "health-check",
+							PostDeploy: config.Errands{{Name: "health-check"}},
 						},
 					}
 
@@ -213,7 +213,7 @@ var _ = Describe("updating a service instance", func() {
 						},
 					},
 					LifecycleErrands: &config.LifecycleErrands{
-						PostDeploy: "health-check",
+						PostDeploy: config.Errands{{Name: "health-check"}},
 					},
 				}
 				conf.ServiceCatalog.Plans = append(conf.ServiceCatalog.Plans, postDeployErrandPlan)
@@ -236,10 +236,10 @@ var _ = Describe("updating a service instance", func() {
 				Expect(operationData.BoshContextID).NotTo(BeEmpty())
 				Expect(*operationData).To(Equal(
 					broker.OperationData{
-						OperationType:        broker.OperationTypeUpdate,
-						BoshTaskID:           taskID,
-						BoshContextID:        operationData.BoshContextID,
-						PostDeployErrandName: "health-check",
+						OperationType: broker.OperationTypeUpdate,
+						BoshTaskID:    taskID,
+						BoshContextID: operationData.BoshContextID,
+						PostErrands:   config.Errands{{Name: "health-check"}},
 					},
 				))
 			})
@@ -262,7 +262,7 @@ var _ = Describe("updating a service instance", func() {
 						},
 					},
 					LifecycleErrands: &config.LifecycleErrands{
-						PostDeploy: "health-check",
+						PostDeploy: config.Errands{{Name: "health-check"}},
 					},
 				}
 				conf.ServiceCatalog.Plans = append(conf.ServiceCatalog.Plans, postDeployErrandPlan)
@@ -491,7 +491,7 @@ var _ = Describe("updating a service instance", func() {
 						},
 					},
 					LifecycleErrands: &config.LifecycleErrands{
-						PostDeploy: "health-check",
+						PostDeploy: config.Errands{{Name: "health-check"}},
 					},
 				}
 				conf.ServiceCatalog.Plans = config.Plans{postDeployErrandPlan}
@@ -512,10 +512,10 @@ var _ = Describe("updating a service instance", func() {
 				operationData := operationDataFromUpdateResponse(updateResp)
 				Expect(operationData.BoshContextID).NotTo(BeEmpty())
 				Expect(*operationData).To(Equal(broker.OperationData{
-					OperationType:        broker.OperationTypeUpdate,
-					BoshTaskID:           taskID,
-					BoshContextID:        operationData.BoshContextID,
-					PostDeployErrandName: "health-check",
+					OperationType: broker.OperationTypeUpdate,
+					BoshTaskID:    taskID,
+					BoshContextID: operationData.BoshContextID,
+					PostErrands:   config.Errands{{Name: "health-check"}},
 				}))
 			})
 		})
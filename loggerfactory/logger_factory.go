@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 
 	"github.com/pborman/uuid"
 	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
@@ -34,9 +35,26 @@ func (l *LoggerFactory) NewWithContext(ctx context.Context) *log.Logger {
 	}
 
 	prefix := fmt.Sprintf("[%s] [%s] ", l.name, brokercontext.GetReqID(ctx))
+	if tenant := brokercontext.GetTenant(ctx); !tenant.IsEmpty() {
+		prefix += tenantPrefix(tenant)
+	}
 	return log.New(l.out, prefix, l.flag)
 }
 
+func tenantPrefix(tenant brokercontext.Tenant) string {
+	var fields []string
+	if tenant.Platform != "" {
+		fields = append(fields, "platform="+tenant.Platform)
+	}
+	if tenant.OrganizationGUID != "" {
+		fields = append(fields, "organization_guid="+tenant.OrganizationGUID)
+	}
+	if tenant.SpaceGUID != "" {
+		fields = append(fields, "space_guid="+tenant.SpaceGUID)
+	}
+	return fmt.Sprintf("[%s] ", strings.Join(fields, " "))
+}
+
 func (l *LoggerFactory) NewWithRequestID() *log.Logger {
 	prefix := fmt.Sprintf("[%s] [%s] ", l.name, uuid.New())
 	return log.New(l.out, prefix, l.flag)
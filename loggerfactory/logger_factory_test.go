@@ -65,6 +65,26 @@ var _ = Describe("Logger Factory", func() {
 
 				Expect(logs.String()).To(MatchRegexp(`\[some-name\] \[some-request-id\] some log message`))
 			})
+
+			Context("and the tenant is present in context", func() {
+				BeforeEach(func() {
+					ctx = brokercontext.WithTenant(ctx, brokercontext.Tenant{
+						Platform:         "cloudfoundry",
+						OrganizationGUID: "some-org",
+						SpaceGUID:        "some-space",
+					})
+				})
+
+				It("logs messages with the request ID and tenant", func() {
+					logs := &bytes.Buffer{}
+					factory := loggerfactory.New(logs, "some-name", 0)
+
+					logger := factory.NewWithContext(ctx)
+					logger.Println("some log message")
+
+					Expect(logs.String()).To(Equal("[some-name] [some-request-id] [platform=cloudfoundry organization_guid=some-org space_guid=some-space] some log message\n"))
+				})
+			})
 		})
 
 		Context("when request ID not present in context", func() {
@@ -13,10 +13,12 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pborman/uuid"
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/boshdirector"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
@@ -27,7 +29,7 @@ import (
 
 type api struct {
 	manageableBroker ManageableBroker
-	serviceOffering  config.ServiceOffering
+	serviceOfferings []config.ServiceOffering
 	loggerFactory    *loggerfactory.LoggerFactory
 }
 
@@ -35,14 +37,37 @@ type api struct {
 type ManageableBroker interface {
 	Instances(logger *log.Logger) ([]string, error)
 	OrphanDeployments(logger *log.Logger) ([]string, error)
-	Upgrade(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, error)
+	Upgrade(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, string, error)
 	CountInstancesOfPlans(logger *log.Logger) (map[cf.ServicePlan]int, error)
+	CountInstancesOfPlansByOrg(logger *log.Logger) (map[cf.ServicePlan]map[string]int, error)
+	CancelOperation(ctx context.Context, instanceID string, logger *log.Logger) ([]int, error)
+	PurgeInstance(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, error)
+	RunErrand(ctx context.Context, instanceID string, errand config.Errand, logger *log.Logger) (int, error)
+	DashboardURLs(ctx context.Context, logger *log.Logger) ([]broker.InstanceDashboardURL, error)
+	ResyncDashboardURLs(ctx context.Context, logger *log.Logger) ([]broker.InstanceDashboardURL, error)
 }
 
 type Instance struct {
 	InstanceID string `json:"instance_id"`
 }
 
+type UpgradeOperation struct {
+	broker.OperationData
+	DashboardURL string `json:"dashboard_url,omitempty"`
+}
+
+type CancelledOperation struct {
+	BoshTaskIDs []int `json:"bosh_task_ids"`
+}
+
+type ErrandRun struct {
+	Instances []string `json:"instances"`
+}
+
+type ErrandTask struct {
+	BoshTaskID int `json:"bosh_task_id"`
+}
+
 type Deployment struct {
 	Name string `json:"deployment_name"`
 }
@@ -53,12 +78,17 @@ type Metric struct {
 	Unit  string  `json:"unit"`
 }
 
-func AttachRoutes(r *mux.Router, manageableBroker ManageableBroker, serviceOffering config.ServiceOffering, loggerFactory *loggerfactory.LoggerFactory) {
-	a := &api{manageableBroker: manageableBroker, serviceOffering: serviceOffering, loggerFactory: loggerFactory}
+func AttachRoutes(r *mux.Router, manageableBroker ManageableBroker, serviceOfferings []config.ServiceOffering, loggerFactory *loggerfactory.LoggerFactory) {
+	a := &api{manageableBroker: manageableBroker, serviceOfferings: serviceOfferings, loggerFactory: loggerFactory}
 	r.HandleFunc("/mgmt/service_instances", a.listAllInstances).Methods("GET")
 	r.HandleFunc("/mgmt/service_instances/{instance_id}", a.upgradeInstance).Methods("PATCH")
+	r.HandleFunc("/mgmt/service_instances/{instance_id}", a.purgeInstance).Methods("DELETE")
+	r.HandleFunc("/mgmt/service_instances/{instance_id}/operation", a.cancelOperation).Methods("DELETE")
+	r.HandleFunc("/mgmt/service_instances/{instance_id}/errands/{errand_name}", a.runErrand).Methods("POST")
 	r.HandleFunc("/mgmt/metrics", a.metrics).Methods("GET")
 	r.HandleFunc("/mgmt/orphan_deployments", a.listOrphanDeployments).Methods("GET")
+	r.HandleFunc("/mgmt/dashboard_urls", a.listDashboardURLs).Methods("GET")
+	r.HandleFunc("/mgmt/dashboard_urls", a.resyncDashboardURLs).Methods("POST")
 }
 
 func (a *api) listOrphanDeployments(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +109,32 @@ func (a *api) listOrphanDeployments(w http.ResponseWriter, r *http.Request) {
 	a.writeJson(w, orphanDeployments, logger)
 }
 
+func (a *api) listDashboardURLs(w http.ResponseWriter, r *http.Request) {
+	logger := a.loggerFactory.NewWithRequestID()
+
+	dashboardURLs, err := a.manageableBroker.DashboardURLs(r.Context(), logger)
+	if err != nil {
+		logger.Printf("error occurred generating dashboard URLs: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJson(w, dashboardURLs, logger)
+}
+
+func (a *api) resyncDashboardURLs(w http.ResponseWriter, r *http.Request) {
+	logger := a.loggerFactory.NewWithRequestID()
+
+	dashboardURLs, err := a.manageableBroker.ResyncDashboardURLs(r.Context(), logger)
+	if err != nil {
+		logger.Printf("error occurred re-syncing dashboard URLs: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJson(w, dashboardURLs, logger)
+}
+
 func (a *api) listAllInstances(w http.ResponseWriter, r *http.Request) {
 	logger := a.loggerFactory.NewWithRequestID()
 
@@ -102,16 +158,16 @@ func (a *api) upgradeInstance(w http.ResponseWriter, r *http.Request) {
 	instanceID := vars["instance_id"]
 
 	requestID := uuid.New()
-	ctx := brokercontext.New(r.Context(), string(broker.OperationTypeUpgrade), requestID, a.serviceOffering.Name, instanceID)
+	ctx := brokercontext.New(r.Context(), string(broker.OperationTypeUpgrade), requestID, a.serviceNames(), instanceID)
 
 	logger := a.loggerFactory.NewWithContext(ctx)
 
-	operationData, err := a.manageableBroker.Upgrade(ctx, instanceID, logger)
+	operationData, dashboardURL, err := a.manageableBroker.Upgrade(ctx, instanceID, logger)
 
 	switch err.(type) {
 	case nil:
 		w.WriteHeader(http.StatusAccepted)
-		a.writeJson(w, operationData, logger)
+		a.writeJson(w, UpgradeOperation{OperationData: operationData, DashboardURL: dashboardURL}, logger)
 	case cf.ResourceNotFoundError:
 		w.WriteHeader(http.StatusNotFound)
 	case task.DeploymentNotFoundError:
@@ -125,6 +181,105 @@ func (a *api) upgradeInstance(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *api) purgeInstance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	instanceID := vars["instance_id"]
+
+	requestID := uuid.New()
+	ctx := brokercontext.New(r.Context(), string(broker.OperationTypeDelete), requestID, a.serviceNames(), instanceID)
+
+	logger := a.loggerFactory.NewWithContext(ctx)
+
+	operationData, err := a.manageableBroker.PurgeInstance(ctx, instanceID, logger)
+
+	switch err.(type) {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+		a.writeJson(w, operationData, logger)
+	case broker.OperationInProgressError:
+		w.WriteHeader(http.StatusConflict)
+	case error:
+		if err == brokerapi.ErrInstanceDoesNotExist {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		logger.Printf("error occurred purging instance %s: %s", instanceID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		a.writeJson(w, brokerapi.ErrorResponse{Description: err.Error()}, logger)
+	}
+}
+
+func (a *api) cancelOperation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	instanceID := vars["instance_id"]
+
+	requestID := uuid.New()
+	ctx := brokercontext.New(r.Context(), "cancel", requestID, a.serviceNames(), instanceID)
+
+	logger := a.loggerFactory.NewWithContext(ctx)
+
+	cancelledTaskIDs, err := a.manageableBroker.CancelOperation(ctx, instanceID, logger)
+
+	switch err.(type) {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+		a.writeJson(w, CancelledOperation{BoshTaskIDs: cancelledTaskIDs}, logger)
+	case broker.NoOperationInProgressError:
+		w.WriteHeader(http.StatusNotFound)
+	case error:
+		logger.Printf("error occurred cancelling operation of instance %s: %s", instanceID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		a.writeJson(w, brokerapi.ErrorResponse{Description: err.Error()}, logger)
+	}
+}
+
+func (a *api) runErrand(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	instanceID := vars["instance_id"]
+	errand := config.Errand{Name: vars["errand_name"]}
+
+	requestID := uuid.New()
+	ctx := brokercontext.New(r.Context(), "errand", requestID, a.serviceNames(), instanceID)
+
+	logger := a.loggerFactory.NewWithContext(ctx)
+
+	if r.ContentLength != 0 {
+		var errandRun ErrandRun
+		if err := json.NewDecoder(r.Body).Decode(&errandRun); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			a.writeJson(w, brokerapi.ErrorResponse{Description: fmt.Sprintf("invalid request body: %s", err)}, logger)
+			return
+		}
+		errand.Instances = errandRun.Instances
+	}
+
+	for _, instance := range errand.Instances {
+		if _, err := boshdirector.ParseErrandInstance(instance); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			a.writeJson(w, brokerapi.ErrorResponse{Description: err.Error()}, logger)
+			return
+		}
+	}
+
+	taskID, err := a.manageableBroker.RunErrand(ctx, instanceID, errand, logger)
+
+	switch err.(type) {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+		a.writeJson(w, ErrandTask{BoshTaskID: taskID}, logger)
+	case broker.OperationInProgressError:
+		w.WriteHeader(http.StatusConflict)
+	case error:
+		if err == brokerapi.ErrInstanceDoesNotExist {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		logger.Printf("error occurred running errand %s on instance %s: %s", errand.Name, instanceID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		a.writeJson(w, brokerapi.ErrorResponse{Description: err.Error()}, logger)
+	}
+}
+
 func (a *api) metrics(w http.ResponseWriter, r *http.Request) {
 	logger := a.loggerFactory.NewWithRequestID()
 
@@ -132,21 +287,22 @@ func (a *api) metrics(w http.ResponseWriter, r *http.Request) {
 	instanceCountsByPlan, err := a.manageableBroker.CountInstancesOfPlans(logger)
 
 	if err != nil {
-		logger.Printf("error getting instance count for service offering %s: %s", a.serviceOffering.Name, err)
+		logger.Printf("error getting instance count for service offering %s: %s", a.serviceNames(), err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	if len(instanceCountsByPlan) == 0 {
-		logger.Printf("The %s service broker must be registered with Cloud Foundry before metrics can be collected", a.serviceOffering.Name)
+		logger.Printf("The %s service broker must be registered with Cloud Foundry before metrics can be collected", a.serviceNames())
 		w.WriteHeader(http.StatusServiceUnavailable)
 		return
 	}
 
-	totalInstances := 0
+	totalInstances := map[string]int{}
+	instanceCountsByPlanID := map[string]int{}
 
 	for plan, instanceCount := range instanceCountsByPlan {
-		serviceOfferingPlan, err := a.getPlan(plan.ServicePlanEntity.UniqueID)
+		serviceOffering, serviceOfferingPlan, err := a.getPlan(plan.ServicePlanEntity.UniqueID)
 		if err != nil {
 			logger.Println(err)
 			a.writeJson(w, []interface{}{}, logger)
@@ -154,7 +310,7 @@ func (a *api) metrics(w http.ResponseWriter, r *http.Request) {
 		}
 
 		countMetric := Metric{
-			Key:   fmt.Sprintf("/on-demand-broker/%s/%s/total_instances", a.serviceOffering.Name, serviceOfferingPlan.Name),
+			Key:   fmt.Sprintf("/on-demand-broker/%s/%s/total_instances", serviceOffering.Name, serviceOfferingPlan.Name),
 			Unit:  "count",
 			Value: float64(instanceCount),
 		}
@@ -163,47 +319,156 @@ func (a *api) metrics(w http.ResponseWriter, r *http.Request) {
 		if serviceOfferingPlan.Quotas.ServiceInstanceLimit != nil {
 			limit := *serviceOfferingPlan.Quotas.ServiceInstanceLimit
 			quotaMetric := Metric{
-				Key:   fmt.Sprintf("/on-demand-broker/%s/%s/quota_remaining", a.serviceOffering.Name, serviceOfferingPlan.Name),
+				Key:   fmt.Sprintf("/on-demand-broker/%s/%s/quota_remaining", serviceOffering.Name, serviceOfferingPlan.Name),
 				Unit:  "count",
 				Value: float64(limit - instanceCount),
 			}
 			brokerMetrics = append(brokerMetrics, quotaMetric)
 		}
 
-		totalInstances = totalInstances + instanceCount
-	}
+		brokerMetrics = append(brokerMetrics, resourceQuotaMetrics(
+			fmt.Sprintf("/on-demand-broker/%s/%s", serviceOffering.Name, serviceOfferingPlan.Name),
+			serviceOfferingPlan.Quotas.Resources,
+			config.ResourceUsage{}.Plus(serviceOfferingPlan.ResourceUsage(serviceOffering.PersistentDiskTypeSizesGB), instanceCount),
+		)...)
 
-	totalCountMetric := Metric{
-		Key:   fmt.Sprintf("/on-demand-broker/%s/total_instances", a.serviceOffering.Name),
-		Unit:  "count",
-		Value: float64(totalInstances),
+		totalInstances[serviceOffering.ID] = totalInstances[serviceOffering.ID] + instanceCount
+		instanceCountsByPlanID[plan.ServicePlanEntity.UniqueID] = instanceCount
 	}
-	brokerMetrics = append(brokerMetrics, totalCountMetric)
 
-	if a.serviceOffering.GlobalQuotas.ServiceInstanceLimit != nil {
-		limit := *a.serviceOffering.GlobalQuotas.ServiceInstanceLimit
-		quotaMetric := Metric{
-			Key:   fmt.Sprintf("/on-demand-broker/%s/quota_remaining", a.serviceOffering.Name),
+	for _, serviceOffering := range a.serviceOfferings {
+		totalCountMetric := Metric{
+			Key:   fmt.Sprintf("/on-demand-broker/%s/total_instances", serviceOffering.Name),
 			Unit:  "count",
-			Value: float64(limit - totalInstances),
+			Value: float64(totalInstances[serviceOffering.ID]),
+		}
+		brokerMetrics = append(brokerMetrics, totalCountMetric)
+
+		if serviceOffering.GlobalQuotas.ServiceInstanceLimit != nil {
+			limit := *serviceOffering.GlobalQuotas.ServiceInstanceLimit
+			quotaMetric := Metric{
+				Key:   fmt.Sprintf("/on-demand-broker/%s/quota_remaining", serviceOffering.Name),
+				Unit:  "count",
+				Value: float64(limit - totalInstances[serviceOffering.ID]),
+			}
+			brokerMetrics = append(brokerMetrics, quotaMetric)
+		}
+
+		brokerMetrics = append(brokerMetrics, resourceQuotaMetrics(
+			fmt.Sprintf("/on-demand-broker/%s", serviceOffering.Name),
+			serviceOffering.GlobalQuotas.Resources,
+			serviceOffering.ResourceUsage(instanceCountsByPlanID),
+		)...)
+	}
+
+	if a.hasOrgQuotas() {
+		orgMetrics, err := a.orgMetrics(logger)
+		if err != nil {
+			logger.Printf("error getting instance count by org for service offering %s: %s", a.serviceNames(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
-		brokerMetrics = append(brokerMetrics, quotaMetric)
+		brokerMetrics = append(brokerMetrics, orgMetrics...)
 	}
 
 	a.writeJson(w, brokerMetrics, logger)
 }
 
+// orgMetrics reports usage per org for plans with org quotas. Counting by org
+// needs a lookup per space, so plans without org quotas are left out.
+func (a *api) orgMetrics(logger *log.Logger) ([]Metric, error) {
+	instanceCountsByOrg, err := a.manageableBroker.CountInstancesOfPlansByOrg(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []Metric
+	for plan, orgCounts := range instanceCountsByOrg {
+		serviceOffering, serviceOfferingPlan, err := a.getPlan(plan.ServicePlanEntity.UniqueID)
+		if err != nil {
+			return nil, err
+		}
+
+		orgQuota := serviceOfferingPlan.Quotas.Orgs
+		if orgQuota == nil {
+			continue
+		}
+
+		orgs := map[string]int{}
+		for orgGUID := range orgQuota.Overrides {
+			orgs[orgGUID] = 0
+		}
+		for orgGUID, count := range orgCounts {
+			orgs[orgGUID] = count
+		}
+
+		for orgGUID, count := range orgs {
+			metrics = append(metrics, Metric{
+				Key:   fmt.Sprintf("/on-demand-broker/%s/%s/orgs/%s/total_instances", serviceOffering.Name, serviceOfferingPlan.Name, orgGUID),
+				Unit:  "count",
+				Value: float64(count),
+			})
+
+			if limit, found := orgQuota.LimitFor(orgGUID); found {
+				metrics = append(metrics, Metric{
+					Key:   fmt.Sprintf("/on-demand-broker/%s/%s/orgs/%s/quota_remaining", serviceOffering.Name, serviceOfferingPlan.Name, orgGUID),
+					Unit:  "count",
+					Value: float64(limit - count),
+				})
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+func resourceQuotaMetrics(keyPrefix string, limits *config.ResourceLimits, usage config.ResourceUsage) []Metric {
+	var metrics []Metric
+	for resource, limit := range limits.Limits() {
+		unit := "count"
+		if resource == config.PersistentDiskGBResource {
+			unit = "GB"
+		}
+
+		metrics = append(metrics, Metric{
+			Key:   fmt.Sprintf("%s/%s/quota_remaining", keyPrefix, resource),
+			Unit:  unit,
+			Value: float64(limit - usage[resource]),
+		})
+	}
+	return metrics
+}
+
+func (a *api) hasOrgQuotas() bool {
+	for _, serviceOffering := range a.serviceOfferings {
+		for _, plan := range serviceOffering.Plans {
+			if plan.Quotas.Orgs != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (a *api) writeJson(w io.Writer, obj interface{}, logger *log.Logger) {
 	if err := json.NewEncoder(w).Encode(obj); err != nil {
 		logger.Printf("error occurred encoding json: %s", err)
 	}
 }
 
-func (a *api) getPlan(planID string) (config.Plan, error) {
-	for _, plan := range a.serviceOffering.Plans {
-		if plan.ID == planID {
-			return plan, nil
+func (a *api) getPlan(planID string) (config.ServiceOffering, config.Plan, error) {
+	for _, serviceOffering := range a.serviceOfferings {
+		if plan, found := serviceOffering.FindPlanByID(planID); found {
+			return serviceOffering, plan, nil
 		}
 	}
-	return config.Plan{}, fmt.Errorf("no plan found with marketplace ID %s", planID)
+	return config.ServiceOffering{}, config.Plan{}, fmt.Errorf("no plan found with marketplace ID %s", planID)
+}
+
+func (a *api) serviceNames() string {
+	var names []string
+	for _, serviceOffering := range a.serviceOfferings {
+		names = append(names, serviceOffering.Name)
+	}
+	return strings.Join(names, ",")
 }
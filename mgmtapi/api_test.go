@@ -15,11 +15,13 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
 	"github.com/gorilla/mux"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
 	"github.com/pivotal-cf/on-demand-service-broker/config"
@@ -27,6 +29,7 @@ import (
 	"github.com/pivotal-cf/on-demand-service-broker/mgmtapi"
 	"github.com/pivotal-cf/on-demand-service-broker/mgmtapi/fake_manageable_broker"
 	"github.com/pivotal-cf/on-demand-service-broker/task"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
 
 var _ = Describe("Management API", func() {
@@ -36,6 +39,7 @@ var _ = Describe("Management API", func() {
 		logs             *gbytes.Buffer
 		loggerFactory    *loggerfactory.LoggerFactory
 		serviceOffering  config.ServiceOffering
+		otherOfferings   []config.ServiceOffering
 	)
 
 	BeforeEach(func() {
@@ -44,6 +48,7 @@ var _ = Describe("Management API", func() {
 			Name:  "some_service_offering",
 			Plans: []config.Plan{{ID: "foo_id", Name: "foo_plan"}, {ID: "bar_id", Name: "bar_plan"}},
 		}
+		otherOfferings = nil
 		logs = gbytes.NewBuffer()
 		loggerFactory = loggerfactory.New(io.MultiWriter(GinkgoWriter, logs), "mgmtapi-unit-tests", log.LstdFlags)
 		manageableBroker = new(fake_manageable_broker.FakeManageableBroker)
@@ -51,7 +56,7 @@ var _ = Describe("Management API", func() {
 
 	JustBeforeEach(func() {
 		router := mux.NewRouter()
-		mgmtapi.AttachRoutes(router, manageableBroker, serviceOffering, loggerFactory)
+		mgmtapi.AttachRoutes(router, manageableBroker, append([]config.ServiceOffering{serviceOffering}, otherOfferings...), loggerFactory)
 		server = httptest.NewServer(router)
 	})
 
@@ -136,7 +141,7 @@ var _ = Describe("Management API", func() {
 					BoshContextID: contextID,
 					PlanID:        planID,
 					OperationType: broker.OperationTypeUpgrade,
-				}, nil)
+				}, "https://dashboard.example.com/"+instanceID, nil)
 			})
 
 			It("upgrades the instance using the broker", func() {
@@ -157,11 +162,17 @@ var _ = Describe("Management API", func() {
 				Expect(upgradeRespBody.PlanID).To(Equal(planID))
 				Expect(upgradeRespBody.OperationType).To(Equal(broker.OperationTypeUpgrade))
 			})
+
+			It("responds with the regenerated dashboard URL", func() {
+				var upgradeRespBody mgmtapi.UpgradeOperation
+				Expect(json.NewDecoder(upgradeResp.Body).Decode(&upgradeRespBody)).To(Succeed())
+				Expect(upgradeRespBody.DashboardURL).To(Equal("https://dashboard.example.com/" + instanceID))
+			})
 		})
 
 		Context("when the CF service instance is not found", func() {
 			BeforeEach(func() {
-				manageableBroker.UpgradeReturns(broker.OperationData{}, cf.ResourceNotFoundError{})
+				manageableBroker.UpgradeReturns(broker.OperationData{}, "", cf.ResourceNotFoundError{})
 			})
 
 			It("responds with HTTP 404 Not Found", func() {
@@ -171,7 +182,7 @@ var _ = Describe("Management API", func() {
 
 		Context("when the bosh deployment is not found", func() {
 			BeforeEach(func() {
-				manageableBroker.UpgradeReturns(broker.OperationData{}, task.NewDeploymentNotFoundError(errors.New("error finding deployment")))
+				manageableBroker.UpgradeReturns(broker.OperationData{}, "", task.NewDeploymentNotFoundError(errors.New("error finding deployment")))
 			})
 
 			It("responds with HTTP 410 Gone", func() {
@@ -181,7 +192,7 @@ var _ = Describe("Management API", func() {
 
 		Context("when there is an operation in progress", func() {
 			BeforeEach(func() {
-				manageableBroker.UpgradeReturns(broker.OperationData{}, broker.NewOperationInProgressError(errors.New("operation in progress error")))
+				manageableBroker.UpgradeReturns(broker.OperationData{}, "", broker.NewOperationInProgressError(errors.New("operation in progress error")))
 			})
 
 			It("responds with HTTP 409 Conflict", func() {
@@ -191,7 +202,7 @@ var _ = Describe("Management API", func() {
 
 		Context("when it fails", func() {
 			BeforeEach(func() {
-				manageableBroker.UpgradeReturns(broker.OperationData{}, errors.New("upgrade error"))
+				manageableBroker.UpgradeReturns(broker.OperationData{}, "", errors.New("upgrade error"))
 			})
 
 			It("responds with HTTP 500", func() {
@@ -208,6 +219,251 @@ var _ = Describe("Management API", func() {
 		})
 	})
 
+	Describe("purging an instance", func() {
+		var (
+			instanceID = "283974"
+
+			purgeResp *http.Response
+		)
+
+		JustBeforeEach(func() {
+			var err error
+			purgeResp, err = Delete(fmt.Sprintf("%s/mgmt/service_instances/%s", server.URL, instanceID))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when it succeeds", func() {
+			BeforeEach(func() {
+				manageableBroker.PurgeInstanceReturns(broker.OperationData{
+					BoshTaskID:    42,
+					OperationType: broker.OperationTypeDelete,
+					Force:         true,
+				}, nil)
+			})
+
+			It("purges the instance using the broker", func() {
+				Expect(manageableBroker.PurgeInstanceCallCount()).To(Equal(1))
+				_, actualInstanceID, _ := manageableBroker.PurgeInstanceArgsForCall(0)
+				Expect(actualInstanceID).To(Equal(instanceID))
+			})
+
+			It("responds with HTTP 202 and the operation data", func() {
+				Expect(purgeResp.StatusCode).To(Equal(http.StatusAccepted))
+				var operationData broker.OperationData
+				Expect(json.NewDecoder(purgeResp.Body).Decode(&operationData)).To(Succeed())
+				Expect(operationData).To(Equal(broker.OperationData{
+					BoshTaskID:    42,
+					OperationType: broker.OperationTypeDelete,
+					Force:         true,
+				}))
+			})
+		})
+
+		Context("when the deployment is already gone", func() {
+			BeforeEach(func() {
+				manageableBroker.PurgeInstanceReturns(broker.OperationData{}, brokerapi.ErrInstanceDoesNotExist)
+			})
+
+			It("responds with HTTP 410 Gone", func() {
+				Expect(purgeResp.StatusCode).To(Equal(http.StatusGone))
+			})
+		})
+
+		Context("when there is an operation in progress", func() {
+			BeforeEach(func() {
+				manageableBroker.PurgeInstanceReturns(broker.OperationData{}, broker.NewOperationInProgressError(errors.New("operation in progress error")))
+			})
+
+			It("responds with HTTP 409 Conflict", func() {
+				Expect(purgeResp.StatusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("when it fails", func() {
+			BeforeEach(func() {
+				manageableBroker.PurgeInstanceReturns(broker.OperationData{}, errors.New("purge error"))
+			})
+
+			It("responds with HTTP 500 and the error", func() {
+				Expect(purgeResp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(ioutil.ReadAll(purgeResp.Body)).To(MatchJSON(`{"description": "purge error"}`))
+			})
+
+			It("logs the error", func() {
+				Eventually(logs).Should(gbytes.Say(fmt.Sprintf("error occurred purging instance %s: purge error", instanceID)))
+			})
+		})
+	})
+
+	Describe("running an errand", func() {
+		var (
+			instanceID = "283974"
+			errandName = "cluster-init"
+
+			requestBody string
+			errandResp  *http.Response
+		)
+
+		BeforeEach(func() {
+			requestBody = `{"instances": ["redis-server/first"]}`
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			errandResp, err = http.Post(
+				fmt.Sprintf("%s/mgmt/service_instances/%s/errands/%s", server.URL, instanceID, errandName),
+				"application/json",
+				strings.NewReader(requestBody),
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when it succeeds", func() {
+			BeforeEach(func() {
+				manageableBroker.RunErrandReturns(42, nil)
+			})
+
+			It("runs the errand on the requested instances using the broker", func() {
+				Expect(manageableBroker.RunErrandCallCount()).To(Equal(1))
+				_, actualInstanceID, actualErrand, _ := manageableBroker.RunErrandArgsForCall(0)
+				Expect(actualInstanceID).To(Equal(instanceID))
+				Expect(actualErrand).To(Equal(config.Errand{Name: errandName, Instances: []string{"redis-server/first"}}))
+			})
+
+			It("responds with HTTP 202 and the task ID", func() {
+				Expect(errandResp.StatusCode).To(Equal(http.StatusAccepted))
+				Expect(ioutil.ReadAll(errandResp.Body)).To(MatchJSON(`{"bosh_task_id": 42}`))
+			})
+		})
+
+		Context("when no instances are requested", func() {
+			BeforeEach(func() {
+				requestBody = ""
+			})
+
+			It("lets BOSH choose where the errand runs", func() {
+				_, _, actualErrand, _ := manageableBroker.RunErrandArgsForCall(0)
+				Expect(actualErrand).To(Equal(config.Errand{Name: errandName}))
+			})
+		})
+
+		Context("when an instance is invalid", func() {
+			BeforeEach(func() {
+				requestBody = `{"instances": ["redis-server/"]}`
+			})
+
+			It("responds with HTTP 400 without running the errand", func() {
+				Expect(errandResp.StatusCode).To(Equal(http.StatusBadRequest))
+				body, err := ioutil.ReadAll(errandResp.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(body)).To(ContainSubstring(`errand instance \"redis-server/\" must be a group`))
+				Expect(manageableBroker.RunErrandCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the body is not valid JSON", func() {
+			BeforeEach(func() {
+				requestBody = `{"instances":`
+			})
+
+			It("responds with HTTP 400", func() {
+				Expect(errandResp.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(manageableBroker.RunErrandCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the instance does not exist", func() {
+			BeforeEach(func() {
+				manageableBroker.RunErrandReturns(0, brokerapi.ErrInstanceDoesNotExist)
+			})
+
+			It("responds with HTTP 404 Not Found", func() {
+				Expect(errandResp.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when there is an operation in progress", func() {
+			BeforeEach(func() {
+				manageableBroker.RunErrandReturns(0, broker.NewOperationInProgressError(errors.New("operation in progress error")))
+			})
+
+			It("responds with HTTP 409 Conflict", func() {
+				Expect(errandResp.StatusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("when it fails", func() {
+			BeforeEach(func() {
+				manageableBroker.RunErrandReturns(0, errors.New("errand error"))
+			})
+
+			It("responds with HTTP 500 and the error", func() {
+				Expect(errandResp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(ioutil.ReadAll(errandResp.Body)).To(MatchJSON(`{"description": "errand error"}`))
+			})
+
+			It("logs the error", func() {
+				Eventually(logs).Should(gbytes.Say(fmt.Sprintf("error occurred running errand %s on instance %s: errand error", errandName, instanceID)))
+			})
+		})
+	})
+
+	Describe("cancelling an instance's operation", func() {
+		var (
+			instanceID = "283974"
+
+			cancelResp *http.Response
+		)
+
+		JustBeforeEach(func() {
+			var err error
+			cancelResp, err = Delete(fmt.Sprintf("%s/mgmt/service_instances/%s/operation", server.URL, instanceID))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when it succeeds", func() {
+			BeforeEach(func() {
+				manageableBroker.CancelOperationReturns([]int{42, 43}, nil)
+			})
+
+			It("cancels the operation using the broker", func() {
+				Expect(manageableBroker.CancelOperationCallCount()).To(Equal(1))
+				_, actualInstanceID, _ := manageableBroker.CancelOperationArgsForCall(0)
+				Expect(actualInstanceID).To(Equal(instanceID))
+			})
+
+			It("responds with HTTP 202 and the cancelled tasks", func() {
+				Expect(cancelResp.StatusCode).To(Equal(http.StatusAccepted))
+				Expect(ioutil.ReadAll(cancelResp.Body)).To(MatchJSON(`{"bosh_task_ids": [42, 43]}`))
+			})
+		})
+
+		Context("when there is no operation in progress", func() {
+			BeforeEach(func() {
+				manageableBroker.CancelOperationReturns(nil, broker.NewNoOperationInProgressError(errors.New("nothing to cancel")))
+			})
+
+			It("responds with HTTP 404 Not Found", func() {
+				Expect(cancelResp.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when it fails", func() {
+			BeforeEach(func() {
+				manageableBroker.CancelOperationReturns(nil, errors.New("cancel error"))
+			})
+
+			It("responds with HTTP 500 and the error", func() {
+				Expect(cancelResp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(ioutil.ReadAll(cancelResp.Body)).To(MatchJSON(`{"description": "cancel error"}`))
+			})
+
+			It("logs the error", func() {
+				Eventually(logs).Should(gbytes.Say(fmt.Sprintf("error occurred cancelling operation of instance %s: cancel error", instanceID)))
+			})
+		})
+	})
+
 	Describe("producing service metrics", func() {
 		var instancesForPlanResponse *http.Response
 
@@ -233,6 +489,10 @@ var _ = Describe("Management API", func() {
 					Expect(manageableBroker.CountInstancesOfPlansCallCount()).To(Equal(1))
 				})
 
+				It("does not count instances by org", func() {
+					Expect(manageableBroker.CountInstancesOfPlansByOrgCallCount()).To(Equal(0))
+				})
+
 				It("returns the correct number of instances", func() {
 					defer instancesForPlanResponse.Body.Close()
 					var brokerMetrics []mgmtapi.Metric
@@ -316,6 +576,88 @@ var _ = Describe("Management API", func() {
 			})
 		})
 
+		Context("when an org quota is set", func() {
+			BeforeEach(func() {
+				defaultLimit := 3
+				serviceOffering.Plans[0].Quotas = config.Quotas{Orgs: &config.ScopeQuota{
+					ServiceInstanceLimit: &defaultLimit,
+					Overrides:            map[string]int{"big-org": 10, "empty-org": 4},
+				}}
+				manageableBroker.CountInstancesOfPlansReturns(map[cf.ServicePlan]int{
+					cfServicePlan("1234", "foo_id", "url", "name"): 3,
+					cfServicePlan("5678", "bar_id", "url", "name"): 1,
+				}, nil)
+			})
+
+			Context("when the instance count by org can be retrieved", func() {
+				BeforeEach(func() {
+					manageableBroker.CountInstancesOfPlansByOrgReturns(map[cf.ServicePlan]map[string]int{
+						cfServicePlan("1234", "foo_id", "url", "name"): {"big-org": 2, "small-org": 1},
+						cfServicePlan("5678", "bar_id", "url", "name"): {"small-org": 1},
+					}, nil)
+				})
+
+				It("reports usage per org for the plan with the org quota", func() {
+					defer instancesForPlanResponse.Body.Close()
+					var brokerMetrics []mgmtapi.Metric
+
+					Expect(json.NewDecoder(instancesForPlanResponse.Body).Decode(&brokerMetrics)).To(Succeed())
+					Expect(brokerMetrics).To(ConsistOf(
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/total_instances", Value: 3, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/bar_plan/total_instances", Value: 1, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/total_instances", Value: 4, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/orgs/big-org/total_instances", Value: 2, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/orgs/big-org/quota_remaining", Value: 8, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/orgs/small-org/total_instances", Value: 1, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/orgs/small-org/quota_remaining", Value: 2, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/orgs/empty-org/total_instances", Value: 0, Unit: "count"},
+						mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/orgs/empty-org/quota_remaining", Value: 4, Unit: "count"},
+					))
+				})
+			})
+
+			Context("when the instance count by org cannot be retrieved", func() {
+				BeforeEach(func() {
+					manageableBroker.CountInstancesOfPlansByOrgReturns(nil, errors.New("error counting instances by org"))
+				})
+
+				It("returns HTTP 500 and logs why", func() {
+					Expect(instancesForPlanResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+					Expect(logs).To(gbytes.Say("error counting instances by org"))
+				})
+			})
+		})
+
+		Context("when resource quotas are set", func() {
+			BeforeEach(func() {
+				planInstanceLimit := 10
+				globalDiskLimit := 100
+				serviceOffering.PersistentDiskTypeSizesGB = map[string]int{"ten": 10}
+				serviceOffering.GlobalQuotas = config.Quotas{Resources: &config.ResourceLimits{PersistentDiskGB: &globalDiskLimit}}
+				serviceOffering.Plans[0].InstanceGroups = []serviceadapter.InstanceGroup{{Name: "redis", Instances: 3, PersistentDiskType: "ten"}}
+				serviceOffering.Plans[0].Quotas = config.Quotas{Resources: &config.ResourceLimits{Instances: &planInstanceLimit}}
+				serviceOffering.Plans[1].InstanceGroups = []serviceadapter.InstanceGroup{{Name: "redis", Instances: 1, PersistentDiskType: "ten"}}
+				manageableBroker.CountInstancesOfPlansReturns(map[cf.ServicePlan]int{
+					cfServicePlan("1234", "foo_id", "url", "name"): 2,
+					cfServicePlan("5678", "bar_id", "url", "name"): 1,
+				}, nil)
+			})
+
+			It("reports the remaining resources of the plan and the service", func() {
+				defer instancesForPlanResponse.Body.Close()
+				var brokerMetrics []mgmtapi.Metric
+
+				Expect(json.NewDecoder(instancesForPlanResponse.Body).Decode(&brokerMetrics)).To(Succeed())
+				Expect(brokerMetrics).To(ConsistOf(
+					mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/total_instances", Value: 2, Unit: "count"},
+					mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/foo_plan/instances/quota_remaining", Value: 4, Unit: "count"},
+					mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/bar_plan/total_instances", Value: 1, Unit: "count"},
+					mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/total_instances", Value: 3, Unit: "count"},
+					mgmtapi.Metric{Key: "/on-demand-broker/some_service_offering/persistent_disk_gb/quota_remaining", Value: 30, Unit: "GB"},
+				))
+			})
+		})
+
 		Context("when a plan quota is set", func() {
 			BeforeEach(func() {
 				limit := 7
@@ -456,6 +798,56 @@ var _ = Describe("Management API", func() {
 				Expect(manageableBroker.CountInstancesOfPlansCallCount()).To(Equal(1))
 			})
 		})
+
+		Context("when the broker serves more than one service offering", func() {
+			BeforeEach(func() {
+				limit := 10
+				otherOfferings = []config.ServiceOffering{{
+					ID:           "other_service_offering-id",
+					Name:         "other_service_offering",
+					Plans:        []config.Plan{{ID: "baz_id", Name: "baz_plan"}},
+					GlobalQuotas: config.Quotas{ServiceInstanceLimit: &limit},
+				}}
+				manageableBroker.CountInstancesOfPlansReturns(map[cf.ServicePlan]int{
+					cfServicePlan("1234", "foo_id", "url", "name"): 2,
+					cfServicePlan("5678", "baz_id", "url", "name"): 4,
+				}, nil)
+			})
+
+			It("reports the instances and quotas of each offering separately", func() {
+				defer instancesForPlanResponse.Body.Close()
+				var brokerMetrics []mgmtapi.Metric
+
+				Expect(json.NewDecoder(instancesForPlanResponse.Body).Decode(&brokerMetrics)).To(Succeed())
+				Expect(brokerMetrics).To(ConsistOf(
+					mgmtapi.Metric{
+						Key:   "/on-demand-broker/some_service_offering/foo_plan/total_instances",
+						Value: 2,
+						Unit:  "count",
+					},
+					mgmtapi.Metric{
+						Key:   "/on-demand-broker/some_service_offering/total_instances",
+						Value: 2,
+						Unit:  "count",
+					},
+					mgmtapi.Metric{
+						Key:   "/on-demand-broker/other_service_offering/baz_plan/total_instances",
+						Value: 4,
+						Unit:  "count",
+					},
+					mgmtapi.Metric{
+						Key:   "/on-demand-broker/other_service_offering/total_instances",
+						Value: 4,
+						Unit:  "count",
+					},
+					mgmtapi.Metric{
+						Key:   "/on-demand-broker/other_service_offering/quota_remaining",
+						Value: 6,
+						Unit:  "count",
+					},
+				))
+			})
+		})
 	})
 
 	Describe("listing orphan service deployments", func() {
@@ -526,6 +918,89 @@ var _ = Describe("Management API", func() {
 			})
 		})
 	})
+
+	Describe("listing dashboard URLs", func() {
+		var listResp *http.Response
+
+		JustBeforeEach(func() {
+			var err error
+			listResp, err = http.Get(fmt.Sprintf("%s/mgmt/dashboard_urls", server.URL))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the dashboard URLs are generated", func() {
+			BeforeEach(func() {
+				manageableBroker.DashboardURLsReturns([]broker.InstanceDashboardURL{
+					{InstanceID: "instance-1", DashboardURL: "https://dashboard.example.com/instance-1"},
+					{InstanceID: "instance-2", Error: "instance does not exist"},
+				}, nil)
+			})
+
+			It("returns HTTP 200 with the URL of each instance", func() {
+				Expect(listResp.StatusCode).To(Equal(http.StatusOK))
+				defer listResp.Body.Close()
+				body, err := ioutil.ReadAll(listResp.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body).To(MatchJSON(`[
+					{"service_instance_id": "instance-1", "dashboard_url": "https://dashboard.example.com/instance-1"},
+					{"service_instance_id": "instance-2", "error": "instance does not exist"}
+				]`))
+			})
+		})
+
+		Context("when the broker returns an error", func() {
+			BeforeEach(func() {
+				manageableBroker.DashboardURLsReturns(nil, errors.New("cloud controller is down"))
+			})
+
+			It("returns HTTP 500 and logs the error", func() {
+				Expect(listResp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Eventually(logs).Should(gbytes.Say("error occurred generating dashboard URLs: cloud controller is down"))
+			})
+		})
+	})
+
+	Describe("re-syncing dashboard URLs", func() {
+		var resyncResp *http.Response
+
+		JustBeforeEach(func() {
+			var err error
+			resyncResp, err = http.Post(fmt.Sprintf("%s/mgmt/dashboard_urls", server.URL), "application/json", nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the dashboard URLs are re-synced", func() {
+			BeforeEach(func() {
+				manageableBroker.ResyncDashboardURLsReturns([]broker.InstanceDashboardURL{
+					{InstanceID: "instance-1"},
+					{InstanceID: "instance-2", Error: "cloud controller is down"},
+				}, nil)
+			})
+
+			It("returns HTTP 200 with the outcome for each instance", func() {
+				Expect(resyncResp.StatusCode).To(Equal(http.StatusOK))
+				Expect(manageableBroker.ResyncDashboardURLsCallCount()).To(Equal(1))
+				defer resyncResp.Body.Close()
+				body, err := ioutil.ReadAll(resyncResp.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body).To(MatchJSON(`[
+					{"service_instance_id": "instance-1"},
+					{"service_instance_id": "instance-2", "error": "cloud controller is down"}
+				]`))
+			})
+		})
+
+		Context("when the broker returns an error", func() {
+			BeforeEach(func() {
+				manageableBroker.ResyncDashboardURLsReturns(nil, errors.New("cloud controller is down"))
+			})
+
+			It("returns HTTP 500 and logs the error", func() {
+				Expect(resyncResp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Eventually(logs).Should(gbytes.Say("error occurred re-syncing dashboard URLs: cloud controller is down"))
+			})
+		})
+	})
 })
 
 func Patch(url string) (resp *http.Response, err error) {
@@ -536,6 +1011,14 @@ func Patch(url string) (resp *http.Response, err error) {
 	return http.DefaultClient.Do(req)
 }
 
+func Delete(url string) (resp *http.Response, err error) {
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
 func cfServicePlan(guid, uniqueID, servicePlanUrl, name string) cf.ServicePlan {
 	return cf.ServicePlan{
 		Metadata: cf.Metadata{
@@ -8,6 +8,7 @@ import (
 
 	"github.com/pivotal-cf/on-demand-service-broker/broker"
 	"github.com/pivotal-cf/on-demand-service-broker/cf"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
 	"github.com/pivotal-cf/on-demand-service-broker/mgmtapi"
 )
 
@@ -38,7 +39,7 @@ type FakeManageableBroker struct {
 		result1 []string
 		result2 error
 	}
-	UpgradeStub        func(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, error)
+	UpgradeStub        func(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, string, error)
 	upgradeMutex       sync.RWMutex
 	upgradeArgsForCall []struct {
 		ctx        context.Context
@@ -47,11 +48,13 @@ type FakeManageableBroker struct {
 	}
 	upgradeReturns struct {
 		result1 broker.OperationData
-		result2 error
+		result2 string
+		result3 error
 	}
 	upgradeReturnsOnCall map[int]struct {
 		result1 broker.OperationData
-		result2 error
+		result2 string
+		result3 error
 	}
 	CountInstancesOfPlansStub        func(logger *log.Logger) (map[cf.ServicePlan]int, error)
 	countInstancesOfPlansMutex       sync.RWMutex
@@ -66,6 +69,93 @@ type FakeManageableBroker struct {
 		result1 map[cf.ServicePlan]int
 		result2 error
 	}
+	CountInstancesOfPlansByOrgStub        func(logger *log.Logger) (map[cf.ServicePlan]map[string]int, error)
+	countInstancesOfPlansByOrgMutex       sync.RWMutex
+	countInstancesOfPlansByOrgArgsForCall []struct {
+		logger *log.Logger
+	}
+	countInstancesOfPlansByOrgReturns struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}
+	countInstancesOfPlansByOrgReturnsOnCall map[int]struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}
+	CancelOperationStub        func(ctx context.Context, instanceID string, logger *log.Logger) ([]int, error)
+	cancelOperationMutex       sync.RWMutex
+	cancelOperationArgsForCall []struct {
+		ctx        context.Context
+		instanceID string
+		logger     *log.Logger
+	}
+	cancelOperationReturns struct {
+		result1 []int
+		result2 error
+	}
+	cancelOperationReturnsOnCall map[int]struct {
+		result1 []int
+		result2 error
+	}
+	PurgeInstanceStub        func(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, error)
+	purgeInstanceMutex       sync.RWMutex
+	purgeInstanceArgsForCall []struct {
+		ctx        context.Context
+		instanceID string
+		logger     *log.Logger
+	}
+	purgeInstanceReturns struct {
+		result1 broker.OperationData
+		result2 error
+	}
+	purgeInstanceReturnsOnCall map[int]struct {
+		result1 broker.OperationData
+		result2 error
+	}
+	RunErrandStub        func(ctx context.Context, instanceID string, errand config.Errand, logger *log.Logger) (int, error)
+	runErrandMutex       sync.RWMutex
+	runErrandArgsForCall []struct {
+		ctx        context.Context
+		instanceID string
+		errand     config.Errand
+		logger     *log.Logger
+	}
+	runErrandReturns struct {
+		result1 int
+		result2 error
+	}
+	runErrandReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	DashboardURLsStub        func(ctx context.Context, logger *log.Logger) ([]broker.InstanceDashboardURL, error)
+	dashboardURLsMutex       sync.RWMutex
+	dashboardURLsArgsForCall []struct {
+		ctx    context.Context
+		logger *log.Logger
+	}
+	dashboardURLsReturns struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}
+	dashboardURLsReturnsOnCall map[int]struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}
+	ResyncDashboardURLsStub        func(ctx context.Context, logger *log.Logger) ([]broker.InstanceDashboardURL, error)
+	resyncDashboardURLsMutex       sync.RWMutex
+	resyncDashboardURLsArgsForCall []struct {
+		ctx    context.Context
+		logger *log.Logger
+	}
+	resyncDashboardURLsReturns struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}
+	resyncDashboardURLsReturnsOnCall map[int]struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -172,7 +262,7 @@ func (fake *FakeManageableBroker) OrphanDeploymentsReturnsOnCall(i int, result1
 	}{result1, result2}
 }
 
-func (fake *FakeManageableBroker) Upgrade(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, error) {
+func (fake *FakeManageableBroker) Upgrade(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, string, error) {
 	fake.upgradeMutex.Lock()
 	ret, specificReturn := fake.upgradeReturnsOnCall[len(fake.upgradeArgsForCall)]
 	fake.upgradeArgsForCall = append(fake.upgradeArgsForCall, struct {
@@ -186,9 +276,9 @@ func (fake *FakeManageableBroker) Upgrade(ctx context.Context, instanceID string
 		return fake.UpgradeStub(ctx, instanceID, logger)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1, ret.result2, ret.result3
 	}
-	return fake.upgradeReturns.result1, fake.upgradeReturns.result2
+	return fake.upgradeReturns.result1, fake.upgradeReturns.result2, fake.upgradeReturns.result3
 }
 
 func (fake *FakeManageableBroker) UpgradeCallCount() int {
@@ -203,26 +293,29 @@ func (fake *FakeManageableBroker) UpgradeArgsForCall(i int) (context.Context, st
 	return fake.upgradeArgsForCall[i].ctx, fake.upgradeArgsForCall[i].instanceID, fake.upgradeArgsForCall[i].logger
 }
 
-func (fake *FakeManageableBroker) UpgradeReturns(result1 broker.OperationData, result2 error) {
+func (fake *FakeManageableBroker) UpgradeReturns(result1 broker.OperationData, result2 string, result3 error) {
 	fake.UpgradeStub = nil
 	fake.upgradeReturns = struct {
 		result1 broker.OperationData
-		result2 error
-	}{result1, result2}
+		result2 string
+		result3 error
+	}{result1, result2, result3}
 }
 
-func (fake *FakeManageableBroker) UpgradeReturnsOnCall(i int, result1 broker.OperationData, result2 error) {
+func (fake *FakeManageableBroker) UpgradeReturnsOnCall(i int, result1 broker.OperationData, result2 string, result3 error) {
 	fake.UpgradeStub = nil
 	if fake.upgradeReturnsOnCall == nil {
 		fake.upgradeReturnsOnCall = make(map[int]struct {
 			result1 broker.OperationData
-			result2 error
+			result2 string
+			result3 error
 		})
 	}
 	fake.upgradeReturnsOnCall[i] = struct {
 		result1 broker.OperationData
-		result2 error
-	}{result1, result2}
+		result2 string
+		result3 error
+	}{result1, result2, result3}
 }
 
 func (fake *FakeManageableBroker) CountInstancesOfPlans(logger *log.Logger) (map[cf.ServicePlan]int, error) {
@@ -276,6 +369,321 @@ func (fake *FakeManageableBroker) CountInstancesOfPlansReturnsOnCall(i int, resu
 	}{result1, result2}
 }
 
+func (fake *FakeManageableBroker) CountInstancesOfPlansByOrg(logger *log.Logger) (map[cf.ServicePlan]map[string]int, error) {
+	fake.countInstancesOfPlansByOrgMutex.Lock()
+	ret, specificReturn := fake.countInstancesOfPlansByOrgReturnsOnCall[len(fake.countInstancesOfPlansByOrgArgsForCall)]
+	fake.countInstancesOfPlansByOrgArgsForCall = append(fake.countInstancesOfPlansByOrgArgsForCall, struct {
+		logger *log.Logger
+	}{logger})
+	fake.recordInvocation("CountInstancesOfPlansByOrg", []interface{}{logger})
+	fake.countInstancesOfPlansByOrgMutex.Unlock()
+	if fake.CountInstancesOfPlansByOrgStub != nil {
+		return fake.CountInstancesOfPlansByOrgStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.countInstancesOfPlansByOrgReturns.result1, fake.countInstancesOfPlansByOrgReturns.result2
+}
+
+func (fake *FakeManageableBroker) CountInstancesOfPlansByOrgCallCount() int {
+	fake.countInstancesOfPlansByOrgMutex.RLock()
+	defer fake.countInstancesOfPlansByOrgMutex.RUnlock()
+	return len(fake.countInstancesOfPlansByOrgArgsForCall)
+}
+
+func (fake *FakeManageableBroker) CountInstancesOfPlansByOrgArgsForCall(i int) *log.Logger {
+	fake.countInstancesOfPlansByOrgMutex.RLock()
+	defer fake.countInstancesOfPlansByOrgMutex.RUnlock()
+	return fake.countInstancesOfPlansByOrgArgsForCall[i].logger
+}
+
+func (fake *FakeManageableBroker) CountInstancesOfPlansByOrgReturns(result1 map[cf.ServicePlan]map[string]int, result2 error) {
+	fake.CountInstancesOfPlansByOrgStub = nil
+	fake.countInstancesOfPlansByOrgReturns = struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) CountInstancesOfPlansByOrgReturnsOnCall(i int, result1 map[cf.ServicePlan]map[string]int, result2 error) {
+	fake.CountInstancesOfPlansByOrgStub = nil
+	if fake.countInstancesOfPlansByOrgReturnsOnCall == nil {
+		fake.countInstancesOfPlansByOrgReturnsOnCall = make(map[int]struct {
+			result1 map[cf.ServicePlan]map[string]int
+			result2 error
+		})
+	}
+	fake.countInstancesOfPlansByOrgReturnsOnCall[i] = struct {
+		result1 map[cf.ServicePlan]map[string]int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) CancelOperation(ctx context.Context, instanceID string, logger *log.Logger) ([]int, error) {
+	fake.cancelOperationMutex.Lock()
+	ret, specificReturn := fake.cancelOperationReturnsOnCall[len(fake.cancelOperationArgsForCall)]
+	fake.cancelOperationArgsForCall = append(fake.cancelOperationArgsForCall, struct {
+		ctx        context.Context
+		instanceID string
+		logger     *log.Logger
+	}{ctx, instanceID, logger})
+	fake.recordInvocation("CancelOperation", []interface{}{ctx, instanceID, logger})
+	fake.cancelOperationMutex.Unlock()
+	if fake.CancelOperationStub != nil {
+		return fake.CancelOperationStub(ctx, instanceID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.cancelOperationReturns.result1, fake.cancelOperationReturns.result2
+}
+
+func (fake *FakeManageableBroker) CancelOperationCallCount() int {
+	fake.cancelOperationMutex.RLock()
+	defer fake.cancelOperationMutex.RUnlock()
+	return len(fake.cancelOperationArgsForCall)
+}
+
+func (fake *FakeManageableBroker) CancelOperationArgsForCall(i int) (context.Context, string, *log.Logger) {
+	fake.cancelOperationMutex.RLock()
+	defer fake.cancelOperationMutex.RUnlock()
+	return fake.cancelOperationArgsForCall[i].ctx, fake.cancelOperationArgsForCall[i].instanceID, fake.cancelOperationArgsForCall[i].logger
+}
+
+func (fake *FakeManageableBroker) CancelOperationReturns(result1 []int, result2 error) {
+	fake.CancelOperationStub = nil
+	fake.cancelOperationReturns = struct {
+		result1 []int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) CancelOperationReturnsOnCall(i int, result1 []int, result2 error) {
+	fake.CancelOperationStub = nil
+	if fake.cancelOperationReturnsOnCall == nil {
+		fake.cancelOperationReturnsOnCall = make(map[int]struct {
+			result1 []int
+			result2 error
+		})
+	}
+	fake.cancelOperationReturnsOnCall[i] = struct {
+		result1 []int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) PurgeInstance(ctx context.Context, instanceID string, logger *log.Logger) (broker.OperationData, error) {
+	fake.purgeInstanceMutex.Lock()
+	ret, specificReturn := fake.purgeInstanceReturnsOnCall[len(fake.purgeInstanceArgsForCall)]
+	fake.purgeInstanceArgsForCall = append(fake.purgeInstanceArgsForCall, struct {
+		ctx        context.Context
+		instanceID string
+		logger     *log.Logger
+	}{ctx, instanceID, logger})
+	fake.recordInvocation("PurgeInstance", []interface{}{ctx, instanceID, logger})
+	fake.purgeInstanceMutex.Unlock()
+	if fake.PurgeInstanceStub != nil {
+		return fake.PurgeInstanceStub(ctx, instanceID, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.purgeInstanceReturns.result1, fake.purgeInstanceReturns.result2
+}
+
+func (fake *FakeManageableBroker) PurgeInstanceCallCount() int {
+	fake.purgeInstanceMutex.RLock()
+	defer fake.purgeInstanceMutex.RUnlock()
+	return len(fake.purgeInstanceArgsForCall)
+}
+
+func (fake *FakeManageableBroker) PurgeInstanceArgsForCall(i int) (context.Context, string, *log.Logger) {
+	fake.purgeInstanceMutex.RLock()
+	defer fake.purgeInstanceMutex.RUnlock()
+	return fake.purgeInstanceArgsForCall[i].ctx, fake.purgeInstanceArgsForCall[i].instanceID, fake.purgeInstanceArgsForCall[i].logger
+}
+
+func (fake *FakeManageableBroker) PurgeInstanceReturns(result1 broker.OperationData, result2 error) {
+	fake.PurgeInstanceStub = nil
+	fake.purgeInstanceReturns = struct {
+		result1 broker.OperationData
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) PurgeInstanceReturnsOnCall(i int, result1 broker.OperationData, result2 error) {
+	fake.PurgeInstanceStub = nil
+	if fake.purgeInstanceReturnsOnCall == nil {
+		fake.purgeInstanceReturnsOnCall = make(map[int]struct {
+			result1 broker.OperationData
+			result2 error
+		})
+	}
+	fake.purgeInstanceReturnsOnCall[i] = struct {
+		result1 broker.OperationData
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) RunErrand(ctx context.Context, instanceID string, errand config.Errand, logger *log.Logger) (int, error) {
+	fake.runErrandMutex.Lock()
+	ret, specificReturn := fake.runErrandReturnsOnCall[len(fake.runErrandArgsForCall)]
+	fake.runErrandArgsForCall = append(fake.runErrandArgsForCall, struct {
+		ctx        context.Context
+		instanceID string
+		errand     config.Errand
+		logger     *log.Logger
+	}{ctx, instanceID, errand, logger})
+	fake.recordInvocation("RunErrand", []interface{}{ctx, instanceID, errand, logger})
+	fake.runErrandMutex.Unlock()
+	if fake.RunErrandStub != nil {
+		return fake.RunErrandStub(ctx, instanceID, errand, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.runErrandReturns.result1, fake.runErrandReturns.result2
+}
+
+func (fake *FakeManageableBroker) RunErrandCallCount() int {
+	fake.runErrandMutex.RLock()
+	defer fake.runErrandMutex.RUnlock()
+	return len(fake.runErrandArgsForCall)
+}
+
+func (fake *FakeManageableBroker) RunErrandArgsForCall(i int) (context.Context, string, config.Errand, *log.Logger) {
+	fake.runErrandMutex.RLock()
+	defer fake.runErrandMutex.RUnlock()
+	return fake.runErrandArgsForCall[i].ctx, fake.runErrandArgsForCall[i].instanceID, fake.runErrandArgsForCall[i].errand, fake.runErrandArgsForCall[i].logger
+}
+
+func (fake *FakeManageableBroker) RunErrandReturns(result1 int, result2 error) {
+	fake.RunErrandStub = nil
+	fake.runErrandReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) RunErrandReturnsOnCall(i int, result1 int, result2 error) {
+	fake.RunErrandStub = nil
+	if fake.runErrandReturnsOnCall == nil {
+		fake.runErrandReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.runErrandReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) DashboardURLs(ctx context.Context, logger *log.Logger) ([]broker.InstanceDashboardURL, error) {
+	fake.dashboardURLsMutex.Lock()
+	ret, specificReturn := fake.dashboardURLsReturnsOnCall[len(fake.dashboardURLsArgsForCall)]
+	fake.dashboardURLsArgsForCall = append(fake.dashboardURLsArgsForCall, struct {
+		ctx    context.Context
+		logger *log.Logger
+	}{ctx, logger})
+	fake.recordInvocation("DashboardURLs", []interface{}{ctx, logger})
+	fake.dashboardURLsMutex.Unlock()
+	if fake.DashboardURLsStub != nil {
+		return fake.DashboardURLsStub(ctx, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.dashboardURLsReturns.result1, fake.dashboardURLsReturns.result2
+}
+
+func (fake *FakeManageableBroker) DashboardURLsCallCount() int {
+	fake.dashboardURLsMutex.RLock()
+	defer fake.dashboardURLsMutex.RUnlock()
+	return len(fake.dashboardURLsArgsForCall)
+}
+
+func (fake *FakeManageableBroker) DashboardURLsArgsForCall(i int) (context.Context, *log.Logger) {
+	fake.dashboardURLsMutex.RLock()
+	defer fake.dashboardURLsMutex.RUnlock()
+	return fake.dashboardURLsArgsForCall[i].ctx, fake.dashboardURLsArgsForCall[i].logger
+}
+
+func (fake *FakeManageableBroker) DashboardURLsReturns(result1 []broker.InstanceDashboardURL, result2 error) {
+	fake.DashboardURLsStub = nil
+	fake.dashboardURLsReturns = struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) DashboardURLsReturnsOnCall(i int, result1 []broker.InstanceDashboardURL, result2 error) {
+	fake.DashboardURLsStub = nil
+	if fake.dashboardURLsReturnsOnCall == nil {
+		fake.dashboardURLsReturnsOnCall = make(map[int]struct {
+			result1 []broker.InstanceDashboardURL
+			result2 error
+		})
+	}
+	fake.dashboardURLsReturnsOnCall[i] = struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) ResyncDashboardURLs(ctx context.Context, logger *log.Logger) ([]broker.InstanceDashboardURL, error) {
+	fake.resyncDashboardURLsMutex.Lock()
+	ret, specificReturn := fake.resyncDashboardURLsReturnsOnCall[len(fake.resyncDashboardURLsArgsForCall)]
+	fake.resyncDashboardURLsArgsForCall = append(fake.resyncDashboardURLsArgsForCall, struct {
+		ctx    context.Context
+		logger *log.Logger
+	}{ctx, logger})
+	fake.recordInvocation("ResyncDashboardURLs", []interface{}{ctx, logger})
+	fake.resyncDashboardURLsMutex.Unlock()
+	if fake.ResyncDashboardURLsStub != nil {
+		return fake.ResyncDashboardURLsStub(ctx, logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.resyncDashboardURLsReturns.result1, fake.resyncDashboardURLsReturns.result2
+}
+
+func (fake *FakeManageableBroker) ResyncDashboardURLsCallCount() int {
+	fake.resyncDashboardURLsMutex.RLock()
+	defer fake.resyncDashboardURLsMutex.RUnlock()
+	return len(fake.resyncDashboardURLsArgsForCall)
+}
+
+func (fake *FakeManageableBroker) ResyncDashboardURLsArgsForCall(i int) (context.Context, *log.Logger) {
+	fake.resyncDashboardURLsMutex.RLock()
+	defer fake.resyncDashboardURLsMutex.RUnlock()
+	return fake.resyncDashboardURLsArgsForCall[i].ctx, fake.resyncDashboardURLsArgsForCall[i].logger
+}
+
+func (fake *FakeManageableBroker) ResyncDashboardURLsReturns(result1 []broker.InstanceDashboardURL, result2 error) {
+	fake.ResyncDashboardURLsStub = nil
+	fake.resyncDashboardURLsReturns = struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeManageableBroker) ResyncDashboardURLsReturnsOnCall(i int, result1 []broker.InstanceDashboardURL, result2 error) {
+	fake.ResyncDashboardURLsStub = nil
+	if fake.resyncDashboardURLsReturnsOnCall == nil {
+		fake.resyncDashboardURLsReturnsOnCall = make(map[int]struct {
+			result1 []broker.InstanceDashboardURL
+			result2 error
+		})
+	}
+	fake.resyncDashboardURLsReturnsOnCall[i] = struct {
+		result1 []broker.InstanceDashboardURL
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeManageableBroker) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -287,6 +695,18 @@ func (fake *FakeManageableBroker) Invocations() map[string][][]interface{} {
 	defer fake.upgradeMutex.RUnlock()
 	fake.countInstancesOfPlansMutex.RLock()
 	defer fake.countInstancesOfPlansMutex.RUnlock()
+	fake.countInstancesOfPlansByOrgMutex.RLock()
+	defer fake.countInstancesOfPlansByOrgMutex.RUnlock()
+	fake.cancelOperationMutex.RLock()
+	defer fake.cancelOperationMutex.RUnlock()
+	fake.purgeInstanceMutex.RLock()
+	defer fake.purgeInstanceMutex.RUnlock()
+	fake.runErrandMutex.RLock()
+	defer fake.runErrandMutex.RUnlock()
+	fake.dashboardURLsMutex.RLock()
+	defer fake.dashboardURLsMutex.RUnlock()
+	fake.resyncDashboardURLsMutex.RLock()
+	defer fake.resyncDashboardURLsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
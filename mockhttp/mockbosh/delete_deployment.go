@@ -18,6 +18,12 @@ func DeleteDeployment(deploymentName string) *deleteDeployMock {
 	}
 }
 
+func ForceDeleteDeployment(deploymentName string) *deleteDeployMock {
+	return &deleteDeployMock{
+		Handler: mockhttp.NewMockedHttpRequest("DELETE", "/deployments/"+deploymentName+"?force=true"),
+	}
+}
+
 func (d *deleteDeployMock) WithoutContextID() *deleteDeployMock {
 	d.WithoutHeader(BoshContextIDHeader)
 	return d
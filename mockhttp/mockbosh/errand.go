@@ -40,6 +40,11 @@ func (e *errandMock) WithoutContextID() *errandMock {
 	return e
 }
 
+func (e *errandMock) WithInstances(instances string) *errandMock {
+	e.WithBody(fmt.Sprintf(`{"instances":%s}`, instances))
+	return e
+}
+
 func (e *errandMock) RedirectsToTask(taskID int) *mockhttp.Handler {
 	return e.RedirectsTo(taskURL(taskID))
 }
@@ -31,3 +31,17 @@ func (t *taskMock) RespondsWithTaskContainingState(provisioningTaskState string)
 		State: provisioningTaskState,
 	})
 }
+
+type cancelTaskMock struct {
+	*mockhttp.Handler
+}
+
+func CancelTask(taskID int) *cancelTaskMock {
+	return &cancelTaskMock{
+		Handler: mockhttp.NewMockedHttpRequest("DELETE", fmt.Sprintf("/tasks/%d", taskID)),
+	}
+}
+
+func (t *cancelTaskMock) RespondsCancelled() *mockhttp.Handler {
+	return t.RespondsNoContent()
+}
@@ -47,3 +47,24 @@ func (t *taskOutputMock) RespondsWithTaskOutput(taskOutput []boshdirector.BoshTa
 
 	return t.RespondsOKWith(string(output.Bytes()))
 }
+
+type taskEventsMock struct {
+	*mockhttp.Handler
+}
+
+func TaskEvents(taskId int) *taskEventsMock {
+	return &taskEventsMock{
+		Handler: mockhttp.NewMockedHttpRequest("GET", fmt.Sprintf("/tasks/%d/output?type=event", taskId)),
+	}
+}
+
+func (t *taskEventsMock) RespondsWithEvents(events []boshdirector.BoshTaskEvent) *mockhttp.Handler {
+	output := bytes.NewBuffer([]byte{})
+	encoder := json.NewEncoder(output)
+
+	for _, event := range events {
+		Expect(encoder.Encode(event)).ToNot(HaveOccurred())
+	}
+
+	return t.RespondsOKWith(string(output.Bytes()))
+}
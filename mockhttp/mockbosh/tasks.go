@@ -29,6 +29,12 @@ func TasksByContext(deploymentName, contextID string) *tasksMock {
 	}
 }
 
+func InFlightTasks() *tasksMock {
+	return &tasksMock{
+		Handler: mockhttp.NewMockedHttpRequest("GET", "/tasks?state=queued,processing,cancelling"),
+	}
+}
+
 func (t *tasksMock) RespondsWithNoTasks() *mockhttp.Handler {
 	return t.RespondsOKWithJSON([]boshdirector.BoshTask{})
 }
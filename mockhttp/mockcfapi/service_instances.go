@@ -62,6 +62,11 @@ func DeleteServiceInstance(instanceGUID string) *mockhttp.Handler {
 	return mockhttp.NewMockedHttpRequest("DELETE", path)
 }
 
+func UpdateServiceInstance(instanceGUID string) *mockhttp.Handler {
+	path := fmt.Sprintf("/v2/service_instances/%s?accepts_incomplete=true", instanceGUID)
+	return mockhttp.NewMockedHttpRequest("PUT", path)
+}
+
 type listServiceInstancesMock struct {
 	*mockhttp.Handler
 }
@@ -76,6 +81,24 @@ func ListServiceInstances(servicePlanGUID string) *listServiceInstancesMock {
 	}
 }
 
+func ListServiceInstancesInOrg(servicePlanGUID, orgGUID string) *listServiceInstancesMock {
+	return &listServiceInstancesMock{
+		mockhttp.NewMockedHttpRequest(
+			"GET",
+			fmt.Sprintf("/v2/service_plans/%s/service_instances?q=organization_guid:%s&results-per-page=100", servicePlanGUID, orgGUID),
+		),
+	}
+}
+
+func ListServiceInstancesInSpace(servicePlanGUID, spaceGUID string) *listServiceInstancesMock {
+	return &listServiceInstancesMock{
+		mockhttp.NewMockedHttpRequest(
+			"GET",
+			fmt.Sprintf("/v2/service_plans/%s/service_instances?q=space_guid:%s&results-per-page=100", servicePlanGUID, spaceGUID),
+		),
+	}
+}
+
 func ListServiceInstancesForPage(servicePlanGUID string, page int) *listServiceInstancesMock {
 	return &listServiceInstancesMock{
 		mockhttp.NewMockedHttpRequest(
@@ -0,0 +1,285 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package operationstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/on-demand-service-broker/atomicfile"
+	"github.com/pivotal-cf/on-demand-service-broker/storecipher"
+)
+
+// FileStore keeps instances in memory and writes them to a JSON file after
+// every change. An instance is dropped once it has been deleted. With an
+// empty path nothing is persisted.
+type FileStore struct {
+	path      string
+	file      *atomicfile.File
+	mutex     sync.Mutex
+	instances map[string]Instance
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	return newFileStore(path, nil)
+}
+
+// NewEncryptedFileStore is a FileStore whose file is encrypted with the
+// given key, as the requests of operations that deploy later may hold
+// secrets.
+func NewEncryptedFileStore(path, encryptionKey string) (*FileStore, error) {
+	if encryptionKey == "" {
+		return nil, errors.New("operation store encryption key can't be empty")
+	}
+
+	c, err := storecipher.New(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return newFileStore(path, c)
+}
+
+func newFileStore(path string, c *storecipher.Cipher) (*FileStore, error) {
+	s := &FileStore{path: path, instances: map[string]Instance{}}
+	if path == "" {
+		return s, nil
+	}
+
+	var encrypt func([]byte) ([]byte, error)
+	if c != nil {
+		encrypt = c.Encrypt
+	}
+	s.file = atomicfile.New(path, encrypt)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading operation store %s: %s", path, err)
+	}
+
+	if len(data) > 0 && c != nil {
+		if data, err = c.Decrypt(data); err != nil {
+			return nil, fmt.Errorf("decrypting operation store %s: %s", path, err)
+		}
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.instances); err != nil {
+			return nil, fmt.Errorf("parsing operation store %s: %s", path, err)
+		}
+	}
+
+	// files written before deleted instances were dropped may still hold them
+	for instanceID, instance := range s.instances {
+		if instance.State == InstanceDeleted {
+			delete(s.instances, instanceID)
+		}
+	}
+
+	return s, nil
+}
+
+// StartOperation records a new operation for the instance. Starting an
+// operation again with the BOSH context ID of the instance's operation in
+// progress replaces that record, as an operation that deploys later is
+// recorded before its first task starts and again once it has.
+func (s *FileStore) StartOperation(instanceID string, operation Operation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	instance, found := s.instances[instanceID]
+	if !found {
+		instance = Instance{
+			ID:         instanceID,
+			PlanID:     operation.PlanID,
+			Parameters: operation.Parameters,
+			State:      InstanceActive,
+			CreatedAt:  now,
+		}
+	}
+
+	if operation.Type == operationTypeCreate {
+		instance.State = InstanceCreating
+	}
+
+	operations := copyOperations(instance.Operations)
+	operation.State = OperationInProgress
+	operation.StartedAt = now
+	operation.FinishedAt = nil
+	if last, found := instance.LastOperation(); found && last.restartedBy(operation) {
+		operation.StartedAt = last.StartedAt
+		operations[len(operations)-1] = operation
+	} else {
+		operations = append(operations, operation)
+	}
+	instance.Operations = operations
+	instance.UpdatedAt = now
+
+	return s.save(instance)
+}
+
+func (s *FileStore) UpdateOperation(instanceID string, boshTaskID int, status OperationStatus) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	instance, found := s.instances[instanceID]
+	if !found {
+		return fmt.Errorf("instance %s not found", instanceID)
+	}
+
+	operations := copyOperations(instance.Operations)
+	index := -1
+	for i := len(operations) - 1; i >= 0; i-- {
+		if operations[i].hasBoshTask(boshTaskID) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("operation with BOSH task %d not found for instance %s", boshTaskID, instanceID)
+	}
+
+	operation := operations[index]
+	if status.BoshTaskID != 0 && !operation.hasBoshTask(status.BoshTaskID) {
+		operation.BoshTaskIDs = append(append([]int{}, operation.BoshTaskIDs...), status.BoshTaskID)
+	} else if operation.State == status.State && operation.Description == status.Description && (operation.Cancelled || !status.Cancelled) {
+		return nil
+	}
+
+	now := time.Now()
+	operation.State = status.State
+	operation.Description = status.Description
+	operation.Cancelled = operation.Cancelled || status.Cancelled
+	if status.State != OperationInProgress {
+		operation.FinishedAt = &now
+		operation.RequestParams = nil
+		instance = applyOperation(instance, operation)
+	}
+
+	if instance.State == InstanceDeleted {
+		return s.remove(instanceID)
+	}
+
+	operations[index] = operation
+	instance.Operations = operations
+	instance.UpdatedAt = now
+
+	return s.save(instance)
+}
+
+func (s *FileStore) GetInstance(instanceID string) (Instance, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	instance, found := s.instances[instanceID]
+	if !found {
+		return Instance{}, false, nil
+	}
+	instance.Operations = copyOperations(instance.Operations)
+	return instance, true, nil
+}
+
+func applyOperation(instance Instance, operation Operation) Instance {
+	if operation.State == OperationFailed {
+		if operation.Type == operationTypeCreate {
+			instance.State = InstanceFailed
+		}
+		return instance
+	}
+
+	switch operation.Type {
+	case operationTypeCreate:
+		instance.State = InstanceActive
+		instance.PlanID = operation.PlanID
+		instance.Parameters = operation.Parameters
+	case operationTypeUpdate:
+		instance.State = InstanceActive
+		if operation.PlanID != "" {
+			instance.PlanID = operation.PlanID
+		}
+		instance.Parameters = mergeParameters(instance.Parameters, operation.Parameters)
+	case operationTypeDelete:
+		instance.State = InstanceDeleted
+	}
+
+	return instance
+}
+
+func mergeParameters(previous, next map[string]interface{}) map[string]interface{} {
+	if len(previous) == 0 {
+		return next
+	}
+
+	merged := map[string]interface{}{}
+	for k, v := range previous {
+		merged[k] = v
+	}
+	for k, v := range next {
+		merged[k] = v
+	}
+	return merged
+}
+
+func copyOperations(operations []Operation) []Operation {
+	return append([]Operation{}, operations...)
+}
+
+// save must be called with the mutex held. If the file cannot be written the
+// in-memory change is rolled back.
+func (s *FileStore) save(instance Instance) error {
+	previous, existed := s.instances[instance.ID]
+	s.instances[instance.ID] = instance
+
+	if err := s.persist(); err != nil {
+		if existed {
+			s.instances[instance.ID] = previous
+		} else {
+			delete(s.instances, instance.ID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// remove must be called with the mutex held. If the file cannot be written
+// the instance is kept.
+func (s *FileStore) remove(instanceID string) error {
+	previous := s.instances[instanceID]
+	delete(s.instances, instanceID)
+
+	if err := s.persist(); err != nil {
+		s.instances[instanceID] = previous
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) persist() error {
+	if s.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(s.instances)
+	if err != nil {
+		return err
+	}
+
+	if err := s.file.Write(data); err != nil {
+		return fmt.Errorf("writing operation store %s: %s", s.path, err)
+	}
+	return nil
+}
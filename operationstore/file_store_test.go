@@ -0,0 +1,420 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package operationstore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/operationstore"
+)
+
+var _ = Describe("FileStore", func() {
+	const instanceID = "some-instance-id"
+
+	var (
+		storeDir  string
+		storePath string
+		store     *operationstore.FileStore
+	)
+
+	createOperation := operationstore.Operation{
+		Type:        "create",
+		PlanID:      "some-plan",
+		Parameters:  map[string]interface{}{"foo": "bar"},
+		BoshTaskIDs: []int{1},
+	}
+
+	BeforeEach(func() {
+		var err error
+		storeDir, err = ioutil.TempDir("", "operation-store")
+		Expect(err).NotTo(HaveOccurred())
+		storePath = filepath.Join(storeDir, "operations.json")
+
+		store, err = operationstore.NewFileStore(storePath)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(storeDir)).To(Succeed())
+	})
+
+	getInstance := func() operationstore.Instance {
+		instance, found, err := store.GetInstance(instanceID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		return instance
+	}
+
+	It("reports unknown instances as not found", func() {
+		_, found, err := store.GetInstance("unknown")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	Context("when a create operation is started", func() {
+		BeforeEach(func() {
+			Expect(store.StartOperation(instanceID, createOperation)).To(Succeed())
+		})
+
+		It("records the instance as creating", func() {
+			instance := getInstance()
+			Expect(instance.ID).To(Equal(instanceID))
+			Expect(instance.PlanID).To(Equal("some-plan"))
+			Expect(instance.Parameters).To(Equal(map[string]interface{}{"foo": "bar"}))
+			Expect(instance.State).To(Equal(operationstore.InstanceCreating))
+			Expect(instance.CreatedAt).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		It("records the operation as in progress", func() {
+			operation, found := getInstance().LastOperation()
+			Expect(found).To(BeTrue())
+			Expect(operation.Type).To(Equal("create"))
+			Expect(operation.BoshTaskIDs).To(Equal([]int{1}))
+			Expect(operation.State).To(Equal(operationstore.OperationInProgress))
+			Expect(operation.StartedAt).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(operation.FinishedAt).To(BeNil())
+		})
+
+		It("persists the instance across restarts", func() {
+			reopened, err := operationstore.NewFileStore(storePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			instance, found, err := reopened.GetInstance(instanceID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(instance.PlanID).To(Equal("some-plan"))
+			Expect(instance.Operations).To(HaveLen(1))
+		})
+
+		It("does not rewrite the file when a poll reports the same status", func() {
+			status := operationstore.OperationStatus{
+				BoshTaskID:  1,
+				State:       operationstore.OperationInProgress,
+				Description: "Instance provisioning in progress",
+			}
+			Expect(store.UpdateOperation(instanceID, 1, status)).To(Succeed())
+			Expect(os.Remove(storePath)).To(Succeed())
+
+			Expect(store.UpdateOperation(instanceID, 1, status)).To(Succeed())
+
+			_, err := os.Stat(storePath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		Context("and a post-deploy errand task is reported", func() {
+			BeforeEach(func() {
+				Expect(store.UpdateOperation(instanceID, 1, operationstore.OperationStatus{
+					BoshTaskID:  2,
+					State:       operationstore.OperationInProgress,
+					Description: "Instance provisioning in progress",
+				})).To(Succeed())
+			})
+
+			It("records the errand task against the operation", func() {
+				operation, _ := getInstance().LastOperation()
+				Expect(operation.BoshTaskIDs).To(Equal([]int{1, 2}))
+				Expect(operation.Description).To(Equal("Instance provisioning in progress"))
+			})
+		})
+
+		Context("and the broker cancels the operation", func() {
+			BeforeEach(func() {
+				Expect(store.UpdateOperation(instanceID, 1, operationstore.OperationStatus{
+					State:     operationstore.OperationInProgress,
+					Cancelled: true,
+				})).To(Succeed())
+				Expect(store.UpdateOperation(instanceID, 1, operationstore.OperationStatus{
+					BoshTaskID:  1,
+					State:       operationstore.OperationFailed,
+					Description: "Instance provisioning failed: cancelled by operator",
+				})).To(Succeed())
+			})
+
+			It("keeps the operation marked as cancelled once it has finished", func() {
+				operation, _ := getInstance().LastOperation()
+				Expect(operation.Cancelled).To(BeTrue())
+				Expect(operation.State).To(Equal(operationstore.OperationFailed))
+			})
+		})
+
+		Context("and the operation succeeds", func() {
+			BeforeEach(func() {
+				Expect(store.UpdateOperation(instanceID, 1, operationstore.OperationStatus{
+					BoshTaskID:  1,
+					State:       operationstore.OperationSucceeded,
+					Description: "Instance provisioning completed",
+				})).To(Succeed())
+			})
+
+			It("marks the instance as active", func() {
+				Expect(getInstance().State).To(Equal(operationstore.InstanceActive))
+			})
+
+			It("records the outcome of the operation", func() {
+				operation, _ := getInstance().LastOperation()
+				Expect(operation.State).To(Equal(operationstore.OperationSucceeded))
+				Expect(operation.Description).To(Equal("Instance provisioning completed"))
+				Expect(operation.FinishedAt).NotTo(BeNil())
+			})
+
+			Context("and an update changing plan and parameters succeeds", func() {
+				BeforeEach(func() {
+					Expect(store.StartOperation(instanceID, operationstore.Operation{
+						Type:        "update",
+						PlanID:      "another-plan",
+						Parameters:  map[string]interface{}{"baz": "qux"},
+						BoshTaskIDs: []int{3},
+					})).To(Succeed())
+					Expect(store.UpdateOperation(instanceID, 3, operationstore.OperationStatus{
+						BoshTaskID: 3,
+						State:      operationstore.OperationSucceeded,
+					})).To(Succeed())
+				})
+
+				It("applies the new plan and merges the parameters", func() {
+					instance := getInstance()
+					Expect(instance.PlanID).To(Equal("another-plan"))
+					Expect(instance.Parameters).To(Equal(map[string]interface{}{"foo": "bar", "baz": "qux"}))
+					Expect(instance.Operations).To(HaveLen(2))
+				})
+			})
+
+			Context("and an update fails", func() {
+				BeforeEach(func() {
+					Expect(store.StartOperation(instanceID, operationstore.Operation{
+						Type:        "update",
+						PlanID:      "another-plan",
+						BoshTaskIDs: []int{3},
+					})).To(Succeed())
+					Expect(store.UpdateOperation(instanceID, 3, operationstore.OperationStatus{
+						BoshTaskID: 3,
+						State:      operationstore.OperationFailed,
+					})).To(Succeed())
+				})
+
+				It("keeps the previous plan", func() {
+					instance := getInstance()
+					Expect(instance.PlanID).To(Equal("some-plan"))
+					Expect(instance.State).To(Equal(operationstore.InstanceActive))
+				})
+			})
+
+			Context("and a delete succeeds", func() {
+				BeforeEach(func() {
+					Expect(store.StartOperation(instanceID, operationstore.Operation{
+						Type:        "delete",
+						BoshTaskIDs: []int{4},
+					})).To(Succeed())
+					Expect(store.UpdateOperation(instanceID, 4, operationstore.OperationStatus{
+						BoshTaskID: 4,
+						State:      operationstore.OperationSucceeded,
+					})).To(Succeed())
+				})
+
+				It("drops the instance", func() {
+					_, found, err := store.GetInstance(instanceID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(found).To(BeFalse())
+
+					reopened, err := operationstore.NewFileStore(storePath)
+					Expect(err).NotTo(HaveOccurred())
+					_, found, err = reopened.GetInstance(instanceID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(found).To(BeFalse())
+				})
+			})
+		})
+
+		Context("and the operation fails", func() {
+			BeforeEach(func() {
+				Expect(store.UpdateOperation(instanceID, 1, operationstore.OperationStatus{
+					BoshTaskID: 1,
+					State:      operationstore.OperationFailed,
+				})).To(Succeed())
+			})
+
+			It("marks the instance as failed", func() {
+				Expect(getInstance().State).To(Equal(operationstore.InstanceFailed))
+			})
+		})
+	})
+
+	Context("when an operation is started with a request to deploy later", func() {
+		BeforeEach(func() {
+			deferred := createOperation
+			deferred.BoshContextID = "some-context-id"
+			deferred.RequestParams = map[string]interface{}{"parameters": map[string]interface{}{"password": "secret"}}
+			Expect(store.StartOperation(instanceID, deferred)).To(Succeed())
+		})
+
+		It("keeps the request while the operation is in progress", func() {
+			operation, _ := getInstance().LastOperation()
+			Expect(operation.RequestParams).To(HaveKey("parameters"))
+		})
+
+		It("drops the request once the operation finishes", func() {
+			Expect(store.UpdateOperation(instanceID, 1, operationstore.OperationStatus{
+				BoshTaskID: 1,
+				State:      operationstore.OperationSucceeded,
+			})).To(Succeed())
+
+			operation, _ := getInstance().LastOperation()
+			Expect(operation.RequestParams).To(BeNil())
+
+			contents, err := ioutil.ReadFile(storePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).NotTo(ContainSubstring("secret"))
+		})
+	})
+
+	Context("when an operation recorded before its first task is started again once it has", func() {
+		BeforeEach(func() {
+			deferred := createOperation
+			deferred.BoshContextID = "some-context-id"
+			deferred.BoshTaskIDs = []int{0}
+			Expect(store.StartOperation(instanceID, deferred)).To(Succeed())
+
+			deferred.BoshTaskIDs = []int{7}
+			Expect(store.StartOperation(instanceID, deferred)).To(Succeed())
+		})
+
+		It("replaces the record rather than adding another operation", func() {
+			instance := getInstance()
+			Expect(instance.Operations).To(HaveLen(1))
+			Expect(instance.Operations[0].BoshTaskIDs).To(Equal([]int{7}))
+		})
+	})
+
+	Context("when an operation is started for an instance the store has not seen", func() {
+		BeforeEach(func() {
+			Expect(store.StartOperation(instanceID, operationstore.Operation{
+				Type:        "upgrade",
+				PlanID:      "some-plan",
+				BoshTaskIDs: []int{5},
+			})).To(Succeed())
+		})
+
+		It("records the instance as active", func() {
+			instance := getInstance()
+			Expect(instance.State).To(Equal(operationstore.InstanceActive))
+			Expect(instance.PlanID).To(Equal("some-plan"))
+		})
+	})
+
+	It("returns an error when updating an unknown instance", func() {
+		err := store.UpdateOperation("unknown", 1, operationstore.OperationStatus{State: operationstore.OperationSucceeded})
+		Expect(err).To(MatchError("instance unknown not found"))
+	})
+
+	It("returns an error when updating an unknown operation", func() {
+		Expect(store.StartOperation(instanceID, createOperation)).To(Succeed())
+		err := store.UpdateOperation(instanceID, 99, operationstore.OperationStatus{State: operationstore.OperationSucceeded})
+		Expect(err).To(MatchError("operation with BOSH task 99 not found for instance some-instance-id"))
+	})
+
+	Context("when the store file cannot be written", func() {
+		BeforeEach(func() {
+			var err error
+			store, err = operationstore.NewFileStore(filepath.Join(storeDir, "missing-dir", "operations.json"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error and does not keep the change", func() {
+			Expect(store.StartOperation(instanceID, createOperation)).To(MatchError(ContainSubstring("writing operation store")))
+
+			_, found, err := store.GetInstance(instanceID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Context("when the store file holds a deleted instance", func() {
+		It("drops it when the store is opened", func() {
+			Expect(ioutil.WriteFile(storePath, []byte(`{
+				"deleted-instance-id": {"ID": "deleted-instance-id", "State": "deleted"},
+				"some-instance-id": {"ID": "some-instance-id", "State": "active"}
+			}`), 0600)).To(Succeed())
+
+			reopened, err := operationstore.NewFileStore(storePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, found, err := reopened.GetInstance("deleted-instance-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+			_, found, err = reopened.GetInstance(instanceID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+		})
+	})
+
+	Context("when the store file is not valid JSON", func() {
+		It("returns an error", func() {
+			Expect(ioutil.WriteFile(storePath, []byte("not json"), 0600)).To(Succeed())
+			_, err := operationstore.NewFileStore(storePath)
+			Expect(err).To(MatchError(ContainSubstring("parsing operation store")))
+		})
+	})
+
+	Context("when the store is encrypted", func() {
+		const encryptionKey = "some-encryption-key"
+
+		BeforeEach(func() {
+			var err error
+			store, err = operationstore.NewEncryptedFileStore(storePath, encryptionKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			deferred := createOperation
+			deferred.BoshContextID = "some-context-id"
+			deferred.RequestParams = map[string]interface{}{"parameters": map[string]interface{}{"password": "secret"}}
+			Expect(store.StartOperation(instanceID, deferred)).To(Succeed())
+		})
+
+		It("does not write requests in plain text", func() {
+			contents, err := ioutil.ReadFile(storePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).NotTo(ContainSubstring("secret"))
+			Expect(string(contents)).NotTo(ContainSubstring(instanceID))
+		})
+
+		It("reads the instances back with the same key", func() {
+			reopened, err := operationstore.NewEncryptedFileStore(storePath, encryptionKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			instance, found, err := reopened.GetInstance(instanceID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(instance.Operations[0].RequestParams).To(HaveKey("parameters"))
+		})
+
+		It("fails to open with another key", func() {
+			_, err := operationstore.NewEncryptedFileStore(storePath, "another-key")
+			Expect(err).To(MatchError(ContainSubstring("decrypting operation store")))
+		})
+
+		It("needs a key", func() {
+			_, err := operationstore.NewEncryptedFileStore(storePath, "")
+			Expect(err).To(MatchError("operation store encryption key can't be empty"))
+		})
+	})
+
+	Context("when no path is configured", func() {
+		It("keeps instances in memory", func() {
+			memoryStore, err := operationstore.NewFileStore("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(memoryStore.StartOperation(instanceID, createOperation)).To(Succeed())
+
+			_, found, err := memoryStore.GetInstance(instanceID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+		})
+	})
+})
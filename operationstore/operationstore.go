@@ -0,0 +1,95 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package operationstore
+
+import "time"
+
+type OperationState string
+
+const (
+	OperationInProgress = OperationState("in progress")
+	OperationSucceeded  = OperationState("succeeded")
+	OperationFailed     = OperationState("failed")
+)
+
+type InstanceState string
+
+const (
+	InstanceCreating = InstanceState("creating")
+	InstanceActive   = InstanceState("active")
+	InstanceFailed   = InstanceState("failed")
+	InstanceDeleted  = InstanceState("deleted")
+)
+
+const (
+	operationTypeCreate = "create"
+	operationTypeUpdate = "update"
+	operationTypeDelete = "delete"
+)
+
+type Instance struct {
+	ID         string
+	PlanID     string
+	Parameters map[string]interface{} `json:",omitempty"`
+	State      InstanceState
+	Operations []Operation
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// LastOperation returns the most recently started operation for the instance.
+func (i Instance) LastOperation() (Operation, bool) {
+	if len(i.Operations) == 0 {
+		return Operation{}, false
+	}
+	return i.Operations[len(i.Operations)-1], true
+}
+
+type Operation struct {
+	Type          string
+	PlanID        string                 `json:",omitempty"`
+	Parameters    map[string]interface{} `json:",omitempty"`
+	BoshTaskIDs   []int
+	BoshContextID string `json:",omitempty"`
+	Forced        bool   `json:",omitempty"`
+	Cancelled     bool   `json:",omitempty"`
+	State         OperationState
+	Description   string `json:",omitempty"`
+	StartedAt     time.Time
+	FinishedAt    *time.Time `json:",omitempty"`
+
+	// RequestParams are the full request of an operation whose deploy starts
+	// after it was accepted. They are dropped once the operation finishes.
+	RequestParams map[string]interface{} `json:",omitempty"`
+
+	// RequestID identifies the request that started the operation.
+	RequestID string `json:",omitempty"`
+}
+
+func (o Operation) restartedBy(next Operation) bool {
+	return o.BoshContextID != "" && o.BoshContextID == next.BoshContextID && o.State == OperationInProgress
+}
+
+func (o Operation) hasBoshTask(taskID int) bool {
+	for _, id := range o.BoshTaskIDs {
+		if id == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// OperationStatus is the latest known status of an operation. BoshTaskID is
+// the task currently reported on, which differs from the task that started
+// the operation once a lifecycle errand runs. Cancelled marks an operation
+// the broker cancelled; it is never cleared.
+type OperationStatus struct {
+	BoshTaskID  int
+	State       OperationState
+	Description string
+	Cancelled   bool
+}
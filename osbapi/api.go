@@ -0,0 +1,260 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package osbapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/loggerfactory"
+)
+
+type api struct {
+	broker        Broker
+	adminUserIDs  []string
+	loggerFactory *loggerfactory.LoggerFactory
+	lagerLogger   lager.Logger
+}
+
+//go:generate counterfeiter -o fakes/fake_broker.go . Broker
+type Broker interface {
+	GetInstance(ctx context.Context, instanceID string) (broker.InstanceDetails, error)
+	AsyncBind(ctx context.Context, instanceID, bindingID string, details brokerapi.BindDetails) (broker.AsyncBindingSpec, error)
+	AsyncUnbind(ctx context.Context, instanceID, bindingID string, details brokerapi.UnbindDetails) (broker.AsyncUnbindingSpec, error)
+	LastBindingOperation(ctx context.Context, instanceID, bindingID, operationData string) (brokerapi.LastOperation, error)
+	GetBinding(ctx context.Context, instanceID, bindingID string) (brokerapi.Binding, error)
+	ForceDeprovision(ctx context.Context, instanceID string, details brokerapi.DeprovisionDetails, asyncAllowed bool, requestedBy string) (brokerapi.DeprovisionServiceSpec, error)
+}
+
+const OriginatingIdentityHeader = "X-Broker-API-Originating-Identity"
+
+var ErrForceDeprovisionForbidden = errors.New("Only admin users can force the deletion of a service instance")
+
+type AsyncOperationResponse struct {
+	OperationData string `json:"operation,omitempty"`
+}
+
+// AttachRoutes adds the Open Service Broker API endpoints that brokerapi does
+// not serve. It must be called before brokerapi.AttachRoutes. Bindings can
+// only be fetched when they are kept in a store that survives restarts.
+func AttachRoutes(r *mux.Router, b Broker, adminUserIDs []string, fetchBindings bool, loggerFactory *loggerfactory.LoggerFactory) {
+	a := &api{
+		broker:        b,
+		adminUserIDs:  adminUserIDs,
+		loggerFactory: loggerFactory,
+		lagerLogger:   lager.NewLogger("on-demand-service-broker"),
+	}
+	r.HandleFunc("/v2/service_instances/{instance_id}", a.getInstance).Methods("GET")
+
+	// only forced deprovisions are served here, the rest are left to brokerapi
+	r.HandleFunc("/v2/service_instances/{instance_id}", a.forceDeprovision).Methods("DELETE").Queries("force", "true")
+
+	// synchronous binding requests are left to brokerapi
+	bindingPath := "/v2/service_instances/{instance_id}/service_bindings/{binding_id}"
+	r.HandleFunc(bindingPath, a.bind).Methods("PUT").Queries("accepts_incomplete", "true")
+	r.HandleFunc(bindingPath, a.unbind).Methods("DELETE").Queries("accepts_incomplete", "true")
+	r.HandleFunc(bindingPath+"/last_operation", a.lastBindingOperation).Methods("GET")
+	if fetchBindings {
+		r.HandleFunc(bindingPath, a.getBinding).Methods("GET")
+	}
+}
+
+func (a *api) getInstance(w http.ResponseWriter, r *http.Request) {
+	instanceID := mux.Vars(r)["instance_id"]
+	logger := a.loggerFactory.NewWithRequestID()
+
+	instance, err := a.broker.GetInstance(r.Context(), instanceID)
+	if err != nil {
+		a.writeError(w, err, map[error]int{
+			brokerapi.ErrInstanceDoesNotExist: http.StatusNotFound,
+		}, logger)
+		return
+	}
+
+	a.writeJson(w, http.StatusOK, instance, logger)
+}
+
+func (a *api) forceDeprovision(w http.ResponseWriter, r *http.Request) {
+	instanceID := mux.Vars(r)["instance_id"]
+	logger := a.loggerFactory.NewWithRequestID()
+
+	userID := originatingUserID(r)
+	if !a.isAdmin(userID) {
+		logger.Printf("user %q is not allowed to force the deletion of instance %s\n", userID, instanceID)
+		a.writeJson(w, http.StatusForbidden, brokerapi.ErrorResponse{Description: ErrForceDeprovisionForbidden.Error()}, logger)
+		return
+	}
+
+	details := brokerapi.DeprovisionDetails{
+		PlanID:    r.FormValue("plan_id"),
+		ServiceID: r.FormValue("service_id"),
+	}
+
+	spec, err := a.broker.ForceDeprovision(r.Context(), instanceID, details, r.FormValue("accepts_incomplete") == "true", userID)
+	if err != nil {
+		a.writeError(w, err, map[error]int{
+			brokerapi.ErrInstanceDoesNotExist: http.StatusGone,
+			brokerapi.ErrAsyncRequired:        http.StatusUnprocessableEntity,
+		}, logger)
+		return
+	}
+
+	a.writeJson(w, http.StatusAccepted, AsyncOperationResponse{OperationData: spec.OperationData}, logger)
+}
+
+func (a *api) isAdmin(userID string) bool {
+	if userID == "" {
+		return false
+	}
+
+	for _, adminUserID := range a.adminUserIDs {
+		if adminUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// originatingUserID reads the user from a Cloud Foundry originating identity,
+// which is the platform followed by base64 encoded JSON.
+func originatingUserID(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get(OriginatingIdentityHeader), " ", 2)
+	if len(parts) != 2 || parts[0] != "cloudfoundry" {
+		return ""
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var identity struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(decoded, &identity); err != nil {
+		return ""
+	}
+	return identity.UserID
+}
+
+func (a *api) bind(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	logger := a.loggerFactory.NewWithRequestID()
+
+	var details brokerapi.BindDetails
+	if err := json.NewDecoder(r.Body).Decode(&details); err != nil {
+		a.writeJson(w, http.StatusUnprocessableEntity, brokerapi.ErrorResponse{Description: err.Error()}, logger)
+		return
+	}
+
+	spec, err := a.broker.AsyncBind(r.Context(), vars["instance_id"], vars["binding_id"], details)
+	if err != nil {
+		a.writeError(w, err, map[error]int{
+			brokerapi.ErrInstanceDoesNotExist: http.StatusNotFound,
+			brokerapi.ErrBindingAlreadyExists: http.StatusConflict,
+			brokerapi.ErrAppGuidNotProvided:   http.StatusUnprocessableEntity,
+			brokerapi.ErrRawParamsInvalid:     http.StatusUnprocessableEntity,
+		}, logger)
+		return
+	}
+
+	if spec.IsAsync {
+		a.writeJson(w, http.StatusAccepted, AsyncOperationResponse{OperationData: spec.OperationData}, logger)
+		return
+	}
+
+	a.writeJson(w, http.StatusCreated, spec.Binding, logger)
+}
+
+func (a *api) unbind(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	logger := a.loggerFactory.NewWithRequestID()
+
+	details := brokerapi.UnbindDetails{
+		PlanID:    r.FormValue("plan_id"),
+		ServiceID: r.FormValue("service_id"),
+	}
+
+	spec, err := a.broker.AsyncUnbind(r.Context(), vars["instance_id"], vars["binding_id"], details)
+	if err != nil {
+		a.writeError(w, err, map[error]int{
+			brokerapi.ErrInstanceDoesNotExist: http.StatusGone,
+			brokerapi.ErrBindingDoesNotExist:  http.StatusGone,
+		}, logger)
+		return
+	}
+
+	if spec.IsAsync {
+		a.writeJson(w, http.StatusAccepted, AsyncOperationResponse{OperationData: spec.OperationData}, logger)
+		return
+	}
+
+	a.writeJson(w, http.StatusOK, brokerapi.EmptyResponse{}, logger)
+}
+
+func (a *api) lastBindingOperation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	logger := a.loggerFactory.NewWithRequestID()
+
+	lastOperation, err := a.broker.LastBindingOperation(r.Context(), vars["instance_id"], vars["binding_id"], r.FormValue("operation"))
+	if err != nil {
+		a.writeError(w, err, map[error]int{}, logger)
+		return
+	}
+
+	a.writeJson(w, http.StatusOK, brokerapi.LastOperationResponse{
+		State:       lastOperation.State,
+		Description: lastOperation.Description,
+	}, logger)
+}
+
+func (a *api) getBinding(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	logger := a.loggerFactory.NewWithRequestID()
+
+	binding, err := a.broker.GetBinding(r.Context(), vars["instance_id"], vars["binding_id"])
+	if err != nil {
+		a.writeError(w, err, map[error]int{
+			brokerapi.ErrBindingDoesNotExist: http.StatusNotFound,
+		}, logger)
+		return
+	}
+
+	a.writeJson(w, http.StatusOK, binding, logger)
+}
+
+func (a *api) writeError(w http.ResponseWriter, err error, statusCodes map[error]int, logger *log.Logger) {
+	if failure, ok := err.(*brokerapi.FailureResponse); ok {
+		logger.Printf("%s: %s\n", failure.LoggerAction(), failure)
+		a.writeJson(w, failure.ValidatedStatusCode(a.lagerLogger), failure.ErrorResponse(), logger)
+		return
+	}
+
+	status, found := statusCodes[err]
+	if !found {
+		status = http.StatusInternalServerError
+	}
+
+	a.writeJson(w, status, brokerapi.ErrorResponse{Description: err.Error()}, logger)
+}
+
+func (a *api) writeJson(w http.ResponseWriter, status int, obj interface{}, logger *log.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		logger.Printf("error occurred encoding json: %s", err)
+	}
+}
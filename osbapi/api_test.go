@@ -0,0 +1,471 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package osbapi_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/on-demand-service-broker/broker"
+	"github.com/pivotal-cf/on-demand-service-broker/loggerfactory"
+	"github.com/pivotal-cf/on-demand-service-broker/osbapi"
+	"github.com/pivotal-cf/on-demand-service-broker/osbapi/fakes"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+)
+
+var _ = Describe("OSB API", func() {
+	var (
+		server        *httptest.Server
+		fakeBroker    *fakes.FakeBroker
+		logs          *gbytes.Buffer
+		loggerFactory *loggerfactory.LoggerFactory
+		fetchBindings bool
+	)
+
+	BeforeEach(func() {
+		logs = gbytes.NewBuffer()
+		loggerFactory = loggerfactory.New(io.MultiWriter(GinkgoWriter, logs), "osbapi-unit-tests", log.LstdFlags)
+		fakeBroker = new(fakes.FakeBroker)
+		fetchBindings = true
+	})
+
+	JustBeforeEach(func() {
+		router := mux.NewRouter()
+		osbapi.AttachRoutes(router, fakeBroker, []string{"some-admin-user-id"}, fetchBindings, loggerFactory)
+		server = httptest.NewServer(router)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("fetching a service instance", func() {
+		var getResp *http.Response
+
+		JustBeforeEach(func() {
+			var err error
+			getResp, err = http.Get(fmt.Sprintf("%s/v2/service_instances/some-instance-id", server.URL))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the instance exists", func() {
+			BeforeEach(func() {
+				fakeBroker.GetInstanceReturns(broker.InstanceDetails{
+					ServiceID:    "some-service-id",
+					PlanID:       "some-plan-id",
+					DashboardURL: "https://dashboard.example.com",
+					Parameters:   map[string]interface{}{"foo": "bar"},
+				}, nil)
+			})
+
+			It("returns HTTP 200", func() {
+				Expect(getResp.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("fetches the requested instance", func() {
+				Expect(fakeBroker.GetInstanceCallCount()).To(Equal(1))
+				_, instanceID := fakeBroker.GetInstanceArgsForCall(0)
+				Expect(instanceID).To(Equal("some-instance-id"))
+			})
+
+			It("returns the instance", func() {
+				var body map[string]interface{}
+				Expect(json.NewDecoder(getResp.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]interface{}{
+					"service_id":    "some-service-id",
+					"plan_id":       "some-plan-id",
+					"dashboard_url": "https://dashboard.example.com",
+					"parameters":    map[string]interface{}{"foo": "bar"},
+				}))
+			})
+		})
+
+		Context("when the instance does not exist", func() {
+			BeforeEach(func() {
+				fakeBroker.GetInstanceReturns(broker.InstanceDetails{}, brokerapi.ErrInstanceDoesNotExist)
+			})
+
+			It("returns HTTP 404", func() {
+				Expect(getResp.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when fetching the instance fails", func() {
+			BeforeEach(func() {
+				fakeBroker.GetInstanceReturns(broker.InstanceDetails{}, errors.New("something went wrong"))
+			})
+
+			It("returns HTTP 500", func() {
+				Expect(getResp.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+
+			It("returns the error description", func() {
+				var body brokerapi.ErrorResponse
+				Expect(json.NewDecoder(getResp.Body).Decode(&body)).To(Succeed())
+				Expect(body.Description).To(Equal("something went wrong"))
+			})
+		})
+	})
+
+	Describe("binding asynchronously", func() {
+		var (
+			bindResp *http.Response
+			bindBody string
+		)
+
+		BeforeEach(func() {
+			bindBody = `{"service_id":"some-service-id","plan_id":"some-plan-id","parameters":{"foo":"bar"}}`
+		})
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(
+				"PUT",
+				fmt.Sprintf("%s/v2/service_instances/some-instance-id/service_bindings/some-binding-id?accepts_incomplete=true", server.URL),
+				strings.NewReader(bindBody),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			bindResp, err = http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the broker binds asynchronously", func() {
+			BeforeEach(func() {
+				fakeBroker.AsyncBindReturns(broker.AsyncBindingSpec{IsAsync: true, OperationData: "some-operation"}, nil)
+			})
+
+			It("returns HTTP 202 with the operation", func() {
+				Expect(bindResp.StatusCode).To(Equal(http.StatusAccepted))
+				var body map[string]interface{}
+				Expect(json.NewDecoder(bindResp.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]interface{}{"operation": "some-operation"}))
+			})
+
+			It("passes the request details to the broker", func() {
+				Expect(fakeBroker.AsyncBindCallCount()).To(Equal(1))
+				_, instanceID, bindingID, details := fakeBroker.AsyncBindArgsForCall(0)
+				Expect(instanceID).To(Equal("some-instance-id"))
+				Expect(bindingID).To(Equal("some-binding-id"))
+				Expect(details.PlanID).To(Equal("some-plan-id"))
+				Expect(details.ServiceID).To(Equal("some-service-id"))
+				Expect(string(details.RawParameters)).To(MatchJSON(`{"foo":"bar"}`))
+			})
+		})
+
+		Context("when the broker binds synchronously", func() {
+			BeforeEach(func() {
+				fakeBroker.AsyncBindReturns(broker.AsyncBindingSpec{
+					Binding: brokerapi.Binding{Credentials: map[string]interface{}{"user": "some-user"}},
+				}, nil)
+			})
+
+			It("returns HTTP 201 with the binding", func() {
+				Expect(bindResp.StatusCode).To(Equal(http.StatusCreated))
+				var body map[string]interface{}
+				Expect(json.NewDecoder(bindResp.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(HaveKeyWithValue("credentials", map[string]interface{}{"user": "some-user"}))
+			})
+		})
+
+		Context("when the binding already exists", func() {
+			BeforeEach(func() {
+				fakeBroker.AsyncBindReturns(broker.AsyncBindingSpec{}, brokerapi.ErrBindingAlreadyExists)
+			})
+
+			It("returns HTTP 409", func() {
+				Expect(bindResp.StatusCode).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("when the parameters do not match the plan's schema", func() {
+			BeforeEach(func() {
+				fakeBroker.AsyncBindReturns(broker.AsyncBindingSpec{}, brokerapi.NewFailureResponse(
+					errors.New("parameters.foo: Invalid type. Expected: integer, given: string"),
+					http.StatusBadRequest,
+					broker.InvalidParametersLoggerAction,
+				))
+			})
+
+			It("returns HTTP 400 with the violations", func() {
+				Expect(bindResp.StatusCode).To(Equal(http.StatusBadRequest))
+				var body brokerapi.ErrorResponse
+				Expect(json.NewDecoder(bindResp.Body).Decode(&body)).To(Succeed())
+				Expect(body.Description).To(Equal("parameters.foo: Invalid type. Expected: integer, given: string"))
+			})
+
+			It("logs the failure", func() {
+				Eventually(logs).Should(gbytes.Say("invalid-parameters: parameters.foo"))
+			})
+		})
+
+		Context("when the service adapter reports a structured error", func() {
+			BeforeEach(func() {
+				adapterErr := serviceadapter.ErrorForExitCode(
+					1,
+					`{"user_message":"role must be read or write","operator_message":"unknown role 'admin'","error_code":"bad_parameter"}`,
+				).(serviceadapter.UnknownFailureError)
+				fakeBroker.AsyncBindReturns(broker.AsyncBindingSpec{}, broker.NewAdapterError(context.Background(), adapterErr).ErrorForCFUser())
+			})
+
+			It("returns the status the adapter asked for with its user message", func() {
+				Expect(bindResp.StatusCode).To(Equal(http.StatusBadRequest))
+				var body brokerapi.ErrorResponse
+				Expect(json.NewDecoder(bindResp.Body).Decode(&body)).To(Succeed())
+				Expect(body.Description).To(Equal("role must be read or write"))
+			})
+		})
+
+		Context("when the request body is not valid JSON", func() {
+			BeforeEach(func() {
+				bindBody = "not-json"
+			})
+
+			It("returns HTTP 422 without calling the broker", func() {
+				Expect(bindResp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+				Expect(fakeBroker.AsyncBindCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("force deprovisioning", func() {
+		var (
+			identity        string
+			deprovisionResp *http.Response
+		)
+
+		cloudFoundryIdentity := func(userID string) string {
+			return "cloudfoundry " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"user_id":%q}`, userID)))
+		}
+
+		BeforeEach(func() {
+			identity = cloudFoundryIdentity("some-admin-user-id")
+			fakeBroker.ForceDeprovisionReturns(brokerapi.DeprovisionServiceSpec{IsAsync: true, OperationData: "some-operation"}, nil)
+		})
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(
+				"DELETE",
+				fmt.Sprintf("%s/v2/service_instances/some-instance-id?accepts_incomplete=true&force=true&plan_id=some-plan-id&service_id=some-service-id", server.URL),
+				nil,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			if identity != "" {
+				req.Header.Set(osbapi.OriginatingIdentityHeader, identity)
+			}
+			deprovisionResp, err = http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when an admin forces the deprovision", func() {
+			It("returns HTTP 202 with the operation", func() {
+				Expect(deprovisionResp.StatusCode).To(Equal(http.StatusAccepted))
+				var body map[string]interface{}
+				Expect(json.NewDecoder(deprovisionResp.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]interface{}{"operation": "some-operation"}))
+			})
+
+			It("passes the request and the user to the broker", func() {
+				Expect(fakeBroker.ForceDeprovisionCallCount()).To(Equal(1))
+				_, instanceID, details, asyncAllowed, requestedBy := fakeBroker.ForceDeprovisionArgsForCall(0)
+				Expect(instanceID).To(Equal("some-instance-id"))
+				Expect(details).To(Equal(brokerapi.DeprovisionDetails{PlanID: "some-plan-id", ServiceID: "some-service-id"}))
+				Expect(asyncAllowed).To(BeTrue())
+				Expect(requestedBy).To(Equal("some-admin-user-id"))
+			})
+		})
+
+		Context("when the instance does not exist", func() {
+			BeforeEach(func() {
+				fakeBroker.ForceDeprovisionReturns(brokerapi.DeprovisionServiceSpec{}, brokerapi.ErrInstanceDoesNotExist)
+			})
+
+			It("returns HTTP 410", func() {
+				Expect(deprovisionResp.StatusCode).To(Equal(http.StatusGone))
+			})
+		})
+
+		Context("when the user is not an admin", func() {
+			BeforeEach(func() {
+				identity = cloudFoundryIdentity("some-other-user-id")
+			})
+
+			It("returns HTTP 403 without deprovisioning", func() {
+				Expect(deprovisionResp.StatusCode).To(Equal(http.StatusForbidden))
+				Expect(fakeBroker.ForceDeprovisionCallCount()).To(Equal(0))
+				Eventually(logs).Should(gbytes.Say(`user "some-other-user-id" is not allowed to force the deletion of instance some-instance-id`))
+			})
+		})
+
+		Context("when the request does not identify the user", func() {
+			BeforeEach(func() {
+				identity = ""
+			})
+
+			It("returns HTTP 403", func() {
+				Expect(deprovisionResp.StatusCode).To(Equal(http.StatusForbidden))
+				Expect(fakeBroker.ForceDeprovisionCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the identity cannot be decoded", func() {
+			BeforeEach(func() {
+				identity = "cloudfoundry not-base64"
+			})
+
+			It("returns HTTP 403", func() {
+				Expect(deprovisionResp.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("unbinding asynchronously", func() {
+		var unbindResp *http.Response
+
+		JustBeforeEach(func() {
+			req, err := http.NewRequest(
+				"DELETE",
+				fmt.Sprintf("%s/v2/service_instances/some-instance-id/service_bindings/some-binding-id?accepts_incomplete=true&plan_id=some-plan-id&service_id=some-service-id", server.URL),
+				nil,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			unbindResp, err = http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the broker unbinds asynchronously", func() {
+			BeforeEach(func() {
+				fakeBroker.AsyncUnbindReturns(broker.AsyncUnbindingSpec{IsAsync: true, OperationData: "some-operation"}, nil)
+			})
+
+			It("returns HTTP 202 with the operation", func() {
+				Expect(unbindResp.StatusCode).To(Equal(http.StatusAccepted))
+				var body map[string]interface{}
+				Expect(json.NewDecoder(unbindResp.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]interface{}{"operation": "some-operation"}))
+			})
+
+			It("passes the plan and service to the broker", func() {
+				_, instanceID, bindingID, details := fakeBroker.AsyncUnbindArgsForCall(0)
+				Expect(instanceID).To(Equal("some-instance-id"))
+				Expect(bindingID).To(Equal("some-binding-id"))
+				Expect(details).To(Equal(brokerapi.UnbindDetails{PlanID: "some-plan-id", ServiceID: "some-service-id"}))
+			})
+		})
+
+		Context("when the broker unbinds synchronously", func() {
+			It("returns HTTP 200", func() {
+				Expect(unbindResp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the binding does not exist", func() {
+			BeforeEach(func() {
+				fakeBroker.AsyncUnbindReturns(broker.AsyncUnbindingSpec{}, brokerapi.ErrBindingDoesNotExist)
+			})
+
+			It("returns HTTP 410", func() {
+				Expect(unbindResp.StatusCode).To(Equal(http.StatusGone))
+			})
+		})
+	})
+
+	Describe("polling a binding operation", func() {
+		var pollResp *http.Response
+
+		JustBeforeEach(func() {
+			var err error
+			pollResp, err = http.Get(fmt.Sprintf("%s/v2/service_instances/some-instance-id/service_bindings/some-binding-id/last_operation?operation=some-operation", server.URL))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the broker reports the operation", func() {
+			BeforeEach(func() {
+				fakeBroker.LastBindingOperationReturns(brokerapi.LastOperation{State: brokerapi.InProgress, Description: "Binding in progress"}, nil)
+			})
+
+			It("returns HTTP 200 with the state", func() {
+				Expect(pollResp.StatusCode).To(Equal(http.StatusOK))
+				var body map[string]interface{}
+				Expect(json.NewDecoder(pollResp.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]interface{}{"state": "in progress", "description": "Binding in progress"}))
+			})
+
+			It("passes the operation to the broker", func() {
+				_, instanceID, bindingID, operationData := fakeBroker.LastBindingOperationArgsForCall(0)
+				Expect(instanceID).To(Equal("some-instance-id"))
+				Expect(bindingID).To(Equal("some-binding-id"))
+				Expect(operationData).To(Equal("some-operation"))
+			})
+		})
+
+		Context("when the broker fails to report the operation", func() {
+			BeforeEach(func() {
+				fakeBroker.LastBindingOperationReturns(brokerapi.LastOperation{}, errors.New("bosh is down"))
+			})
+
+			It("returns HTTP 500", func() {
+				Expect(pollResp.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	Describe("fetching a binding", func() {
+		var getResp *http.Response
+
+		JustBeforeEach(func() {
+			var err error
+			getResp, err = http.Get(fmt.Sprintf("%s/v2/service_instances/some-instance-id/service_bindings/some-binding-id", server.URL))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the binding exists", func() {
+			BeforeEach(func() {
+				fakeBroker.GetBindingReturns(brokerapi.Binding{Credentials: map[string]interface{}{"user": "some-user"}}, nil)
+			})
+
+			It("returns HTTP 200 with the binding", func() {
+				Expect(getResp.StatusCode).To(Equal(http.StatusOK))
+				var body map[string]interface{}
+				Expect(json.NewDecoder(getResp.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(HaveKeyWithValue("credentials", map[string]interface{}{"user": "some-user"}))
+			})
+		})
+
+		Context("when the binding does not exist", func() {
+			BeforeEach(func() {
+				fakeBroker.GetBindingReturns(brokerapi.Binding{}, brokerapi.ErrBindingDoesNotExist)
+			})
+
+			It("returns HTTP 404", func() {
+				Expect(getResp.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when bindings are not kept in a persistent store", func() {
+			BeforeEach(func() {
+				fetchBindings = false
+			})
+
+			It("does not serve the binding", func() {
+				Expect(getResp.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(fakeBroker.GetBindingCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
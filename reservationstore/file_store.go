@@ -0,0 +1,121 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package reservationstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pivotal-cf/on-demand-service-broker/atomicfile"
+)
+
+// FileStore keeps reservations in memory and writes them to a JSON file after
+// every change, so that they outlive a broker restart. With an empty path
+// nothing is persisted.
+type FileStore struct {
+	path         string
+	file         *atomicfile.File
+	mutex        sync.Mutex
+	reservations map[string]Reservation
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, reservations: map[string]Reservation{}}
+	if path == "" {
+		return s, nil
+	}
+	s.file = atomicfile.New(path, nil)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading reservation store %s: %s", path, err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.reservations); err != nil {
+			return nil, fmt.Errorf("parsing reservation store %s: %s", path, err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Reserve(reservation Reservation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, existed := s.reservations[reservation.InstanceID]
+	s.reservations[reservation.InstanceID] = reservation
+
+	if err := s.persist(); err != nil {
+		if existed {
+			s.reservations[reservation.InstanceID] = previous
+		} else {
+			delete(s.reservations, reservation.InstanceID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) Release(instanceID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, existed := s.reservations[instanceID]
+	if !existed {
+		return nil
+	}
+	delete(s.reservations, instanceID)
+
+	if err := s.persist(); err != nil {
+		s.reservations[instanceID] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Reservations returns every reservation, oldest first.
+func (s *FileStore) Reservations() ([]Reservation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reservations := []Reservation{}
+	for _, reservation := range s.reservations {
+		reservations = append(reservations, reservation)
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].ReservedAt.Before(reservations[j].ReservedAt)
+	})
+
+	return reservations, nil
+}
+
+func (s *FileStore) persist() error {
+	if s.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(s.reservations)
+	if err != nil {
+		return err
+	}
+
+	if err := s.file.Write(data); err != nil {
+		return fmt.Errorf("writing reservation store %s: %s", s.path, err)
+	}
+	return nil
+}
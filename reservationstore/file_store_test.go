@@ -0,0 +1,122 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package reservationstore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/reservationstore"
+)
+
+var _ = Describe("FileStore", func() {
+	var (
+		storeDir  string
+		storePath string
+		store     *reservationstore.FileStore
+
+		now   = time.Now().UTC().Truncate(time.Second)
+		older = reservationstore.Reservation{
+			InstanceID:       "older-instance",
+			ServiceID:        "some-service",
+			PlanID:           "some-plan",
+			OrganizationGUID: "some-org",
+			SpaceGUID:        "some-space",
+			ReservedAt:       now.Add(-time.Minute),
+		}
+		newer = reservationstore.Reservation{
+			InstanceID: "newer-instance",
+			ServiceID:  "some-service",
+			PlanID:     "some-plan",
+			ReservedAt: now,
+		}
+	)
+
+	BeforeEach(func() {
+		var err error
+		storeDir, err = ioutil.TempDir("", "reservation-store")
+		Expect(err).NotTo(HaveOccurred())
+		storePath = filepath.Join(storeDir, "reservations.json")
+
+		store, err = reservationstore.NewFileStore(storePath)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(storeDir)).To(Succeed())
+	})
+
+	It("starts empty", func() {
+		Expect(store.Reservations()).To(BeEmpty())
+	})
+
+	It("returns reservations oldest first", func() {
+		Expect(store.Reserve(newer)).To(Succeed())
+		Expect(store.Reserve(older)).To(Succeed())
+
+		Expect(store.Reservations()).To(Equal([]reservationstore.Reservation{older, newer}))
+	})
+
+	It("forgets released reservations", func() {
+		Expect(store.Reserve(older)).To(Succeed())
+		Expect(store.Reserve(newer)).To(Succeed())
+		Expect(store.Release(older.InstanceID)).To(Succeed())
+
+		Expect(store.Reservations()).To(Equal([]reservationstore.Reservation{newer}))
+	})
+
+	It("ignores releasing an unknown instance", func() {
+		Expect(store.Release("unknown")).To(Succeed())
+	})
+
+	It("keeps reservations across restarts", func() {
+		Expect(store.Reserve(older)).To(Succeed())
+		Expect(store.Reserve(newer)).To(Succeed())
+		Expect(store.Release(newer.InstanceID)).To(Succeed())
+
+		reopened, err := reservationstore.NewFileStore(storePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reopened.Reservations()).To(Equal([]reservationstore.Reservation{older}))
+	})
+
+	It("fails to open a corrupt store", func() {
+		Expect(ioutil.WriteFile(storePath, []byte("{not json"), 0600)).To(Succeed())
+
+		_, err := reservationstore.NewFileStore(storePath)
+		Expect(err).To(MatchError(ContainSubstring("parsing reservation store " + storePath)))
+	})
+
+	Context("when the store cannot be written", func() {
+		BeforeEach(func() {
+			var err error
+			store, err = reservationstore.NewFileStore(filepath.Join(storeDir, "missing-dir", "reservations.json"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error and does not keep the reservation", func() {
+			Expect(store.Reserve(older)).To(MatchError(ContainSubstring("writing reservation store")))
+			Expect(store.Reservations()).To(BeEmpty())
+		})
+	})
+
+	Context("without a path", func() {
+		BeforeEach(func() {
+			var err error
+			store, err = reservationstore.NewFileStore("")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("keeps reservations in memory", func() {
+			Expect(store.Reserve(older)).To(Succeed())
+			Expect(store.Reservations()).To(Equal([]reservationstore.Reservation{older}))
+		})
+	})
+})
@@ -0,0 +1,27 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package reservationstore
+
+import "time"
+
+// Reservation holds a quota slot for an instance that is being provisioned
+// or moved to another plan, but is not yet counted by Cloud Foundry.
+// PreviousPlanID is only set for plan changes, and names the plan Cloud
+// Foundry still counts the instance in.
+type Reservation struct {
+	InstanceID       string
+	ServiceID        string
+	PlanID           string
+	PreviousPlanID   string `json:",omitempty"`
+	OrganizationGUID string `json:",omitempty"`
+	SpaceGUID        string `json:",omitempty"`
+	ReservedAt       time.Time
+}
+
+func (r Reservation) IsPlanChange() bool {
+	return r.PreviousPlanID != ""
+}
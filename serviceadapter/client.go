@@ -7,8 +7,12 @@
 package serviceadapter
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
@@ -17,12 +21,114 @@ const SuccessExitCode = 0
 
 //go:generate counterfeiter -o fakes/fake_command_runner.go . CommandRunner
 type CommandRunner interface {
-	Run(arg ...string) ([]byte, []byte, *int, error)
+	Run(ctx context.Context, stdin []byte, arg ...string) ([]byte, []byte, *int, error)
+}
+
+// Timeouts limit how long each subcommand may run for. A subcommand without
+// a timeout runs for as long as the context it was called with allows.
+type Timeouts struct {
+	GenerateManifest time.Duration
+	CreateBinding    time.Duration
+	DeleteBinding    time.Duration
+	DashboardURL     time.Duration
 }
 
 type Client struct {
 	ExternalBinPath string
 	CommandRunner   CommandRunner
+	Timeouts        Timeouts
+}
+
+func (c *Client) run(ctx context.Context, timeout time.Duration, subcommand string, arg ...string) ([]byte, []byte, *int, error) {
+	return c.runWithInput(ctx, timeout, nil, subcommand, arg...)
+}
+
+// runWithInput passes stdin to the adapter, keeping secrets out of its
+// arguments where other processes could read them.
+func (c *Client) runWithInput(ctx context.Context, timeout time.Duration, stdin []byte, subcommand string, arg ...string) ([]byte, []byte, *int, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stdout, stderr, exitCode, err := c.CommandRunner.Run(ctx, stdin, append([]string{c.ExternalBinPath, subcommand}, arg...)...)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return stdout, stderr, nil, timeoutError(c.adapterName(), subcommand, timeout)
+		}
+		return stdout, stderr, nil, adapterError(c.adapterName(), stdout, stderr, err)
+	}
+
+	return stdout, stderr, exitCode, nil
+}
+
+// adapterName names the adapter in errors: the executable, or the address of
+// a long-running adapter, which has no executable.
+func (c *Client) adapterName() string {
+	if named, ok := c.CommandRunner.(fmt.Stringer); ok {
+		return named.String()
+	}
+	return c.ExternalBinPath
+}
+
+type healthChecker interface {
+	CheckHealth() error
+}
+
+// CheckHealth checks that a long-running adapter is ready for calls. There
+// is nothing to check for an adapter that is executed for every call.
+func (c *Client) CheckHealth() error {
+	if checker, ok := c.CommandRunner.(healthChecker); ok {
+		return checker.CheckHealth()
+	}
+	return nil
+}
+
+// WaitUntilHealthy checks the adapter's health until it is healthy, doubling
+// the wait between checks from initialBackoff, and returns the last error
+// once it has waited for longer than maxWait.
+func (c *Client) WaitUntilHealthy(initialBackoff, maxWait time.Duration, logger *log.Logger) error {
+	deadline := time.Now().Add(maxWait)
+	backoff := initialBackoff
+	for {
+		err := c.CheckHealth()
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		logger.Printf("waiting %s for the service adapter to become healthy: %s\n", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// MonitorHealth checks the adapter's health every interval, and logs when it
+// stops or starts being healthy. It returns once stop is closed.
+func (c *Client) MonitorHealth(interval time.Duration, stop <-chan struct{}, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := c.CheckHealth()
+		switch {
+		case err != nil && healthy:
+			logger.Printf("service adapter is unhealthy: %s\n", err)
+		case err == nil && !healthy:
+			logger.Printf("service adapter at %s is healthy again\n", c.adapterName())
+		}
+		healthy = err == nil
+	}
 }
 
 func SanitiseForJSON(properties sdk.Properties) sdk.Properties {
@@ -72,11 +178,60 @@ func ErrorForExitCode(code int, message string) error {
 		return err
 	}
 
-	return UnknownFailureError{errors.New(message)}
+	return unknownFailureError(message)
 }
 
+// An adapter that fails with any other exit code can write a structured
+// error to stdout to tell the broker which part of it is safe to show the
+// user:
+//
+//	{
+//	  "user_message": "replicas must be 3 or fewer on this plan",
+//	  "operator_message": "plan small allows 3 replicas, 5 requested",
+//	  "error_code": "bad_parameter"
+//	}
+//
+// The error code is for the operator, except for the codes below, which
+// tell the broker how to respond. Anything else written to stdout is shown
+// to the user as it is.
+const (
+	// ErrorCodeBadParameter is answered with 400 Bad Request.
+	ErrorCodeBadParameter = "bad_parameter"
+	// ErrorCodeInternal is answered with 500 Internal Server Error, as are
+	// the other codes.
+	ErrorCodeInternal = "internal"
+)
+
+type structuredFailure struct {
+	UserMessage     string `json:"user_message"`
+	OperatorMessage string `json:"operator_message"`
+	ErrorCode       string `json:"error_code"`
+}
+
+func unknownFailureError(stdout string) UnknownFailureError {
+	var failure structuredFailure
+	if err := json.Unmarshal([]byte(stdout), &failure); err != nil || failure == (structuredFailure{}) {
+		return UnknownFailureError{error: errors.New(stdout)}
+	}
+
+	return UnknownFailureError{
+		error:           errors.New(failure.UserMessage),
+		OperatorMessage: failure.OperatorMessage,
+		ErrorCode:       failure.ErrorCode,
+	}
+}
+
+// UnknownFailureError holds the message the adapter wrote for the user.
+// OperatorMessage and ErrorCode are only set when it wrote a structured
+// error.
 type UnknownFailureError struct {
 	error
+	OperatorMessage string
+	ErrorCode       string
+}
+
+func (e UnknownFailureError) Structured() bool {
+	return e.OperatorMessage != "" || e.ErrorCode != ""
 }
 
 type NotImplementedError struct {
@@ -95,12 +250,29 @@ type BindingNotFoundError struct {
 	error
 }
 
+// TimeoutError is returned when the adapter was stopped because it did not
+// finish before its timeout or the deadline of the request it was run for.
+type TimeoutError struct {
+	error
+}
+
 func NewNotImplementedError(msg string) NotImplementedError {
 	return NotImplementedError{errors.New(msg)}
 }
 
 func NewUnknownFailureError(msg string) UnknownFailureError {
-	return UnknownFailureError{errors.New(msg)}
+	return UnknownFailureError{error: errors.New(msg)}
+}
+
+func NewTimeoutError(msg string) TimeoutError {
+	return TimeoutError{errors.New(msg)}
+}
+
+func timeoutError(adapterPath, subcommand string, timeout time.Duration) error {
+	if timeout > 0 {
+		return NewTimeoutError(fmt.Sprintf("external service adapter at %s was stopped after running %s for longer than %s", adapterPath, subcommand, timeout))
+	}
+	return NewTimeoutError(fmt.Sprintf("external service adapter at %s was stopped after running %s past the request deadline", adapterPath, subcommand))
 }
 
 func invalidJSONError(adapterPath string, stdout, stderr []byte, err error) error {
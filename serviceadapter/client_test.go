@@ -7,12 +7,18 @@
 package serviceadapter_test
 
 import (
+	"context"
+	"io/ioutil"
+	"log"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/types"
 
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter/fakes"
 	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
 
@@ -69,5 +75,102 @@ var _ = Describe("Adapter Client", func() {
 			BeAssignableToTypeOf(serviceadapter.UnknownFailureError{}),
 			Equal("some other error"),
 		),
+		Entry(
+			"structured error",
+			sdk.ErrorExitCode, `{"user_message": "replicas must be 3 or fewer", "operator_message": "plan small allows 3 replicas", "error_code": "bad_parameter"}`,
+			BeAssignableToTypeOf(serviceadapter.UnknownFailureError{}),
+			Equal("replicas must be 3 or fewer"),
+		),
+		Entry(
+			"JSON that is not a structured error",
+			sdk.ErrorExitCode, `{"replicas": 5}`,
+			BeAssignableToTypeOf(serviceadapter.UnknownFailureError{}),
+			Equal(`{"replicas": 5}`),
+		),
 	)
+
+	Describe("structured errors", func() {
+		It("keeps the operator message and error code apart from the user message", func() {
+			err := serviceadapter.ErrorForExitCode(sdk.ErrorExitCode, `{"user_message": "replicas must be 3 or fewer", "operator_message": "plan small allows 3 replicas", "error_code": "bad_parameter"}`)
+
+			failure := err.(serviceadapter.UnknownFailureError)
+			Expect(failure.Structured()).To(BeTrue())
+			Expect(failure.OperatorMessage).To(Equal("plan small allows 3 replicas"))
+			Expect(failure.ErrorCode).To(Equal(serviceadapter.ErrorCodeBadParameter))
+		})
+
+		It("can leave out the user message", func() {
+			err := serviceadapter.ErrorForExitCode(sdk.ErrorExitCode, `{"operator_message": "redis-server failed to start", "error_code": "internal"}`)
+
+			Expect(err).To(MatchError(""))
+			Expect(err.(serviceadapter.UnknownFailureError).Structured()).To(BeTrue())
+		})
+
+		It("treats a plain message as unstructured", func() {
+			err := serviceadapter.ErrorForExitCode(sdk.ErrorExitCode, "something went wrong")
+
+			Expect(err.(serviceadapter.UnknownFailureError).Structured()).To(BeFalse())
+		})
+	})
+
+	Describe("timeouts", func() {
+		var (
+			cmdRunner *fakes.FakeCommandRunner
+			client    *serviceadapter.Client
+			logger    *log.Logger
+		)
+
+		BeforeEach(func() {
+			cmdRunner = new(fakes.FakeCommandRunner)
+			cmdRunner.RunStub = func(ctx context.Context, stdin []byte, arg ...string) ([]byte, []byte, *int, error) {
+				<-ctx.Done()
+				return nil, nil, nil, ctx.Err()
+			}
+			client = &serviceadapter.Client{
+				ExternalBinPath: "/thing",
+				CommandRunner:   cmdRunner,
+				Timeouts:        serviceadapter.Timeouts{CreateBinding: 10 * time.Millisecond},
+			}
+			logger = log.New(ioutil.Discard, "", 0)
+		})
+
+		It("stops a subcommand that runs for longer than its timeout", func() {
+			_, err := client.CreateBinding(context.Background(), "binding-id", nil, nil, nil, logger)
+
+			Expect(err).To(BeAssignableToTypeOf(serviceadapter.TimeoutError{}))
+			Expect(err).To(MatchError("external service adapter at /thing was stopped after running create-binding for longer than 10ms"))
+		})
+
+		It("stops a subcommand that runs past the deadline of the request", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			err := client.DeleteBinding(ctx, "binding-id", nil, nil, nil, nil, logger)
+
+			Expect(err).To(BeAssignableToTypeOf(serviceadapter.TimeoutError{}))
+			Expect(err).To(MatchError("external service adapter at /thing was stopped after running delete-binding past the request deadline"))
+		})
+
+		It("does not report a timeout when the request is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := client.GenerateDashboardUrl(ctx, "instance-id", sdk.Plan{}, nil, logger)
+
+			Expect(err).NotTo(BeAssignableToTypeOf(serviceadapter.TimeoutError{}))
+			Expect(err).To(MatchError(ContainSubstring("context canceled")))
+		})
+
+		It("sets no deadline for a subcommand without a timeout", func() {
+			cmdRunner.RunReturns([]byte(`{"dashboard_url": "https://dashboard.example.com"}`), nil, intPtr(serviceadapter.SuccessExitCode), nil)
+			cmdRunner.RunStub = nil
+
+			_, err := client.GenerateDashboardUrl(context.Background(), "instance-id", sdk.Plan{}, nil, logger)
+
+			Expect(err).NotTo(HaveOccurred())
+			ctx, _, _ := cmdRunner.RunArgsForCall(0)
+			_, hasDeadline := ctx.Deadline()
+			Expect(hasDeadline).To(BeFalse())
+		})
+	})
 })
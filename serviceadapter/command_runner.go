@@ -8,6 +8,7 @@ package serviceadapter
 
 import (
 	"bytes"
+	"context"
 	"os/exec"
 	"syscall"
 )
@@ -18,12 +19,36 @@ func NewCommandRunner() CommandRunner {
 
 type commandRunner struct{}
 
-func (c commandRunner) Run(arg ...string) ([]byte, []byte, *int, error) {
-	var stderr bytes.Buffer
+// Run starts the adapter in a process group of its own so that, when ctx is
+// done before the adapter exits, any processes the adapter started are
+// killed along with it.
+func (c commandRunner) Run(ctx context.Context, stdin []byte, arg ...string) ([]byte, []byte, *int, error) {
+	var stdout, stderr bytes.Buffer
 	cmd := exec.Command(arg[0], arg[1:]...)
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, err
+	}
 
-	stdout, err := cmd.Output()
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-exited:
+	case <-ctx.Done():
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-exited
+		return stdout.Bytes(), stderr.Bytes(), nil, ctx.Err()
+	}
 
 	var exitCode *int
 
@@ -36,7 +61,7 @@ func (c commandRunner) Run(arg ...string) ([]byte, []byte, *int, error) {
 		exitCode = intPtr(0)
 	}
 
-	return stdout, stderr.Bytes(), exitCode, err
+	return stdout.Bytes(), stderr.Bytes(), exitCode, err
 }
 
 func intPtr(val int) *int {
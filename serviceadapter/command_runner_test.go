@@ -7,8 +7,13 @@
 package serviceadapter_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -18,6 +23,8 @@ import (
 var _ = Describe("CommandRunner", func() {
 	var (
 		scriptPath string
+		ctx        context.Context
+		stdin      []byte
 
 		stdout         string
 		stderr         string
@@ -25,10 +32,15 @@ var _ = Describe("CommandRunner", func() {
 		actualExitCode *int
 	)
 
+	BeforeEach(func() {
+		ctx = context.Background()
+		stdin = nil
+	})
+
 	JustBeforeEach(func() {
 		runner := serviceadapter.NewCommandRunner()
 		var stdoutBytes, stderrBytes []byte
-		stdoutBytes, stderrBytes, actualExitCode, runErr = runner.Run(scriptPath)
+		stdoutBytes, stderrBytes, actualExitCode, runErr = runner.Run(ctx, stdin, scriptPath)
 		stdout = string(stdoutBytes)
 		stderr = string(stderrBytes)
 	})
@@ -55,6 +67,18 @@ var _ = Describe("CommandRunner", func() {
 		os.Remove(scriptPath)
 	})
 
+	Context("when input is given", func() {
+		BeforeEach(func() {
+			stdin = []byte(`{"password":"secret"}`)
+			scriptPath = createScript("cat")
+		})
+
+		It("passes it to the command on standard input", func() {
+			Expect(runErr).NotTo(HaveOccurred())
+			Expect(stdout).To(Equal(`{"password":"secret"}`))
+		})
+	})
+
 	Context("when the command runs normally", func() {
 		BeforeEach(func() {
 			scriptPath = createScript("echo output; echo error >&2")
@@ -127,4 +151,45 @@ var _ = Describe("CommandRunner", func() {
 			Expect(*actualExitCode).To(Equal(23))
 		})
 	})
+
+	Context("when the context is done before the command exits", func() {
+		var (
+			pidFile string
+			cancel  context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			tempFile, err := ioutil.TempFile("", "pid")
+			Expect(err).NotTo(HaveOccurred())
+			tempFile.Close()
+			pidFile = tempFile.Name()
+
+			scriptPath = createScript("sleep 60 & echo $! > " + pidFile + "; echo started; wait")
+			ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+		})
+
+		AfterEach(func() {
+			cancel()
+			os.Remove(pidFile)
+		})
+
+		It("returns the context's error and no exit code", func() {
+			Expect(runErr).To(Equal(context.DeadlineExceeded))
+			Expect(actualExitCode).To(BeNil())
+			Expect(stdout).To(Equal("started\n"))
+		})
+
+		It("kills the processes the command started", func() {
+			contents, err := ioutil.ReadFile(pidFile)
+			Expect(err).NotTo(HaveOccurred())
+			pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+			Expect(err).NotTo(HaveOccurred())
+
+			// a killed process can linger as a zombie until it is reaped
+			Eventually(func() bool {
+				state, _ := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(pid)).Output()
+				return len(state) > 0 && !strings.HasPrefix(strings.TrimSpace(string(state)), "Z")
+			}).Should(BeFalse())
+		})
+	})
 })
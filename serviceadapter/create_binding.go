@@ -7,6 +7,7 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
@@ -14,7 +15,7 @@ import (
 	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
 
-func (c *Client) CreateBinding(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (sdk.Binding, error) {
+func (c *Client) CreateBinding(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) (sdk.Binding, error) {
 	var binding sdk.Binding
 
 	serialisedBoshVMs, err := json.Marshal(deploymentTopology)
@@ -27,20 +28,20 @@ func (c *Client) CreateBinding(bindingID string, deploymentTopology bosh.BoshVMs
 		return binding, err
 	}
 
-	stdout, stderr, exitCode, err := c.CommandRunner.Run(c.ExternalBinPath, "create-binding", bindingID, string(serialisedBoshVMs), string(manifest), string(serialisedRequestParams))
+	stdout, stderr, exitCode, err := c.run(ctx, c.Timeouts.CreateBinding, "create-binding", bindingID, string(serialisedBoshVMs), string(manifest), string(serialisedRequestParams))
 	if err != nil {
-		return binding, adapterError(c.ExternalBinPath, stdout, stderr, err)
+		return binding, err
 	}
 
 	if err := ErrorForExitCode(*exitCode, string(stdout)); err != nil {
-		logger.Printf(adapterFailedMessage(*exitCode, c.ExternalBinPath, stdout, stderr))
+		logger.Printf(adapterFailedMessage(*exitCode, c.adapterName(), stdout, stderr))
 		return binding, err
 	}
 
 	logger.Printf("service adapter ran create-binding successfully, stderr logs: %s", string(stderr))
 
 	if err := json.Unmarshal(stdout, &binding); err != nil {
-		return binding, invalidJSONError(c.ExternalBinPath, stdout, stderr, err)
+		return binding, invalidJSONError(c.adapterName(), stdout, stderr, err)
 	}
 
 	return binding, nil
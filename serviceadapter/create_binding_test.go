@@ -7,6 +7,7 @@
 package serviceadapter_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -63,7 +64,7 @@ var _ = Describe("external service adapter", func() {
 	})
 
 	JustBeforeEach(func() {
-		adapterBinding, createBindingErr = a.CreateBinding(bindingID, deploymentTopology, manifest, requestParams, logger)
+		adapterBinding, createBindingErr = a.CreateBinding(context.Background(), bindingID, deploymentTopology, manifest, requestParams, logger)
 	})
 
 	It("invokes external binding creator with serialised params", func() {
@@ -74,7 +75,7 @@ var _ = Describe("external service adapter", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(cmdRunner.RunCallCount()).To(Equal(1))
-		argsPassed := cmdRunner.RunArgsForCall(0)
+		_, _, argsPassed := cmdRunner.RunArgsForCall(0)
 		Expect(argsPassed).To(ConsistOf(externalBinPath, "create-binding", bindingID, string(serialisedVMs), string(manifest), string(serialisedRequestParams)))
 	})
 
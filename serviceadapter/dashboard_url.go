@@ -7,26 +7,27 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
 	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 )
 
-func (c *Client) GenerateDashboardUrl(instanceID string, plan sdk.Plan, manifest []byte, logger *log.Logger) (string, error) {
+func (c *Client) GenerateDashboardUrl(ctx context.Context, instanceID string, plan sdk.Plan, manifest []byte, logger *log.Logger) (string, error) {
 	plan.Properties = SanitiseForJSON(plan.Properties)
 	planJSON, err := json.Marshal(plan)
 	if err != nil {
 		return "", err
 	}
 
-	stdout, stderr, exitCode, err := c.CommandRunner.Run(c.ExternalBinPath, "dashboard-url", instanceID, string(planJSON), string(manifest))
+	stdout, stderr, exitCode, err := c.run(ctx, c.Timeouts.DashboardURL, "dashboard-url", instanceID, string(planJSON), string(manifest))
 	if err != nil {
-		return "", adapterError(c.ExternalBinPath, stdout, stderr, err)
+		return "", err
 	}
 
 	if err := ErrorForExitCode(*exitCode, string(stdout)); err != nil {
-		logger.Printf(adapterFailedMessage(*exitCode, c.ExternalBinPath, stdout, stderr))
+		logger.Printf(adapterFailedMessage(*exitCode, c.adapterName(), stdout, stderr))
 		return "", err
 	}
 
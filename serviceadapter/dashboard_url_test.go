@@ -7,6 +7,7 @@
 package serviceadapter_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -66,14 +67,14 @@ var _ = Describe("dashboard url", func() {
 	})
 
 	JustBeforeEach(func() {
-		actualDashboardUrl, actualError = a.GenerateDashboardUrl(instanceID, plan, manifest, logger)
+		actualDashboardUrl, actualError = a.GenerateDashboardUrl(context.Background(), instanceID, plan, manifest, logger)
 	})
 
 	It("invokes external dashboard url generator with serialised params", func() {
 		Expect(cmdRunner.RunCallCount()).To(Equal(1))
 		planJson, err := json.Marshal(plan)
 		Expect(err).NotTo(HaveOccurred())
-		argsPassed := cmdRunner.RunArgsForCall(0)
+		_, _, argsPassed := cmdRunner.RunArgsForCall(0)
 		Expect(argsPassed).To(ConsistOf(externalBinPath, "dashboard-url", instanceID, string(planJson), string(manifest)))
 	})
 
@@ -104,7 +105,7 @@ var _ = Describe("dashboard url", func() {
 		It("converts plan properties to be json serializable", func() {
 			Expect(actualError).NotTo(HaveOccurred())
 			Expect(cmdRunner.RunCallCount()).To(Equal(1))
-			argsPassed := cmdRunner.RunArgsForCall(0)
+			_, _, argsPassed := cmdRunner.RunArgsForCall(0)
 
 			convertedPlan := sdk.Plan{
 				Properties: sdk.Properties{
@@ -7,13 +7,14 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
 	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
 )
 
-func (c *Client) DeleteBinding(bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams map[string]interface{}, logger *log.Logger) error {
+func (c *Client) DeleteBinding(ctx context.Context, bindingID string, deploymentTopology bosh.BoshVMs, manifest []byte, requestParams, bindingCredentials map[string]interface{}, logger *log.Logger) error {
 	serialisedBoshVMs, err := json.Marshal(deploymentTopology)
 	if err != nil {
 		return err
@@ -24,13 +25,25 @@ func (c *Client) DeleteBinding(bindingID string, deploymentTopology bosh.BoshVMs
 		return err
 	}
 
-	stdout, stderr, exitCode, err := c.CommandRunner.Run(c.ExternalBinPath, "delete-binding", bindingID, string(serialisedBoshVMs), string(manifest), string(serialisedRequestParams))
+	args := []string{bindingID, string(serialisedBoshVMs), string(manifest), string(serialisedRequestParams)}
+
+	// credentials are unknown for bindings created before the broker stored
+	// them; adapters that do not expect them ignore stdin
+	var serialisedCredentials []byte
+	if bindingCredentials != nil {
+		serialisedCredentials, err = json.Marshal(bindingCredentials)
+		if err != nil {
+			return err
+		}
+	}
+
+	stdout, stderr, exitCode, err := c.runWithInput(ctx, c.Timeouts.DeleteBinding, serialisedCredentials, "delete-binding", args...)
 	if err != nil {
-		return adapterError(c.ExternalBinPath, stdout, stderr, err)
+		return err
 	}
 
 	if err := ErrorForExitCode(*exitCode, string(stdout)); err != nil {
-		logger.Printf(adapterFailedMessage(*exitCode, c.ExternalBinPath, stdout, stderr))
+		logger.Printf(adapterFailedMessage(*exitCode, c.adapterName(), stdout, stderr))
 		return err
 	}
 
@@ -7,10 +7,12 @@
 package serviceadapter_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"log"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -33,6 +35,7 @@ var _ = Describe("external service adapter", func() {
 		deploymentTopology bosh.BoshVMs
 		manifest           []byte
 		requestParams      map[string]interface{}
+		bindingCredentials map[string]interface{}
 
 		deleteBindingError error
 	)
@@ -54,10 +57,11 @@ var _ = Describe("external service adapter", func() {
 			"plan_id":    "some-plan-id",
 			"service_id": "some-service-id",
 		}
+		bindingCredentials = nil
 	})
 
 	JustBeforeEach(func() {
-		deleteBindingError = a.DeleteBinding(bindingID, deploymentTopology, manifest, requestParams, logger)
+		deleteBindingError = a.DeleteBinding(context.Background(), bindingID, deploymentTopology, manifest, requestParams, bindingCredentials, logger)
 	})
 
 	It("invokes external executable with params to delete binding", func() {
@@ -68,10 +72,24 @@ var _ = Describe("external service adapter", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(cmdRunner.RunCallCount()).To(Equal(1))
-		argsPassed := cmdRunner.RunArgsForCall(0)
+		_, _, argsPassed := cmdRunner.RunArgsForCall(0)
 		Expect(argsPassed).To(ConsistOf(externalBinPath, "delete-binding", bindingID, string(serialisedBoshVMs), string(manifest), string(serialisedRequestParams)))
 	})
 
+	Context("when the binding credentials are known", func() {
+		BeforeEach(func() {
+			bindingCredentials = map[string]interface{}{"username": "some-user"}
+		})
+
+		It("passes the credentials to the external executable on stdin", func() {
+			Expect(cmdRunner.RunCallCount()).To(Equal(1))
+			_, stdin, argsPassed := cmdRunner.RunArgsForCall(0)
+			Expect(stdin).To(MatchJSON(`{"username":"some-user"}`))
+			Expect(argsPassed).To(HaveLen(6))
+			Expect(strings.Join(argsPassed, " ")).NotTo(ContainSubstring("some-user"))
+		})
+	})
+
 	Context("when the external adapter succeeds", func() {
 		It("returns no error", func() {
 			Expect(deleteBindingError).ToNot(HaveOccurred())
@@ -2,16 +2,19 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
 )
 
 type FakeCommandRunner struct {
-	RunStub        func(arg ...string) ([]byte, []byte, *int, error)
+	RunStub        func(ctx context.Context, stdin []byte, arg ...string) ([]byte, []byte, *int, error)
 	runMutex       sync.RWMutex
 	runArgsForCall []struct {
-		arg []string
+		ctx   context.Context
+		stdin []byte
+		arg   []string
 	}
 	runReturns struct {
 		result1 []byte
@@ -29,16 +32,23 @@ type FakeCommandRunner struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCommandRunner) Run(arg ...string) ([]byte, []byte, *int, error) {
+func (fake *FakeCommandRunner) Run(ctx context.Context, stdin []byte, arg ...string) ([]byte, []byte, *int, error) {
+	var stdinCopy []byte
+	if stdin != nil {
+		stdinCopy = make([]byte, len(stdin))
+		copy(stdinCopy, stdin)
+	}
 	fake.runMutex.Lock()
 	ret, specificReturn := fake.runReturnsOnCall[len(fake.runArgsForCall)]
 	fake.runArgsForCall = append(fake.runArgsForCall, struct {
-		arg []string
-	}{arg})
-	fake.recordInvocation("Run", []interface{}{arg})
+		ctx   context.Context
+		stdin []byte
+		arg   []string
+	}{ctx, stdinCopy, arg})
+	fake.recordInvocation("Run", []interface{}{ctx, stdinCopy, arg})
 	fake.runMutex.Unlock()
 	if fake.RunStub != nil {
-		return fake.RunStub(arg...)
+		return fake.RunStub(ctx, stdin, arg...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3, ret.result4
@@ -52,10 +62,10 @@ func (fake *FakeCommandRunner) RunCallCount() int {
 	return len(fake.runArgsForCall)
 }
 
-func (fake *FakeCommandRunner) RunArgsForCall(i int) []string {
+func (fake *FakeCommandRunner) RunArgsForCall(i int) (context.Context, []byte, []string) {
 	fake.runMutex.RLock()
 	defer fake.runMutex.RUnlock()
-	return fake.runArgsForCall[i].arg
+	return fake.runArgsForCall[i].ctx, fake.runArgsForCall[i].stdin, fake.runArgsForCall[i].arg
 }
 
 func (fake *FakeCommandRunner) RunReturns(result1 []byte, result2 []byte, result3 *int, result4 error) {
@@ -7,6 +7,7 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"strings"
@@ -29,7 +30,7 @@ type manifestValidator struct {
 	deploymentName string
 }
 
-func (c *Client) GenerateManifest(serviceDeployment sdk.ServiceDeployment, plan sdk.Plan, requestParams map[string]interface{}, previousManifest []byte, previousPlan *sdk.Plan, logger *log.Logger) ([]byte, error) {
+func (c *Client) GenerateManifest(ctx context.Context, serviceDeployment sdk.ServiceDeployment, plan sdk.Plan, requestParams map[string]interface{}, previousManifest []byte, previousPlan *sdk.Plan, logger *log.Logger) ([]byte, error) {
 	serialisedServiceDeployment, err := json.Marshal(serviceDeployment)
 	if err != nil {
 		return nil, err
@@ -54,25 +55,25 @@ func (c *Client) GenerateManifest(serviceDeployment sdk.ServiceDeployment, plan
 		return nil, err
 	}
 
-	stdout, stderr, exitCode, err := c.CommandRunner.Run(
-		c.ExternalBinPath, "generate-manifest", string(serialisedServiceDeployment),
+	stdout, stderr, exitCode, err := c.run(
+		ctx, c.Timeouts.GenerateManifest, "generate-manifest", string(serialisedServiceDeployment),
 		string(serialisedPlan), string(serialisedRequestParams),
 		string(previousManifest), string(serialisedPreviousPlan),
 	)
 
 	if err != nil {
-		return nil, adapterError(c.ExternalBinPath, stdout, stderr, err)
+		return nil, err
 	}
 
 	if err := ErrorForExitCode(*exitCode, string(stdout)); err != nil {
-		logger.Printf(adapterFailedMessage(*exitCode, c.ExternalBinPath, stdout, stderr))
+		logger.Printf(adapterFailedMessage(*exitCode, c.adapterName(), stdout, stderr))
 		return nil, err
 	}
 
 	logger.Printf("service adapter ran generate-manifest successfully, stderr logs: %s", string(stderr))
 
 	validator := manifestValidator{deploymentName: serviceDeployment.DeploymentName}
-	if err := validator.validateManifest(c.ExternalBinPath, stdout, stderr); err != nil {
+	if err := validator.validateManifest(c.adapterName(), stdout, stderr); err != nil {
 		return nil, err
 	}
 
@@ -7,6 +7,7 @@
 package serviceadapter_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -87,7 +88,7 @@ var _ = Describe("external service adapter", func() {
 	})
 
 	JustBeforeEach(func() {
-		manifest, generateErr = a.GenerateManifest(serviceDeployment, plan, params, previousManifest, previousPlan, logger)
+		manifest, generateErr = a.GenerateManifest(context.Background(), serviceDeployment, plan, params, previousManifest, previousPlan, logger)
 	})
 
 	It("invokes external manifest generator with serialised parameters", func() {
@@ -105,7 +106,7 @@ var _ = Describe("external service adapter", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(cmdRunner.RunCallCount()).To(Equal(1))
-		argsPassed := cmdRunner.RunArgsForCall(0)
+		_, _, argsPassed := cmdRunner.RunArgsForCall(0)
 		Expect(argsPassed).To(ConsistOf(externalBinPath, "generate-manifest",
 			string(serialisedServiceDeployment), string(serialisedPlan),
 			string(serialisedParams), string(previousManifest), string(serialisedPreviousPlan)))
@@ -229,7 +230,7 @@ stemcells:
 		})
 
 		It("it writes 'null' to the argument list", func() {
-			argsPassed := cmdRunner.RunArgsForCall(0)
+			_, _, argsPassed := cmdRunner.RunArgsForCall(0)
 			Expect(argsPassed[6]).To(Equal("null"))
 		})
 	})
@@ -0,0 +1,122 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package serviceadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pivotal-cf/on-demand-service-broker/network"
+)
+
+const HealthPath = "/health"
+
+// HTTPCommandRunner runs the adapter's subcommands on a long-running adapter
+// process instead of executing it for every call. Each subcommand is POSTed
+// to the path of the same name with its arguments in the body:
+//
+//	POST /generate-manifest {"arguments": ["<service deployment>", ...]}
+//
+// and the adapter responds with what it would have written and the code it
+// would have exited with:
+//
+//	200 {"stdout": "...", "stderr": "...", "exit_code": 0}
+//
+// so that exit codes mean the same as for an adapter that is executed.
+type HTTPCommandRunner struct {
+	doer network.Doer
+	url  string
+}
+
+type commandRequest struct {
+	Arguments []string `json:"arguments"`
+	Stdin     string   `json:"stdin,omitempty"`
+}
+
+type commandResponse struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode *int   `json:"exit_code"`
+}
+
+func NewHTTPCommandRunner(doer network.Doer, adapterURL string) HTTPCommandRunner {
+	return HTTPCommandRunner{doer: doer, url: strings.TrimSuffix(adapterURL, "/")}
+}
+
+func (r HTTPCommandRunner) String() string {
+	return r.url
+}
+
+// Run expects the same arguments as the exec command runner. The first names
+// the adapter executable, which a long-running adapter does not have, so it
+// is not sent. The request is abandoned when ctx is done.
+func (r HTTPCommandRunner) Run(ctx context.Context, stdin []byte, arg ...string) ([]byte, []byte, *int, error) {
+	if len(arg) < 2 {
+		return nil, nil, nil, fmt.Errorf("no subcommand to send to the service adapter at %s", r.url)
+	}
+
+	body, err := json.Marshal(commandRequest{Arguments: arg[2:], Stdin: string(stdin)})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	request, err := http.NewRequest("POST", r.url+"/"+arg[1], bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := r.doer.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, nil, fmt.Errorf("service adapter responded to %s with status %d: %s", arg[1], response.StatusCode, responseBody)
+	}
+
+	var result commandResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, nil, nil, fmt.Errorf("service adapter responded to %s with invalid JSON: %s", arg[1], err)
+	}
+	if result.ExitCode == nil {
+		return []byte(result.Stdout), []byte(result.Stderr), nil, fmt.Errorf("service adapter responded to %s without an exit code", arg[1])
+	}
+
+	return []byte(result.Stdout), []byte(result.Stderr), result.ExitCode, nil
+}
+
+// CheckHealth reports whether the adapter process is up and ready for
+// subcommands.
+func (r HTTPCommandRunner) CheckHealth() error {
+	request, err := http.NewRequest("GET", r.url+HealthPath, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := r.doer.Do(request)
+	if err != nil {
+		return fmt.Errorf("service adapter at %s is not reachable: %s", r.url, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("service adapter at %s is not healthy: health check responded with status %d", r.url, response.StatusCode)
+	}
+	return nil
+}
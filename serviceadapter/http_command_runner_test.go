@@ -0,0 +1,240 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/pivotal-cf/on-demand-service-broker/serviceadapter"
+	sdk "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("HTTPCommandRunner", func() {
+	var (
+		server *ghttp.Server
+		runner serviceadapter.HTTPCommandRunner
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		runner = serviceadapter.NewHTTPCommandRunner(&http.Client{}, server.URL()+"/")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("running a subcommand", func() {
+		It("posts the arguments to the subcommand's path", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/create-binding"),
+				ghttp.VerifyJSON(`{"arguments": ["binding-id", "{}", "name: a-manifest"]}`),
+				ghttp.RespondWith(http.StatusOK, `{"stdout": "output", "stderr": "error", "exit_code": 0}`),
+			))
+
+			stdout, stderr, exitCode, err := runner.Run(context.Background(), nil, "http://adapter", "create-binding", "binding-id", "{}", "name: a-manifest")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(stdout)).To(Equal("output"))
+			Expect(string(stderr)).To(Equal("error"))
+			Expect(exitCode).To(Equal(intPtr(0)))
+		})
+
+		It("posts the input alongside the arguments", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/delete-binding"),
+				ghttp.VerifyJSON(`{"arguments": ["binding-id"], "stdin": "{\"password\":\"secret\"}"}`),
+				ghttp.RespondWith(http.StatusOK, `{"stdout": "", "stderr": "", "exit_code": 0}`),
+			))
+
+			_, _, exitCode, err := runner.Run(context.Background(), []byte(`{"password":"secret"}`), "http://adapter", "delete-binding", "binding-id")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exitCode).To(Equal(intPtr(0)))
+		})
+
+		It("returns the exit code the adapter responds with", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"stdout": "", "stderr": "", "exit_code": 10}`))
+
+			_, _, exitCode, err := runner.Run(context.Background(), nil, "http://adapter", "dashboard-url")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exitCode).To(Equal(intPtr(sdk.NotImplementedExitCode)))
+		})
+
+		It("returns an error when the adapter does not respond with OK", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, "adapter crashed"))
+
+			_, _, exitCode, err := runner.Run(context.Background(), nil, "http://adapter", "generate-manifest")
+
+			Expect(err).To(MatchError("service adapter responded to generate-manifest with status 500: adapter crashed"))
+			Expect(exitCode).To(BeNil())
+		})
+
+		It("returns an error when the response has no exit code", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"stdout": "output"}`))
+
+			_, _, _, err := runner.Run(context.Background(), nil, "http://adapter", "generate-manifest")
+
+			Expect(err).To(MatchError("service adapter responded to generate-manifest without an exit code"))
+		})
+
+		It("abandons the request when the context is done", func() {
+			unblock := make(chan struct{})
+			defer close(unblock)
+			server.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+				<-unblock
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			_, _, exitCode, err := runner.Run(ctx, nil, "http://adapter", "generate-manifest")
+
+			Expect(err).To(HaveOccurred())
+			Expect(ctx.Err()).To(Equal(context.DeadlineExceeded))
+			Expect(exitCode).To(BeNil())
+		})
+
+		It("returns an error when the adapter cannot be reached", func() {
+			server.Close()
+
+			_, _, _, err := runner.Run(context.Background(), nil, "http://adapter", "generate-manifest")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("through the client", func() {
+		var client *serviceadapter.Client
+
+		BeforeEach(func() {
+			client = &serviceadapter.Client{CommandRunner: runner}
+		})
+
+		It("keeps the meaning of the exit codes", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"stdout": "", "stderr": "", "exit_code": 10}`))
+
+			_, err := client.GenerateDashboardUrl(context.Background(), "instance-id", sdk.Plan{}, []byte("name: a-manifest"), log.New(ioutil.Discard, "", 0))
+
+			Expect(err).To(BeAssignableToTypeOf(serviceadapter.NotImplementedError{}))
+		})
+
+		It("validates the outputs", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"stdout": "name: another-deployment", "stderr": "", "exit_code": 0}`))
+
+			_, err := client.GenerateManifest(
+				context.Background(),
+				sdk.ServiceDeployment{DeploymentName: "a-deployment"},
+				sdk.Plan{},
+				nil,
+				nil,
+				nil,
+				log.New(ioutil.Discard, "", 0),
+			)
+
+			Expect(err).To(MatchError(ContainSubstring("incorrect deployment name")))
+		})
+
+		It("names the adapter by its URL in errors", func() {
+			adapterURL := server.URL()
+			server.Close()
+
+			_, err := client.GenerateDashboardUrl(context.Background(), "instance-id", sdk.Plan{}, []byte("name: a-manifest"), log.New(ioutil.Discard, "", 0))
+
+			Expect(err).To(MatchError(ContainSubstring("external service adapter at " + adapterURL + ":")))
+		})
+	})
+
+	Describe("checking health", func() {
+		It("succeeds when the adapter is healthy", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", serviceadapter.HealthPath),
+				ghttp.RespondWith(http.StatusOK, ""),
+			))
+
+			client := &serviceadapter.Client{CommandRunner: runner}
+			Expect(client.CheckHealth()).To(Succeed())
+		})
+
+		It("fails when the adapter is not healthy", func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusServiceUnavailable, ""))
+
+			Expect(runner.CheckHealth()).To(MatchError(ContainSubstring("health check responded with status 503")))
+		})
+
+		It("has nothing to check for an adapter that is executed", func() {
+			client := &serviceadapter.Client{ExternalBinPath: "/path/to/adapter", CommandRunner: serviceadapter.NewCommandRunner()}
+			Expect(client.CheckHealth()).To(Succeed())
+		})
+	})
+
+	Describe("waiting for the adapter to become healthy", func() {
+		var (
+			client    *serviceadapter.Client
+			logBuffer *gbytes.Buffer
+			logger    *log.Logger
+		)
+
+		BeforeEach(func() {
+			client = &serviceadapter.Client{CommandRunner: runner}
+			logBuffer = gbytes.NewBuffer()
+			logger = log.New(logBuffer, "", 0)
+		})
+
+		It("retries until the adapter is healthy", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+				ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+				ghttp.RespondWith(http.StatusOK, ""),
+			)
+
+			Expect(client.WaitUntilHealthy(time.Millisecond, time.Second, logger)).To(Succeed())
+			Expect(server.ReceivedRequests()).To(HaveLen(3))
+			Expect(logBuffer).To(gbytes.Say("waiting 1ms for the service adapter to become healthy"))
+			Expect(logBuffer).To(gbytes.Say("waiting 2ms for the service adapter to become healthy"))
+		})
+
+		It("gives up once it has waited for long enough", func() {
+			server.RouteToHandler("GET", serviceadapter.HealthPath, ghttp.RespondWith(http.StatusServiceUnavailable, ""))
+
+			err := client.WaitUntilHealthy(10*time.Millisecond, 50*time.Millisecond, logger)
+
+			Expect(err).To(MatchError(ContainSubstring("health check responded with status 503")))
+			Expect(len(server.ReceivedRequests())).To(BeNumerically("<", 4))
+		})
+	})
+
+	Describe("monitoring the adapter's health", func() {
+		It("logs when the adapter stops and starts being healthy", func() {
+			server.AllowUnhandledRequests = true
+			server.UnhandledRequestStatusCode = http.StatusOK
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, ""),
+				ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+				ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+			)
+			logBuffer := gbytes.NewBuffer()
+			client := &serviceadapter.Client{CommandRunner: runner}
+
+			stop := make(chan struct{})
+			defer close(stop)
+			go client.MonitorHealth(time.Millisecond, stop, log.New(logBuffer, "", 0))
+
+			Eventually(logBuffer).Should(gbytes.Say("service adapter is unhealthy: .*health check responded with status 503"))
+			Eventually(logBuffer).Should(gbytes.Say("service adapter at " + server.URL() + " is healthy again"))
+			Consistently(logBuffer, 20*time.Millisecond).ShouldNot(gbytes.Say("unhealthy"))
+		})
+	})
+})
@@ -68,7 +68,7 @@ func (b *BoshHelperClient) RunErrandWithoutCheckingSuccess(deploymentName, erran
 }
 
 func (b *BoshHelperClient) runErrandAndWait(deploymentName, errandName, contextID string, logger *log.Logger) int {
-	taskID, err := b.Client.RunErrand(deploymentName, errandName, contextID, logger)
+	taskID, err := b.Client.RunErrand(deploymentName, errandName, nil, contextID, logger)
 	Expect(err).NotTo(HaveOccurred())
 	b.waitForTaskToFinish(taskID)
 	return taskID
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"log"
 	"sync"
 
@@ -9,9 +10,10 @@ import (
 )
 
 type FakeManifestGenerator struct {
-	GenerateManifestStub        func(deploymentName, planID string, requestParams map[string]interface{}, oldManifest []byte, previousPlanID *string, logger *log.Logger) (task.RawBoshManifest, error)
+	GenerateManifestStub        func(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, oldManifest []byte, previousPlanID *string, logger *log.Logger) (task.RawBoshManifest, error)
 	generateManifestMutex       sync.RWMutex
 	generateManifestArgsForCall []struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		requestParams  map[string]interface{}
@@ -31,7 +33,7 @@ type FakeManifestGenerator struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeManifestGenerator) GenerateManifest(deploymentName string, planID string, requestParams map[string]interface{}, oldManifest []byte, previousPlanID *string, logger *log.Logger) (task.RawBoshManifest, error) {
+func (fake *FakeManifestGenerator) GenerateManifest(ctx context.Context, deploymentName string, planID string, requestParams map[string]interface{}, oldManifest []byte, previousPlanID *string, logger *log.Logger) (task.RawBoshManifest, error) {
 	var oldManifestCopy []byte
 	if oldManifest != nil {
 		oldManifestCopy = make([]byte, len(oldManifest))
@@ -40,17 +42,18 @@ func (fake *FakeManifestGenerator) GenerateManifest(deploymentName string, planI
 	fake.generateManifestMutex.Lock()
 	ret, specificReturn := fake.generateManifestReturnsOnCall[len(fake.generateManifestArgsForCall)]
 	fake.generateManifestArgsForCall = append(fake.generateManifestArgsForCall, struct {
+		ctx            context.Context
 		deploymentName string
 		planID         string
 		requestParams  map[string]interface{}
 		oldManifest    []byte
 		previousPlanID *string
 		logger         *log.Logger
-	}{deploymentName, planID, requestParams, oldManifestCopy, previousPlanID, logger})
-	fake.recordInvocation("GenerateManifest", []interface{}{deploymentName, planID, requestParams, oldManifestCopy, previousPlanID, logger})
+	}{ctx, deploymentName, planID, requestParams, oldManifestCopy, previousPlanID, logger})
+	fake.recordInvocation("GenerateManifest", []interface{}{ctx, deploymentName, planID, requestParams, oldManifestCopy, previousPlanID, logger})
 	fake.generateManifestMutex.Unlock()
 	if fake.GenerateManifestStub != nil {
-		return fake.GenerateManifestStub(deploymentName, planID, requestParams, oldManifest, previousPlanID, logger)
+		return fake.GenerateManifestStub(ctx, deploymentName, planID, requestParams, oldManifest, previousPlanID, logger)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -64,10 +67,10 @@ func (fake *FakeManifestGenerator) GenerateManifestCallCount() int {
 	return len(fake.generateManifestArgsForCall)
 }
 
-func (fake *FakeManifestGenerator) GenerateManifestArgsForCall(i int) (string, string, map[string]interface{}, []byte, *string, *log.Logger) {
+func (fake *FakeManifestGenerator) GenerateManifestArgsForCall(i int) (context.Context, string, string, map[string]interface{}, []byte, *string, *log.Logger) {
 	fake.generateManifestMutex.RLock()
 	defer fake.generateManifestMutex.RUnlock()
-	return fake.generateManifestArgsForCall[i].deploymentName, fake.generateManifestArgsForCall[i].planID, fake.generateManifestArgsForCall[i].requestParams, fake.generateManifestArgsForCall[i].oldManifest, fake.generateManifestArgsForCall[i].previousPlanID, fake.generateManifestArgsForCall[i].logger
+	return fake.generateManifestArgsForCall[i].ctx, fake.generateManifestArgsForCall[i].deploymentName, fake.generateManifestArgsForCall[i].planID, fake.generateManifestArgsForCall[i].requestParams, fake.generateManifestArgsForCall[i].oldManifest, fake.generateManifestArgsForCall[i].previousPlanID, fake.generateManifestArgsForCall[i].logger
 }
 
 func (fake *FakeManifestGenerator) GenerateManifestReturns(result1 task.RawBoshManifest, result2 error) {
@@ -7,15 +7,27 @@
 package task
 
 import (
+	"context"
+	"fmt"
 	"log"
 
+	"github.com/pivotal-cf/on-demand-service-broker/brokercontext"
 	"github.com/pivotal-cf/on-demand-service-broker/config"
 	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"gopkg.in/yaml.v2"
+)
+
+// BOSH deployment tags identifying the tenant of a service instance.
+const (
+	PlatformTag         = "platform"
+	OrganizationGUIDTag = "organization_guid"
+	SpaceGUIDTag        = "space_guid"
 )
 
 //go:generate counterfeiter -o fakes/fake_service_adapter_client.go . ServiceAdapterClient
 type ServiceAdapterClient interface {
 	GenerateManifest(
+		ctx context.Context,
 		serviceReleases serviceadapter.ServiceDeployment,
 		plan serviceadapter.Plan,
 		requestParams map[string]interface{},
@@ -48,6 +60,7 @@ func NewManifestGenerator(
 type RawBoshManifest []byte
 
 func (m manifestGenerator) GenerateManifest(
+	ctx context.Context,
 	deploymentName, planID string,
 	requestParams map[string]interface{},
 	oldManifest []byte,
@@ -69,12 +82,89 @@ func (m manifestGenerator) GenerateManifest(
 
 	logger.Printf("service adapter will generate manifest for deployment %s\n", deploymentName)
 
-	manifest, err := m.adapterClient.GenerateManifest(serviceDeployment, plan, requestParams, oldManifest, previousPlan, logger)
+	manifest, err := m.adapterClient.GenerateManifest(ctx, serviceDeployment, plan, requestParams, oldManifest, previousPlan, logger)
 	if err != nil {
 		logger.Printf("generate manifest: %v\n", err)
+		return manifest, err
+	}
+
+	return withTenantTags(manifest, brokercontext.TenantFromRequestParams(requestParams), oldManifest)
+}
+
+// withTenantTags tags the deployment with the tenant it belongs to. Requests
+// that do not carry the tenant, such as upgrades, keep the tags already on the
+// deployment.
+func withTenantTags(manifest []byte, tenant brokercontext.Tenant, oldManifest []byte) ([]byte, error) {
+	tags := previousTenantTags(oldManifest)
+	setTag(tags, PlatformTag, tenant.Platform)
+	setTag(tags, OrganizationGUIDTag, tenant.OrganizationGUID)
+	setTag(tags, SpaceGUIDTag, tenant.SpaceGUID)
+
+	if len(tags) == 0 {
+		return manifest, nil
+	}
+
+	var content yaml.MapSlice
+	if err := yaml.Unmarshal(manifest, &content); err != nil {
+		return nil, fmt.Errorf("adding tenant tags to manifest: %s", err)
+	}
+
+	tagsIndex := -1
+	manifestTags := yaml.MapSlice{}
+	for i, item := range content {
+		if item.Key == "tags" {
+			tagsIndex = i
+			manifestTags, _ = item.Value.(yaml.MapSlice)
+		}
+	}
+
+	for _, key := range []string{PlatformTag, OrganizationGUIDTag, SpaceGUIDTag} {
+		if value, ok := tags[key]; ok {
+			manifestTags = setMapSliceValue(manifestTags, key, value)
+		}
+	}
+
+	if tagsIndex == -1 {
+		content = append(content, yaml.MapItem{Key: "tags", Value: manifestTags})
+	} else {
+		content[tagsIndex].Value = manifestTags
 	}
 
-	return manifest, err
+	return yaml.Marshal(content)
+}
+
+func previousTenantTags(oldManifest []byte) map[string]string {
+	tags := map[string]string{}
+
+	var previous struct {
+		Tags map[string]interface{} `yaml:"tags"`
+	}
+	if err := yaml.Unmarshal(oldManifest, &previous); err != nil {
+		return tags
+	}
+
+	for _, key := range []string{PlatformTag, OrganizationGUIDTag, SpaceGUIDTag} {
+		if value, ok := previous.Tags[key].(string); ok {
+			setTag(tags, key, value)
+		}
+	}
+	return tags
+}
+
+func setTag(tags map[string]string, key, value string) {
+	if value != "" {
+		tags[key] = value
+	}
+}
+
+func setMapSliceValue(slice yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range slice {
+		if item.Key == key {
+			slice[i].Value = value
+			return slice
+		}
+	}
+	return append(slice, yaml.MapItem{Key: key, Value: value})
 }
 
 func (m manifestGenerator) findPlans(planID string, previousPlanID *string) (serviceadapter.Plan, *serviceadapter.Plan, error) {
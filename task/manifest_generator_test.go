@@ -7,6 +7,7 @@
 package task_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -138,7 +139,7 @@ var _ = Describe("Manifest Generator", func() {
 		})
 
 		JustBeforeEach(func() {
-			manifest, err = mg.GenerateManifest(deploymentName, planGUID, requestParams, oldManifest, previousPlanID, logger)
+			manifest, err = mg.GenerateManifest(context.Background(), deploymentName, planGUID, requestParams, oldManifest, previousPlanID, logger)
 		})
 
 		Context("when called with correct arguments", func() {
@@ -165,7 +166,7 @@ var _ = Describe("Manifest Generator", func() {
 			})
 
 			It("calls the service adapter with the service deployment", func() {
-				passedServiceDeployment, _, _, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+				_, passedServiceDeployment, _, _, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 				expectedServiceDeployment := serviceadapter.ServiceDeployment{
 					DeploymentName: deploymentName,
 					Releases:       serviceReleases,
@@ -175,22 +176,22 @@ var _ = Describe("Manifest Generator", func() {
 			})
 
 			It("calls the service adapter with the plan", func() {
-				_, passedPlan, _, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+				_, _, passedPlan, _, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 				Expect(passedPlan.InstanceGroups).To(Equal(existingPlan.InstanceGroups))
 			})
 
 			It("calls the service adapter with the request params", func() {
-				_, _, passedRequestParams, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+				_, _, _, passedRequestParams, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 				Expect(passedRequestParams).To(Equal(requestParams))
 			})
 
 			It("calls the service adapter with the old manifest", func() {
-				_, _, _, passedOldManifest, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+				_, _, _, _, passedOldManifest, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 				Expect(passedOldManifest).To(Equal(oldManifest))
 			})
 
 			It("merges global and plan properties", func() {
-				_, actualPlan, _, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+				_, _, actualPlan, _, _, _, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 				expectedProperties := serviceadapter.Properties{
 					"a_global_property":          "global_value",
 					"some_other_global_property": "other_global_value",
@@ -206,12 +207,12 @@ var _ = Describe("Manifest Generator", func() {
 				})
 
 				It("calls the service adapter with the previous plan", func() {
-					_, _, _, _, passedPreviousPlan, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+					_, _, _, _, _, passedPreviousPlan, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 					Expect(passedPreviousPlan.InstanceGroups).To(Equal(secondPlan.InstanceGroups))
 				})
 
 				It("merges global and previous plan properties, overriding global with plan props", func() {
-					_, _, _, _, previousPlan, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+					_, _, _, _, _, previousPlan, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 					expectedProperties := serviceadapter.Properties{
 						"a_global_property":          "overrides_global_value",
 						"some_other_global_property": "other_global_value",
@@ -228,7 +229,7 @@ var _ = Describe("Manifest Generator", func() {
 				})
 
 				It("calls the service adapter with the nil previous plan", func() {
-					_, _, _, _, passedPreviousPlan, _ := serviceAdapter.GenerateManifestArgsForCall(0)
+					_, _, _, _, _, passedPreviousPlan, _ := serviceAdapter.GenerateManifestArgsForCall(0)
 					Expect(passedPreviousPlan).To(BeNil())
 				})
 			})
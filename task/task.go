@@ -7,6 +7,7 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"reflect"
@@ -26,6 +27,7 @@ type BoshClient interface {
 //go:generate counterfeiter -o fakes/fake_manifest_generator.go . ManifestGenerator
 type ManifestGenerator interface {
 	GenerateManifest(
+		ctx context.Context,
 		deploymentName,
 		planID string,
 		requestParams map[string]interface{},
@@ -46,16 +48,16 @@ func NewDeployer(boshClient BoshClient, manifestGenerator ManifestGenerator) dep
 	}
 }
 
-func (d deployer) Create(deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error) {
+func (d deployer) Create(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error) {
 	err := d.assertNoOperationsInProgress(deploymentName, logger)
 	if err != nil {
 		return 0, nil, err
 	}
 
-	return d.doDeploy(deploymentName, planID, "create", requestParams, nil, nil, boshContextID, logger)
+	return d.doDeploy(ctx, deploymentName, planID, "create", requestParams, nil, nil, boshContextID, logger)
 }
 
-func (d deployer) Upgrade(deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error) {
+func (d deployer) Upgrade(ctx context.Context, deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error) {
 	err := d.assertNoOperationsInProgress(deploymentName, logger)
 	if err != nil {
 		return 0, nil, err
@@ -66,10 +68,11 @@ func (d deployer) Upgrade(deploymentName, planID string, previousPlanID *string,
 		return 0, nil, err
 	}
 
-	return d.doDeploy(deploymentName, planID, "upgrade", nil, oldManifest, previousPlanID, boshContextID, logger)
+	return d.doDeploy(ctx, deploymentName, planID, "upgrade", nil, oldManifest, previousPlanID, boshContextID, logger)
 }
 
 func (d deployer) Update(
+	ctx context.Context,
 	deploymentName,
 	planID string,
 	requestParams map[string]interface{},
@@ -86,11 +89,29 @@ func (d deployer) Update(
 		return 0, nil, err
 	}
 
-	if err := d.checkForPendingChanges(deploymentName, previousPlanID, oldManifest, logger); err != nil {
+	if err := d.checkForPendingChanges(ctx, deploymentName, previousPlanID, oldManifest, logger); err != nil {
 		return 0, nil, err
 	}
 
-	return d.doDeploy(deploymentName, planID, "update", requestParams, oldManifest, previousPlanID, boshContextID, logger)
+	return d.doDeploy(ctx, deploymentName, planID, "update", requestParams, oldManifest, previousPlanID, boshContextID, logger)
+}
+
+// GenerateManifest generates the manifest an update or upgrade of an
+// existing deployment would deploy, without deploying it.
+func (d deployer) GenerateManifest(
+	ctx context.Context,
+	deploymentName,
+	planID string,
+	requestParams map[string]interface{},
+	previousPlanID *string,
+	logger *log.Logger,
+) ([]byte, error) {
+	oldManifest, err := d.getDeploymentManifest(deploymentName, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.manifestGenerator.GenerateManifest(ctx, deploymentName, planID, requestParams, oldManifest, previousPlanID, logger)
 }
 
 func (d deployer) getDeploymentManifest(deploymentName string, logger *log.Logger) ([]byte, error) {
@@ -121,12 +142,13 @@ func (d deployer) assertNoOperationsInProgress(deploymentName string, logger *lo
 }
 
 func (d deployer) checkForPendingChanges(
+	ctx context.Context,
 	deploymentName string,
 	previousPlanID *string,
 	rawOldManifest RawBoshManifest,
 	logger *log.Logger,
 ) error {
-	regeneratedManifestContent, err := d.manifestGenerator.GenerateManifest(deploymentName, *previousPlanID, map[string]interface{}{}, rawOldManifest, previousPlanID, logger)
+	regeneratedManifestContent, err := d.manifestGenerator.GenerateManifest(ctx, deploymentName, *previousPlanID, map[string]interface{}{}, rawOldManifest, previousPlanID, logger)
 	if err != nil {
 		return err
 	}
@@ -155,6 +177,7 @@ func (d deployer) checkForPendingChanges(
 }
 
 func (d deployer) doDeploy(
+	ctx context.Context,
 	deploymentName,
 	planID string,
 	operationType string,
@@ -165,7 +188,7 @@ func (d deployer) doDeploy(
 	logger *log.Logger,
 ) (int, []byte, error) {
 
-	manifest, err := d.manifestGenerator.GenerateManifest(deploymentName, planID, requestParams, oldManifest, previousPlanID, logger)
+	manifest, err := d.manifestGenerator.GenerateManifest(ctx, deploymentName, planID, requestParams, oldManifest, previousPlanID, logger)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -7,6 +7,7 @@
 package task_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -19,9 +20,10 @@ import (
 )
 
 type deployer interface {
-	Create(deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error)
-	Update(deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
-	Upgrade(deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	Create(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, boshContextID string, logger *log.Logger) (int, []byte, error)
+	Update(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	Upgrade(ctx context.Context, deploymentName, planID string, previousPlanID *string, boshContextID string, logger *log.Logger) (int, []byte, error)
+	GenerateManifest(ctx context.Context, deploymentName, planID string, requestParams map[string]interface{}, previousPlanID *string, logger *log.Logger) ([]byte, error)
 }
 
 var _ = Describe("Deployer", func() {
@@ -63,6 +65,7 @@ var _ = Describe("Deployer", func() {
 	Describe("Create()", func() {
 		JustBeforeEach(func() {
 			returnedTaskID, deployedManifest, deployError = deployer.Create(
+				context.Background(),
 				deploymentName,
 				planID,
 				requestParams,
@@ -96,7 +99,7 @@ var _ = Describe("Deployer", func() {
 
 			It("calls new manifest with correct params", func() {
 				Expect(manifestGenerator.GenerateManifestCallCount()).To(Equal(1))
-				passedDeploymentName, passedPlanID, passedRequestParams, passedPreviousManifest, passedPreviousPlanID, _ := manifestGenerator.GenerateManifestArgsForCall(0)
+				_, passedDeploymentName, passedPlanID, passedRequestParams, passedPreviousManifest, passedPreviousPlanID, _ := manifestGenerator.GenerateManifestArgsForCall(0)
 
 				Expect(passedDeploymentName).To(Equal(deploymentName))
 				Expect(passedPlanID).To(Equal(planID))
@@ -252,6 +255,7 @@ var _ = Describe("Deployer", func() {
 	Describe("Upgrade()", func() {
 		JustBeforeEach(func() {
 			returnedTaskID, deployedManifest, deployError = deployer.Upgrade(
+				context.Background(),
 				deploymentName,
 				planID,
 				previousPlanID,
@@ -297,7 +301,7 @@ var _ = Describe("Deployer", func() {
 
 			It("calls new manifest with correct params", func() {
 				Expect(manifestGenerator.GenerateManifestCallCount()).To(Equal(1))
-				passedDeploymentName, passedPlanID, passedRequestParams, passedPreviousManifest, passedPreviousPlanID, _ := manifestGenerator.GenerateManifestArgsForCall(0)
+				_, passedDeploymentName, passedPlanID, passedRequestParams, passedPreviousManifest, passedPreviousPlanID, _ := manifestGenerator.GenerateManifestArgsForCall(0)
 
 				Expect(passedDeploymentName).To(Equal(deploymentName))
 				Expect(passedPlanID).To(Equal(planID))
@@ -512,6 +516,7 @@ var _ = Describe("Deployer", func() {
 
 			It("wraps the error", func() {
 				returnedTaskID, deployedManifest, deployError = deployer.Update(
+					context.Background(),
 					deploymentName,
 					planID,
 					requestParams,
@@ -529,6 +534,7 @@ var _ = Describe("Deployer", func() {
 				BeforeEach(func() {
 					requestParams = map[string]interface{}{"foo": "bar"}
 					manifestGenerator.GenerateManifestStub = func(
+						_ context.Context,
 						_, _ string,
 						requestParams map[string]interface{},
 						previousManifest []byte,
@@ -546,6 +552,7 @@ var _ = Describe("Deployer", func() {
 
 				It("deploys successfully", func() {
 					returnedTaskID, deployedManifest, deployError = deployer.Update(
+						context.Background(),
 						deploymentName,
 						planID,
 						requestParams,
@@ -564,10 +571,10 @@ var _ = Describe("Deployer", func() {
 
 					Expect(manifestGenerator.GenerateManifestCallCount()).To(Equal(2))
 
-					_, _, passedRequestParams, _, _, _ := manifestGenerator.GenerateManifestArgsForCall(0)
+					_, _, _, passedRequestParams, _, _, _ := manifestGenerator.GenerateManifestArgsForCall(0)
 					Expect(passedRequestParams).To(BeEmpty())
 
-					_, _, passedRequestParams, _, _, _ = manifestGenerator.GenerateManifestArgsForCall(1)
+					_, _, _, passedRequestParams, _, _, _ = manifestGenerator.GenerateManifestArgsForCall(1)
 					Expect(passedRequestParams).To(Equal(requestParams))
 
 					Expect(boshClient.DeployCallCount()).To(Equal(1))
@@ -582,6 +589,7 @@ var _ = Describe("Deployer", func() {
 						requestParams = map[string]interface{}{}
 
 						returnedTaskID, deployedManifest, deployError = deployer.Update(
+							context.Background(),
 							deploymentName,
 							planID,
 							requestParams,
@@ -598,6 +606,7 @@ var _ = Describe("Deployer", func() {
 			Context("and the manifest generator fails to generate the manifest the second time", func() {
 				BeforeEach(func() {
 					manifestGenerator.GenerateManifestStub = func(
+						_ context.Context,
 						_, _ string,
 						requestParams map[string]interface{},
 						previousManifest []byte,
@@ -613,6 +622,7 @@ var _ = Describe("Deployer", func() {
 
 				It("wraps the error", func() {
 					returnedTaskID, deployedManifest, deployError = deployer.Update(
+						context.Background(),
 						deploymentName,
 						planID,
 						requestParams,
@@ -634,6 +644,7 @@ var _ = Describe("Deployer", func() {
 
 			It("fails without deploying", func() {
 				returnedTaskID, deployedManifest, deployError = deployer.Update(
+					context.Background(),
 					deploymentName,
 					planID,
 					requestParams,
@@ -655,6 +666,7 @@ var _ = Describe("Deployer", func() {
 
 			It("returns a deployment not found error", func() {
 				returnedTaskID, deployedManifest, deployError = deployer.Update(
+					context.Background(),
 					deploymentName,
 					planID,
 					requestParams,
@@ -675,6 +687,7 @@ var _ = Describe("Deployer", func() {
 
 			It("wraps the error", func() {
 				returnedTaskID, deployedManifest, deployError = deployer.Update(
+					context.Background(),
 					deploymentName,
 					planID,
 					requestParams,
@@ -694,6 +707,7 @@ var _ = Describe("Deployer", func() {
 
 			It("returns a deployment not found error", func() {
 				returnedTaskID, deployedManifest, deployError = deployer.Update(
+					context.Background(),
 					deploymentName,
 					planID,
 					requestParams,
@@ -718,6 +732,7 @@ var _ = Describe("Deployer", func() {
 			boshClient.DeployReturns(42, nil)
 
 			returnedTaskID, deployedManifest, deployError = deployer.Update(
+				context.Background(),
 				deploymentName,
 				planID,
 				requestParams,
@@ -729,7 +744,7 @@ var _ = Describe("Deployer", func() {
 			Expect(deployError).To(BeNil())
 
 			Expect(manifestGenerator.GenerateManifestCallCount()).To(Equal(2))
-			_, _, passedRequestParams, _, _, _ := manifestGenerator.GenerateManifestArgsForCall(1)
+			_, _, _, passedRequestParams, _, _, _ := manifestGenerator.GenerateManifestArgsForCall(1)
 			Expect(passedRequestParams).To(Equal(requestParams))
 
 			manifestToDeploy, _, _ := boshClient.DeployArgsForCall(0)
@@ -743,6 +758,7 @@ var _ = Describe("Deployer", func() {
 			manifestGenerator.GenerateManifestReturns(generatedManifest, nil)
 
 			_, _, deployError = deployer.Update(
+				context.Background(),
 				deploymentName,
 				planID,
 				requestParams,
@@ -769,6 +785,7 @@ var _ = Describe("Deployer", func() {
 			boshClient.DeployReturns(42, nil)
 
 			returnedTaskID, deployedManifest, deployError = deployer.Update(
+				context.Background(),
 				deploymentName,
 				planID,
 				requestParams,
@@ -794,6 +811,7 @@ var _ = Describe("Deployer", func() {
 			boshClient.DeployReturns(42, nil)
 
 			returnedTaskID, deployedManifest, deployError = deployer.Update(
+				context.Background(),
 				deploymentName,
 				planID,
 				requestParams,
@@ -807,6 +825,58 @@ var _ = Describe("Deployer", func() {
 		})
 
 	})
+
+	Describe("GenerateManifest()", func() {
+		var (
+			generatedManifest []byte
+			generateErr       error
+		)
+
+		BeforeEach(func() {
+			oldManifest = []byte("---\nold-manifest-fetched-from-bosh: bar")
+			previousPlanID = stringPointer(existingPlanID)
+
+			boshClient.GetDeploymentReturns(oldManifest, true, nil)
+			manifestGenerator.GenerateManifestReturns(manifest, nil)
+		})
+
+		JustBeforeEach(func() {
+			generatedManifest, generateErr = deployer.GenerateManifest(
+				context.Background(),
+				deploymentName,
+				planID,
+				requestParams,
+				previousPlanID,
+				logger,
+			)
+		})
+
+		It("generates the manifest from the deployed one without deploying", func() {
+			Expect(generateErr).NotTo(HaveOccurred())
+			Expect(generatedManifest).To(Equal(manifest))
+
+			Expect(manifestGenerator.GenerateManifestCallCount()).To(Equal(1))
+			_, actualDeploymentName, actualPlanID, actualRequestParams, actualOldManifest, actualPreviousPlanID, _ := manifestGenerator.GenerateManifestArgsForCall(0)
+			Expect(actualDeploymentName).To(Equal(deploymentName))
+			Expect(actualPlanID).To(Equal(planID))
+			Expect(actualRequestParams).To(Equal(requestParams))
+			Expect(actualOldManifest).To(Equal(oldManifest))
+			Expect(actualPreviousPlanID).To(Equal(previousPlanID))
+
+			Expect(boshClient.DeployCallCount()).To(Equal(0))
+		})
+
+		Context("when the deployment cannot be found", func() {
+			BeforeEach(func() {
+				boshClient.GetDeploymentReturns(nil, false, nil)
+			})
+
+			It("returns a deployment not found error", func() {
+				Expect(generateErr).To(MatchError(ContainSubstring("not found")))
+				Expect(manifestGenerator.GenerateManifestCallCount()).To(Equal(0))
+			})
+		})
+	})
 })
 
 func stringPointer(s string) *string {
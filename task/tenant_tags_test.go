@@ -0,0 +1,108 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+// This program and the accompanying materials are made available under the terms of the under the Apache License, Version 2.0 (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the specific language governing permissions and limitations under the License.
+
+package task_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-service-broker/config"
+	. "github.com/pivotal-cf/on-demand-service-broker/task"
+	"github.com/pivotal-cf/on-demand-service-broker/task/fakes"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("Manifest Generator tenant tags", func() {
+	var (
+		serviceAdapter *fakes.FakeServiceAdapterClient
+		requestParams  map[string]interface{}
+		oldManifest    []byte
+
+		manifest []byte
+		err      error
+	)
+
+	manifestTags := func(manifest []byte) map[string]interface{} {
+		var content struct {
+			Tags map[string]interface{} `yaml:"tags"`
+		}
+		Expect(yaml.Unmarshal(manifest, &content)).To(Succeed())
+		return content.Tags
+	}
+
+	BeforeEach(func() {
+		serviceAdapter = new(fakes.FakeServiceAdapterClient)
+		serviceAdapter.GenerateManifestReturns([]byte("name: some-deployment-name\ntags:\n  team: data\n"), nil)
+		requestParams = map[string]interface{}{
+			"organization_guid": "some-org",
+			"space_guid":        "some-space",
+			"context": map[string]interface{}{
+				"platform": "cloudfoundry",
+			},
+		}
+		oldManifest = nil
+	})
+
+	JustBeforeEach(func() {
+		mg := NewManifestGenerator(
+			serviceAdapter,
+			config.ServiceOffering{Plans: []config.Plan{{ID: existingPlanID}}},
+			serviceadapter.Stemcell{},
+			serviceadapter.ServiceReleases{},
+		)
+		manifest, err = mg.GenerateManifest(context.Background(), deploymentName, existingPlanID, requestParams, oldManifest, nil, logger)
+	})
+
+	It("tags the deployment with the tenant alongside the adapter's tags", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifestTags(manifest)).To(Equal(map[string]interface{}{
+			"team":              "data",
+			PlatformTag:         "cloudfoundry",
+			OrganizationGUIDTag: "some-org",
+			SpaceGUIDTag:        "some-space",
+		}))
+	})
+
+	Context("when the request does not carry the tenant", func() {
+		BeforeEach(func() {
+			requestParams = map[string]interface{}{}
+			oldManifest = []byte("name: some-deployment-name\ntags:\n  organization_guid: old-org\n  space_guid: old-space\n  team: ops\n")
+		})
+
+		It("keeps the tenant tags of the existing deployment", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifestTags(manifest)).To(Equal(map[string]interface{}{
+				"team":              "data",
+				OrganizationGUIDTag: "old-org",
+				SpaceGUIDTag:        "old-space",
+			}))
+		})
+	})
+
+	Context("when there is no tenant at all", func() {
+		BeforeEach(func() {
+			requestParams = map[string]interface{}{}
+		})
+
+		It("returns the adapter's manifest untouched", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest).To(Equal([]byte("name: some-deployment-name\ntags:\n  team: data\n")))
+		})
+	})
+
+	Context("when the adapter's manifest is not valid YAML", func() {
+		BeforeEach(func() {
+			serviceAdapter.GenerateManifestReturns([]byte("{not yaml"), nil)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("adding tenant tags to manifest")))
+		})
+	})
+})